@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"taskd/internal/cli"
+	"taskd/internal/config"
 	"taskd/internal/task"
 )
 
@@ -45,28 +46,87 @@ func main() {
 func runDaemonMode() {
 	fmt.Println("Starting TaskD daemon...")
 	
+	// The daemon owns every task process it starts
+	manager := task.GetManager()
+	manager.SetDaemonMode(true)
+	
+	// Forget the runtime state of tasks deleted while no daemon was running
+	if err := manager.CleanupRuntimeState(); err != nil {
+		fmt.Printf("Warning: failed to clean up runtime state: %v\n", err)
+	}
+	
+	// Serve CLI requests over the IPC socket
+	server := task.NewDaemonServer(manager)
+	if err := server.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to start daemon server: %v\n", err)
+		os.Exit(1)
+	}
+	
+	if err := task.GetDaemonManager().RecordDaemonStarted(); err != nil {
+		fmt.Printf("Warning: failed to record daemon state: %v\n", err)
+	}
+	
+	// Serve the Prometheus metrics when a port is configured
+	var metrics *task.MetricsServer
+	if port := config.GetMetricsPort(); port > 0 {
+		metrics = task.NewMetricsServer(manager, port)
+		if err := metrics.Start(); err != nil {
+			fmt.Printf("Warning: failed to start metrics server: %v\n", err)
+			metrics = nil
+		}
+	}
+	
 	// Initialize task monitor with 5 second check interval
 	monitor := task.NewTaskMonitor(5 * time.Second)
 	
+	// Start scheduled tasks at the ticks of their schedule
+	scheduler := task.NewScheduler()
+	scheduler.Start()
+	
 	// Set up signal handling for graceful shutdown
-	setupSignalHandling(monitor)
+	shutdownDone := setupSignalHandling(monitor, scheduler, server, metrics, manager)
 	
-	// Start monitoring
+	// Start monitoring, until the shutdown stops the monitor
 	monitor.Start()
+	
+	// The tasks are still being stopped, wait for the shutdown to complete
+	<-shutdownDone
 }
 
 // setupSignalHandling sets up signal handling for graceful daemon shutdown
-func setupSignalHandling(monitor *task.TaskMonitor) {
+// The returned channel is closed once the shutdown has completed.
+func setupSignalHandling(monitor *task.TaskMonitor, scheduler *task.Scheduler, server *task.DaemonServer, metrics *task.MetricsServer, manager *task.Manager) <-chan struct{} {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	
+	done := make(chan struct{})
 	go func() {
-		sig := <-sigChan
-		fmt.Printf("Received signal %v, shutting down daemon...\n", sig)
+		defer close(done)
+		
+		select {
+		case sig := <-sigChan:
+			fmt.Printf("Received signal %v, shutting down daemon...\n", sig)
+		case <-server.ShutdownRequested():
+			fmt.Println("Shutdown requested, shutting down daemon...")
+		}
 		
-		// Stop monitoring gracefully
+		// Stop scheduling and monitoring first so stopped tasks are not started again
+		scheduler.Stop()
 		monitor.Stop()
 		
-		os.Exit(0)
+		// The daemon can only supervise processes it started, so stop them all
+		manager.StopAllTasks()
+		
+		// Stop answering requests only after the tasks are gone, so clients
+		// waiting for the daemon to exit know the shutdown is complete
+		server.Stop()
+		if metrics != nil {
+			metrics.Stop()
+		}
+		
+		if err := task.GetDaemonManager().RecordDaemonStopped(); err != nil {
+			fmt.Printf("Warning: failed to record daemon state: %v\n", err)
+		}
 	}()
+	return done
 }
\ No newline at end of file
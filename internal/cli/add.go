@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"taskd/internal/task"
@@ -25,23 +26,73 @@ var addCmd = &cobra.Command{
 		
 		// Get command line arguments
 		exec, _ := cmd.Flags().GetString("exec")
+		shell, _ := cmd.Flags().GetBool("shell")
 		workdir, _ := cmd.Flags().GetString("workdir")
 		env, _ := cmd.Flags().GetStringSlice("env")
+		envFile, _ := cmd.Flags().GetStringSlice("env-file")
 		inheritEnv, _ := cmd.Flags().GetBool("inherit-env")
 		stdin, _ := cmd.Flags().GetString("stdin")
 		stdout, _ := cmd.Flags().GetString("stdout")
 		stderr, _ := cmd.Flags().GetString("stderr")
 		displayName, _ := cmd.Flags().GetString("display-name")
 		description, _ := cmd.Flags().GetString("description")
+		restartPolicy, _ := cmd.Flags().GetString("restart")
+		maxRetry, _ := cmd.Flags().GetInt("max-retry")
+		restartDelay, _ := cmd.Flags().GetString("restart-delay")
+		capture, _ := cmd.Flags().GetString("capture")
+		stopSignal, _ := cmd.Flags().GetString("stop-signal")
+		stopTimeout, _ := cmd.Flags().GetString("stop-timeout")
+		schedule, _ := cmd.Flags().GetString("schedule")
+		scheduleOverlap, _ := cmd.Flags().GetString("schedule-overlap")
+		scheduleJitter, _ := cmd.Flags().GetString("schedule-jitter")
+		requires, _ := cmd.Flags().GetStringSlice("requires")
+		wants, _ := cmd.Flags().GetStringSlice("wants")
+		after, _ := cmd.Flags().GetStringSlice("after")
+		ready, _ := cmd.Flags().GetString("ready")
+		readyTimeout, _ := cmd.Flags().GetString("ready-timeout")
+		liveness, _ := cmd.Flags().GetString("liveness")
+		livenessInterval, _ := cmd.Flags().GetString("liveness-interval")
+		livenessThreshold, _ := cmd.Flags().GetInt("liveness-threshold")
+		memory, _ := cmd.Flags().GetString("memory")
+		cpu, _ := cmd.Flags().GetFloat64("cpu")
+		cpuWeight, _ := cmd.Flags().GetInt("cpu-weight")
+		openFiles, _ := cmd.Flags().GetInt("open-files")
+		processes, _ := cmd.Flags().GetInt("processes")
+		nice, _ := cmd.Flags().GetInt("nice")
 		
 		// Validate executable
 		if err := validateExecutable(exec); err != nil {
 			return fmt.Errorf("invalid executable: %w", err)
 		}
+		executable, execArgs, err := parseCommandLine(exec, shell)
+		if err != nil {
+			return fmt.Errorf("invalid executable: %w", err)
+		}
+		
+		// Validate environment variables
+		if err := validateEnvironmentVariables(env); err != nil {
+			return fmt.Errorf("invalid environment variables: %w", err)
+		}
+		
+		// Variables are expanded when the task starts, validate what they resolve to now
+		resolved, err := (&task.Config{
+			Executable: executable,
+			Args:       execArgs,
+			Shell:      shell,
+			WorkDir:    workdir,
+			Env:        env,
+			EnvFile:    envFile,
+			Stdin:      stdin,
+			Stdout:     stdout,
+			Stderr:     stderr,
+		}).Expand(taskName, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid variable: %w", err)
+		}
 		
 		// Validate working directory
-		if workdir != "" {
-			if err := validateWorkingDirectory(workdir); err != nil {
+		if resolved.WorkDir != "" {
+			if err := validateWorkingDirectory(resolved.WorkDir); err != nil {
 				return fmt.Errorf("invalid working directory: %w", err)
 			}
 		}
@@ -50,34 +101,120 @@ var addCmd = &cobra.Command{
 		if workdir == "" {
 			if homeDir, err := os.UserHomeDir(); err == nil {
 				workdir = homeDir
+				resolved.WorkDir = homeDir
 			}
 		}
 		
-		// Validate environment variables
-		if err := validateEnvironmentVariables(env); err != nil {
-			return fmt.Errorf("invalid environment variables: %w", err)
+		// Validate restart policy
+		restart := task.RestartPolicy{
+			Policy:   restartPolicy,
+			MaxRetry: maxRetry,
+			Delay:    restartDelay,
+		}
+		if err := validateRestartOptions(restart); err != nil {
+			return fmt.Errorf("invalid restart policy: %w", err)
+		}
+		
+		// Validate stop settings
+		if err := validateStopOptions(stopSignal, stopTimeout); err != nil {
+			return err
+		}
+		if stopSignal != "" {
+			stopSignal, _ = task.NormalizeStopSignal(stopSignal)
+		}
+		
+		// Validate schedule
+		if err := validateScheduleOptions(schedule, scheduleOverlap, scheduleJitter); err != nil {
+			return err
+		}
+		
+		// Validate readiness probe
+		readiness, err := parseReadinessProbe(ready, readyTimeout)
+		if err != nil {
+			return err
+		}
+		if err := task.ValidateReadinessProbe(readiness, stdout); err != nil {
+			return fmt.Errorf("invalid readiness probe: %w", err)
+		}
+		
+		// Validate liveness probe
+		livenessProbe, err := parseLivenessProbe(liveness)
+		if err != nil {
+			return err
+		}
+		livenessProbe.Interval = livenessInterval
+		livenessProbe.FailureThreshold = livenessThreshold
+		if err := task.ValidateLivenessProbe(livenessProbe); err != nil {
+			return fmt.Errorf("invalid liveness probe: %w", err)
+		}
+		
+		// Validate resource limits
+		limits := task.ResourceLimits{
+			Memory:    memory,
+			CPU:       cpu,
+			CPUWeight: cpuWeight,
+			OpenFiles: openFiles,
+			Processes: processes,
+			Nice:      nice,
+		}
+		if err := task.ValidateResourceLimits(limits); err != nil {
+			return fmt.Errorf("invalid limits: %w", err)
+		}
+		
+		// Validate capture format
+		if err := task.ValidateCaptureFormat(capture); err != nil {
+			return fmt.Errorf("invalid capture format: %w", err)
 		}
 		
 		// Validate IO redirection paths
-		if err := validateIOPaths(stdin, stdout, stderr, workdir); err != nil {
+		if err := validateIOPaths(resolved.Stdin, resolved.Stdout, resolved.Stderr, resolved.WorkDir); err != nil {
 			return fmt.Errorf("invalid IO redirection: %w", err)
 		}
 		
+		// Validate env files and secret references
+		if err := validateEnvSources(resolved); err != nil {
+			return fmt.Errorf("invalid environment: %w", err)
+		}
+		
 		// Check for configuration conflicts
-		if err := validateConfigurationConflicts(taskName, exec, stdin, stdout, stderr); err != nil {
+		if err := validateConfigurationConflicts(taskName, commandProgram(exec, shell), stdin, stdout, stderr); err != nil {
 			return fmt.Errorf("configuration conflict: %w", err)
 		}
 		
 		taskConfig := &task.Config{
 			DisplayName: displayName,
 			Description: description,
-			Executable:  exec,
+			Executable:  executable,
+			Args:        execArgs,
+			Shell:       shell,
 			WorkDir:     workdir,
 			Env:         env,
+			EnvFile:     envFile,
 			InheritEnv:  inheritEnv,
 			Stdin:       stdin,
 			Stdout:      stdout,
 			Stderr:      stderr,
+			StopSignal:  stopSignal,
+			StopTimeout: stopTimeout,
+			Restart:     restart,
+			Log:         task.LogConfig{Capture: capture},
+			
+			Schedule:        schedule,
+			ScheduleOverlap: scheduleOverlap,
+			ScheduleJitter:  scheduleJitter,
+			
+			Requires: requires,
+			Wants:    wants,
+			After:    after,
+			
+			Readiness: readiness,
+			Liveness:  livenessProbe,
+			Limits:    limits,
+		}
+		
+		// Validate dependencies
+		if err := validateDependencies(taskName, taskConfig); err != nil {
+			return err
 		}
 		
 		// Display configuration warnings before adding the task
@@ -87,6 +224,18 @@ var addCmd = &cobra.Command{
 			return fmt.Errorf("failed to add task: %w", err)
 		}
 		
+		// Scheduled tasks are started by the daemon
+		if schedule != "" {
+			if err := task.GetDaemonManager().EnsureDaemonRunning(); err != nil {
+				fmt.Printf("Warning: failed to start the daemon, the task will not run on schedule until it is started: %v\n", err)
+			}
+		}
+		
+		// Let a running daemon pick up the new task
+		if err := task.NewDaemonClient().ReloadTask(taskName); err != nil {
+			fmt.Printf("Warning: failed to register task with daemon: %v\n", err)
+		}
+		
 		// Display success message with helpful information
 		displayTaskAddedSuccess(taskName, taskConfig)
 		return nil
@@ -96,15 +245,40 @@ var addCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(addCmd)
 	
-	addCmd.Flags().StringP("exec", "e", "", "executable path and arguments (required)")
+	addCmd.Flags().StringP("exec", "e", "", "executable path and arguments, quoted like in a shell (required)")
+	addCmd.Flags().Bool("shell", false, "run the executable as a command line of sh -c (cmd /c on Windows)")
 	addCmd.Flags().StringP("workdir", "w", "", "working directory")
-	addCmd.Flags().StringSliceP("env", "E", nil, "environment variables (format: KEY=VALUE)")
+	addCmd.Flags().StringSliceP("env", "E", nil, "environment variables (format: KEY=VALUE, or KEY=secret://NAME for a secret)")
+	addCmd.Flags().StringSlice("env-file", nil, "dotenv files read when the task starts (relative paths resolved from working directory)")
 	addCmd.Flags().BoolP("inherit-env", "i", true, "inherit system environment variables")
 	addCmd.Flags().String("stdin", "", "standard input file")
 	addCmd.Flags().String("stdout", "", "standard output redirect file (relative paths resolved from working directory)")
 	addCmd.Flags().String("stderr", "", "standard error redirect file (relative paths resolved from working directory)")
 	addCmd.Flags().String("display-name", "", "display name for the task (optional)")
 	addCmd.Flags().String("description", "", "description of the task (optional)")
+	addCmd.Flags().String("restart", "", "restart policy: always, on-failure or never (default: always if auto-start, otherwise never)")
+	addCmd.Flags().Int("max-retry", 0, "maximum number of automatic restarts (0 means unlimited)")
+	addCmd.Flags().String("restart-delay", "", "delay before an automatic restart (e.g. 5s, 1m)")
+	addCmd.Flags().String("stop-signal", "", "signal sent first when stopping the task (default: SIGTERM)")
+	addCmd.Flags().String("stop-timeout", "", "how long to wait for a graceful exit before killing the task (default: 10s)")
+	addCmd.Flags().String("capture", "", "write output lines with a timestamp and stream tag: text or json")
+	addCmd.Flags().String("schedule", "", "start the task on a schedule: a cron expression (e.g. \"0 3 * * *\") or an interval (e.g. \"@every 1h\")")
+	addCmd.Flags().String("schedule-overlap", "", "when the task is still running at a scheduled run: skip (default), queue or replace")
+	addCmd.Flags().String("schedule-jitter", "", "random delay of up to this duration added to each scheduled run (e.g. 5m)")
+	addCmd.Flags().StringSlice("requires", nil, "tasks started before this one, which does not start without them")
+	addCmd.Flags().StringSlice("wants", nil, "tasks started before this one, which starts even if they fail")
+	addCmd.Flags().StringSlice("after", nil, "tasks started before this one when both are started")
+	addCmd.Flags().String("ready", "", "readiness probe: tcp:ADDRESS, an http(s):// URL, log:REGEX or command:COMMAND")
+	addCmd.Flags().String("ready-timeout", "", "how long the task may take to become ready (default: 30s)")
+	addCmd.Flags().String("liveness", "", "liveness probe: tcp:ADDRESS, an http(s):// URL or command:COMMAND")
+	addCmd.Flags().String("liveness-interval", "", "time between liveness checks (default: 10s)")
+	addCmd.Flags().Int("liveness-threshold", 0, "consecutive failed liveness checks before the task is stopped (default: 3)")
+	addCmd.Flags().String("memory", "", "maximum memory of the task (e.g. 512M, 2G)")
+	addCmd.Flags().Float64("cpu", 0, "CPU quota of the task in cores (e.g. 0.5, 2)")
+	addCmd.Flags().Int("cpu-weight", 0, "share of the CPU relative to other tasks, 1-10000 (default: 100)")
+	addCmd.Flags().Int("open-files", 0, "maximum open files of each process of the task")
+	addCmd.Flags().Int("processes", 0, "maximum number of processes of the task")
+	addCmd.Flags().Int("nice", 0, "scheduling priority, -20 (highest) to 19 (lowest)")
 	
 	addCmd.MarkFlagRequired("exec")
 }
@@ -142,6 +316,39 @@ func validateExecutable(exec string) error {
 	return nil
 }
 
+// parseCommandLine splits the command line given with --exec into the executable
+// and arguments stored in the task configuration, so that the file is unambiguous
+// The arguments are never nil, so that an executable without arguments is stored
+// unquoted with an empty args list. In shell mode the command line is stored as it is.
+func parseCommandLine(commandLine string, shell bool) (string, []string, error) {
+	if shell {
+		return commandLine, nil, nil
+	}
+	
+	parts, err := task.SplitCommandLine(commandLine)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("executable cannot be empty")
+	}
+	return parts[0], parts[1:], nil
+}
+
+// commandProgram returns the lower-case name of the program a command line runs,
+// without directory and .exe extension, empty in shell mode
+func commandProgram(commandLine string, shell bool) string {
+	if shell {
+		return ""
+	}
+	parts, err := task.SplitCommandLine(commandLine)
+	if err != nil || len(parts) == 0 {
+		return ""
+	}
+	name := strings.ToLower(filepath.Base(strings.ReplaceAll(parts[0], `\`, "/")))
+	return strings.TrimSuffix(name, ".exe")
+}
+
 // validateWorkingDirectory validates the working directory
 func validateWorkingDirectory(workdir string) error {
 	if workdir == "" {
@@ -190,6 +397,143 @@ func validateEnvironmentVariables(envVars []string) error {
 	return nil
 }
 
+// validateEnvSources checks that the env files of a task can be read and warns about
+// secret references to secrets that are not set
+func validateEnvSources(config *task.Config) error {
+	store := task.NewSecretStore()
+	_, err := config.LoadEnv(func(name string) (string, error) {
+		if err := task.ValidateSecretName(name); err != nil {
+			return "", err
+		}
+		if _, err := store.Get(name); err != nil {
+			fmt.Printf("Warning: %v, set it with 'taskd secret set %s'\n", err, name)
+		}
+		return "", nil
+	})
+	return err
+}
+
+// validateRestartOptions validates the restart policy settings
+func validateRestartOptions(restart task.RestartPolicy) error {
+	if err := task.ValidateRestartPolicy(restart.Policy); err != nil {
+		return err
+	}
+	
+	if restart.MaxRetry < 0 {
+		return fmt.Errorf("max retry cannot be negative: %d", restart.MaxRetry)
+	}
+	
+	if err := task.ValidateRestartDelay(restart.Delay); err != nil {
+		return err
+	}
+	
+	return task.ValidateRestartBackoff(restart)
+}
+
+// validateStopOptions validates the stop signal and stop timeout of a task
+func validateStopOptions(stopSignal, stopTimeout string) error {
+	if err := task.ValidateStopSignal(stopSignal); err != nil {
+		return fmt.Errorf("invalid stop signal: %w", err)
+	}
+	if err := task.ValidateStopTimeout(stopTimeout); err != nil {
+		return fmt.Errorf("invalid stop timeout: %w", err)
+	}
+	return nil
+}
+
+// validateScheduleOptions validates the schedule, overlap policy and jitter of a task
+func validateScheduleOptions(schedule, overlap, jitter string) error {
+	if err := task.ValidateSchedule(schedule); err != nil {
+		return err
+	}
+	if err := task.ValidateScheduleOverlap(overlap); err != nil {
+		return fmt.Errorf("invalid schedule overlap: %w", err)
+	}
+	if err := task.ValidateScheduleJitter(jitter); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateDependencies rejects dependency cycles and warns about dependencies that do not exist yet
+func validateDependencies(taskName string, config *task.Config) error {
+	missing, err := task.ValidateDependencies(taskName, config)
+	if err != nil {
+		return fmt.Errorf("invalid dependencies: %w", err)
+	}
+	for _, name := range missing {
+		fmt.Printf("Warning: task '%s' depends on '%s', which does not exist yet\n", taskName, name)
+	}
+	return nil
+}
+
+// parseProbeFlag splits a probe flag into its kind and target: KIND:TARGET, or an
+// http:// or https:// URL for the http kind. kinds are the kinds the probe accepts.
+func parseProbeFlag(value string, kinds ...string) (string, string, error) {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return "http", value, nil
+	}
+	
+	kind, target, found := strings.Cut(value, ":")
+	if !found || target == "" {
+		return "", "", fmt.Errorf("invalid probe '%s': expected KIND:TARGET or an http(s):// URL", value)
+	}
+	for _, accepted := range kinds {
+		if kind == accepted && kind != "http" {
+			return kind, target, nil
+		}
+	}
+	return "", "", fmt.Errorf("invalid probe kind '%s': expected one of %s", kind, strings.Join(kinds, ", "))
+}
+
+// parseReadinessProbe parses the --ready flag: tcp:ADDRESS, an http:// or https:// URL,
+// log:REGEX or command:COMMAND
+func parseReadinessProbe(value, timeout string) (task.ReadinessProbe, error) {
+	probe := task.ReadinessProbe{Timeout: timeout}
+	if value == "" {
+		return probe, nil
+	}
+	
+	kind, target, err := parseProbeFlag(value, task.ReadinessTCP, task.ReadinessHTTP, task.ReadinessLog, task.ReadinessCommand)
+	if err != nil {
+		return probe, fmt.Errorf("invalid readiness probe: %w", err)
+	}
+	switch kind {
+	case task.ReadinessTCP:
+		probe.TCP = target
+	case task.ReadinessHTTP:
+		probe.HTTP = target
+	case task.ReadinessLog:
+		probe.Log = target
+	case task.ReadinessCommand:
+		probe.Command = target
+	}
+	return probe, nil
+}
+
+// parseLivenessProbe parses the --liveness flag: tcp:ADDRESS, an http:// or https:// URL
+// or command:COMMAND
+func parseLivenessProbe(value string) (task.LivenessProbe, error) {
+	var probe task.LivenessProbe
+	if value == "" {
+		return probe, nil
+	}
+	
+	kind, target, err := parseProbeFlag(value, task.LivenessTCP, task.LivenessHTTP, task.LivenessCommand)
+	if err != nil {
+		return probe, fmt.Errorf("invalid liveness probe: %w", err)
+	}
+	switch kind {
+	case task.LivenessTCP:
+		probe.TCP = target
+	case task.LivenessHTTP:
+		probe.HTTP = target
+	case task.LivenessCommand:
+		probe.Command = target
+	}
+	return probe, nil
+}
+
 // validateIOPaths validates input/output redirection paths
 func validateIOPaths(stdin, stdout, stderr, workdir string) error {
 	pathResolver := task.NewPathResolver()
@@ -358,20 +702,18 @@ func validateIOConflicts(stdin, stdout, stderr string) error {
 	return nil
 }
 
-// validateExecutableConflicts checks for conflicts between executable and IO redirection
-func validateExecutableConflicts(executable, stdin, stdout, stderr string) error {
-	// Check if executable might interfere with IO redirection
-	lowerExec := strings.ToLower(executable)
-	
+// validateExecutableConflicts checks for conflicts between the program and IO redirection
+// program is the name returned by commandProgram
+func validateExecutableConflicts(program, stdin, stdout, stderr string) error {
 	// Warn about potentially problematic combinations
-	if strings.Contains(lowerExec, "cat") || strings.Contains(lowerExec, "type") {
+	if program == "cat" || program == "type" {
 		if stdin == "" {
 			// This is just a warning, not an error
-			fmt.Printf("Warning: '%s' command without stdin redirection may wait for input\n", executable)
+			fmt.Printf("Warning: '%s' command without stdin redirection may wait for input\n", program)
 		}
 	}
 	
-	if strings.Contains(lowerExec, "tee") {
+	if program == "tee" {
 		if stdout != "" || stderr != "" {
 			fmt.Printf("Warning: 'tee' command with output redirection may cause duplicate output\n")
 		}
@@ -388,6 +730,12 @@ func displayTaskAddedSuccess(taskName string, config *task.Config) {
 	fmt.Printf("Summary:\n")
 	fmt.Printf("  Name:       %s\n", taskName)
 	fmt.Printf("  Executable: %s\n", config.Executable)
+	if len(config.Args) > 0 {
+		fmt.Printf("  Arguments:  %s\n", formatArgs(config.Args))
+	}
+	if config.Shell {
+		fmt.Printf("  Shell:      yes\n")
+	}
 	fmt.Printf("  Work Dir:   %s\n", config.WorkDir)
 	
 	// Display IO redirection info if configured
@@ -406,6 +754,39 @@ func displayTaskAddedSuccess(taskName string, config *task.Config) {
 		fmt.Printf("%s\n", strings.Join(ioInfo, ", "))
 	}
 	
+	// Display restart policy if configured
+	if config.Restart.Policy != "" {
+		fmt.Printf("  Restart:    %s\n", formatRestartPolicy(config.RestartPolicyName(), config.MaxRestarts(), config.Restart.Delay))
+	}
+	
+	// Display schedule if configured
+	if config.Schedule != "" {
+		fmt.Printf("  Schedule:   %s\n", formatSchedule(config.Schedule, config.ScheduleOverlapPolicy(), config.ScheduleJitter))
+		fmt.Printf("  Next Run:   %s\n", config.NextScheduledRun(time.Now()).Format("2006-01-02 15:04:05"))
+	}
+	
+	// Display dependencies if configured
+	if len(config.Requires) > 0 {
+		fmt.Printf("  Requires:   %s\n", strings.Join(config.Requires, ", "))
+	}
+	if len(config.Wants) > 0 {
+		fmt.Printf("  Wants:      %s\n", strings.Join(config.Wants, ", "))
+	}
+	if len(config.After) > 0 {
+		fmt.Printf("  After:      %s\n", strings.Join(config.After, ", "))
+	}
+	
+	// Display readiness probe if configured
+	if probe := config.Readiness; probe.Kind() != "" {
+		fmt.Printf("  Readiness:  %s %s (timeout %s)\n", probe.Kind(), probe.Target(), probe.TimeoutDuration())
+	}
+	if probe := config.Liveness; probe.Kind() != "" {
+		fmt.Printf("  Liveness:   %s\n", formatLivenessProbe(probe.Kind()+" "+probe.Target(), probe.IntervalDuration().String(), probe.Threshold()))
+	}
+	if !config.Limits.IsZero() {
+		fmt.Printf("  Limits:     %s\n", config.Limits)
+	}
+	
 	fmt.Printf("\n")
 	
 	// Display next steps
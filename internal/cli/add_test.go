@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"taskd/internal/task"
+)
+
+func TestParseReadinessProbe(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    task.ReadinessProbe
+		wantErr bool
+	}{
+		{"", task.ReadinessProbe{}, false},
+		{"tcp:8080", task.ReadinessProbe{TCP: "8080"}, false},
+		{"tcp:db.local:5432", task.ReadinessProbe{TCP: "db.local:5432"}, false},
+		{"http://127.0.0.1:8080/health", task.ReadinessProbe{HTTP: "http://127.0.0.1:8080/health"}, false},
+		{"log:listening on :\\d+", task.ReadinessProbe{Log: "listening on :\\d+"}, false},
+		{"command:pg_isready -q", task.ReadinessProbe{Command: "pg_isready -q"}, false},
+		{"http:localhost", task.ReadinessProbe{}, true},
+		{"udp:53", task.ReadinessProbe{}, true},
+		{"8080", task.ReadinessProbe{}, true},
+		{"tcp:", task.ReadinessProbe{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReadinessProbe(tt.value, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseReadinessProbe(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseReadinessProbe(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseLivenessProbe(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    task.LivenessProbe
+		wantErr bool
+	}{
+		{"", task.LivenessProbe{}, false},
+		{"tcp:6379", task.LivenessProbe{TCP: "6379"}, false},
+		{"https://example.com/health", task.LivenessProbe{HTTP: "https://example.com/health"}, false},
+		{"command:redis-cli ping", task.LivenessProbe{Command: "redis-cli ping"}, false},
+		// Log lines only tell when a task is ready
+		{"log:ready", task.LivenessProbe{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLivenessProbe(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLivenessProbe(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseLivenessProbe(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommandLine(t *testing.T) {
+	tests := []struct {
+		commandLine    string
+		shell          bool
+		wantExecutable string
+		wantArgs       []string
+		wantErr        bool
+	}{
+		{"python app.py", false, "python", []string{"app.py"}, false},
+		{`"my app" --name "my svc"`, false, "my app", []string{"--name", "my svc"}, false},
+		{"app", false, "app", []string{}, false},
+		{`"my app"`, false, "my app", []string{}, false},
+		{"echo $HOME | tee out", true, "echo $HOME | tee out", nil, false},
+		{`app "unterminated`, false, "", nil, true},
+		{"   ", false, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		executable, args, err := parseCommandLine(tt.commandLine, tt.shell)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCommandLine(%q) error = %v, wantErr %v", tt.commandLine, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (executable != tt.wantExecutable || !reflect.DeepEqual(args, tt.wantArgs)) {
+			t.Errorf("parseCommandLine(%q) = %q, %q, want %q, %q", tt.commandLine, executable, args, tt.wantExecutable, tt.wantArgs)
+		}
+	}
+}
+
+func TestCommandProgram(t *testing.T) {
+	tests := []struct {
+		commandLine string
+		shell       bool
+		want        string
+	}{
+		{"/bin/cat file.txt", false, "cat"},
+		{"TEE.EXE out.log", false, "tee"},
+		{"concatenate --all", false, "concatenate"},
+		{"cat file.txt", true, ""},
+		{"", false, ""},
+	}
+
+	for _, tt := range tests {
+		if got := commandProgram(tt.commandLine, tt.shell); got != tt.want {
+			t.Errorf("commandProgram(%q) = %q, want %q", tt.commandLine, got, tt.want)
+		}
+	}
+}
@@ -44,15 +44,16 @@ func runDelCommand(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Check if task exists
-	taskInfo, err := task.GetTaskStatus(taskName)
+	client := task.NewDaemonClient()
+	taskInfo, err := client.GetTaskDetailInfo(taskName)
 	if err != nil {
 		return fmt.Errorf("task '%s' not found", taskName)
 	}
 	
 	// Stop the task if it's running
-	if taskInfo.Status == "running" {
+	if isActiveStatus(taskInfo.Status) {
 		fmt.Printf("Task '%s' is currently running. Stopping...\n", taskName)
-		if err := task.StopTask(taskName); err != nil {
+		if err := client.StopTask(taskName); err != nil {
 			return fmt.Errorf("failed to stop task '%s': %w", taskName, err)
 		}
 		fmt.Printf("Task '%s' stopped.\n", taskName)
@@ -72,6 +73,11 @@ func runDelCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 	
+	// Let a running daemon forget the task
+	if err := client.ReloadTask(taskName); err != nil {
+		fmt.Printf("Warning: failed to remove task from daemon: %v\n", err)
+	}
+	
 	fmt.Printf("Task '%s' deleted successfully.\n", taskName)
 	return nil
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/spf13/cobra"
@@ -37,6 +38,42 @@ Examples:
   # Clear IO redirection
   taskd edit mytask --clear-stdin --clear-stdout --clear-stderr
   
+  # Stop with SIGINT and allow 30 seconds to shut down
+  taskd edit mytask --stop-signal SIGINT --stop-timeout 30s
+  
+  # Write output as JSON lines with a timestamp and stream tag
+  taskd edit mytask --capture json
+  
+  # Restart on failure, at most 5 times, 10 seconds after the task exits
+  taskd edit mytask --restart on-failure --max-retry 5 --restart-delay 10s
+  
+  # Run every night at 3:00, up to 10 minutes later
+  taskd edit mytask --schedule "0 3 * * *" --schedule-jitter 10m
+  
+  # Stop running on a schedule
+  taskd edit mytask --schedule ""
+  
+  # Start the database and the cache before this task
+  taskd edit mytask --requires db --wants cache
+  
+  # Remove the required tasks
+  taskd edit mytask --requires ""
+  
+  # Consider the task started once it accepts connections on port 8080
+  taskd edit mytask --ready tcp:8080 --ready-timeout 1m
+  
+  # Remove the readiness probe
+  taskd edit mytask --ready ""
+  
+  # Restart the task when its health endpoint fails 5 checks in a row
+  taskd edit mytask --liveness http://127.0.0.1:8080/health --liveness-threshold 5
+  
+  # Limit the task to 512 MB of memory and half a CPU, at a low priority
+  taskd edit mytask --memory 512M --cpu 0.5 --nice 10
+  
+  # Run the command through the shell to use pipes and variables
+  taskd edit mytask --shell --exec "./export.sh | gzip > export.gz"
+  
   # Combine multiple changes
   taskd edit mytask --exec "node server.js" --workdir "/app" --stdout "server.log"`,
 	Args: cobra.ExactArgs(1),
@@ -50,13 +87,13 @@ Examples:
 		}
 		
 		// Get current task configuration
-		currentInfo, err := task.GetTaskDetailInfo(taskName)
+		currentInfo, err := task.NewDaemonClient().GetTaskDetailInfo(taskName)
 		if err != nil {
 			return fmt.Errorf("task '%s' not found: %w", taskName, err)
 		}
 		
 		// Check if task is running
-		if currentInfo.Status == "running" {
+		if isActiveStatus(currentInfo.Status) {
 			return fmt.Errorf("cannot edit task '%s' while it is running. Please stop the task first", taskName)
 		}
 		
@@ -92,13 +129,54 @@ type EditConfig struct {
 	DisplayName *string   // pointer to distinguish between empty string and not set
 	Description *string
 	Executable  *string   // pointer to distinguish between empty string and not set
+	Shell       *bool
 	WorkDir     *string
 	Env         []string
+	EnvFile     []string // nil when not set and empty to remove them
 	InheritEnv  *bool
 	Stdin       *string
 	Stdout      *string
 	Stderr      *string
 	
+	// Restart policy
+	RestartPolicy *string
+	MaxRetry      *int
+	RestartDelay  *string
+	
+	// Stop behaviour
+	StopSignal  *string
+	StopTimeout *string
+	
+	// Output capture format
+	Capture *string
+	
+	// Schedule
+	Schedule        *string
+	ScheduleOverlap *string
+	ScheduleJitter  *string
+	
+	// Dependencies, nil when not set and empty to remove them
+	Requires []string
+	Wants    []string
+	After    []string
+	
+	// Readiness probe, an empty Ready removes it
+	Ready        *string
+	ReadyTimeout *string
+	
+	// Liveness probe, an empty Liveness removes it
+	Liveness          *string
+	LivenessInterval  *string
+	LivenessThreshold *int
+	
+	// Resource limits, zero removes a limit
+	Memory    *string
+	CPU       *float64
+	CPUWeight *int
+	OpenFiles *int
+	Processes *int
+	Nice      *int
+	
 	// Clear flags
 	ClearEnv    bool
 	ClearStdin  bool
@@ -129,6 +207,12 @@ func parseEditFlags(cmd *cobra.Command, currentInfo *task.TaskDetailInfo) (*Edit
 		config.Executable = &exec
 	}
 	
+	// Parse shell mode
+	if cmd.Flags().Changed("shell") {
+		shell, _ := cmd.Flags().GetBool("shell")
+		config.Shell = &shell
+	}
+	
 	// Parse working directory
 	if cmd.Flags().Changed("workdir") {
 		workdir, _ := cmd.Flags().GetString("workdir")
@@ -141,6 +225,12 @@ func parseEditFlags(cmd *cobra.Command, currentInfo *task.TaskDetailInfo) (*Edit
 		config.Env = env
 	}
 	
+	// Parse env files
+	if cmd.Flags().Changed("env-file") {
+		config.EnvFile, _ = cmd.Flags().GetStringSlice("env-file")
+		config.EnvFile = nonEmptyNames(config.EnvFile)
+	}
+	
 	// Parse inherit environment
 	if cmd.Flags().Changed("inherit-env") {
 		inheritEnv, _ := cmd.Flags().GetBool("inherit-env")
@@ -163,6 +253,122 @@ func parseEditFlags(cmd *cobra.Command, currentInfo *task.TaskDetailInfo) (*Edit
 		config.Stderr = &stderr
 	}
 	
+	// Parse restart policy
+	if cmd.Flags().Changed("restart") {
+		restartPolicy, _ := cmd.Flags().GetString("restart")
+		config.RestartPolicy = &restartPolicy
+	}
+	
+	if cmd.Flags().Changed("max-retry") {
+		maxRetry, _ := cmd.Flags().GetInt("max-retry")
+		config.MaxRetry = &maxRetry
+	}
+	
+	if cmd.Flags().Changed("restart-delay") {
+		restartDelay, _ := cmd.Flags().GetString("restart-delay")
+		config.RestartDelay = &restartDelay
+	}
+	
+	if cmd.Flags().Changed("stop-signal") {
+		stopSignal, _ := cmd.Flags().GetString("stop-signal")
+		config.StopSignal = &stopSignal
+	}
+	
+	if cmd.Flags().Changed("stop-timeout") {
+		stopTimeout, _ := cmd.Flags().GetString("stop-timeout")
+		config.StopTimeout = &stopTimeout
+	}
+	
+	if cmd.Flags().Changed("capture") {
+		capture, _ := cmd.Flags().GetString("capture")
+		config.Capture = &capture
+	}
+	
+	if cmd.Flags().Changed("schedule") {
+		schedule, _ := cmd.Flags().GetString("schedule")
+		config.Schedule = &schedule
+	}
+	
+	if cmd.Flags().Changed("schedule-overlap") {
+		scheduleOverlap, _ := cmd.Flags().GetString("schedule-overlap")
+		config.ScheduleOverlap = &scheduleOverlap
+	}
+	
+	if cmd.Flags().Changed("schedule-jitter") {
+		scheduleJitter, _ := cmd.Flags().GetString("schedule-jitter")
+		config.ScheduleJitter = &scheduleJitter
+	}
+	
+	if cmd.Flags().Changed("requires") {
+		config.Requires, _ = cmd.Flags().GetStringSlice("requires")
+		config.Requires = nonEmptyNames(config.Requires)
+	}
+	
+	if cmd.Flags().Changed("wants") {
+		config.Wants, _ = cmd.Flags().GetStringSlice("wants")
+		config.Wants = nonEmptyNames(config.Wants)
+	}
+	
+	if cmd.Flags().Changed("after") {
+		config.After, _ = cmd.Flags().GetStringSlice("after")
+		config.After = nonEmptyNames(config.After)
+	}
+	
+	if cmd.Flags().Changed("ready") {
+		ready, _ := cmd.Flags().GetString("ready")
+		config.Ready = &ready
+	}
+	
+	if cmd.Flags().Changed("ready-timeout") {
+		readyTimeout, _ := cmd.Flags().GetString("ready-timeout")
+		config.ReadyTimeout = &readyTimeout
+	}
+	
+	if cmd.Flags().Changed("liveness") {
+		liveness, _ := cmd.Flags().GetString("liveness")
+		config.Liveness = &liveness
+	}
+	
+	if cmd.Flags().Changed("liveness-interval") {
+		livenessInterval, _ := cmd.Flags().GetString("liveness-interval")
+		config.LivenessInterval = &livenessInterval
+	}
+	
+	if cmd.Flags().Changed("liveness-threshold") {
+		livenessThreshold, _ := cmd.Flags().GetInt("liveness-threshold")
+		config.LivenessThreshold = &livenessThreshold
+	}
+	
+	if cmd.Flags().Changed("memory") {
+		memory, _ := cmd.Flags().GetString("memory")
+		config.Memory = &memory
+	}
+	
+	if cmd.Flags().Changed("cpu") {
+		cpu, _ := cmd.Flags().GetFloat64("cpu")
+		config.CPU = &cpu
+	}
+	
+	if cmd.Flags().Changed("cpu-weight") {
+		cpuWeight, _ := cmd.Flags().GetInt("cpu-weight")
+		config.CPUWeight = &cpuWeight
+	}
+	
+	if cmd.Flags().Changed("open-files") {
+		openFiles, _ := cmd.Flags().GetInt("open-files")
+		config.OpenFiles = &openFiles
+	}
+	
+	if cmd.Flags().Changed("processes") {
+		processes, _ := cmd.Flags().GetInt("processes")
+		config.Processes = &processes
+	}
+	
+	if cmd.Flags().Changed("nice") {
+		nice, _ := cmd.Flags().GetInt("nice")
+		config.Nice = &nice
+	}
+	
 	// Parse clear flags
 	config.ClearEnv, _ = cmd.Flags().GetBool("clear-env")
 	config.ClearStdin, _ = cmd.Flags().GetBool("clear-stdin")
@@ -178,12 +384,37 @@ func hasAnyChanges(config *EditConfig) bool {
 	if config.DisplayName != nil ||
 		config.Description != nil ||
 		config.Executable != nil ||
+		config.Shell != nil ||
 		config.WorkDir != nil ||
 		len(config.Env) > 0 ||
+		config.EnvFile != nil ||
 		config.InheritEnv != nil ||
 		config.Stdin != nil ||
 		config.Stdout != nil ||
-		config.Stderr != nil {
+		config.Stderr != nil ||
+		config.RestartPolicy != nil ||
+		config.MaxRetry != nil ||
+		config.RestartDelay != nil ||
+		config.StopSignal != nil ||
+		config.StopTimeout != nil ||
+		config.Capture != nil ||
+		config.Schedule != nil ||
+		config.ScheduleOverlap != nil ||
+		config.ScheduleJitter != nil ||
+		config.Requires != nil ||
+		config.Wants != nil ||
+		config.After != nil ||
+		config.Ready != nil ||
+		config.ReadyTimeout != nil ||
+		config.Liveness != nil ||
+		config.LivenessInterval != nil ||
+		config.LivenessThreshold != nil ||
+		config.Memory != nil ||
+		config.CPU != nil ||
+		config.CPUWeight != nil ||
+		config.OpenFiles != nil ||
+		config.Processes != nil ||
+		config.Nice != nil {
 		return true
 	}
 	
@@ -210,16 +441,16 @@ func validateEditConfig(config *EditConfig, currentInfo *task.TaskDetailInfo) er
 	}
 	
 	// Validate executable if provided
+	shell := currentInfo.Shell
+	if config.Shell != nil {
+		shell = *config.Shell
+	}
 	if config.Executable != nil {
 		if err := validateExecutable(*config.Executable); err != nil {
 			return fmt.Errorf("invalid executable: %w", err)
 		}
-	}
-	
-	// Validate working directory if provided
-	if config.WorkDir != nil && *config.WorkDir != "" {
-		if err := validateWorkingDirectory(*config.WorkDir); err != nil {
-			return fmt.Errorf("invalid working directory: %w", err)
+		if _, _, err := parseCommandLine(*config.Executable, shell); err != nil {
+			return fmt.Errorf("invalid executable: %w", err)
 		}
 	}
 	
@@ -230,7 +461,17 @@ func validateEditConfig(config *EditConfig, currentInfo *task.TaskDetailInfo) er
 		}
 	}
 	
-	// Validate IO paths if provided
+	// Variables are expanded when the task starts, validate what the new values resolve to now
+	env := currentInfo.Env
+	if config.ClearEnv {
+		env = nil
+	} else if len(config.Env) > 0 {
+		env = config.Env
+	}
+	envFile := currentInfo.EnvFile
+	if config.EnvFile != nil {
+		envFile = config.EnvFile
+	}
 	workdir := currentInfo.WorkDir  // Use current task's working directory
 	if config.WorkDir != nil {
 		workdir = *config.WorkDir    // Or use the new working directory if being updated
@@ -260,24 +501,111 @@ func validateEditConfig(config *EditConfig, currentInfo *task.TaskDetailInfo) er
 		stderr = ""
 	}
 	
+	resolved, err := (&task.Config{
+		WorkDir: workdir,
+		Env:     env,
+		EnvFile: envFile,
+		Stdin:   stdin,
+		Stdout:  stdout,
+		Stderr:  stderr,
+	}).Expand(config.Name, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid variable: %w", err)
+	}
+	
+	// Validate working directory if provided
+	if config.WorkDir != nil && *config.WorkDir != "" {
+		if err := validateWorkingDirectory(resolved.WorkDir); err != nil {
+			return fmt.Errorf("invalid working directory: %w", err)
+		}
+	}
+	
+	// Validate restart policy if provided
+	var restart task.RestartPolicy
+	if config.RestartPolicy != nil {
+		restart.Policy = *config.RestartPolicy
+	}
+	if config.MaxRetry != nil {
+		restart.MaxRetry = *config.MaxRetry
+	}
+	if config.RestartDelay != nil {
+		restart.Delay = *config.RestartDelay
+	}
+	if err := validateRestartOptions(restart); err != nil {
+		return fmt.Errorf("invalid restart policy: %w", err)
+	}
+	
+	// Validate stop settings if provided
+	var stopSignal, stopTimeout string
+	if config.StopSignal != nil {
+		stopSignal = *config.StopSignal
+	}
+	if config.StopTimeout != nil {
+		stopTimeout = *config.StopTimeout
+	}
+	if err := validateStopOptions(stopSignal, stopTimeout); err != nil {
+		return err
+	}
+	
+	// Validate schedule settings if provided
+	var schedule, scheduleOverlap, scheduleJitter string
+	if config.Schedule != nil {
+		schedule = *config.Schedule
+	}
+	if config.ScheduleOverlap != nil {
+		scheduleOverlap = *config.ScheduleOverlap
+	}
+	if config.ScheduleJitter != nil {
+		scheduleJitter = *config.ScheduleJitter
+	}
+	if err := validateScheduleOptions(schedule, scheduleOverlap, scheduleJitter); err != nil {
+		return err
+	}
+	
+	// Validate the readiness probe syntax if provided, the whole probe is checked once applied
+	if config.Ready != nil {
+		if _, err := parseReadinessProbe(*config.Ready, ""); err != nil {
+			return err
+		}
+	}
+	if config.Liveness != nil {
+		if _, err := parseLivenessProbe(*config.Liveness); err != nil {
+			return err
+		}
+	}
+	
+	// Validate capture format if provided
+	if config.Capture != nil {
+		if err := task.ValidateCaptureFormat(*config.Capture); err != nil {
+			return fmt.Errorf("invalid capture format: %w", err)
+		}
+	}
+	
 	// Only validate IO paths if they are being set (not cleared)
 	if stdin != "" || stdout != "" || stderr != "" {
-		if err := validateIOPaths(stdin, stdout, stderr, workdir); err != nil {
+		if err := validateIOPaths(resolved.Stdin, resolved.Stdout, resolved.Stderr, resolved.WorkDir); err != nil {
 			return fmt.Errorf("invalid IO redirection: %w", err)
 		}
 	}
 	
+	// Validate the env files and secrets if the environment changes
+	if len(config.Env) > 0 || config.EnvFile != nil || config.WorkDir != nil {
+		if err := validateEnvSources(resolved); err != nil {
+			return fmt.Errorf("invalid environment: %w", err)
+		}
+	}
+	
 	// Check for configuration conflicts (but skip task name conflict for edit)
-	execStr := ""
+	program := ""
 	if config.Executable != nil {
-		execStr = *config.Executable
+		program = commandProgram(*config.Executable, shell)
 	}
 	
 	if err := validateIOConflicts(stdin, stdout, stderr); err != nil {
 		return fmt.Errorf("configuration conflict: %w", err)
 	}
 	
-	if err := validateExecutableConflicts(execStr, stdin, stdout, stderr); err != nil {
+	if err := validateExecutableConflicts(program, stdin, stdout, stderr); err != nil {
 		return fmt.Errorf("configuration conflict: %w", err)
 	}
 	
@@ -311,8 +639,21 @@ func applyTaskEdit(taskName string, editConfig *EditConfig) error {
 		newConfig.Description = *editConfig.Description
 	}
 	
-	if editConfig.Executable != nil {
-		newConfig.Executable = *editConfig.Executable
+	// A new command line, or the current one when only the shell mode changes,
+	// is stored as the executable and its arguments again
+	if editConfig.Executable != nil || editConfig.Shell != nil {
+		commandLine := currentConfig.CommandLine()
+		if editConfig.Executable != nil {
+			commandLine = *editConfig.Executable
+		}
+		if editConfig.Shell != nil {
+			newConfig.Shell = *editConfig.Shell
+		}
+		executable, args, err := parseCommandLine(commandLine, newConfig.Shell)
+		if err != nil {
+			return fmt.Errorf("invalid executable: %w", err)
+		}
+		newConfig.Executable, newConfig.Args = executable, args
 	}
 	
 	if editConfig.WorkDir != nil {
@@ -329,6 +670,9 @@ func applyTaskEdit(taskName string, editConfig *EditConfig) error {
 	} else if len(editConfig.Env) > 0 {
 		newConfig.Env = editConfig.Env
 	}
+	if editConfig.EnvFile != nil {
+		newConfig.EnvFile = editConfig.EnvFile
+	}
 	
 	// Handle IO redirection
 	if editConfig.ClearStdin {
@@ -349,6 +693,133 @@ func applyTaskEdit(taskName string, editConfig *EditConfig) error {
 		newConfig.Stderr = *editConfig.Stderr
 	}
 	
+	// Handle restart policy
+	if editConfig.RestartPolicy != nil {
+		newConfig.Restart.Policy = *editConfig.RestartPolicy
+	}
+	
+	if editConfig.MaxRetry != nil {
+		newConfig.Restart.MaxRetry = *editConfig.MaxRetry
+		// The legacy max_retry_num would apply again with --max-retry 0 (unlimited)
+		newConfig.MaxRetryNum = 0
+	}
+	
+	if editConfig.RestartDelay != nil {
+		newConfig.Restart.Delay = *editConfig.RestartDelay
+	}
+	
+	if editConfig.StopSignal != nil {
+		newConfig.StopSignal = *editConfig.StopSignal
+		if newConfig.StopSignal != "" {
+			newConfig.StopSignal, _ = task.NormalizeStopSignal(newConfig.StopSignal)
+		}
+	}
+	
+	if editConfig.StopTimeout != nil {
+		newConfig.StopTimeout = *editConfig.StopTimeout
+	}
+	
+	if editConfig.Capture != nil {
+		newConfig.Log.Capture = *editConfig.Capture
+	}
+	
+	if editConfig.Schedule != nil {
+		newConfig.Schedule = *editConfig.Schedule
+	}
+	
+	if editConfig.ScheduleOverlap != nil {
+		newConfig.ScheduleOverlap = *editConfig.ScheduleOverlap
+	}
+	
+	if editConfig.ScheduleJitter != nil {
+		newConfig.ScheduleJitter = *editConfig.ScheduleJitter
+	}
+	
+	if editConfig.Requires != nil {
+		newConfig.Requires = editConfig.Requires
+	}
+	
+	if editConfig.Wants != nil {
+		newConfig.Wants = editConfig.Wants
+	}
+	
+	if editConfig.After != nil {
+		newConfig.After = editConfig.After
+	}
+	
+	if editConfig.Ready != nil {
+		probe, _ := parseReadinessProbe(*editConfig.Ready, "")
+		if probe.Kind() != "" {
+			probe.Timeout = newConfig.Readiness.Timeout
+			probe.Interval = newConfig.Readiness.Interval
+		}
+		newConfig.Readiness = probe
+	}
+	
+	if editConfig.ReadyTimeout != nil {
+		newConfig.Readiness.Timeout = *editConfig.ReadyTimeout
+	}
+	
+	// The probe depends on the output redirection, check it as a whole
+	if err := task.ValidateReadinessProbe(newConfig.Readiness, newConfig.Stdout); err != nil {
+		return fmt.Errorf("invalid readiness probe: %w", err)
+	}
+	
+	if editConfig.Liveness != nil {
+		probe, _ := parseLivenessProbe(*editConfig.Liveness)
+		if probe.Kind() != "" {
+			probe.Interval = newConfig.Liveness.Interval
+			probe.Timeout = newConfig.Liveness.Timeout
+			probe.FailureThreshold = newConfig.Liveness.FailureThreshold
+		}
+		newConfig.Liveness = probe
+	}
+	
+	if editConfig.LivenessInterval != nil {
+		newConfig.Liveness.Interval = *editConfig.LivenessInterval
+	}
+	
+	if editConfig.LivenessThreshold != nil {
+		newConfig.Liveness.FailureThreshold = *editConfig.LivenessThreshold
+	}
+	
+	if err := task.ValidateLivenessProbe(newConfig.Liveness); err != nil {
+		return fmt.Errorf("invalid liveness probe: %w", err)
+	}
+	
+	if editConfig.Memory != nil {
+		newConfig.Limits.Memory = *editConfig.Memory
+	}
+	if editConfig.CPU != nil {
+		newConfig.Limits.CPU = *editConfig.CPU
+	}
+	if editConfig.CPUWeight != nil {
+		newConfig.Limits.CPUWeight = *editConfig.CPUWeight
+	}
+	if editConfig.OpenFiles != nil {
+		newConfig.Limits.OpenFiles = *editConfig.OpenFiles
+	}
+	if editConfig.Processes != nil {
+		newConfig.Limits.Processes = *editConfig.Processes
+	}
+	if editConfig.Nice != nil {
+		newConfig.Limits.Nice = *editConfig.Nice
+	}
+	
+	if err := task.ValidateResourceLimits(newConfig.Limits); err != nil {
+		return fmt.Errorf("invalid limits: %w", err)
+	}
+	
+	// Dependencies must not form a cycle with the other tasks
+	if err := validateDependencies(taskName, &newConfig); err != nil {
+		return err
+	}
+	
+	// The backoff settings only come from the configuration file, check them as a whole
+	if err := validateRestartOptions(newConfig.Restart); err != nil {
+		return fmt.Errorf("invalid restart policy: %w", err)
+	}
+	
 	// Save updated configuration
 	if err := saveTaskConfig(configPath, &newConfig); err != nil {
 		return fmt.Errorf("failed to save updated configuration: %w", err)
@@ -359,12 +830,35 @@ func applyTaskEdit(taskName string, editConfig *EditConfig) error {
 		return fmt.Errorf("failed to reload task in memory: %w", err)
 	}
 	
+	// Scheduled tasks are started by the daemon
+	if newConfig.Schedule != "" {
+		if err := task.GetDaemonManager().EnsureDaemonRunning(); err != nil {
+			fmt.Printf("Warning: failed to start the daemon, the task will not run on schedule until it is started: %v\n", err)
+		}
+	}
+	
+	// Let a running daemon pick up the new configuration
+	if err := task.NewDaemonClient().ReloadTask(taskName); err != nil {
+		fmt.Printf("Warning: failed to reload task in daemon: %v\n", err)
+	}
+	
 	return nil
 }
 
+// nonEmptyNames returns the names of a list flag, never nil so that an empty flag removes the list
+func nonEmptyNames(values []string) []string {
+	names := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			names = append(names, value)
+		}
+	}
+	return names
+}
+
 // Helper functions
 func loadTaskConfig(configPath string, config *task.Config) error {
-	if _, err := toml.DecodeFile(configPath, config); err != nil {
+	if err := task.DecodeConfigFile(configPath, config); err != nil {
 		return fmt.Errorf("failed to decode TOML file: %w", err)
 	}
 	return nil
@@ -397,15 +891,54 @@ func init() {
 	// Configuration flags
 	editCmd.Flags().String("display-name", "", "update display name for the task")
 	editCmd.Flags().String("description", "", "update description of the task")
-	editCmd.Flags().StringP("exec", "e", "", "update executable path and arguments")
+	editCmd.Flags().StringP("exec", "e", "", "update executable path and arguments, quoted like in a shell")
+	editCmd.Flags().Bool("shell", false, "update whether the executable runs as a command line of sh -c (cmd /c on Windows)")
 	editCmd.Flags().StringP("workdir", "w", "", "update working directory")
-	editCmd.Flags().StringSliceP("env", "E", nil, "update environment variables (format: KEY=VALUE, replaces all existing)")
+	editCmd.Flags().StringSliceP("env", "E", nil, "update environment variables (format: KEY=VALUE or KEY=secret://NAME, replaces all existing)")
+	editCmd.Flags().StringSlice("env-file", nil, "update the dotenv files read when the task starts (empty removes them)")
 	editCmd.Flags().BoolP("inherit-env", "i", false, "update inherit system environment variables setting")
 	
 	// IO redirection flags
 	editCmd.Flags().String("stdin", "", "update standard input file")
 	editCmd.Flags().String("stdout", "", "update standard output redirect file")
 	editCmd.Flags().String("stderr", "", "update standard error redirect file")
+	editCmd.Flags().String("capture", "", "update output capture format: text, json or empty to write raw output")
+	
+	// Restart policy flags
+	editCmd.Flags().String("restart", "", "update restart policy: always, on-failure or never (empty restores the default)")
+	editCmd.Flags().Int("max-retry", 0, "update maximum number of automatic restarts (0 means unlimited)")
+	editCmd.Flags().String("restart-delay", "", "update delay before an automatic restart (e.g. 5s, 1m)")
+	
+	// Stop flags
+	editCmd.Flags().String("stop-signal", "", "update signal sent first when stopping the task (empty restores SIGTERM)")
+	editCmd.Flags().String("stop-timeout", "", "update how long to wait for a graceful exit before killing the task (e.g. 30s)")
+	
+	// Schedule flags
+	editCmd.Flags().String("schedule", "", "update the schedule: a cron expression, an interval like \"@every 1h\", or empty to stop scheduling")
+	editCmd.Flags().String("schedule-overlap", "", "update what happens when the task is still running at a scheduled run: skip, queue or replace")
+	editCmd.Flags().String("schedule-jitter", "", "update the random delay added to each scheduled run (e.g. 5m)")
+	
+	// Dependency flags
+	editCmd.Flags().StringSlice("requires", nil, "update the tasks started before this one, which does not start without them (empty removes them)")
+	editCmd.Flags().StringSlice("wants", nil, "update the tasks started before this one, which starts even if they fail (empty removes them)")
+	editCmd.Flags().StringSlice("after", nil, "update the tasks started before this one when both are started (empty removes them)")
+	
+	// Readiness flags
+	editCmd.Flags().String("ready", "", "update the readiness probe: tcp:ADDRESS, an http(s):// URL, log:REGEX, command:COMMAND or empty to remove it")
+	editCmd.Flags().String("ready-timeout", "", "update how long the task may take to become ready (e.g. 1m)")
+	
+	// Liveness flags
+	editCmd.Flags().String("liveness", "", "update the liveness probe: tcp:ADDRESS, an http(s):// URL, command:COMMAND or empty to remove it")
+	editCmd.Flags().String("liveness-interval", "", "update the time between liveness checks (e.g. 30s)")
+	editCmd.Flags().Int("liveness-threshold", 0, "update the consecutive failed liveness checks before the task is stopped (0 restores the default of 3)")
+	
+	// Resource limit flags
+	editCmd.Flags().String("memory", "", "update the maximum memory of the task (e.g. 512M, empty removes the limit)")
+	editCmd.Flags().Float64("cpu", 0, "update the CPU quota of the task in cores (0 removes the limit)")
+	editCmd.Flags().Int("cpu-weight", 0, "update the share of the CPU relative to other tasks, 1-10000 (0 restores the default)")
+	editCmd.Flags().Int("open-files", 0, "update the maximum open files of each process of the task (0 removes the limit)")
+	editCmd.Flags().Int("processes", 0, "update the maximum number of processes of the task (0 removes the limit)")
+	editCmd.Flags().Int("nice", 0, "update the scheduling priority, -20 (highest) to 19 (lowest)")
 	
 	// Clear flags
 	editCmd.Flags().Bool("clear-env", false, "clear all environment variables")
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	taskdconfig "taskd/internal/config"
+	"taskd/internal/task"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [task-name]",
+	Short: "Show the past runs of a task",
+	Long: `Show the past runs of a task, oldest first, with how long each run lasted,
+its exit code, why it ended and its last error.
+
+A run ends for one of these reasons:
+  user-stop  stopped by 'taskd stop' or 'taskd del'
+  restart    stopped by 'taskd restart'
+  shutdown   stopped because the daemon shut down
+  crash      exited by itself with a non-zero exit code
+  exited     exited by itself with exit code 0
+
+The daemon keeps the last 100 runs of each task, in history/<task>.jsonl or in
+state.db with the bolt state backend.
+
+Examples:
+  # Show the last 20 runs
+  taskd history mytask
+
+  # Show the failed runs of the last day
+  taskd history mytask --failed --since 24h
+
+  # Show every recorded run as JSON
+  taskd history mytask -n 0 -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistoryCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().IntP("limit", "n", 20, "number of most recent runs to show (0 shows all)")
+	historyCmd.Flags().Bool("failed", false, "show only runs that crashed")
+	historyCmd.Flags().String("since", "", "show runs that ended since a time (e.g. 2026-01-27 10:00:00) or a duration ago (e.g. 10m, 2h)")
+	addOutputFlags(historyCmd, outputJSON, outputYAML, outputWide)
+}
+
+// runHistoryDocument the output of history -o json|yaml
+type runHistoryDocument struct {
+	documentHeader
+	Task string         `json:"task"`
+	Runs []historyEntry `json:"runs"`
+}
+
+// historyEntry a run in the history document
+type historyEntry struct {
+	*task.RunRecord
+	Duration string `json:"duration"`
+}
+
+// newRunHistoryDocument returns the history document of a task
+func newRunHistoryDocument(taskName string, records []*task.RunRecord) *runHistoryDocument {
+	entries := make([]historyEntry, len(records))
+	for i, record := range records {
+		entries[i] = historyEntry{RunRecord: record, Duration: formatRunDuration(record.Duration())}
+	}
+	return &runHistoryDocument{documentHeader: newDocumentHeader("RunHistory"), Task: taskName, Runs: entries}
+}
+
+func runHistoryCommand(cmd *cobra.Command, args []string) error {
+	taskName := args[0]
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	failed, _ := cmd.Flags().GetBool("failed")
+	sinceValue, _ := cmd.Flags().GetString("since")
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	// The daemon itself has no run history
+	if err := task.NewBuiltinTaskHandler().ValidateOperation(taskName, "history"); err != nil {
+		return err
+	}
+
+	if limit < 0 {
+		return fmt.Errorf("invalid --limit value %d: must be 0 or more", limit)
+	}
+
+	var since time.Time
+	if sinceValue != "" {
+		var err error
+		if since, err = parseSince(sinceValue, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Only the history is read, the tasks and their runtime state are not loaded
+	records, err := task.LoadRunHistory(taskName)
+	if err != nil {
+		return fmt.Errorf("failed to load history of task '%s': %w", taskName, err)
+	}
+
+	// The history of a deleted task is kept, so only report unknown tasks without one
+	if len(records) == 0 {
+		configPath := filepath.Join(taskdconfig.GetTaskDTasksDir(), taskName+".toml")
+		if _, err := os.Stat(configPath); err != nil {
+			return fmt.Errorf("task '%s' not found", taskName)
+		}
+	}
+
+	records = filterRunRecords(records, failed, since, limit)
+
+	if isDocumentFormat(format) {
+		return printDocument(cmd, os.Stdout, format, newRunHistoryDocument(taskName, records))
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No recorded runs of task '%s' match.\n", taskName)
+		return nil
+	}
+
+	displayRunRecords(records, format == outputWide)
+	return nil
+}
+
+// filterRunRecords selects the runs to show, keeping the most recent limit of them
+func filterRunRecords(records []*task.RunRecord, failed bool, since time.Time, limit int) []*task.RunRecord {
+	var selected []*task.RunRecord
+	for _, record := range records {
+		if failed && !record.Failed() {
+			continue
+		}
+		if !since.IsZero() && record.EndTime.Before(since) {
+			continue
+		}
+		selected = append(selected, record)
+	}
+
+	if limit > 0 && len(selected) > limit {
+		selected = selected[len(selected)-limit:]
+	}
+	return selected
+}
+
+// displayRunRecords shows runs in a table, wide shows the whole last error
+func displayRunRecords(records []*task.RunRecord, wide bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "START TIME\tDURATION\tPID\tEXIT CODE\tREASON\tLAST ERROR")
+	fmt.Fprintln(w, "----------\t--------\t---\t---------\t------\t----------")
+
+	for _, r := range records {
+		lastError := r.LastError
+		if !wide {
+			lastError = truncateString(lastError, 40)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n",
+			r.StartTime.Format("2006-01-02 15:04:05"), formatRunDuration(r.Duration()),
+			r.PID, r.ExitCode, r.Reason, lastError)
+	}
+
+	w.Flush()
+}
+
+// formatRunDuration rounds a run duration for display
+func formatRunDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"taskd/internal/task"
+)
+
+func TestFilterRunRecords(t *testing.T) {
+	now := time.Date(2026, 1, 27, 12, 0, 0, 0, time.Local)
+	records := []*task.RunRecord{
+		{PID: 1, EndTime: now.Add(-3 * time.Hour), Reason: task.StopReasonCrash},
+		{PID: 2, EndTime: now.Add(-2 * time.Hour), Reason: task.StopReasonUser},
+		{PID: 3, EndTime: now.Add(-time.Hour), Reason: task.StopReasonCrash},
+		{PID: 4, EndTime: now.Add(-time.Minute), Reason: task.StopReasonExited},
+	}
+
+	tests := []struct {
+		name   string
+		failed bool
+		since  time.Time
+		limit  int
+		want   []int
+	}{
+		{"all", false, time.Time{}, 0, []int{1, 2, 3, 4}},
+		{"limit keeps the most recent", false, time.Time{}, 2, []int{3, 4}},
+		{"failed", true, time.Time{}, 0, []int{1, 3}},
+		{"since", false, now.Add(-90 * time.Minute), 0, []int{3, 4}},
+		{"combined", true, now.Add(-150 * time.Minute), 5, []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterRunRecords(records, tt.failed, tt.since, tt.limit)
+			var pids []int
+			for _, record := range got {
+				pids = append(pids, record.PID)
+			}
+			if len(pids) != len(tt.want) {
+				t.Fatalf("filterRunRecords() = %v, want %v", pids, tt.want)
+			}
+			for i := range pids {
+				if pids[i] != tt.want[i] {
+					t.Fatalf("filterRunRecords() = %v, want %v", pids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunHistoryCommandOnlyReads(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TASKD_HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte(`state_backend = "bolt"`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, "tasks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "tasks", "web.toml"), []byte(`executable = "sleep 30"`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := task.AppendRunRecord(&task.RunRecord{Task: "web", PID: 100, Reason: task.StopReasonUser}); err != nil {
+		t.Fatalf("AppendRunRecord() failed: %v", err)
+	}
+
+	dbPath := filepath.Join(home, "state.db")
+	before, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	err = runHistoryCommand(historyCmd, []string{"web"})
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("runHistoryCommand() = %v", err)
+	}
+
+	// The history is read without loading the tasks or writing the runtime state
+	if after, _ := os.ReadFile(dbPath); !bytes.Equal(before, after) {
+		t.Error("history rewrote the state database")
+	}
+	if _, err := os.Stat(filepath.Join(home, "runtime.json")); !os.IsNotExist(err) {
+		t.Errorf("history wrote runtime.json: %v", err)
+	}
+}
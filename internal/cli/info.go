@@ -2,6 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"taskd/internal/task"
@@ -13,18 +17,41 @@ var infoCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskName := args[0]
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
 		
-		info, err := task.GetTaskDetailInfo(taskName)
+		info, err := task.NewDaemonClient().GetTaskDetailInfo(taskName)
 		if err != nil {
 			return fmt.Errorf("failed to get task info: %w", err)
 		}
 		
+		switch {
+		case isDocumentFormat(format):
+			return printDocument(cmd, os.Stdout, format, newTaskDocument(info))
+		case format == outputName:
+			fmt.Println(info.Name)
+			return nil
+		}
+		
 		// Display complete task information (including all original status information)
 		displayTaskInfo(info)
 		return nil
 	},
 }
 
+// taskDocument the output of info -o json|yaml
+type taskDocument struct {
+	documentHeader
+	Task *task.TaskDetailInfo `json:"task"`
+}
+
+// newTaskDocument returns the document of a task
+func newTaskDocument(info *task.TaskDetailInfo) *taskDocument {
+	return &taskDocument{documentHeader: newDocumentHeader("Task"), Task: info}
+}
+
 func displayTaskInfo(info *task.TaskDetailInfo) {
 	// Display header
 	fmt.Printf("===============================================================\n")
@@ -51,11 +78,18 @@ func displayTaskInfo(info *task.TaskDetailInfo) {
 		fmt.Printf("Process ID:       %d\n", info.PID)
 	}
 	
-	if info.StartTime != "" && info.StartTime != "0001-01-01 00:00:00" {
-		fmt.Printf("Start Time:       %s\n", info.StartTime)
+	if len(info.ChildPIDs) > 0 {
+		fmt.Printf("Child Processes:  %s\n", formatPIDs(info.ChildPIDs))
+	}
+	
+	if info.StartTime != "" {
+		fmt.Printf("Start Time:       %s\n", formatDisplayTime(info.StartTime))
 	}
 	
 	fmt.Printf("Executable:       %s\n", info.Executable)
+	if info.Resolved != nil {
+		printResolved(18, info.Executable, info.Resolved.Executable)
+	}
 	
 	// Display exit information
 	if info.ExitCode != 0 {
@@ -65,6 +99,27 @@ func displayTaskInfo(info *task.TaskDetailInfo) {
 		fmt.Printf("Last Error:       %s\n", info.LastError)
 	}
 	
+	// Resource usage sampled by the daemon, with its range over the last samples
+	if usage := info.Usage; usage != nil {
+		fmt.Printf("\n")
+		fmt.Printf("---------------------------------------------------------------\n")
+		fmt.Printf("                    RESOURCE USAGE                            \n")
+		fmt.Printf("---------------------------------------------------------------\n")
+		
+		fmt.Printf("Uptime:            %s\n", usage.Uptime)
+		fmt.Printf("CPU Time:          %s\n", usage.CPUTime)
+		fmt.Printf("%-19s%-12s%-12s%-12s%s\n", "", "CURRENT", "MIN", "AVG", "MAX")
+		printUsageRange("Memory (RSS):", usage.RSSRange, formatBytes(float64(usage.RSS)), formatBytes)
+		printUsageRange("CPU:", usage.CPUPercentRange, formatPercent(usage.CPUPercent), formatPercent)
+		if usage.Threads > 0 {
+			printUsageRange("Threads:", usage.ThreadsRange, strconv.Itoa(usage.Threads), formatCount)
+		}
+		if usage.OpenFiles > 0 {
+			printUsageRange("Open Files:", usage.OpenFilesRange, strconv.Itoa(usage.OpenFiles), formatCount)
+		}
+		fmt.Printf("Samples:           %d over %s, last at %s\n", usage.Samples, usage.Window, formatDisplayTime(usage.SampledAt))
+	}
+	
 	fmt.Printf("\n")
 	fmt.Printf("---------------------------------------------------------------\n")
 	fmt.Printf("                    CONFIGURATION                             \n")
@@ -72,16 +127,18 @@ func displayTaskInfo(info *task.TaskDetailInfo) {
 	
 	// Display configuration information
 	fmt.Printf("Working Directory: %s\n", info.WorkDir)
+	if info.Resolved != nil {
+		printResolved(19, info.WorkDir, info.Resolved.WorkDir)
+	}
 	
 	if len(info.Args) > 0 {
-		fmt.Printf("Arguments:         ")
-		for i, arg := range info.Args {
-			if i > 0 {
-				fmt.Printf(" ")
-			}
-			fmt.Printf("\"%s\"", arg)
+		fmt.Printf("Arguments:         %s\n", formatArgs(info.Args))
+		if info.Resolved != nil {
+			printResolved(19, formatArgs(info.Args), formatArgs(info.Resolved.Args))
 		}
-		fmt.Printf("\n")
+	}
+	if info.Shell {
+		fmt.Printf("Shell:             %s\n", getBoolIndicator(info.Shell))
 	}
 	
 	if len(info.Env) > 0 {
@@ -91,8 +148,85 @@ func displayTaskInfo(info *task.TaskDetailInfo) {
 		}
 	}
 	
+	if len(info.EnvFile) > 0 {
+		fmt.Printf("Env Files:         \n")
+		for i, file := range info.EnvFile {
+			fmt.Printf("                   %s\n", file)
+			if info.Resolved != nil && i < len(info.Resolved.EnvFile) {
+				printResolved(19, file, info.Resolved.EnvFile[i])
+			}
+		}
+	}
+	
+	// The variables the task gets from its env files and env list, secret values are masked
+	if info.Resolved != nil && strings.Join(info.Resolved.Env, "\n") != strings.Join(info.Env, "\n") {
+		fmt.Printf("Resolved Env:      \n")
+		for _, env := range info.Resolved.Env {
+			fmt.Printf("                   %s\n", env)
+		}
+	}
+	
+	if info.ExpandError != "" {
+		fmt.Printf("Resolve Error:     %s\n", info.ExpandError)
+	}
+	
 	fmt.Printf("Inherit Env:       %s\n", getBoolIndicator(info.InheritEnv))
 	
+	if info.RestartPolicy != "" {
+		fmt.Printf("Restart Policy:    %s\n", formatRestartPolicy(info.RestartPolicy, info.MaxRetry, info.RestartDelay))
+		if info.RestartPolicy != task.RestartNever {
+			fmt.Printf("Restart Backoff:   %s\n", formatRestartBackoff(info))
+		}
+		if info.RetryNum > 0 {
+			fmt.Printf("Restarts:          %d\n", info.RetryNum)
+		}
+	}
+	
+	if info.StopSignal != "" {
+		fmt.Printf("Stop:              %s, kill after %s\n", info.StopSignal, info.StopTimeout)
+	}
+	
+	if info.Schedule != "" {
+		fmt.Printf("Schedule:          %s\n", formatSchedule(info.Schedule, info.ScheduleOverlap, info.ScheduleJitter))
+		fmt.Printf("Last Run:          %s\n", formatScheduledRun(info.LastScheduledRun))
+		fmt.Printf("Next Run:          %s\n", formatScheduledRun(info.NextScheduledRun))
+	}
+	
+	if len(info.Requires) > 0 {
+		fmt.Printf("Requires:          %s\n", strings.Join(info.Requires, ", "))
+	}
+	if len(info.Wants) > 0 {
+		fmt.Printf("Wants:             %s\n", strings.Join(info.Wants, ", "))
+	}
+	if len(info.After) > 0 {
+		fmt.Printf("After:             %s\n", strings.Join(info.After, ", "))
+	}
+	if len(info.RequiredBy) > 0 {
+		fmt.Printf("Required By:       %s\n", strings.Join(info.RequiredBy, ", "))
+	}
+	
+	if info.Readiness != "" {
+		fmt.Printf("Readiness:         %s (timeout %s, every %s)\n", info.Readiness, info.ReadinessTimeout, info.ReadinessInterval)
+	}
+	
+	if info.Liveness != "" {
+		fmt.Printf("Liveness:          %s, timeout %s\n", formatLivenessProbe(info.Liveness, info.LivenessInterval, info.FailureThreshold), info.LivenessTimeout)
+		if info.Health != "" {
+			fmt.Printf("Health:            %s\n", formatHealthState(info.Health, info.HealthFailures, info.FailureThreshold))
+		}
+		if info.LastProbeTime != "" {
+			lastProbe := formatDisplayTime(info.LastProbeTime)
+			if info.LastProbeOutput != "" {
+				lastProbe += " " + truncateString(info.LastProbeOutput, 60)
+			}
+			fmt.Printf("Last Probe:        %s\n", lastProbe)
+		}
+	}
+	
+	if info.Limits != "" {
+		fmt.Printf("Limits:            %s\n", info.Limits)
+	}
+	
 	// Display IO redirection information
 	if info.IOInfo.StdinPath != "" || info.IOInfo.StdoutPath != "" || info.IOInfo.StderrPath != "" {
 		fmt.Printf("\n")
@@ -100,23 +234,131 @@ func displayTaskInfo(info *task.TaskDetailInfo) {
 		fmt.Printf("                  IO REDIRECTION                              \n")
 		fmt.Printf("---------------------------------------------------------------\n")
 		
+		// The configured paths, followed by the files they resolve to
 		if info.IOInfo.StdinPath != "" {
-			fmt.Printf("Standard Input:    %s\n", info.IOInfo.StdinPath)
+			fmt.Printf("Standard Input:    %s\n", info.Stdin)
+			printResolved(19, info.Stdin, info.IOInfo.StdinPath)
 		}
 		if info.IOInfo.StdoutPath != "" {
-			fmt.Printf("Standard Output:   %s\n", info.IOInfo.StdoutPath)
+			fmt.Printf("Standard Output:   %s\n", info.Stdout)
+			printResolved(19, info.Stdout, info.IOInfo.StdoutPath)
 		}
 		if info.IOInfo.StderrPath != "" {
-			fmt.Printf("Standard Error:    %s\n", info.IOInfo.StderrPath)
+			fmt.Printf("Standard Error:    %s\n", info.Stderr)
+			printResolved(19, info.Stderr, info.IOInfo.StderrPath)
 		}
 		if info.IOInfo.SameOutput {
 			fmt.Printf("Note:              Standard output and error are redirected to the same file\n")
 		}
+		if info.IOInfo.Capture != "" {
+			fmt.Printf("Capture:           %s (timestamped, stream-tagged lines)\n", info.IOInfo.Capture)
+		}
 	}
 	
 	fmt.Printf("===============================================================\n")
 }
 
+// formatPIDs formats a list of process IDs
+func formatPIDs(pids []int) string {
+	parts := make([]string, len(pids))
+	for i, pid := range pids {
+		parts[i] = strconv.Itoa(pid)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printUsageRange prints the current value of a resource followed by its minimum, average and maximum
+func printUsageRange(label string, r task.UsageRange, current string, format func(float64) string) {
+	fmt.Printf("%-19s%-12s%-12s%-12s%s\n", label, current, format(r.Min), format(r.Avg), format(r.Max))
+}
+
+// formatBytes formats a size in bytes with a binary unit, e.g. "12.5 MiB"
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for bytes >= 1024 && i < len(units)-1 {
+		bytes /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[i])
+}
+
+// formatPercent formats a CPU percentage
+func formatPercent(percent float64) string {
+	return fmt.Sprintf("%.1f%%", percent)
+}
+
+// formatCount formats an average count, with one decimal when it is not whole
+func formatCount(count float64) string {
+	return strconv.FormatFloat(math.Round(count*10)/10, 'f', -1, 64)
+}
+
+// formatRestartPolicy formats a restart policy with its retry limit and delay
+func formatRestartPolicy(policy string, maxRetry int, delay string) string {
+	if policy == task.RestartNever {
+		return policy
+	}
+	
+	result := policy
+	if maxRetry > 0 {
+		result += fmt.Sprintf(", max %d retries", maxRetry)
+	} else {
+		result += ", unlimited retries"
+	}
+	if delay != "" {
+		result += fmt.Sprintf(", delay %s", delay)
+	}
+	return result
+}
+
+// formatRestartBackoff formats the exponential backoff settings of a restart policy
+func formatRestartBackoff(info *task.TaskDetailInfo) string {
+	result := fmt.Sprintf("x%g up to %s", info.BackoffFactor, info.MaxRestartDelay)
+	if info.Jitter > 0 {
+		result += fmt.Sprintf(", jitter %g%%", info.Jitter*100)
+	}
+	if info.ResetAfter != "" {
+		result += fmt.Sprintf(", reset after %s up", info.ResetAfter)
+	}
+	return result
+}
+
+// formatLivenessProbe formats a liveness probe with its interval and failure threshold
+func formatLivenessProbe(probe, interval string, threshold int) string {
+	return fmt.Sprintf("%s (every %s, stopped after %d failures)", probe, interval, threshold)
+}
+
+// formatHealthState formats the health of a task with its consecutive failed checks
+func formatHealthState(health string, failures, threshold int) string {
+	if failures == 0 {
+		return health
+	}
+	return fmt.Sprintf("%s (%d of %d checks failed)", health, failures, threshold)
+}
+
+// formatSchedule formats a schedule with its overlap policy and jitter
+func formatSchedule(schedule, overlap, jitter string) string {
+	if overlap == "" {
+		overlap = task.ScheduleOverlapSkip
+	}
+	result := fmt.Sprintf("%s, overlap %s", schedule, overlap)
+	if jitter != "" {
+		result += fmt.Sprintf(", jitter up to %s", jitter)
+	}
+	return result
+}
+
+// formatScheduledRun formats the time of a scheduled run
+func formatScheduledRun(runTime string) string {
+	if runTime == "" {
+		return "-"
+	}
+	return formatDisplayTime(runTime)
+}
+
 // getStatusIndicator returns a simple ASCII indicator for the task status
 func getStatusIndicator(status string) string {
 	switch status {
@@ -128,6 +370,8 @@ func getStatusIndicator(status string) string {
 		return "START"
 	case "stopping":
 		return "STOP"
+	case "crash-loop":
+		return "LOOP"
 	default:
 		return "UNKN"
 	}
@@ -143,4 +387,23 @@ func getBoolIndicator(value bool) string {
 
 func init() {
 	rootCmd.AddCommand(infoCmd)
-}
\ No newline at end of file
+	
+	// The default view already shows every field, so there is no wide format
+	addOutputFlags(infoCmd, outputJSON, outputYAML, outputName)
+}
+// formatArgs formats arguments each in double quotes, separated by spaces
+func formatArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = fmt.Sprintf("\"%s\"", arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// printResolved prints the value a configured value resolves to below it, when they differ
+// indent is the width of the labels of the section
+func printResolved(indent int, configured, resolved string) {
+	if resolved != configured {
+		fmt.Printf("%s-> %s\n", strings.Repeat(" ", indent), resolved)
+	}
+}
@@ -3,7 +3,9 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"taskd/internal/task"
@@ -17,8 +19,12 @@ var listCmd = &cobra.Command{
 		running, _ := cmd.Flags().GetBool("running")
 		stopped, _ := cmd.Flags().GetBool("stopped")
 		verbose, _ := cmd.Flags().GetBool("verbose")
+		format, err := getOutputFormat(cmd)
+		if err != nil {
+			return err
+		}
 		
-		tasks, err := task.ListTasks()
+		tasks, err := task.NewDaemonClient().ListTasks()
 		if err != nil {
 			return fmt.Errorf("failed to get task list: %w", err)
 		}
@@ -26,6 +32,17 @@ var listCmd = &cobra.Command{
 		// Filter tasks based on flags
 		filteredTasks := filterTasks(tasks, running, stopped)
 		
+		// Machine readable output, printed even when no task matches
+		switch {
+		case isDocumentFormat(format):
+			return printDocument(cmd, os.Stdout, format, newTaskListDocument(filteredTasks))
+		case format == outputName:
+			for _, t := range filteredTasks {
+				fmt.Println(t.Name)
+			}
+			return nil
+		}
+		
 		if len(filteredTasks) == 0 {
 			displayNoTasksMessage(running, stopped)
 			return nil
@@ -34,6 +51,8 @@ var listCmd = &cobra.Command{
 		// Display tasks
 		if verbose {
 			displayTasksVerbose(filteredTasks)
+		} else if format == outputWide {
+			displayTasksWide(filteredTasks)
 		} else {
 			displayTasksCompact(filteredTasks)
 		}
@@ -51,6 +70,21 @@ func init() {
 	listCmd.Flags().BoolP("running", "r", false, "show only running tasks")
 	listCmd.Flags().BoolP("stopped", "s", false, "show only stopped tasks")
 	listCmd.Flags().BoolP("verbose", "v", false, "show detailed information")
+	addOutputFlags(listCmd, outputJSON, outputYAML, outputWide, outputName)
+}
+
+// taskListDocument the output of list -o json|yaml
+type taskListDocument struct {
+	documentHeader
+	Tasks []*task.TaskInfo `json:"tasks"`
+}
+
+// newTaskListDocument returns the list document of tasks
+func newTaskListDocument(tasks []*task.TaskInfo) *taskListDocument {
+	if tasks == nil {
+		tasks = []*task.TaskInfo{} // Always print a list
+	}
+	return &taskListDocument{documentHeader: newDocumentHeader("TaskList"), Tasks: tasks}
 }
 // filterTasks filters tasks based on running/stopped flags
 func filterTasks(tasks []*task.TaskInfo, running, stopped bool) []*task.TaskInfo {
@@ -60,15 +94,25 @@ func filterTasks(tasks []*task.TaskInfo, running, stopped bool) []*task.TaskInfo
 	
 	var filtered []*task.TaskInfo
 	for _, t := range tasks {
-		if running && t.Status == "running" {
+		if running && isActiveStatus(t.Status) {
 			filtered = append(filtered, t)
-		} else if stopped && t.Status != "running" {
+		} else if stopped && !isActiveStatus(t.Status) {
 			filtered = append(filtered, t)
 		}
 	}
 	return filtered
 }
 
+// hasScheduledTasks reports whether any of the tasks has a schedule
+func hasScheduledTasks(tasks []*task.TaskInfo) bool {
+	for _, t := range tasks {
+		if t.Schedule != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // displayNoTasksMessage shows appropriate message when no tasks match criteria
 func displayNoTasksMessage(running, stopped bool) {
 	if running {
@@ -88,18 +132,90 @@ func displayTasksCompact(tasks []*task.TaskInfo) {
 	fmt.Printf("Task List\n")
 	fmt.Printf("===============================================================\n")
 	
+	// The health and the run times of scheduled tasks are only shown when there are some
+	checked := hasHealthChecks(tasks)
+	scheduled := hasScheduledTasks(tasks)
+	
+	header := []string{"NAME", "STATUS"}
+	if checked {
+		header = append(header, "HEALTH")
+	}
+	header = append(header, "PID", "START TIME")
+	if scheduled {
+		header = append(header, "LAST RUN", "NEXT RUN")
+	}
+	header = append(header, "EXECUTABLE")
+	
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	fmt.Fprintln(w, "NAME\tSTATUS\tPID\tSTART TIME\tEXECUTABLE")
-	fmt.Fprintln(w, "----\t------\t---\t----------\t----------")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	fmt.Fprintln(w, strings.Join(underline(header), "\t"))
 	
 	for _, t := range tasks {
-		statusIndicator := getSimpleStatusIndicator(t.Status)
-		pidStr := formatPID(t.PID)
-		startTime := formatStartTime(t.StartTime)
-		executable := truncateString(t.Executable, 30)
-		
-		fmt.Fprintf(w, "%s\t[%s] %s\t%s\t%s\t%s\n",
-			t.Name, statusIndicator, t.Status, pidStr, startTime, executable)
+		row := []string{t.Name, fmt.Sprintf("[%s] %s", getSimpleStatusIndicator(t.Status), t.Status)}
+		if checked {
+			row = append(row, formatHealth(t.Health))
+		}
+		row = append(row, formatPID(t.PID), formatStartTime(t.StartTime))
+		if scheduled {
+			row = append(row, formatScheduledRun(t.LastScheduledRun), formatScheduledRun(t.NextScheduledRun))
+		}
+		row = append(row, truncateString(t.Executable, 30))
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	
+	w.Flush()
+}
+
+// underline returns the dashes under each column header
+func underline(header []string) []string {
+	dashes := make([]string, len(header))
+	for i, column := range header {
+		dashes[i] = strings.Repeat("-", len(column))
+	}
+	return dashes
+}
+
+// hasHealthChecks reports whether any of the tasks has a known health
+func hasHealthChecks(tasks []*task.TaskInfo) bool {
+	for _, t := range tasks {
+		if t.Health != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatHealth formats the health of a task, "-" for tasks without a liveness probe
+func formatHealth(health string) string {
+	if health == "" {
+		return "-"
+	}
+	return health
+}
+
+// displayTasksWide shows tasks in a table with every column and nothing truncated
+func displayTasksWide(tasks []*task.TaskInfo) {
+	fmt.Printf("Task List\n")
+	fmt.Printf("===============================================================\n")
+	
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tHEALTH\tPID\tSTART TIME\tEXIT CODE\tSCHEDULE\tLAST RUN\tNEXT RUN\tEXECUTABLE\tLAST ERROR")
+	fmt.Fprintln(w, "----\t------\t------\t---\t----------\t---------\t--------\t--------\t--------\t----------\t----------")
+	
+	for _, t := range tasks {
+		lastError := t.LastError
+		if lastError == "" {
+			lastError = "-"
+		}
+		schedule := t.Schedule
+		if schedule == "" {
+			schedule = "-"
+		}
+		fmt.Fprintf(w, "%s\t[%s] %s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			t.Name, getSimpleStatusIndicator(t.Status), t.Status, formatHealth(t.Health), formatPID(t.PID),
+			formatStartTime(t.StartTime), t.ExitCode, schedule,
+			formatScheduledRun(t.LastScheduledRun), formatScheduledRun(t.NextScheduledRun),
+			t.Executable, lastError)
 	}
 	
 	w.Flush()
@@ -118,19 +234,37 @@ func displayTasksVerbose(tasks []*task.TaskInfo) {
 		statusIndicator := getSimpleStatusIndicator(t.Status)
 		fmt.Printf("Name:       %s\n", t.Name)
 		fmt.Printf("Status:     [%s] %s\n", statusIndicator, t.Status)
+		if t.Health != "" {
+			fmt.Printf("Health:     %s\n", t.Health)
+		}
 		
 		if t.PID > 0 {
 			fmt.Printf("PID:        %d\n", t.PID)
 		}
 		
-		if t.StartTime != "" && t.StartTime != "0001-01-01 00:00:00" {
-			fmt.Printf("Started:    %s\n", t.StartTime)
+		if t.StartTime != "" {
+			fmt.Printf("Started:    %s\n", formatDisplayTime(t.StartTime))
+		}
+		
+		if t.Usage != nil {
+			fmt.Printf("Uptime:     %s\n", t.Usage.Uptime)
+			fmt.Printf("Usage:      %s\n", formatUsage(t.Usage))
 		}
 		
 		fmt.Printf("Executable: %s\n", t.Executable)
 		
+		if t.Schedule != "" {
+			fmt.Printf("Schedule:   %s\n", t.Schedule)
+			fmt.Printf("Last Run:   %s\n", formatScheduledRun(t.LastScheduledRun))
+			fmt.Printf("Next Run:   %s\n", formatScheduledRun(t.NextScheduledRun))
+		}
+		
 		// Try to get additional IO info if available
-		if ioInfo, err := getTaskIOInfo(t.Name); err == nil {
+		info, err := task.NewDaemonClient().GetTaskDetailInfo(t.Name)
+		if err != nil {
+			continue
+		}
+		if ioInfo := info.IOInfo; ioInfo != nil {
 			if ioInfo.StdinPath != "" || ioInfo.StdoutPath != "" || ioInfo.StderrPath != "" {
 				fmt.Printf("IO Setup:\n")
 				if ioInfo.StdinPath != "" {
@@ -144,6 +278,18 @@ func displayTasksVerbose(tasks []*task.TaskInfo) {
 				}
 			}
 		}
+		
+		// Environment from the env files and env list, secret values are masked
+		env := info.Env
+		if info.Resolved != nil {
+			env = info.Resolved.Env
+		}
+		if len(env) > 0 {
+			fmt.Printf("Env:\n")
+			for _, entry := range env {
+				fmt.Printf("  %s\n", entry)
+			}
+		}
 	}
 }
 
@@ -153,10 +299,13 @@ func displayTasksSummary(allTasks []*task.TaskInfo, displayedCount int) {
 	
 	runningCount := 0
 	stoppedCount := 0
+	crashLoopCount := 0
 	
 	for _, t := range allTasks {
-		if t.Status == "running" {
+		if isActiveStatus(t.Status) {
 			runningCount++
+		} else if t.Status == "crash-loop" {
+			crashLoopCount++
 		} else {
 			stoppedCount++
 		}
@@ -168,11 +317,17 @@ func displayTasksSummary(allTasks []*task.TaskInfo, displayedCount int) {
 		fmt.Printf("Total: %d tasks", len(allTasks))
 	}
 	
-	if runningCount > 0 || stoppedCount > 0 {
+	if crashLoopCount > 0 {
+		fmt.Printf(" (%d running, %d stopped, %d crash-looping)", runningCount, stoppedCount, crashLoopCount)
+	} else if runningCount > 0 || stoppedCount > 0 {
 		fmt.Printf(" (%d running, %d stopped)", runningCount, stoppedCount)
 	}
 	fmt.Printf("\n")
 	
+	if crashLoopCount > 0 {
+		fmt.Printf("Crash-looping tasks are not restarted automatically, use 'taskd start <task-name>' once fixed.\n")
+	}
+	
 	// Show helpful commands
 	if len(allTasks) > 0 {
 		fmt.Printf("Use 'taskd info <task-name>' for detailed information.\n")
@@ -181,6 +336,11 @@ func displayTasksSummary(allTasks []*task.TaskInfo, displayedCount int) {
 
 // Helper functions
 
+// isActiveStatus reports whether a task has a running process, ready or still starting
+func isActiveStatus(status string) bool {
+	return status == "running" || status == "starting"
+}
+
 func getSimpleStatusIndicator(status string) string {
 	switch status {
 	case "running":
@@ -191,6 +351,8 @@ func getSimpleStatusIndicator(status string) string {
 		return "START"
 	case "stopping":
 		return "STOP"
+	case "crash-loop":
+		return "LOOP"
 	default:
 		return "UNKN"
 	}
@@ -209,12 +371,34 @@ func formatPID(pid int) string {
 }
 
 func formatStartTime(startTime string) string {
-	if startTime == "" || startTime == "0001-01-01 00:00:00" {
+	if startTime == "" {
 		return "-"
 	}
-	// Try to parse and format the time
-	// For now, just return as-is, but could be improved
-	return startTime
+	return formatDisplayTime(startTime)
+}
+
+// formatDisplayTime formats an RFC 3339 time of the task info in local time
+func formatDisplayTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// formatUsage formats the current resource usage of a task on one line
+func formatUsage(usage *task.ResourceUsage) string {
+	parts := []string{
+		fmt.Sprintf("CPU %s (%s)", formatPercent(usage.CPUPercent), usage.CPUTime),
+		"RSS " + formatBytes(float64(usage.RSS)),
+	}
+	if usage.Threads > 0 {
+		parts = append(parts, fmt.Sprintf("threads %d", usage.Threads))
+	}
+	if usage.OpenFiles > 0 {
+		parts = append(parts, fmt.Sprintf("open files %d", usage.OpenFiles))
+	}
+	return strings.Join(parts, ", ")
 }
 
 func truncateString(s string, maxLen int) string {
@@ -223,10 +407,3 @@ func truncateString(s string, maxLen int) string {
 	}
 	return s[:maxLen-3] + "..."
 }
-
-// getTaskIOInfo tries to get IO information for a task
-func getTaskIOInfo(taskName string) (*task.TaskIOInfo, error) {
-	// This would need to be implemented in the task package
-	// For now, return empty info
-	return &task.TaskIOInfo{}, nil
-}
\ No newline at end of file
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"taskd/internal/task"
+)
+
+// logsPollInterval is how often followed log files are checked for new output
+const logsPollInterval = 250 * time.Millisecond
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [task-name]",
+	Short: "Show the output of a task",
+	Long: `Show the output a task wrote to its stdout and stderr redirect files.
+
+By default both streams are shown, interleaved by the timestamps found at the
+start of each line. Standard error lines are written to stderr.
+
+Examples:
+  # Show the whole output
+  taskd logs mytask
+
+  # Show the last 50 lines and keep following new output
+  taskd logs mytask -n 50 -f
+
+  # Show only standard error from the last hour
+  taskd logs mytask --stderr --since 1h
+
+  # Show output since a point in time
+  taskd logs mytask --since "2026-01-27 10:00:00"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLogsCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().BoolP("follow", "f", false, "keep following new output")
+	logsCmd.Flags().IntP("tail", "n", -1, "number of lines to show from the end (-1 shows all)")
+	logsCmd.Flags().Bool("stdout", false, "show only standard output")
+	logsCmd.Flags().Bool("stderr", false, "show only standard error")
+	logsCmd.Flags().String("since", "", "show output since a time (e.g. 2026-01-27 10:00:00, 2026-01-27T10:00:00Z) or a duration ago (e.g. 10m, 2h)")
+}
+
+// logSource a log file and the stream it holds
+type logSource struct {
+	stream string
+	path   string
+}
+
+func runLogsCommand(cmd *cobra.Command, args []string) error {
+	taskName := args[0]
+
+	follow, _ := cmd.Flags().GetBool("follow")
+	tail, _ := cmd.Flags().GetInt("tail")
+	showStdout, _ := cmd.Flags().GetBool("stdout")
+	showStderr, _ := cmd.Flags().GetBool("stderr")
+	sinceValue, _ := cmd.Flags().GetString("since")
+
+	var since time.Time
+	if sinceValue != "" {
+		var err error
+		if since, err = parseSince(sinceValue, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Neither or both flags show both streams
+	if !showStdout && !showStderr {
+		showStdout, showStderr = true, true
+	}
+
+	sources, err := taskLogSources(taskName, showStdout, showStderr)
+	if err != nil {
+		return err
+	}
+
+	followers := make([]*task.LogFollower, len(sources))
+	for i, source := range sources {
+		followers[i] = task.NewLogFollower(source.path, source.stream)
+		defer followers[i].Close()
+	}
+
+	// Show the existing output
+	var history []task.LogLine
+	for i, follower := range followers {
+		path := sources[i].path
+		lines, err := readExistingLogLines(follower, tail, !follow, func(lines []task.LogLine) []task.LogLine {
+			lines = selectLogStreams(lines, showStdout, showStderr)
+			if !since.IsZero() {
+				lines = task.FilterLogLinesSince(lines, since, logModTime(path))
+			}
+			return lines
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		history = task.MergeLogLines(history, lines)
+	}
+	printLogLines(task.TailLogLines(history, tail))
+
+	if !follow {
+		return nil
+	}
+
+	// Follow new output until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for i, follower := range followers {
+				lines, err := follower.Poll()
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", sources[i].path, err)
+				}
+				printLogLines(selectLogStreams(lines, showStdout, showStderr))
+			}
+		}
+	}
+}
+
+// readExistingLogLines reads the output already in a log file, keeping the lines
+// returned by selectLines. With a tail, only the end of the file is read: twice
+// as many lines each time the selected lines do not cover the tail yet. One more
+// line than the tail is needed, as the first line read may be the end of a
+// captured line split into several records.
+func readExistingLogLines(follower *task.LogFollower, tail int, flush bool, selectLines func([]task.LogLine) []task.LogLine) ([]task.LogLine, error) {
+	for n := tail + 1; ; n *= 2 {
+		atStart := true
+		if tail >= 0 {
+			var err error
+			if atStart, err = follower.SeekTail(n); err != nil {
+				return nil, err
+			}
+		}
+
+		lines, err := follower.Poll()
+		if err != nil {
+			return nil, err
+		}
+		if flush {
+			lines = append(lines, follower.Flush()...)
+		}
+		lines = selectLines(lines)
+		if atStart || len(lines) > tail {
+			return lines, nil
+		}
+	}
+}
+
+// taskLogSources returns the output files of the selected streams of a task
+// The paths come from the daemon, expanded and resolved as the running process uses them.
+func taskLogSources(taskName string, showStdout, showStderr bool) ([]logSource, error) {
+	// Builtin tasks do not have output files
+	if err := task.NewBuiltinTaskHandler().ValidateOperation(taskName, "logs"); err != nil {
+		return nil, err
+	}
+
+	info, err := task.NewDaemonClient().GetTaskDetailInfo(taskName)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := selectLogSources(info.IOInfo, showStdout, showStderr)
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("task '%s' has no output file for the selected streams, configure one with 'taskd edit %s --stdout <file>' or '--stderr <file>'", taskName, taskName)
+	}
+	return sources, nil
+}
+
+// selectLogSources returns the output files of the selected streams
+func selectLogSources(ioInfo *task.TaskIOInfo, showStdout, showStderr bool) []logSource {
+	if ioInfo == nil {
+		return nil
+	}
+
+	var sources []logSource
+	if showStdout && ioInfo.StdoutPath != "" {
+		sources = append(sources, logSource{stream: task.LogStreamStdout, path: ioInfo.StdoutPath})
+	}
+
+	if showStderr && ioInfo.StderrPath != "" {
+		// Both streams share one file, read it once
+		if len(sources) == 1 && sources[0].path == ioInfo.StderrPath {
+			return sources
+		}
+		sources = append(sources, logSource{stream: task.LogStreamStderr, path: ioInfo.StderrPath})
+	}
+
+	return sources
+}
+
+// selectLogStreams keeps the lines of the selected streams
+// Captured output records its stream per line, so one shared file can hold both.
+func selectLogStreams(lines []task.LogLine, showStdout, showStderr bool) []task.LogLine {
+	if showStdout && showStderr {
+		return lines
+	}
+	var selected []task.LogLine
+	for _, line := range lines {
+		if (line.Stream == task.LogStreamStdout && showStdout) || (line.Stream == task.LogStreamStderr && showStderr) {
+			selected = append(selected, line)
+		}
+	}
+	return selected
+}
+
+// parseSince parses the --since value as a duration ago or a point in time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("invalid --since value '%s': duration cannot be negative", value)
+		}
+		return now.Add(-d), nil
+	}
+
+	if t, ok := task.ParseLogTimestamp(strings.TrimSpace(value)); ok {
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --since value '%s': expected a duration (e.g. 10m) or a time (e.g. 2026-01-27 10:00:00)", value)
+}
+
+// logModTime returns the modification time of a log file, or the zero time if it cannot be read
+func logModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// printLogLines writes log lines, standard error lines go to stderr
+func printLogLines(lines []task.LogLine) {
+	for _, line := range lines {
+		if line.Stream == task.LogStreamStderr {
+			fmt.Fprintln(os.Stderr, line.Text)
+		} else {
+			fmt.Fprintln(os.Stdout, line.Text)
+		}
+	}
+}
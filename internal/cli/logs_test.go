@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"taskd/internal/task"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 1, 27, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"duration", "90m", now.Add(-90 * time.Minute), false},
+		{"date and time", "2026-01-27 10:00:00", time.Date(2026, 1, 27, 10, 0, 0, 0, time.Local), false},
+		{"rfc3339", "2026-01-27T10:00:00Z", time.Date(2026, 1, 27, 10, 0, 0, 0, time.UTC), false},
+		{"date only", "2026-01-27", time.Date(2026, 1, 27, 0, 0, 0, 0, time.Local), false},
+		{"negative duration", "-5m", time.Time{}, true},
+		{"garbage", "yesterday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSince(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSince(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSince(%q) = %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectLogSources(t *testing.T) {
+	tests := []struct {
+		name       string
+		ioInfo     *task.TaskIOInfo
+		showStdout bool
+		showStderr bool
+		want       []logSource
+	}{
+		{
+			name:       "both streams",
+			ioInfo:     &task.TaskIOInfo{StdoutPath: "/var/log/out.log", StderrPath: "/var/log/err.log"},
+			showStdout: true,
+			showStderr: true,
+			want: []logSource{
+				{stream: task.LogStreamStdout, path: "/var/log/out.log"},
+				{stream: task.LogStreamStderr, path: "/var/log/err.log"},
+			},
+		},
+		{
+			name:       "shared file is read once",
+			ioInfo:     &task.TaskIOInfo{StdoutPath: "/var/log/out.log", StderrPath: "/var/log/out.log", SameOutput: true},
+			showStdout: true,
+			showStderr: true,
+			want: []logSource{
+				{stream: task.LogStreamStdout, path: "/var/log/out.log"},
+			},
+		},
+		{
+			name:       "stderr only",
+			ioInfo:     &task.TaskIOInfo{StdoutPath: "/var/log/out.log", StderrPath: "/var/log/err.log"},
+			showStderr: true,
+			want: []logSource{
+				{stream: task.LogStreamStderr, path: "/var/log/err.log"},
+			},
+		},
+		{
+			name:       "no redirection",
+			ioInfo:     &task.TaskIOInfo{},
+			showStdout: true,
+			showStderr: true,
+		},
+		{
+			name:       "no IO information",
+			showStdout: true,
+			showStderr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectLogSources(tt.ioInfo, tt.showStdout, tt.showStderr)
+			if len(got) != len(tt.want) {
+				t.Fatalf("selectLogSources() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("source %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadExistingLogLinesTail(t *testing.T) {
+	// Both streams captured to one file, the last stderr line split in two records
+	path := filepath.Join(t.TempDir(), "out.log")
+	os.WriteFile(path, []byte(
+		"2026-01-27T10:30:00Z stderr F first error\n"+
+			"2026-01-27T10:30:01Z stdout F one\n"+
+			"2026-01-27T10:30:02Z stderr F second error\n"+
+			"2026-01-27T10:30:03Z stdout F two\n"+
+			"2026-01-27T10:30:04Z stdout F three\n"+
+			"2026-01-27T10:30:05Z stderr P third \n"+
+			"2026-01-27T10:30:06Z stdout F four\n"+
+			"2026-01-27T10:30:07Z stderr F error\n"), 0644)
+	stderrOnly := func(lines []task.LogLine) []task.LogLine {
+		return selectLogStreams(lines, false, true)
+	}
+
+	tests := []struct {
+		tail int
+		want []string
+	}{
+		{1, []string{"third error"}},
+		{2, []string{"second error", "third error"}},
+		{5, []string{"first error", "second error", "third error"}},
+		{-1, []string{"first error", "second error", "third error"}},
+	}
+
+	for _, tt := range tests {
+		follower := task.NewLogFollower(path, task.LogStreamStdout)
+		lines, err := readExistingLogLines(follower, tt.tail, true, stderrOnly)
+		follower.Close()
+		if err != nil {
+			t.Fatalf("readExistingLogLines() = %v", err)
+		}
+		var got []string
+		for _, line := range task.TailLogLines(lines, tt.tail) {
+			got = append(got, line.Text)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tail %d = %q, want %q", tt.tail, got, tt.want)
+		}
+	}
+}
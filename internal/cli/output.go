@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with -o/--output
+const (
+	outputDefault  = ""
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputWide     = "wide"
+	outputName     = "name"
+	outputTemplate = "template" // Selected by --template
+)
+
+// outputSchemaVersion is the version of the documents printed by -o json|yaml and --template
+// It only changes when a field is removed, renamed or changes meaning; new fields
+// may be added within a version.
+const outputSchemaVersion = 1
+
+// outputFormatsAnnotation lists the -o values a command supports
+const outputFormatsAnnotation = "taskd_output_formats"
+
+// documentHeader starts every machine readable document
+type documentHeader struct {
+	SchemaVersion int    `json:"schema_version"`
+	Kind          string `json:"kind"`
+}
+
+// newDocumentHeader returns the header of a document of the given kind
+func newDocumentHeader(kind string) documentHeader {
+	return documentHeader{SchemaVersion: outputSchemaVersion, Kind: kind}
+}
+
+// addOutputFlags adds -o/--output and --template to a command supporting the given formats
+func addOutputFlags(cmd *cobra.Command, formats ...string) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[outputFormatsAnnotation] = strings.Join(formats, ",")
+
+	cmd.Flags().StringP("output", "o", "", "output format: "+strings.Join(formats, "|"))
+	cmd.Flags().String("template", "", "print the JSON document through a Go template (e.g. '{{.schema_version}}')")
+}
+
+// getOutputFormat returns the output format selected on a command, or outputDefault
+func getOutputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	tmpl, _ := cmd.Flags().GetString("template")
+
+	if tmpl != "" {
+		if format != "" {
+			return "", fmt.Errorf("--template cannot be combined with --output %s", format)
+		}
+		return outputTemplate, nil
+	}
+	if format == "" {
+		return outputDefault, nil
+	}
+
+	supported := strings.Split(cmd.Annotations[outputFormatsAnnotation], ",")
+	for _, f := range supported {
+		if format == f {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --output value '%s': expected one of %s", format, strings.Join(supported, ", "))
+}
+
+// isDocumentFormat reports whether a format prints a document rather than text for humans
+func isDocumentFormat(format string) bool {
+	return format == outputJSON || format == outputYAML || format == outputTemplate
+}
+
+// printDocument writes a document in a document format
+func printDocument(cmd *cobra.Command, w io.Writer, format string, doc interface{}) error {
+	switch format {
+	case outputJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal output: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case outputYAML:
+		data, err := marshalYAML(doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal output: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case outputTemplate:
+		tmpl, _ := cmd.Flags().GetString("template")
+		return executeTemplate(w, tmpl, doc)
+	}
+	return fmt.Errorf("output format '%s' does not print a document", format)
+}
+
+// marshalYAML converts a document to YAML with the same keys and field order as its JSON form
+func marshalYAML(doc interface{}) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is YAML, parsing it keeps the field order that a map would lose
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	clearYAMLStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// clearYAMLStyle switches a node parsed from JSON to block style, quoting only where needed
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// executeTemplate prints a document through a Go template
+// The template sees the JSON form of the document, so it uses the same keys
+// as -o json, e.g. {{range .tasks}}{{.name}}{{end}}.
+func executeTemplate(w io.Writer, text string, doc interface{}) error {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid --template: %w", err)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal output: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("failed to decode output: %w", err)
+	}
+
+	if err := tmpl.Execute(w, value); err != nil {
+		return fmt.Errorf("failed to execute --template: %w", err)
+	}
+	return nil
+}
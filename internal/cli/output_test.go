@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"taskd/internal/task"
+)
+
+// newOutputTestCommand returns a command with the output flags set from args
+func newOutputTestCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "test"}
+	addOutputFlags(cmd, outputJSON, outputYAML, outputWide)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags(%v) = %v", args, err)
+	}
+	return cmd
+}
+
+func TestGetOutputFormat(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{nil, outputDefault, false},
+		{[]string{"-o", "json"}, outputJSON, false},
+		{[]string{"--output=yaml"}, outputYAML, false},
+		{[]string{"-o", "wide"}, outputWide, false},
+		{[]string{"-o", "name"}, "", true}, // Not supported by this command
+		{[]string{"-o", "xml"}, "", true},
+		{[]string{"--template", "{{.kind}}"}, outputTemplate, false},
+		{[]string{"--template", "{{.kind}}", "-o", "json"}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := getOutputFormat(newOutputTestCommand(t, tt.args...))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("getOutputFormat(%v) = %q, want error", tt.args, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("getOutputFormat(%v) = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestPrintDocument(t *testing.T) {
+	doc := newTaskListDocument([]*task.TaskInfo{
+		{Name: "web", Status: "running", PID: 42, StartTime: "2026-01-27T10:00:00Z", Executable: "123"},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"json", []string{"-o", "json"}, `{
+  "schema_version": 1,
+  "kind": "TaskList",
+  "tasks": [
+    {
+      "name": "web",
+      "status": "running",
+      "pid": 42,
+      "start_time": "2026-01-27T10:00:00Z",
+      "executable": "123",
+      "exit_code": 0,
+      "last_error": "",
+      "schedule": "",
+      "last_scheduled_run": "",
+      "next_scheduled_run": ""
+    }
+  ]
+}
+`},
+		// Same keys and order as JSON, strings that look like numbers stay strings
+		{"yaml", []string{"-o", "yaml"}, `schema_version: 1
+kind: TaskList
+tasks:
+  - name: web
+    status: running
+    pid: 42
+    start_time: "2026-01-27T10:00:00Z"
+    executable: "123"
+    exit_code: 0
+    last_error: ""
+    schedule: ""
+    last_scheduled_run: ""
+    next_scheduled_run: ""
+`},
+		{"template", []string{"--template", `{{.schema_version}} {{range .tasks}}{{.name}}={{.pid}}{{end}}`}, "1 web=42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newOutputTestCommand(t, tt.args...)
+			format, err := getOutputFormat(cmd)
+			if err != nil {
+				t.Fatalf("getOutputFormat() = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := printDocument(cmd, &buf, format, doc); err != nil {
+				t.Fatalf("printDocument() = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printDocument() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintDocumentEmptyList(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printDocument(nil, &buf, outputJSON, newTaskListDocument(nil)); err != nil {
+		t.Fatalf("printDocument() = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if tasks, ok := decoded["tasks"].([]interface{}); !ok || len(tasks) != 0 {
+		t.Errorf("tasks = %v, want an empty list", decoded["tasks"])
+	}
+}
+
+func TestExecuteTemplateErrors(t *testing.T) {
+	doc := newTaskListDocument(nil)
+	for _, text := range []string{"{{.kind", "{{.missing}}"} {
+		var buf bytes.Buffer
+		if err := executeTemplate(&buf, text, doc); err == nil || !strings.Contains(err.Error(), "--template") {
+			t.Errorf("executeTemplate(%q) = %v, want a --template error", text, err)
+		}
+	}
+}
+
+func TestInfoRejectsWideOutput(t *testing.T) {
+	for _, format := range strings.Split(infoCmd.Annotations[outputFormatsAnnotation], ",") {
+		if format == outputWide {
+			t.Errorf("info supports -o %s, which shows nothing more than the default view", outputWide)
+		}
+	}
+}
+
+func TestFormatDisplayTime(t *testing.T) {
+	at := time.Date(2026, 1, 27, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{at.Format(time.RFC3339), at.Local().Format("2006-01-02 15:04:05")},
+		{at.In(time.FixedZone("UTC+2", 2*3600)).Format(time.RFC3339), at.Local().Format("2006-01-02 15:04:05")},
+		{"not a time", "not a time"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDisplayTime(tt.value); got != tt.want {
+			t.Errorf("formatDisplayTime(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"taskd/internal/task"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage the secrets injected into task environments",
+	Long: `Manage secrets kept in an encrypted file in the TaskD home directory.
+
+A task gets a secret through an environment variable whose value is secret://NAME,
+in its env list or in one of its env files. The secret is read when the task
+starts, and its value is masked in info, list and JSON output.
+
+Examples:
+  # Store a secret, reading the value from standard input
+  printf '%s' "$DB_PASSWORD" | taskd secret set db-password
+
+  # Pass it to a task
+  taskd edit mytask --env "MYSQL_PWD=secret://db-password"`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set [name] [value]",
+	Short: "Store a secret, reading the value from standard input when it is not given",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read secret value: %w", err)
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+
+		if err := task.NewSecretStore().Set(name, value); err != nil {
+			return fmt.Errorf("failed to store secret: %w", err)
+		}
+		fmt.Printf("Secret '%s' stored\n", name)
+		return nil
+	},
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get [name]",
+	Short: "Print the value of a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := task.NewSecretStore().Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var secretRmCmd = &cobra.Command{
+	Use:   "rm [name]",
+	Short: "Delete a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := task.NewSecretStore().Remove(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Secret '%s' removed\n", args[0])
+		return nil
+	},
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the names of the secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := task.NewSecretStore().Names()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Println("No secrets stored")
+			return nil
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretSetCmd, secretGetCmd, secretRmCmd, secretListCmd)
+}
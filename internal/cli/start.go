@@ -10,15 +10,35 @@ import (
 var startCmd = &cobra.Command{
 	Use:   "start [task-name]",
 	Short: "Start a task",
+	Long: `Start a task.
+
+The tasks it requires and wants (requires and wants in its configuration) are
+started first if they are not running, in dependency order. The task is not
+started if a task it requires fails to start.
+
+A task with a readiness probe (readiness in its configuration) is "starting"
+until the probe succeeds. Tasks that depend on it are started once it is ready.
+With --wait the command returns once the task is ready, or fails with the error
+of the probe.
+
+Examples:
+  # Start a task and wait until it accepts connections
+  taskd start web --wait`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskName := args[0]
 		
-		manager := task.GetManager()
-		if err := manager.StartTask(taskName); err != nil {
+		wait, _ := cmd.Flags().GetBool("wait")
+		
+		client := task.NewDaemonClient()
+		if err := client.StartTaskWithOptions(taskName, task.StartOptions{Wait: wait}); err != nil {
 			return fmt.Errorf("failed to start task: %w", err)
 		}
 		
+		if wait {
+			fmt.Printf("Task '%s' started and ready\n", taskName)
+			return nil
+		}
 		fmt.Printf("Task '%s' started successfully\n", taskName)
 		return nil
 	},
@@ -27,12 +47,42 @@ var startCmd = &cobra.Command{
 var stopCmd = &cobra.Command{
 	Use:   "stop [task-name]",
 	Short: "Stop a task",
+	Long: `Stop a task.
+
+The task's stop signal (stop_signal, default SIGTERM) is sent first. If the task
+has not exited after its stop timeout (stop_timeout, default 10s) it is killed.
+
+A task that running tasks require cannot be stopped unless --cascade is given,
+which stops those tasks first.
+
+Examples:
+  # Give the task up to a minute to shut down
+  taskd stop mytask --timeout 1m
+  
+  # Kill the task immediately
+  taskd stop mytask --force
+  
+  # Stop the database and the tasks that require it
+  taskd stop db --cascade`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskName := args[0]
 		
-		manager := task.GetManager()
-		if err := manager.StopTask(taskName); err != nil {
+		force, _ := cmd.Flags().GetBool("force")
+		cascade, _ := cmd.Flags().GetBool("cascade")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if cmd.Flags().Changed("timeout") {
+			if timeout < 0 {
+				return fmt.Errorf("invalid timeout: cannot be negative")
+			}
+			if timeout == 0 {
+				force = true
+			}
+		}
+		
+		client := task.NewDaemonClient()
+		opts := task.StopOptions{Timeout: timeout, Force: force, Cascade: cascade}
+		if err := client.StopTaskWithOptions(taskName, opts); err != nil {
 			return fmt.Errorf("failed to stop task: %w", err)
 		}
 		
@@ -48,8 +98,8 @@ var restartCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		taskName := args[0]
 		
-		manager := task.GetManager()
-		if err := manager.RestartTask(taskName); err != nil {
+		client := task.NewDaemonClient()
+		if err := client.RestartTask(taskName); err != nil {
 			return fmt.Errorf("failed to restart task: %w", err)
 		}
 		
@@ -62,5 +112,10 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
 	rootCmd.AddCommand(restartCmd)
+	
+	startCmd.Flags().Bool("wait", false, "wait until the task has passed its readiness probe")
+	stopCmd.Flags().Duration("timeout", 0, "how long to wait for a graceful exit before killing the task (default: the task's stop_timeout)")
+	stopCmd.Flags().Bool("force", false, "kill the task immediately without sending the stop signal")
+	stopCmd.Flags().Bool("cascade", false, "stop the running tasks that require this task first")
 	// status command has been replaced by info command
 }
\ No newline at end of file
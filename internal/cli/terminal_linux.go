@@ -0,0 +1,11 @@
+//go:build linux
+
+package cli
+
+import "syscall"
+
+// Terminal attribute ioctls
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,82 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// Console modes
+const (
+	enableProcessedInput            = 0x0001 // ENABLE_PROCESSED_INPUT
+	enableLineInput                 = 0x0002 // ENABLE_LINE_INPUT
+	enableEchoInput                 = 0x0004 // ENABLE_ECHO_INPUT
+	enableVirtualTerminalInput      = 0x0200 // ENABLE_VIRTUAL_TERMINAL_INPUT
+	enableVirtualTerminalProcessing = 0x0004 // ENABLE_VIRTUAL_TERMINAL_PROCESSING
+)
+
+// consoleScreenBufferInfo is CONSOLE_SCREEN_BUFFER_INFO
+type consoleScreenBufferInfo struct {
+	Size              [2]int16
+	CursorPosition    [2]int16
+	Attributes        uint16
+	Window            [4]int16 // Left, Top, Right, Bottom
+	MaximumWindowSize [2]int16
+}
+
+// makeRaw puts the console in raw mode: keys are read one at a time, without
+// echo and without Ctrl-C raising an interrupt, as VT sequences like on Unix.
+// Output escape sequences are enabled as well. It returns a function that
+// restores the previous modes.
+func makeRaw() (func(), error) {
+	stdin := syscall.Handle(os.Stdin.Fd())
+	stdout := syscall.Handle(os.Stdout.Fd())
+
+	var inMode, outMode uint32
+	if err := syscall.GetConsoleMode(stdin, &inMode); err != nil {
+		return nil, err
+	}
+	if err := syscall.GetConsoleMode(stdout, &outMode); err != nil {
+		return nil, err
+	}
+
+	rawIn := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(stdin, rawIn); err != nil {
+		return nil, err
+	}
+	if err := setConsoleMode(stdout, outMode|enableVirtualTerminalProcessing); err != nil {
+		setConsoleMode(stdin, inMode)
+		return nil, err
+	}
+	return func() {
+		setConsoleMode(stdin, inMode)
+		setConsoleMode(stdout, outMode)
+	}, nil
+}
+
+// terminalSize returns the columns and rows of the console window
+func terminalSize() (int, int, error) {
+	var info consoleScreenBufferInfo
+	r, _, err := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, 0, err
+	}
+	return int(info.Window[2]-info.Window[0]) + 1, int(info.Window[3]-info.Window[1]) + 1, nil
+}
+
+// setConsoleMode sets the mode of a console input or output handle
+func setConsoleMode(handle syscall.Handle, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
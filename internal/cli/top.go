@@ -0,0 +1,570 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"taskd/internal/task"
+)
+
+// topLogLines is the number of output lines kept for the output pane
+const topLogLines = 200
+
+// Escape sequences of the top screen
+const (
+	topEnterScreen  = "\x1b[?1049h\x1b[?25l" // Alternate screen, hidden cursor
+	topLeaveScreen  = "\x1b[?25h\x1b[?1049l"
+	topHome         = "\x1b[H"
+	topClearLine    = "\x1b[K"
+	topClearBelow   = "\x1b[J"
+	topReverseVideo = "\x1b[7m"
+	topResetStyle   = "\x1b[0m"
+)
+
+// Keys of the top screen that are not printable characters
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyEnter     = "enter"
+	keyEscape    = "esc"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show a live view of the tasks and their resource usage",
+	Long: `Show the tasks with their status, PID, uptime, restarts, CPU and memory usage,
+refreshed every second until q is pressed.
+
+The CPU and memory usage is sampled by the daemon on each of its checks (see
+Resource Usage in 'taskd info'), tasks started less than a check ago show none yet.
+
+Keys:
+  Up/Down, k/j   select a task
+  s              start the selected task
+  x              stop the selected task
+  r              restart the selected task
+  l              show or hide the output of the selected task
+  /              filter the tasks by name, status or command, Enter to keep the filter
+  Esc            clear the filter
+  q, Ctrl-C      quit
+
+Examples:
+  # Refresh every 5 seconds
+  taskd top --interval 5s`,
+	Args: cobra.NoArgs,
+	RunE: runTopCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+
+	topCmd.Flags().Duration("interval", time.Second, "time between refreshes")
+}
+
+func runTopCommand(cmd *cobra.Command, args []string) error {
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval: must be positive")
+	}
+
+	restore, err := makeRaw()
+	if err != nil {
+		return fmt.Errorf("taskd top needs an interactive terminal: %w", err)
+	}
+	defer restore()
+	fmt.Print(topEnterScreen)
+	defer fmt.Print(topLeaveScreen)
+
+	client := task.NewDaemonClient()
+	view := &topView{}
+	defer view.closeLogs()
+
+	keys := make(chan string, 16)
+	go readKeys(os.Stdin, keys)
+	results := make(chan string, 4)
+
+	draw := func() {
+		if width, height, err := terminalSize(); err == nil {
+			view.width, view.height = width, height
+		}
+		fmt.Print(view.render(time.Now()))
+	}
+	refresh := func() {
+		tasks, err := client.ListTasks()
+		view.update(tasks, err)
+		view.pollLogs()
+		draw()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	refresh()
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			quit, action := view.handleKey(key)
+			if quit {
+				return nil
+			}
+			if action != nil {
+				// Stopping a task waits for its stop timeout, the view keeps refreshing meanwhile
+				view.message = fmt.Sprintf("%s '%s'...", action.progress, action.task)
+				go func(action *topAction) {
+					results <- action.run(client)
+				}(action)
+			}
+			draw()
+		case message := <-results:
+			view.message = message
+			refresh()
+		}
+	}
+}
+
+// topAction a task command started from the top screen
+type topAction struct {
+	verb     string // start, stop or restart
+	progress string // Shown while the command runs, e.g. "Stopping"
+	done     string // Shown when it succeeded, e.g. "stopped"
+	task     string
+}
+
+// topActions the commands of the top screen by key
+var topActions = map[string]topAction{
+	"s": {verb: "start", progress: "Starting", done: "started"},
+	"x": {verb: "stop", progress: "Stopping", done: "stopped"},
+	"r": {verb: "restart", progress: "Restarting", done: "restarted"},
+}
+
+// run sends the command to the daemon and returns the message describing its result
+func (a *topAction) run(client *task.DaemonClient) string {
+	var err error
+	switch a.verb {
+	case "start":
+		err = client.StartTask(a.task)
+	case "stop":
+		err = client.StopTask(a.task)
+	case "restart":
+		err = client.RestartTask(a.task)
+	}
+	if err != nil {
+		return fmt.Sprintf("Failed to %s '%s': %v", a.verb, a.task, err)
+	}
+	return fmt.Sprintf("Task '%s' %s", a.task, a.done)
+}
+
+// topView the state of the top screen
+type topView struct {
+	tasks    []*task.TaskInfo // Tasks of the last refresh, the daemon first and then by name
+	err      error            // Error of the last refresh
+	selected string           // Name of the selected task, kept across refreshes
+	filter   string
+	editing  bool   // The filter is being typed
+	message  string // Result of the last command
+
+	logTask      string // Task whose output is shown, empty when the output pane is hidden
+	logFollowers []*task.LogFollower
+	logLines     []task.LogLine
+	logErr       error
+
+	width, height int
+}
+
+// update replaces the tasks with those of a refresh
+func (v *topView) update(tasks []*task.TaskInfo, err error) {
+	v.err = err
+	if err != nil {
+		return
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if (tasks[i].Name == "taskd") != (tasks[j].Name == "taskd") {
+			return tasks[i].Name == "taskd"
+		}
+		return tasks[i].Name < tasks[j].Name
+	})
+	v.tasks = tasks
+}
+
+// visible returns the tasks matching the filter, by name, status or command
+func (v *topView) visible() []*task.TaskInfo {
+	if v.filter == "" {
+		return v.tasks
+	}
+	filter := strings.ToLower(v.filter)
+	var tasks []*task.TaskInfo
+	for _, t := range v.tasks {
+		if strings.Contains(strings.ToLower(t.Name), filter) ||
+			strings.Contains(strings.ToLower(t.Status), filter) ||
+			strings.Contains(strings.ToLower(t.Executable), filter) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// selectedIndex returns the index of the selected task in the visible tasks
+// The first task is selected when the selected task is not visible.
+func (v *topView) selectedIndex(tasks []*task.TaskInfo) int {
+	for i, t := range tasks {
+		if t.Name == v.selected {
+			return i
+		}
+	}
+	return 0
+}
+
+// selectedTask returns the selected task, nil when no task is visible
+func (v *topView) selectedTask() *task.TaskInfo {
+	tasks := v.visible()
+	if len(tasks) == 0 {
+		return nil
+	}
+	return tasks[v.selectedIndex(tasks)]
+}
+
+// moveSelection selects the visible task delta rows below the selected one
+func (v *topView) moveSelection(delta int) {
+	tasks := v.visible()
+	if len(tasks) == 0 {
+		return
+	}
+	i := v.selectedIndex(tasks) + delta
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(tasks) {
+		i = len(tasks) - 1
+	}
+	v.selected = tasks[i].Name
+}
+
+// handleKey applies a key to the view
+// It reports whether the screen should close, and returns the command to run for the selected task.
+func (v *topView) handleKey(key string) (bool, *topAction) {
+	if v.editing {
+		switch key {
+		case keyEnter:
+			v.editing = false
+		case keyEscape:
+			v.editing = false
+			v.filter = ""
+		case keyBackspace:
+			if _, size := utf8.DecodeLastRuneInString(v.filter); size > 0 {
+				v.filter = v.filter[:len(v.filter)-size]
+			}
+		case keyCtrlC:
+			return true, nil
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				v.filter += key
+			}
+		}
+		return false, nil
+	}
+
+	switch key {
+	case "q", keyCtrlC:
+		return true, nil
+	case keyUp, "k":
+		v.moveSelection(-1)
+	case keyDown, "j":
+		v.moveSelection(1)
+	case "/":
+		v.editing = true
+	case keyEscape:
+		v.filter = ""
+	case "l":
+		v.toggleLogs()
+	case "s", "x", "r":
+		t := v.selectedTask()
+		if t == nil {
+			return false, nil
+		}
+		action := topActions[key]
+		action.task = t.Name
+		return false, &action
+	}
+	return false, nil
+}
+
+// toggleLogs shows the output of the selected task, or hides the output pane
+func (v *topView) toggleLogs() {
+	t := v.selectedTask()
+	showing := v.logTask
+	v.closeLogs()
+	if t == nil || t.Name == showing {
+		return
+	}
+
+	v.logTask = t.Name
+	sources, err := taskLogSources(t.Name, true, true)
+	if err != nil {
+		v.logErr = err
+		return
+	}
+	for _, source := range sources {
+		v.logFollowers = append(v.logFollowers, task.NewLogFollower(source.path, source.stream))
+	}
+	v.pollLogs()
+}
+
+// pollLogs reads the new output of the task shown in the output pane
+func (v *topView) pollLogs() {
+	for _, follower := range v.logFollowers {
+		lines, err := follower.Poll()
+		if err != nil {
+			v.logErr = err
+			continue
+		}
+		v.logLines = task.TailLogLines(task.MergeLogLines(v.logLines, lines), topLogLines)
+	}
+}
+
+// closeLogs hides the output pane
+func (v *topView) closeLogs() {
+	for _, follower := range v.logFollowers {
+		follower.Close()
+	}
+	v.logTask, v.logFollowers, v.logLines, v.logErr = "", nil, nil, nil
+}
+
+// render returns the escape sequences and text that draw the screen
+func (v *topView) render(now time.Time) string {
+	width, height := v.width, v.height
+	if width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("taskd top - %s   %s", now.Format("15:04:05"), topSummary(v.tasks)))
+	lines = append(lines, "Up/Down select, s start, x stop, r restart, l output, / filter, q quit")
+	switch {
+	case v.editing:
+		lines = append(lines, "Filter: "+v.filter+"_")
+	case v.filter != "":
+		lines = append(lines, fmt.Sprintf("Filter: %s (Esc to clear)", v.filter))
+	case v.err != nil:
+		lines = append(lines, fmt.Sprintf("Failed to get task list: %v", v.err))
+	default:
+		lines = append(lines, v.message)
+	}
+	lines = append(lines, "")
+
+	// The output pane takes the lower half of the screen
+	tableRows := height - len(lines) - 1
+	if v.logTask != "" {
+		tableRows = (height - len(lines)) / 2
+	}
+
+	header := fmt.Sprintf("%-16s %-17s %7s %7s %8s %6s %10s  %s", "NAME", "STATUS", "PID", "UPTIME", "RESTARTS", "CPU%", "MEM", "COMMAND")
+	lines = append(lines, padLine(header, width))
+
+	tasks := v.visible()
+	selected := v.selectedIndex(tasks)
+	offset := 0
+	if selected >= tableRows {
+		offset = selected - tableRows + 1
+	}
+	for i := offset; i < len(tasks) && i < offset+tableRows; i++ {
+		row := padLine(formatTopRow(tasks[i], now), width)
+		if i == selected {
+			row = topReverseVideo + row + topResetStyle
+		}
+		lines = append(lines, row)
+	}
+	if len(tasks) == 0 && len(v.tasks) > 0 {
+		lines = append(lines, "No task matches the filter")
+	}
+
+	if v.logTask != "" {
+		lines = append(lines, "", padLine(fmt.Sprintf("--- Output of %s (l to hide) ", v.logTask), width))
+		if v.logErr != nil {
+			lines = append(lines, v.logErr.Error())
+		}
+		logRows := height - len(lines)
+		logLines := v.logLines
+		if len(logLines) > logRows && logRows >= 0 {
+			logLines = logLines[len(logLines)-logRows:]
+		}
+		for _, line := range logLines {
+			lines = append(lines, strings.ReplaceAll(line.Text, "\t", "    "))
+		}
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	var b strings.Builder
+	b.WriteString(topHome)
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(truncateLine(line, width))
+		b.WriteString(topClearLine)
+	}
+	b.WriteString(topClearBelow)
+	return b.String()
+}
+
+// topSummary returns the task counts of the top screen
+func topSummary(tasks []*task.TaskInfo) string {
+	running, crashLooping := 0, 0
+	for _, t := range tasks {
+		if isActiveStatus(t.Status) {
+			running++
+		} else if t.Status == "crash-loop" {
+			crashLooping++
+		}
+	}
+	summary := fmt.Sprintf("Tasks: %d total, %d running, %d stopped", len(tasks), running, len(tasks)-running-crashLooping)
+	if crashLooping > 0 {
+		summary += fmt.Sprintf(", %d crash-looping", crashLooping)
+	}
+	return summary
+}
+
+// formatTopRow formats the row of a task
+func formatTopRow(t *task.TaskInfo, now time.Time) string {
+	cpu, mem := "-", "-"
+	if t.Usage != nil {
+		cpu = fmt.Sprintf("%.1f", t.Usage.CPUPercent)
+		mem = formatBytes(float64(t.Usage.RSS))
+	}
+	status := fmt.Sprintf("[%s] %s", getSimpleStatusIndicator(t.Status), t.Status)
+	return fmt.Sprintf("%-16s %-17s %7s %7s %8d %6s %10s  %s",
+		truncateString(t.Name, 16), status, formatPID(t.PID), formatUptime(t, now), t.RetryNum, cpu, mem, t.Executable)
+}
+
+// formatUptime formats how long a running task has been up, "-" for other tasks
+func formatUptime(t *task.TaskInfo, now time.Time) string {
+	if !isActiveStatus(t.Status) {
+		return "-"
+	}
+	start, err := time.Parse(time.RFC3339, t.StartTime)
+	if err != nil {
+		return "-"
+	}
+	d := now.Sub(start)
+	if d < 0 {
+		d = 0
+	}
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%02dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+}
+
+// padLine pads a line with spaces to the width of the screen, for the selected row highlight
+func padLine(line string, width int) string {
+	if n := utf8.RuneCountInString(line); n < width {
+		return line + strings.Repeat(" ", width-n)
+	}
+	return line
+}
+
+// truncateLine cuts a line at the width of the screen, escape sequences do not count
+func truncateLine(line string, width int) string {
+	var b strings.Builder
+	columns := 0
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			// Copy the escape sequence up to its final letter
+			j := i + 1
+			for j < len(line) && !(line[j] >= 'A' && line[j] <= 'Z' || line[j] >= 'a' && line[j] <= 'z') {
+				j++
+			}
+			if j < len(line) {
+				j++
+			}
+			b.WriteString(line[i:j])
+			i = j
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if columns < width && r >= ' ' {
+			b.WriteRune(r)
+			columns++
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// readKeys sends the keys read from r until it fails
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseKeys splits the bytes read from a raw terminal into keys
+// Arrow keys arrive as "ESC [ A" or "ESC O A", other escape sequences are skipped.
+func parseKeys(data []byte) []string {
+	var keys []string
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == 0x1b:
+			if i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') {
+				j := i + 2
+				for j < len(data) && (data[j] < 0x40 || data[j] > 0x7e) {
+					j++
+				}
+				if j < len(data) {
+					switch data[j] {
+					case 'A':
+						keys = append(keys, keyUp)
+					case 'B':
+						keys = append(keys, keyDown)
+					}
+				}
+				i = j + 1
+				continue
+			}
+			keys = append(keys, keyEscape)
+			i++
+		case c == '\r' || c == '\n':
+			keys = append(keys, keyEnter)
+			i++
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, keyBackspace)
+			i++
+		case c == 0x03:
+			keys = append(keys, keyCtrlC)
+			i++
+		default:
+			r, size := utf8.DecodeRune(data[i:])
+			if r >= ' ' && r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			i += size
+		}
+	}
+	return keys
+}
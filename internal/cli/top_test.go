@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"taskd/internal/task"
+)
+
+// newTopTestView returns a view of a few tasks
+func newTopTestView() *topView {
+	v := &topView{width: 120, height: 20}
+	v.update([]*task.TaskInfo{
+		{Name: "worker", Status: "stopped", Executable: "python worker.py"},
+		{Name: "web", Status: "running", PID: 4242, StartTime: "2026-10-16T10:00:00Z", Executable: "nginx",
+			RetryNum: 2, Usage: &task.ResourceUsage{CPUPercent: 12.5, RSS: 64 << 20}},
+		{Name: "taskd", Status: "running", PID: 100, Executable: "taskd --daemon"},
+	}, nil)
+	return v
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"letters", "sq", []string{"s", "q"}},
+		{"arrows", "\x1b[A\x1b[B\x1bOA", []string{keyUp, keyDown, keyUp}},
+		{"other escape sequence", "\x1b[1;5Cx", []string{"x"}},
+		{"escape", "\x1b", []string{keyEscape}},
+		{"control keys", "\r\x7f\x03", []string{keyEnter, keyBackspace, keyCtrlC}},
+		{"utf-8", "é", []string{"é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeys([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseKeys(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopViewSelection(t *testing.T) {
+	v := newTopTestView()
+
+	// The daemon comes first, then the tasks by name
+	var names []string
+	for _, t := range v.tasks {
+		names = append(names, t.Name)
+	}
+	if want := []string{"taskd", "web", "worker"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("tasks = %v, want %v", names, want)
+	}
+
+	v.handleKey(keyDown)
+	v.handleKey("j")
+	v.handleKey("j") // Stays on the last task
+	if v.selected != "worker" {
+		t.Errorf("selected = %q after moving down, want worker", v.selected)
+	}
+	v.handleKey(keyUp)
+	if v.selected != "web" {
+		t.Errorf("selected = %q after moving up, want web", v.selected)
+	}
+
+	quit, action := v.handleKey("x")
+	if quit || action == nil || action.verb != "stop" || action.task != "web" {
+		t.Errorf("handleKey(x) = %v, %+v, want a stop of web", quit, action)
+	}
+	if quit, _ := v.handleKey("q"); !quit {
+		t.Error("handleKey(q) did not quit")
+	}
+}
+
+func TestTopViewFilter(t *testing.T) {
+	v := newTopTestView()
+
+	for _, key := range []string{"/", "w", "o", "r", "x", keyBackspace, keyEnter} {
+		if quit, action := v.handleKey(key); quit || action != nil {
+			t.Fatalf("handleKey(%q) while typing the filter = %v, %+v", key, quit, action)
+		}
+	}
+	if v.filter != "wor" || v.editing {
+		t.Fatalf("filter = %q (editing %v), want wor", v.filter, v.editing)
+	}
+	if tasks := v.visible(); len(tasks) != 1 || tasks[0].Name != "worker" {
+		t.Errorf("visible() = %d tasks, want worker only", len(tasks))
+	}
+
+	// The filter matches the status too
+	v.filter = "RUNNING"
+	if tasks := v.visible(); len(tasks) != 2 {
+		t.Errorf("visible() with status filter = %d tasks, want 2", len(tasks))
+	}
+
+	v.handleKey(keyEscape)
+	if v.filter != "" || len(v.visible()) != 3 {
+		t.Errorf("filter = %q after Esc, want it cleared", v.filter)
+	}
+}
+
+func TestTopViewRender(t *testing.T) {
+	v := newTopTestView()
+	v.selected = "web"
+	now := time.Date(2026, 10, 16, 11, 2, 3, 0, time.Local)
+
+	screen := v.render(now)
+	if !strings.Contains(screen, "Tasks: 3 total, 2 running, 1 stopped") {
+		t.Errorf("render() has no task summary:\n%s", screen)
+	}
+
+	var webRow string
+	for _, line := range strings.Split(screen, "\r\n") {
+		if strings.Contains(line, "nginx") {
+			webRow = line
+		}
+	}
+	for _, want := range []string{topReverseVideo, "[RUN] running", "4242", "1h02m", "12.5", "64.0 MiB"} {
+		if !strings.Contains(webRow, want) {
+			t.Errorf("row of web %q does not contain %q", webRow, want)
+		}
+	}
+	if !strings.Contains(webRow, "       2 ") {
+		t.Errorf("row of web %q does not show its 2 restarts", webRow)
+	}
+
+	// Lines are cut at the width of the screen
+	v.width = 30
+	for _, line := range strings.Split(v.render(now), "\r\n") {
+		plain := strings.NewReplacer(topReverseVideo, "", topResetStyle, "", topClearLine, "", topHome, "", topClearBelow, "").Replace(line)
+		if len([]rune(plain)) > 30 {
+			t.Errorf("line %q is wider than the screen", plain)
+		}
+	}
+}
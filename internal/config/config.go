@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/spf13/viper"
 )
 
@@ -14,13 +16,21 @@ var (
 
 // GlobalConfig global configuration
 type GlobalConfig struct {
-	LogLevel    string `mapstructure:"log_level"`
-	LogFile     string `mapstructure:"log_file"`
-	PidFile     string `mapstructure:"pid_file"`
-	AutoStart   bool   `mapstructure:"auto_start"`
-	MaxTasks    int    `mapstructure:"max_tasks"`
+	LogLevel     string `mapstructure:"log_level"`
+	LogFile      string `mapstructure:"log_file"`
+	PidFile      string `mapstructure:"pid_file"`
+	AutoStart    bool   `mapstructure:"auto_start"`
+	MaxTasks     int    `mapstructure:"max_tasks"`
+	StateBackend string `mapstructure:"state_backend"`
+	MetricsPort  int    `mapstructure:"metrics_port"`
 }
 
+// Runtime state backends
+const (
+	StateBackendJSON = "json" // runtime.json, the default
+	StateBackendBolt = "bolt" // state.db, an embedded bbolt database that also keeps the run history
+)
+
 // InitConfig initialize configuration
 func InitConfig() {
 	if ConfigFile != "" {
@@ -59,6 +69,40 @@ func setDefaults() {
 	viper.SetDefault("pid_file", "")
 	viper.SetDefault("auto_start", false)
 	viper.SetDefault("max_tasks", 100)
+	viper.SetDefault("state_backend", StateBackendJSON)
+	viper.SetDefault("metrics_port", 0)
+}
+
+// GetStateBackend returns the runtime state backend selected by state_backend in the global configuration
+// The file is read directly because the daemon does not load the configuration through viper.
+func GetStateBackend() string {
+	var config struct {
+		StateBackend string `toml:"state_backend"`
+	}
+	if _, err := toml.DecodeFile(globalConfigPath(), &config); err != nil || config.StateBackend == "" {
+		return StateBackendJSON
+	}
+	return strings.ToLower(strings.TrimSpace(config.StateBackend))
+}
+
+// GetMetricsPort returns the localhost port of the daemon's metrics endpoint, 0 when it is disabled
+// The file is read directly, as for GetStateBackend.
+func GetMetricsPort() int {
+	var config struct {
+		MetricsPort int `toml:"metrics_port"`
+	}
+	if _, err := toml.DecodeFile(globalConfigPath(), &config); err != nil || config.MetricsPort < 0 {
+		return 0
+	}
+	return config.MetricsPort
+}
+
+// globalConfigPath returns the path of the global configuration file
+func globalConfigPath() string {
+	if ConfigFile != "" {
+		return ConfigFile
+	}
+	return filepath.Join(GetTaskDConfigDir(), "config.toml")
 }
 
 func createDefaultConfig() {
@@ -81,6 +125,12 @@ auto_start = false
 
 # Maximum number of tasks
 max_tasks = 100
+
+# Runtime state backend: "json" (runtime.json) or "bolt" (state.db, keeps the run history)
+state_backend = "json"
+
+# Port of the daemon's Prometheus metrics endpoint on 127.0.0.1 (0 disables it)
+metrics_port = 0
 `
 	
 	os.WriteFile(configPath, []byte(defaultConfig), 0644)
@@ -51,4 +51,28 @@ func GetTaskDTasksDir() string {
 // GetTaskDRuntimeFile returns the runtime state file path
 func GetTaskDRuntimeFile() string {
 	return filepath.Join(GetTaskDHome(), "runtime.json")
-}
\ No newline at end of file
+}
+
+// GetTaskDStateDBFile returns the path of the runtime state database of the bolt backend
+func GetTaskDStateDBFile() string {
+	return filepath.Join(GetTaskDHome(), "state.db")
+}
+
+// GetTaskDHistoryDir returns the directory holding the run history of each task
+func GetTaskDHistoryDir() string {
+	return filepath.Join(GetTaskDHome(), "history")
+}
+
+// GetTaskDSocketFile returns the path of the daemon's IPC socket
+func GetTaskDSocketFile() string {
+	return filepath.Join(GetTaskDHome(), "taskd.sock")
+}
+// GetTaskDSecretsFile returns the path of the encrypted secrets file
+func GetTaskDSecretsFile() string {
+	return filepath.Join(GetTaskDHome(), "secrets.enc")
+}
+
+// GetTaskDSecretsKeyFile returns the path of the key that encrypts the secrets file
+func GetTaskDSecretsKeyFile() string {
+	return filepath.Join(GetTaskDHome(), "secrets.key")
+}
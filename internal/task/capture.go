@@ -0,0 +1,201 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capture formats for task output
+const (
+	CaptureText = "text" // "<RFC3339 time> <stream> <F|P> <line>"
+	CaptureJSON = "json" // One JSON object per line
+)
+
+// defaultMaxLineSize is the longest line written as a single record
+const defaultMaxLineSize = 16 * 1024
+
+// Line tags of the text capture format
+const (
+	captureTagFull    = "F" // The record ends a line
+	captureTagPartial = "P" // The line continues in the next record
+)
+
+// ValidateCaptureFormat checks that format is a known capture format (empty disables capture)
+func ValidateCaptureFormat(format string) error {
+	switch format {
+	case "", CaptureText, CaptureJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown capture format '%s' (expected %s or %s)", format, CaptureText, CaptureJSON)
+	}
+}
+
+// CaptureRecord a captured line of task output, as written in json format
+type CaptureRecord struct {
+	Time    time.Time `json:"time"`
+	Stream  string    `json:"stream"`
+	Line    string    `json:"line"`
+	Partial bool      `json:"partial,omitempty"` // The line continues in the next record
+}
+
+// CaptureWriter splits a task output stream into lines and writes each line
+// as a timestamped record tagged with the stream name
+type CaptureWriter struct {
+	mu          *sync.Mutex // Shared by the writers of one output file
+	out         io.Writer
+	stream      string
+	format      string
+	maxLineSize int
+	buf         []byte
+	lineTime    time.Time // Time the first byte of the buffered line was written
+	now         func() time.Time
+}
+
+// NewCaptureWriter creates a capture writer for stream, writing records to out
+// Writers sharing an output file must share mu, so records are not interleaved.
+func NewCaptureWriter(out io.Writer, stream string, logConfig LogConfig, mu *sync.Mutex) *CaptureWriter {
+	maxLineSize := logConfig.MaxLineSize
+	if maxLineSize <= 0 {
+		maxLineSize = defaultMaxLineSize
+	}
+	if mu == nil {
+		mu = &sync.Mutex{}
+	}
+	return &CaptureWriter{
+		mu:          mu,
+		out:         out,
+		stream:      stream,
+		format:      logConfig.Capture,
+		maxLineSize: maxLineSize,
+		now:         time.Now,
+	}
+}
+
+// Write buffers p and writes a record for every complete line
+// Lines longer than the maximum line size are split into partial records.
+func (w *CaptureWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		if len(w.buf) == 0 {
+			w.lineTime = w.now()
+		}
+
+		i := bytes.IndexByte(data, '\n')
+		room := w.maxLineSize - len(w.buf)
+
+		if i >= 0 && i <= room {
+			w.buf = append(w.buf, data[:i]...)
+			data = data[i+1:]
+			if err := w.writeRecord(false); err != nil {
+				return len(p) - len(data), err
+			}
+			continue
+		}
+
+		if i < 0 && len(data) <= room {
+			w.buf = append(w.buf, data...)
+			break
+		}
+
+		// The line does not fit, write what fits as a partial record
+		w.buf = append(w.buf, data[:room]...)
+		data = data[room:]
+		if err := w.writeRecord(true); err != nil {
+			return len(p) - len(data), err
+		}
+	}
+	return len(p), nil
+}
+
+// Close writes the buffered unterminated line as a full record
+// The line ends with the run, so it must not be joined with the first line of
+// the next run. The underlying writer is not closed, it is owned by the TaskIO.
+func (w *CaptureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	return w.writeRecord(false)
+}
+
+// writeRecord writes the buffered line as one record and resets the buffer
+func (w *CaptureWriter) writeRecord(partial bool) error {
+	line := strings.TrimSuffix(string(w.buf), "\r")
+	w.buf = w.buf[:0]
+
+	record, err := formatCaptureRecord(w.format, CaptureRecord{
+		Time:    w.lineTime,
+		Stream:  w.stream,
+		Line:    line,
+		Partial: partial,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(record)
+	return err
+}
+
+// formatCaptureRecord encodes a record as a single line in the given format
+func formatCaptureRecord(format string, record CaptureRecord) ([]byte, error) {
+	if format == CaptureJSON {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode output record: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+
+	tag := captureTagFull
+	if record.Partial {
+		tag = captureTagPartial
+	}
+	return []byte(fmt.Sprintf("%s %s %s %s\n",
+		record.Time.Format(time.RFC3339Nano), record.Stream, tag, record.Line)), nil
+}
+
+// ParseCaptureRecord decodes a line written in text or json capture format
+func ParseCaptureRecord(line string) (CaptureRecord, bool) {
+	if strings.HasPrefix(line, "{") {
+		var record CaptureRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil || record.Stream == "" || record.Time.IsZero() {
+			return CaptureRecord{}, false
+		}
+		return record, true
+	}
+
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return CaptureRecord{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return CaptureRecord{}, false
+	}
+	if fields[1] != LogStreamStdout && fields[1] != LogStreamStderr {
+		return CaptureRecord{}, false
+	}
+	if fields[2] != captureTagFull && fields[2] != captureTagPartial {
+		return CaptureRecord{}, false
+	}
+
+	record := CaptureRecord{
+		Time:    t,
+		Stream:  fields[1],
+		Partial: fields[2] == captureTagPartial,
+	}
+	if len(fields) == 4 {
+		record.Line = fields[3]
+	}
+	return record, true
+}
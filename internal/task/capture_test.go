@@ -0,0 +1,247 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCaptureWriter creates a capture writer into a buffer with a fixed clock
+func newTestCaptureWriter(stream string, logConfig LogConfig) (*CaptureWriter, *bytes.Buffer) {
+	var out bytes.Buffer
+	w := NewCaptureWriter(&out, stream, logConfig, nil)
+	w.now = func() time.Time { return time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC) }
+	return w, &out
+}
+
+func TestCaptureWriterTextFormat(t *testing.T) {
+	w, out := newTestCaptureWriter(LogStreamStderr, LogConfig{Capture: CaptureText})
+
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\r\n"))
+
+	want := "2026-01-27T10:30:00Z stderr F first line\n" +
+		"2026-01-27T10:30:00Z stderr F second line\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCaptureWriterJSONFormat(t *testing.T) {
+	w, out := newTestCaptureWriter(LogStreamStdout, LogConfig{Capture: CaptureJSON})
+
+	w.Write([]byte("say \"hi\"\n"))
+
+	var record CaptureRecord
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out.String(), err)
+	}
+	if record.Stream != LogStreamStdout || record.Line != `say "hi"` || record.Partial {
+		t.Errorf("record = %+v", record)
+	}
+	if !strings.HasSuffix(out.String(), "}\n") || strings.Count(out.String(), "\n") != 1 {
+		t.Errorf("output %q is not a single JSON line", out.String())
+	}
+}
+
+func TestCaptureWriterSplitsLongLines(t *testing.T) {
+	w, out := newTestCaptureWriter(LogStreamStdout, LogConfig{Capture: CaptureText, MaxLineSize: 4})
+
+	w.Write([]byte("abcdefghij\nxy\n"))
+
+	want := []string{
+		"2026-01-27T10:30:00Z stdout P abcd",
+		"2026-01-27T10:30:00Z stdout P efgh",
+		"2026-01-27T10:30:00Z stdout F ij",
+		"2026-01-27T10:30:00Z stdout F xy",
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if !equalTexts(got, want) {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestCaptureWriterLineOfExactMaxSize(t *testing.T) {
+	w, out := newTestCaptureWriter(LogStreamStdout, LogConfig{Capture: CaptureText, MaxLineSize: 4})
+
+	w.Write([]byte("abcd"))
+	w.Write([]byte("\n"))
+
+	want := "2026-01-27T10:30:00Z stdout F abcd\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCaptureWriterCloseFlushesUnterminatedLine(t *testing.T) {
+	w, out := newTestCaptureWriter(LogStreamStdout, LogConfig{Capture: CaptureText})
+
+	w.Write([]byte("no newline"))
+	if out.Len() != 0 {
+		t.Fatalf("unterminated line written before Close(): %q", out.String())
+	}
+
+	w.Close()
+	want := "2026-01-27T10:30:00Z stdout F no newline\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestParseCaptureRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want CaptureRecord
+		ok   bool
+	}{
+		{
+			name: "text",
+			line: "2026-01-27T10:30:00Z stderr F disk full",
+			want: CaptureRecord{Time: time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC), Stream: LogStreamStderr, Line: "disk full"},
+			ok:   true,
+		},
+		{
+			name: "text empty line",
+			line: "2026-01-27T10:30:00Z stdout F",
+			want: CaptureRecord{Time: time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC), Stream: LogStreamStdout},
+			ok:   true,
+		},
+		{
+			name: "json partial",
+			line: `{"time":"2026-01-27T10:30:00Z","stream":"stdout","line":"abc","partial":true}`,
+			want: CaptureRecord{Time: time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC), Stream: LogStreamStdout, Line: "abc", Partial: true},
+			ok:   true,
+		},
+		{name: "plain timestamped line", line: "2026-01-27T10:30:00Z started worker", ok: false},
+		{name: "other json", line: `{"level":"info"}`, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseCaptureRecord(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ParseCaptureRecord(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if ok && (!got.Time.Equal(tt.want.Time) || got.Stream != tt.want.Stream || got.Line != tt.want.Line || got.Partial != tt.want.Partial) {
+				t.Errorf("ParseCaptureRecord(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFollowerJoinsCapturedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	os.WriteFile(path, []byte(
+		"2026-01-27T10:30:00Z stdout P abc\n"+
+			"2026-01-27T10:30:01Z stderr F oops\n"+
+			"2026-01-27T10:30:02Z stdout F def\n"), 0644)
+
+	follower := NewLogFollower(path, LogStreamStdout)
+	defer follower.Close()
+
+	lines, err := follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Poll() = %v, want 2 lines", lines)
+	}
+	if lines[0].Stream != LogStreamStderr || lines[0].Text != "oops" {
+		t.Errorf("first line = %+v, want stderr 'oops'", lines[0])
+	}
+	if lines[1].Stream != LogStreamStdout || lines[1].Text != "abcdef" {
+		t.Errorf("second line = %+v, want stdout 'abcdef'", lines[1])
+	}
+}
+
+func TestLogFollowerDoesNotJoinRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	// Two runs appending to the same file, the first ends without a newline
+	for _, output := range []string{"unterminated", "next run\n"} {
+		w := NewCaptureWriter(file, LogStreamStdout, LogConfig{Capture: CaptureText}, nil)
+		w.Write([]byte(output))
+		w.Close()
+	}
+	file.Close()
+
+	follower := NewLogFollower(path, LogStreamStdout)
+	defer follower.Close()
+
+	lines, err := follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.Text)
+	}
+	if want := []string{"unterminated", "next run"}; !equalTexts(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestCreateTaskIOCaptureSharedFile(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{
+		WorkDir: tempDir,
+		Stdout:  "out.log",
+		Stderr:  "out.log",
+		Log:     LogConfig{Capture: CaptureJSON},
+	}
+
+	taskIO, err := GetIOManager().CreateTaskIO(config)
+	if err != nil {
+		t.Fatalf("CreateTaskIO() = %v", err)
+	}
+
+	stdout, ok := taskIO.Stdout.(*CaptureWriter)
+	if !ok {
+		t.Fatalf("Stdout is %T, want *CaptureWriter", taskIO.Stdout)
+	}
+	stderr, ok := taskIO.Stderr.(*CaptureWriter)
+	if !ok {
+		t.Fatalf("Stderr is %T, want *CaptureWriter", taskIO.Stderr)
+	}
+	if stdout.mu != stderr.mu {
+		t.Error("capture writers of a shared file should share a lock")
+	}
+
+	stdout.Write([]byte("to stdout\n"))
+	stderr.Write([]byte("unterminated"))
+	if err := taskIO.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "out.log"))
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %q", data)
+	}
+	if record, ok := ParseCaptureRecord(lines[1]); !ok || record.Stream != LogStreamStderr || record.Partial {
+		t.Errorf("last record = %q, want a full stderr record flushed on close", lines[1])
+	}
+}
+
+func TestCreateTaskIORejectsUnknownCapture(t *testing.T) {
+	config := &Config{
+		WorkDir: t.TempDir(),
+		Stdout:  "out.log",
+		Log:     LogConfig{Capture: "xml"},
+	}
+
+	if _, err := GetIOManager().CreateTaskIO(config); err == nil {
+		t.Error("CreateTaskIO() with an unknown capture format should fail")
+	}
+}
@@ -0,0 +1,169 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"taskd/internal/config"
+)
+
+// defaultIPCDialTimeout is how long a client waits to connect to the daemon
+const defaultIPCDialTimeout = 2 * time.Second
+
+// DaemonClient talks to the daemon over its IPC socket (runs in CLI process)
+//
+// Task lifecycle commands are always executed by the daemon so that it owns
+// every task process. Read-only commands fall back to the local runtime state
+// when no daemon is running.
+type DaemonClient struct {
+	socketPath     string
+	dialTimeout    time.Duration
+	builtinHandler *BuiltinTaskHandler
+}
+
+// NewDaemonClient creates a client for the default daemon socket
+func NewDaemonClient() *DaemonClient {
+	return newDaemonClientWithPath(config.GetTaskDSocketFile())
+}
+
+// newDaemonClientWithPath creates a client for the given socket path
+func newDaemonClientWithPath(socketPath string) *DaemonClient {
+	return &DaemonClient{
+		socketPath:     socketPath,
+		dialTimeout:    defaultIPCDialTimeout,
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+}
+
+// Ping checks whether the daemon is reachable
+func (c *DaemonClient) Ping() error {
+	_, err := c.call(&IPCRequest{Command: IPCCommandPing})
+	return err
+}
+
+// StartTask asks the daemon to start a task and its dependencies, starting the daemon first if needed
+func (c *DaemonClient) StartTask(name string) error {
+	return c.StartTaskWithOptions(name, StartOptions{})
+}
+
+// StartTaskWithOptions asks the daemon to start a task, waiting for it to be ready if asked to
+func (c *DaemonClient) StartTaskWithOptions(name string, opts StartOptions) error {
+	// The daemon itself is managed locally
+	if c.builtinHandler.IsBuiltinTask(name) {
+		return GetManager().StartTask(name)
+	}
+
+	if err := GetDaemonManager().EnsureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	_, err := c.call(&IPCRequest{Command: IPCCommandStart, Task: name, Wait: opts.Wait})
+	return err
+}
+
+// StopTask asks the daemon to stop a task
+func (c *DaemonClient) StopTask(name string) error {
+	return c.StopTaskWithOptions(name, StopOptions{})
+}
+
+// StopTaskWithOptions asks the daemon to stop a task, overriding its stop timeout, forcing a kill
+// or stopping the tasks that require it first
+func (c *DaemonClient) StopTaskWithOptions(name string, opts StopOptions) error {
+	if c.builtinHandler.IsBuiltinTask(name) {
+		return GetManager().StopTask(name)
+	}
+
+	_, err := c.call(&IPCRequest{Command: IPCCommandStop, Task: name, StopTimeout: opts.Timeout, Force: opts.Force, Cascade: opts.Cascade})
+	if errors.Is(err, ErrDaemonUnavailable) {
+		// No daemon is supervising the task, fall back to the recorded state
+		return GetManager().StopTaskWithOptions(name, opts)
+	}
+	return err
+}
+
+// RestartTask asks the daemon to restart a task, starting the daemon first if needed
+func (c *DaemonClient) RestartTask(name string) error {
+	if c.builtinHandler.IsBuiltinTask(name) {
+		return GetManager().RestartTask(name)
+	}
+
+	if err := GetDaemonManager().EnsureDaemonRunning(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	_, err := c.call(&IPCRequest{Command: IPCCommandRestart, Task: name})
+	return err
+}
+
+// ListTasks lists all tasks as seen by the daemon
+func (c *DaemonClient) ListTasks() ([]*TaskInfo, error) {
+	resp, err := c.call(&IPCRequest{Command: IPCCommandList})
+	if errors.Is(err, ErrDaemonUnavailable) {
+		return ListTasks()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Tasks, nil
+}
+
+// GetTaskDetailInfo gets detailed task information as seen by the daemon
+func (c *DaemonClient) GetTaskDetailInfo(name string) (*TaskDetailInfo, error) {
+	resp, err := c.call(&IPCRequest{Command: IPCCommandInfo, Task: name})
+	if errors.Is(err, ErrDaemonUnavailable) {
+		return GetTaskDetailInfo(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Detail, nil
+}
+
+// ReloadTask tells a running daemon that a task configuration was added, changed or removed
+func (c *DaemonClient) ReloadTask(name string) error {
+	_, err := c.call(&IPCRequest{Command: IPCCommandReload, Task: name})
+	if errors.Is(err, ErrDaemonUnavailable) {
+		// The daemon reads task configurations when it starts
+		return nil
+	}
+	return err
+}
+
+// Shutdown asks the daemon to stop its tasks and exit
+func (c *DaemonClient) Shutdown() error {
+	_, err := c.call(&IPCRequest{Command: IPCCommandShutdown})
+	return err
+}
+
+// call sends a request to the daemon and waits for its response
+func (c *DaemonClient) call(req *IPCRequest) (*IPCResponse, error) {
+	req.Version = IPCProtocolVersion
+
+	conn, err := dialIPC(c.socketPath, c.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ipcRequestDeadline(req)))
+
+	if err := writeIPCMessage(conn, req); err != nil {
+		return nil, fmt.Errorf("failed to send request to daemon: %w", err)
+	}
+
+	var resp IPCResponse
+	if err := readIPCMessage(conn, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read response from daemon: %w", err)
+	}
+
+	if resp.Version != IPCProtocolVersion {
+		return nil, fmt.Errorf("daemon speaks protocol version %d, expected %d, please restart the daemon with 'taskd restart taskd'",
+			resp.Version, IPCProtocolVersion)
+	}
+
+	if !resp.OK {
+		return nil, errors.New(resp.Error)
+	}
+
+	return &resp, nil
+}
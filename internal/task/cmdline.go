@@ -0,0 +1,237 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Command lines are split with the quoting rules of the platform the daemon runs on
+// (SplitCommandLine in cmdline_unix.go and cmdline_windows.go). Both parsers are
+// defined here so that they can be tested on every platform.
+
+// splitPOSIXCommandLine splits a command line with POSIX shell quoting rules
+// Single quotes keep everything literally, double quotes keep everything except
+// \" \\ \$ \` and \newline, and a backslash outside quotes escapes the next character.
+// Variables, globs and operators are not interpreted, use shell mode for them.
+func splitPOSIXCommandLine(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			current.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inArg = true
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			// A backslash-newline continues the line
+			if s[i] != '\n' {
+				current.WriteByte(s[i])
+				inArg = true
+			}
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// splitWindowsCommandLine splits a command line like CommandLineToArgvW
+// The program name ends at the first space, or at the closing quote when it starts
+// with one, and backslashes are kept in it. In the arguments, 2n backslashes
+// before a quote give n backslashes and the quote opens or closes a quoted part,
+// 2n+1 backslashes give n backslashes and a literal quote, and "" inside a
+// quoted part gives a literal quote. Other backslashes are kept as they are.
+func splitWindowsCommandLine(s string) ([]string, error) {
+	s = strings.TrimLeft(s, " \t")
+	if s == "" {
+		return nil, nil
+	}
+
+	var args []string
+	if s[0] == '"' {
+		end := strings.IndexByte(s[1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated double quote")
+		}
+		args = append(args, s[1:1+end])
+		s = s[2+end:]
+	} else {
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+
+	var current strings.Builder
+	inArg, inQuotes := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c == ' ' || c == '\t') && !inQuotes:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			backslashes := 0
+			for i < len(s) && s[i] == '\\' {
+				backslashes++
+				i++
+			}
+			if i < len(s) && s[i] == '"' {
+				current.WriteString(strings.Repeat(`\`, backslashes/2))
+				if backslashes%2 == 1 {
+					current.WriteByte('"')
+				} else {
+					i--
+				}
+			} else {
+				current.WriteString(strings.Repeat(`\`, backslashes))
+				i--
+			}
+			inArg = true
+		case c == '"':
+			if inQuotes && i+1 < len(s) && s[i+1] == '"' {
+				current.WriteByte('"')
+				i++
+			} else {
+				inQuotes = !inQuotes
+			}
+			inArg = true
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated double quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// quotePOSIXArg quotes an argument for splitPOSIXCommandLine when it needs quoting
+func quotePOSIXArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\r'\"\\$`|&;<>()*?[]#~{}!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// quoteWindowsArg quotes an argument for splitWindowsCommandLine when it needs quoting
+func quoteWindowsArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(arg); i++ {
+		switch arg[i] {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			// Backslashes before a quote are doubled, and the quote escaped
+			b.WriteString(strings.Repeat(`\`, backslashes*2+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteByte(arg[i])
+	}
+	// Backslashes before the closing quote are doubled
+	b.WriteString(strings.Repeat(`\`, backslashes*2))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// joinCommandLine joins arguments into a command line, quoting each with quote
+func joinCommandLine(args []string, quote func(string) string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CommandLine returns the command line the task runs, as written by the user
+// Executable and Args are joined with the quoting rules of the platform.
+func (c Config) CommandLine() string {
+	if c.Shell || c.Args == nil {
+		return c.Executable
+	}
+	return JoinCommandLine(append([]string{c.Executable}, c.Args...))
+}
+
+// Command returns the program the task runs and its arguments
+// In shell mode the executable is run by the platform shell, otherwise it is split
+// into arguments unless Args are set, even to an empty list.
+func (c Config) Command() (string, []string, error) {
+	if c.Shell {
+		if strings.TrimSpace(c.Executable) == "" {
+			return "", nil, fmt.Errorf("executable is empty")
+		}
+		program, args := shellCommand(c.Executable)
+		return program, args, nil
+	}
+	if c.Args != nil {
+		if strings.TrimSpace(c.Executable) == "" {
+			return "", nil, fmt.Errorf("executable is empty")
+		}
+		return c.Executable, c.Args, nil
+	}
+
+	parts, err := SplitCommandLine(c.Executable)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid executable '%s': %w", c.Executable, err)
+	}
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("executable is empty")
+	}
+	return parts[0], parts[1:], nil
+}
@@ -0,0 +1,166 @@
+package task
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestSplitPOSIXCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"   ", nil, ""},
+		{"python app.py", []string{"python", "app.py"}, ""},
+		{"  app\t-v \n --port 80 ", []string{"app", "-v", "--port", "80"}, ""},
+		{`app --name "my svc"`, []string{"app", "--name", "my svc"}, ""},
+		{`'/opt/my app/bin/app' --flag`, []string{"/opt/my app/bin/app", "--flag"}, ""},
+		{`app 'it''s' "a"'b'c`, []string{"app", "its", "abc"}, ""},
+		{`app '$HOME \n "x"'`, []string{"app", `$HOME \n "x"`}, ""},
+		{`app "say \"hi\" \$HOME \\ \n"`, []string{"app", `say "hi" $HOME \ \n`}, ""},
+		{`app my\ file \'x\'`, []string{"app", "my file", "'x'"}, ""},
+		{"app one\\\ntwo", []string{"app", "onetwo"}, ""},
+		{`app "" ''`, []string{"app", "", ""}, ""},
+		{`app 'unterminated`, nil, "unterminated single quote"},
+		{`app "unterminated`, nil, "unterminated double quote"},
+		{`app trailing\`, nil, "trailing backslash"},
+	}
+
+	for _, tt := range tests {
+		got, err := splitPOSIXCommandLine(tt.line)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("splitPOSIXCommandLine(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitPOSIXCommandLine(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPOSIXCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSplitWindowsCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr string
+	}{
+		{"", nil, ""},
+		{`app.exe -v`, []string{"app.exe", "-v"}, ""},
+		{`"C:\Program Files\app\app.exe" --name "my svc"`, []string{`C:\Program Files\app\app.exe`, "--name", "my svc"}, ""},
+		{`C:\tools\app.exe C:\data\ "C:\My Data\\"`, []string{`C:\tools\app.exe`, `C:\data\`, `C:\My Data\`}, ""},
+		{`app a\\\"b c\\"d e"`, []string{"app", `a\"b`, `c\d e`}, ""},
+		{`app "a""b" "c"d`, []string{"app", `a"b`, "cd"}, ""},
+		{`app 'single quotes' ""`, []string{"app", "'single", "quotes'", ""}, ""},
+		{`"unterminated program`, nil, "unterminated double quote"},
+		{`app "unterminated`, nil, "unterminated double quote"},
+	}
+
+	for _, tt := range tests {
+		got, err := splitWindowsCommandLine(tt.line)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("splitWindowsCommandLine(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitWindowsCommandLine(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWindowsCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestJoinCommandLineRoundTrip(t *testing.T) {
+	args := []string{"app", "plain", "", "my svc", `it's`, `say "hi"`, `C:\dir\`, `a\\"b`, "$HOME", "tab\there"}
+
+	posix, err := splitPOSIXCommandLine(joinCommandLine(args, quotePOSIXArg))
+	if err != nil || !reflect.DeepEqual(posix, args) {
+		t.Errorf("POSIX round trip = %q, %v, want %q", posix, err, args)
+	}
+	windows, err := splitWindowsCommandLine(joinCommandLine(args, quoteWindowsArg))
+	if err != nil || !reflect.DeepEqual(windows, args) {
+		t.Errorf("Windows round trip = %q, %v, want %q", windows, err, args)
+	}
+}
+
+func TestConfigCommand(t *testing.T) {
+	executable, args, err := (Config{Executable: "app", Args: []string{"--name", "my svc"}}).Command()
+	if err != nil || executable != "app" || !reflect.DeepEqual(args, []string{"--name", "my svc"}) {
+		t.Errorf("Command() with args = %q, %q, %v", executable, args, err)
+	}
+
+	executable, args, err = (Config{Executable: "echo hello"}).Command()
+	if err != nil || executable != "echo" || !reflect.DeepEqual(args, []string{"hello"}) {
+		t.Errorf("Command() = %q, %q, %v, want echo [hello]", executable, args, err)
+	}
+
+	// An empty args list makes the executable the program, spaces included
+	executable, args, err = (Config{Executable: "/opt/my app/run", Args: []string{}}).Command()
+	if err != nil || executable != "/opt/my app/run" || len(args) != 0 {
+		t.Errorf("Command() with empty args = %q, %q, %v, want the executable unsplit", executable, args, err)
+	}
+
+	shellProgram, _ := shellCommand("echo $HOME | tr a b")
+	executable, args, err = (Config{Executable: "echo $HOME | tr a b", Shell: true}).Command()
+	if err != nil || executable != shellProgram || args[len(args)-1] != "echo $HOME | tr a b" {
+		t.Errorf("Command() in shell mode = %q, %q, %v", executable, args, err)
+	}
+
+	if _, _, err := (Config{Executable: "  "}).Command(); err == nil {
+		t.Error("Command() with an empty executable should fail")
+	}
+	if _, _, err := (Config{Executable: `app "unterminated`}).Command(); err == nil {
+		t.Error("Command() with an unterminated quote should fail")
+	}
+}
+
+func TestConfigArgsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		wantArgs string // args line of the file, empty if the key is left out
+	}{
+		{"program without arguments", Config{Executable: "/opt/my app/run", Args: []string{}}, "args = []"},
+		{"program with arguments", Config{Executable: "/opt/my app/run", Args: []string{"-v"}}, `args = ["-v"]`},
+		{"command line", Config{Executable: `"/opt/my app/run" -v`}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := toml.NewEncoder(&buf).Encode(tt.config); err != nil {
+				t.Fatalf("Encode() = %v", err)
+			}
+			if tt.wantArgs != "" && !strings.Contains(buf.String(), tt.wantArgs) {
+				t.Errorf("encoded config has no %q:\n%s", tt.wantArgs, buf.String())
+			}
+			if tt.wantArgs == "" && strings.Contains(buf.String(), "args") {
+				t.Errorf("encoded config has args:\n%s", buf.String())
+			}
+
+			var decoded Config
+			if _, err := toml.Decode(buf.String(), &decoded); err != nil {
+				t.Fatalf("Decode() = %v", err)
+			}
+			executable, args, err := decoded.Command()
+			if err != nil || executable != "/opt/my app/run" {
+				t.Errorf("Command() = %q, %q, %v, want /opt/my app/run", executable, args, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package task
+
+import "os/exec"
+
+// SplitCommandLine splits a command line into a program and its arguments
+// with POSIX shell quoting rules (see splitPOSIXCommandLine)
+func SplitCommandLine(s string) ([]string, error) {
+	return splitPOSIXCommandLine(s)
+}
+
+// JoinCommandLine joins a program and its arguments into a command line that
+// SplitCommandLine splits back into the same arguments
+func JoinCommandLine(args []string) string {
+	return joinCommandLine(args, quotePOSIXArg)
+}
+
+// shellCommand returns the program and arguments that run command with sh
+func shellCommand(command string) (string, []string) {
+	return "/bin/sh", []string{"-c", command}
+}
+
+// setShellCommandLine does nothing on unix, sh receives the command as one argument
+func setShellCommandLine(cmd *exec.Cmd, command string) {}
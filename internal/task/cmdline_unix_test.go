@@ -0,0 +1,28 @@
+//go:build !windows
+
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartTaskQuotedArguments(t *testing.T) {
+	task := startTestTask(t, &Config{Executable: `sh -c 'printf "%s|" "$1"; echo ready; sleep 30' sh "my svc"`})
+
+	data, _ := os.ReadFile(filepath.Join(task.config.WorkDir, "out.log"))
+	if !strings.HasPrefix(string(data), "my svc|") {
+		t.Errorf("output = %q, want the quoted argument passed as one", data)
+	}
+}
+
+func TestStartTaskShellMode(t *testing.T) {
+	task := startTestTask(t, &Config{Executable: "echo one two | tr o 0; echo ready; sleep 30", Shell: true})
+
+	data, _ := os.ReadFile(filepath.Join(task.config.WorkDir, "out.log"))
+	if !strings.HasPrefix(string(data), "0ne tw0\n") {
+		t.Errorf("output = %q, want the pipeline run by the shell", data)
+	}
+}
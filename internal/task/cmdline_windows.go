@@ -0,0 +1,35 @@
+//go:build windows
+
+package task
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// SplitCommandLine splits a command line into a program and its arguments
+// with the rules of CommandLineToArgvW (see splitWindowsCommandLine)
+func SplitCommandLine(s string) ([]string, error) {
+	return splitWindowsCommandLine(s)
+}
+
+// JoinCommandLine joins a program and its arguments into a command line that
+// SplitCommandLine splits back into the same arguments
+func JoinCommandLine(args []string) string {
+	return joinCommandLine(args, quoteWindowsArg)
+}
+
+// shellCommand returns the program and arguments that run command with cmd.exe
+func shellCommand(command string) (string, []string) {
+	return "cmd.exe", []string{"/d", "/s", "/c", command}
+}
+
+// setShellCommandLine passes command to cmd.exe unchanged
+// cmd.exe does not follow the quoting rules of CommandLineToArgvW, so the
+// command line built by exec.Cmd from the arguments is replaced.
+func setShellCommandLine(cmd *exec.Cmd, command string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CmdLine = `cmd.exe /d /s /c "` + command + `"`
+}
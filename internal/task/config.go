@@ -1,47 +1,331 @@
 package task
 
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
 // Config task configuration structure
 type Config struct {
-	DisplayName  string            `toml:"display_name,omitempty"`
-	Description  string            `toml:"description,omitempty"`
-	Executable   string            `toml:"executable"`
-	Args         []string          `toml:"args,omitempty"`
-	WorkDir      string            `toml:"workdir,omitempty"`
-	Env          []string          `toml:"env,omitempty"`
-	InheritEnv   bool              `toml:"inherit_env"`
-	Stdin        string            `toml:"stdin,omitempty"`
-	Stdout       string            `toml:"stdout,omitempty"`
-	Stderr       string            `toml:"stderr,omitempty"`
-	AutoStart    bool              `toml:"auto_start"`
-	MaxRetryNum  int               `toml:"max_retry_num"`  // Maximum retry count, default is 3
-	Restart      RestartPolicy     `toml:"restart,omitempty"`
-	Log          LogConfig         `toml:"log,omitempty"`
+	DisplayName     string         `toml:"display_name,omitempty"`
+	Description     string         `toml:"description,omitempty"`
+	Executable      string         `toml:"executable"`
+	Args            []string       `toml:"args"` // Set, even empty, when executable is the program itself rather than a command line
+	Shell           bool           `toml:"shell,omitempty"` // Run executable as a command line of sh -c or cmd /c, args are not used
+	WorkDir         string         `toml:"workdir,omitempty"`
+	Env             []string       `toml:"env,omitempty"`      // KEY=VALUE, a value secret://NAME is replaced by the secret NAME
+	EnvFile         []string       `toml:"env_file,omitempty"` // Dotenv files read when the task starts, the env list overrides them
+	InheritEnv      bool           `toml:"inherit_env"`
+	Stdin           string         `toml:"stdin,omitempty"`
+	Stdout          string         `toml:"stdout,omitempty"`
+	Stderr          string         `toml:"stderr,omitempty"`
+	AutoStart       bool           `toml:"auto_start"`
+	MaxRetryNum     int            `toml:"max_retry_num"`              // Maximum retry count, default is 3
+	StopSignal      string         `toml:"stop_signal,omitempty"`      // Signal sent first when stopping, default SIGTERM
+	StopTimeout     string         `toml:"stop_timeout,omitempty"`     // How long to wait for a graceful exit before killing, default 10s
+	Schedule        string         `toml:"schedule,omitempty"`         // Cron expression or @every interval, the daemon starts the task at each tick
+	ScheduleOverlap string         `toml:"schedule_overlap,omitempty"` // skip (default), queue or replace when the task is still running at a tick
+	ScheduleJitter  string         `toml:"schedule_jitter,omitempty"`  // Random delay of up to this duration added to each tick
+	Requires        []string       `toml:"requires,omitempty"`         // Tasks started first, the task does not start without them
+	Wants           []string       `toml:"wants,omitempty"`            // Tasks started first, the task starts even if they fail
+	After           []string       `toml:"after,omitempty"`            // Tasks started first when they are started together with this one
+	Restart         RestartPolicy  `toml:"restart,omitempty"`
+	Readiness       ReadinessProbe `toml:"readiness,omitempty"`
+	Liveness        LivenessProbe  `toml:"liveness,omitempty"`
+	Limits          ResourceLimits `toml:"limits,omitempty"`
+	Log             LogConfig      `toml:"log,omitempty"`
 }
 
 // RestartPolicy restart policy configuration
 type RestartPolicy struct {
-	Policy    string `toml:"policy"`    // always, on-failure, never
-	MaxRetry  int    `toml:"max_retry"`
-	Delay     string `toml:"delay"`     // restart delay, e.g. "5s", "1m"
+	Policy        string  `toml:"policy"`                   // always, on-failure, never
+	MaxRetry      int     `toml:"max_retry,omitzero"`       // 0 means unlimited, see DecodeConfigFile
+	Delay         string  `toml:"delay"`                    // initial restart delay, e.g. "5s", "1m"
+	MaxDelay      string  `toml:"max_delay,omitempty"`      // upper bound of the backoff delay
+	BackoffFactor float64 `toml:"backoff_factor,omitempty"` // delay multiplier for each consecutive restart
+	Jitter        float64 `toml:"jitter,omitempty"`         // random spread as a fraction of the delay (0-1)
+	ResetAfter    string  `toml:"reset_after,omitempty"`    // healthy uptime after which the retry count resets
+}
+
+// Restart backoff defaults, used when the [restart] block leaves a setting empty
+const (
+	defaultRestartDelay      = 1 * time.Second
+	defaultMaxRestartDelay   = 5 * time.Minute
+	defaultBackoffFactor     = 2.0
+	defaultRestartResetAfter = 10 * time.Minute
+)
+
+// Stop defaults, used when stop_signal or stop_timeout are not set
+const (
+	defaultStopSignal  = "SIGTERM"
+	defaultStopTimeout = 10 * time.Second
+)
+
+// stopSignalNames are the signals accepted as stop_signal
+// SIGKILL skips the graceful step. On Windows every other signal is delivered
+// as CTRL_BREAK to the task's process group.
+var stopSignalNames = []string{"SIGTERM", "SIGINT", "SIGHUP", "SIGQUIT", "SIGUSR1", "SIGUSR2", "SIGKILL"}
+
+// NormalizeStopSignal converts a signal name like "term" or "SIGTERM" to its canonical form
+func NormalizeStopSignal(signal string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(signal))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for _, known := range stopSignalNames {
+		if name == known {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown stop signal '%s' (expected one of %s)", signal, strings.Join(stopSignalNames, ", "))
+}
+
+// ValidateStopSignal checks that signal is empty or a known stop signal
+func ValidateStopSignal(signal string) error {
+	if signal == "" {
+		return nil
+	}
+	_, err := NormalizeStopSignal(signal)
+	return err
+}
+
+// ValidateStopTimeout checks that timeout is empty or a valid non-negative duration
+func ValidateStopTimeout(timeout string) error {
+	if timeout == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return fmt.Errorf("invalid stop timeout '%s': %w", timeout, err)
+	}
+	if d < 0 {
+		return fmt.Errorf("stop timeout cannot be negative: %s", timeout)
+	}
+	return nil
+}
+
+// StopSignalName returns the canonical name of the signal sent first when stopping the task
+func (c *Config) StopSignalName() string {
+	if name, err := NormalizeStopSignal(c.StopSignal); err == nil && c.StopSignal != "" {
+		return name
+	}
+	return defaultStopSignal
+}
+
+// StopTimeoutDuration returns how long a stopping task may take to exit before it is killed
+func (c *Config) StopTimeoutDuration() time.Duration {
+	return parseDurationOrDefault(c.StopTimeout, defaultStopTimeout)
+}
+
+// Restart policies
+const (
+	RestartAlways    = "always"     // Restart whenever the task exits
+	RestartOnFailure = "on-failure" // Restart only when the task exits with a non-zero code
+	RestartNever     = "never"      // Never restart automatically
+)
+
+// ValidateRestartPolicy checks that policy is a known restart policy (empty means default)
+func ValidateRestartPolicy(policy string) error {
+	switch policy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+		return nil
+	default:
+		return fmt.Errorf("unknown restart policy '%s' (expected %s, %s or %s)",
+			policy, RestartAlways, RestartOnFailure, RestartNever)
+	}
+}
+
+// ValidateRestartDelay checks that delay is empty or a valid non-negative duration
+func ValidateRestartDelay(delay string) error {
+	if delay == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(delay)
+	if err != nil {
+		return fmt.Errorf("invalid restart delay '%s': %w", delay, err)
+	}
+	if d < 0 {
+		return fmt.Errorf("restart delay cannot be negative: %s", delay)
+	}
+	return nil
+}
+
+// ValidateRestartBackoff checks the backoff settings of a restart policy
+func ValidateRestartBackoff(policy RestartPolicy) error {
+	if err := ValidateRestartDelay(policy.MaxDelay); err != nil {
+		return fmt.Errorf("max_delay: %w", err)
+	}
+	if err := ValidateRestartDelay(policy.ResetAfter); err != nil {
+		return fmt.Errorf("reset_after: %w", err)
+	}
+	if policy.BackoffFactor != 0 && policy.BackoffFactor < 1 {
+		return fmt.Errorf("backoff factor must be at least 1: %g", policy.BackoffFactor)
+	}
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1: %g", policy.Jitter)
+	}
+	return nil
+}
+
+// RestartPolicyName returns the effective restart policy
+// Tasks without a [restart] block keep the legacy behaviour: auto_start tasks
+// are restarted whenever they exit, other tasks are never restarted.
+func (c *Config) RestartPolicyName() string {
+	if c.Restart.Policy != "" {
+		return c.Restart.Policy
+	}
+	if c.AutoStart {
+		return RestartAlways
+	}
+	return RestartNever
+}
+
+// DecodeConfigFile decodes a task configuration file
+// An explicit restart.max_retry of 0 (unlimited) overrides the legacy
+// max_retry_num, which is cleared so the limit stays unlimited when the
+// configuration is written back (max_retry = 0 is not written).
+func DecodeConfigFile(path string, config *Config) error {
+	meta, err := toml.DecodeFile(path, config)
+	if err != nil {
+		return err
+	}
+	if meta.IsDefined("restart", "max_retry") && config.Restart.MaxRetry == 0 {
+		config.MaxRetryNum = 0
+	}
+	return nil
+}
+
+// MaxRestarts returns the effective retry limit, 0 means unlimited
+// restart.max_retry takes precedence over the legacy max_retry_num
+func (c *Config) MaxRestarts() int {
+	if c.Restart.MaxRetry > 0 {
+		return c.Restart.MaxRetry
+	}
+	return c.MaxRetryNum
+}
+
+// RestartDelay returns the delay before the first automatic restart
+func (c *Config) RestartDelay() time.Duration {
+	return parseDurationOrDefault(c.Restart.Delay, defaultRestartDelay)
+}
+
+// MaxRestartDelay returns the upper bound of the backoff delay
+func (c *Config) MaxRestartDelay() time.Duration {
+	return parseDurationOrDefault(c.Restart.MaxDelay, defaultMaxRestartDelay)
+}
+
+// RestartBackoffFactor returns the delay multiplier for each consecutive restart
+func (c *Config) RestartBackoffFactor() float64 {
+	if c.Restart.BackoffFactor < 1 {
+		return defaultBackoffFactor
+	}
+	return c.Restart.BackoffFactor
+}
+
+// RestartResetAfter returns how long a task must stay up before its retry count resets
+func (c *Config) RestartResetAfter() time.Duration {
+	return parseDurationOrDefault(c.Restart.ResetAfter, defaultRestartResetAfter)
+}
+
+// NextRestartDelay returns how long to wait before restart number retryNum+1
+func (c *Config) NextRestartDelay(retryNum int) time.Duration {
+	return computeRestartDelay(c.RestartDelay(), c.MaxRestartDelay(), c.RestartBackoffFactor(),
+		c.Restart.Jitter, retryNum, rand.Float64())
+}
+
+// computeRestartDelay computes an exponential backoff delay:
+// base * factor^retryNum, spread by +/- jitter and capped at maxDelay.
+// random is a number in [0, 1) that selects where in the jitter range the delay falls.
+func computeRestartDelay(base, maxDelay time.Duration, factor, jitter float64, retryNum int, random float64) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	if retryNum < 0 {
+		retryNum = 0
+	}
+	
+	delay := float64(base) * math.Pow(factor, float64(retryNum))
+	if maxDelay > 0 && delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+	
+	if jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay += delay * jitter * (2*random - 1)
+	}
+	
+	if maxDelay > 0 && delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// parseDurationOrDefault parses a duration setting, invalid or empty values yield the default
+// Invalid values are rejected when a task is added or edited
+func parseDurationOrDefault(value string, defaultValue time.Duration) time.Duration {
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return defaultValue
+	}
+	return d
+}
+
+// ShouldRestart reports whether the restart policy asks for a task that exited
+// with exitCode to be restarted (the retry limit is checked separately)
+// An unknown exit code (-1) counts as a failure.
+func (c *Config) ShouldRestart(exitCode int) bool {
+	switch c.RestartPolicyName() {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitCode != 0
+	default:
+		return false
+	}
 }
 
 // LogConfig log configuration
 type LogConfig struct {
-	MaxSize    int  `toml:"max_size"`    // MB
-	MaxBackups int  `toml:"max_backups"`
-	MaxAge     int  `toml:"max_age"`     // days
-	Compress   bool `toml:"compress"`
+	MaxSize     int    `toml:"max_size"`    // MB
+	MaxBackups  int    `toml:"max_backups"`
+	MaxAge      int    `toml:"max_age"`     // days
+	Compress    bool   `toml:"compress"`
+	Capture     string `toml:"capture,omitempty"`       // "", text or json
+	MaxLineSize int    `toml:"max_line_size,omitempty"` // bytes, longer lines are split
 }
 
 // TaskInfo task runtime information
 type TaskInfo struct {
-	Name       string    `json:"name"`
-	Status     string    `json:"status"`     // running, stopped, failed
-	PID        int       `json:"pid"`
-	StartTime  string    `json:"start_time"`
-	Executable string    `json:"executable"`
-	ExitCode   int       `json:"exit_code,omitempty"`
-	LastError  string    `json:"last_error,omitempty"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`           // running, stopped, failed
+	Health     string `json:"health,omitempty"` // Health* of a running task with a liveness probe
+	PID        int    `json:"pid"`
+	StartTime  string `json:"start_time"`
+	Executable string `json:"executable"`
+	ExitCode   int    `json:"exit_code"`
+	LastError  string `json:"last_error"`
+	RetryNum   int    `json:"retry_num,omitempty"` // Automatic restarts since the task last stayed up
+
+	// Scheduled tasks, the run times are empty for other tasks
+	Schedule         string `json:"schedule"`
+	LastScheduledRun string `json:"last_scheduled_run"`
+	NextScheduledRun string `json:"next_scheduled_run"`
+
+	// Resource usage of the running processes, only sampled by the daemon
+	Usage *ResourceUsage `json:"usage,omitempty"`
 }
 // TaskDetailInfo detailed task information (merges all fields from original TaskInfo)
 type TaskDetailInfo struct {
@@ -51,17 +335,88 @@ type TaskDetailInfo struct {
 	PID        int    `json:"pid"`
 	StartTime  string `json:"start_time"`
 	Executable string `json:"executable"`
-	ExitCode   int    `json:"exit_code,omitempty"`
-	LastError  string `json:"last_error,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	LastError  string `json:"last_error"`
 	
 	// Extended configuration information
 	DisplayName string   `json:"display_name,omitempty"`
 	Description string   `json:"description,omitempty"`
 	WorkDir     string   `json:"work_dir"`
 	Args        []string `json:"args,omitempty"`
+	Shell       bool     `json:"shell,omitempty"`
 	Env         []string `json:"env,omitempty"`
+	EnvFile     []string `json:"env_file,omitempty"`
 	InheritEnv  bool     `json:"inherit_env"`
 	
-	// IO redirection information
-	IOInfo     *TaskIOInfo `json:"io_info"`
+	// Restart policy information
+	RestartPolicy   string  `json:"restart_policy"`
+	MaxRetry        int     `json:"max_retry"` // 0 means unlimited
+	RestartDelay    string  `json:"restart_delay"`
+	MaxRestartDelay string  `json:"max_restart_delay"`
+	BackoffFactor   float64 `json:"backoff_factor"`
+	Jitter          float64 `json:"jitter,omitempty"`
+	ResetAfter      string  `json:"reset_after"`
+	RetryNum        int     `json:"retry_num"`
+	
+	// Running processes started by the task process
+	ChildPIDs []int `json:"child_pids,omitempty"`
+	
+	// Stop behaviour
+	StopSignal  string `json:"stop_signal"`
+	StopTimeout string `json:"stop_timeout"`
+	
+	// Schedule, the run times are empty for tasks without one
+	Schedule         string `json:"schedule"`
+	ScheduleOverlap  string `json:"schedule_overlap"`
+	ScheduleJitter   string `json:"schedule_jitter"`
+	LastScheduledRun string `json:"last_scheduled_run"`
+	NextScheduledRun string `json:"next_scheduled_run"`
+	
+	// Dependencies, RequiredBy lists the tasks that require this one
+	Requires   []string `json:"requires,omitempty"`
+	Wants      []string `json:"wants,omitempty"`
+	After      []string `json:"after,omitempty"`
+	RequiredBy []string `json:"required_by,omitempty"`
+	
+	// Readiness probe, empty for tasks without one
+	Readiness         string `json:"readiness,omitempty"` // Kind and target, e.g. "tcp 127.0.0.1:8080"
+	ReadinessTimeout  string `json:"readiness_timeout,omitempty"`
+	ReadinessInterval string `json:"readiness_interval,omitempty"`
+	
+	// Liveness probe and the result of its last check, empty for tasks without one
+	Liveness          string `json:"liveness,omitempty"` // Kind and target, e.g. "http http://127.0.0.1:8080/health"
+	LivenessInterval  string `json:"liveness_interval,omitempty"`
+	LivenessTimeout   string `json:"liveness_timeout,omitempty"`
+	FailureThreshold  int    `json:"failure_threshold,omitempty"`
+	Health            string `json:"health,omitempty"`
+	HealthFailures    int    `json:"health_failures,omitempty"`
+	LastProbeTime     string `json:"last_probe_time,omitempty"`
+	LastProbeOutput   string `json:"last_probe_output,omitempty"`
+	
+	// Resource limits, e.g. "memory=512M cpu=0.5", empty for tasks without any
+	Limits string `json:"limits,omitempty"`
+	
+	// Resource usage of the running processes with its range over the last samples,
+	// only sampled by the daemon
+	Usage *ResourceUsage `json:"usage,omitempty"`
+	
+	// IO redirection information, IOInfo holds the resolved paths of Stdin, Stdout and Stderr
+	Stdin  string      `json:"stdin,omitempty"`
+	Stdout string      `json:"stdout,omitempty"`
+	Stderr string      `json:"stderr,omitempty"`
+	IOInfo *TaskIOInfo `json:"io_info"`
+	
+	// Variable expansion, Resolved is only set when it changes the configuration
+	Resolved    *ResolvedConfig `json:"resolved,omitempty"`
+	ExpandError string          `json:"expand_error,omitempty"`
+}
+
+// ResolvedConfig the command, working directory and environment of a task with variables expanded
+// Env holds the variables of the env files followed by the env list, with secret values masked.
+type ResolvedConfig struct {
+	Executable string   `json:"executable"`
+	Args       []string `json:"args,omitempty"`
+	WorkDir    string   `json:"work_dir"`
+	Env        []string `json:"env,omitempty"`
+	EnvFile    []string `json:"env_file,omitempty"`
 }
\ No newline at end of file
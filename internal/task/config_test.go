@@ -1,7 +1,12 @@
 package task
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestConfigStruct(t *testing.T) {
@@ -442,4 +447,272 @@ func TestConfigValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestConfigRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Config
+		wantPolicy  string
+		wantMax     int
+		wantDelay   time.Duration
+		wantRestart map[int]bool // exit code -> restart
+	}{
+		{
+			name:        "legacy auto start",
+			config:      &Config{AutoStart: true, MaxRetryNum: 3},
+			wantPolicy:  RestartAlways,
+			wantMax:     3,
+			wantDelay:   time.Second,
+			wantRestart: map[int]bool{0: true, 1: true},
+		},
+		{
+			name:        "legacy without auto start",
+			config:      &Config{MaxRetryNum: 3},
+			wantPolicy:  RestartNever,
+			wantMax:     3,
+			wantDelay:   time.Second,
+			wantRestart: map[int]bool{0: false, 1: false},
+		},
+		{
+			name:        "on-failure with delay",
+			config:      &Config{Restart: RestartPolicy{Policy: RestartOnFailure, MaxRetry: 5, Delay: "10s"}},
+			wantPolicy:  RestartOnFailure,
+			wantMax:     5,
+			wantDelay:   10 * time.Second,
+			wantRestart: map[int]bool{0: false, 1: true, -1: true},
+		},
+		{
+			name:        "never overrides auto start",
+			config:      &Config{AutoStart: true, Restart: RestartPolicy{Policy: RestartNever}},
+			wantPolicy:  RestartNever,
+			wantDelay:   time.Second,
+			wantRestart: map[int]bool{0: false, 1: false},
+		},
+		{
+			name:        "restart max_retry overrides max_retry_num",
+			config:      &Config{MaxRetryNum: 3, Restart: RestartPolicy{Policy: RestartAlways, MaxRetry: 7}},
+			wantPolicy:  RestartAlways,
+			wantMax:     7,
+			wantDelay:   time.Second,
+			wantRestart: map[int]bool{0: true},
+		},
+		{
+			name:        "invalid delay falls back to default",
+			config:      &Config{Restart: RestartPolicy{Policy: RestartAlways, Delay: "soon"}},
+			wantPolicy:  RestartAlways,
+			wantDelay:   time.Second,
+			wantRestart: map[int]bool{0: true},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.RestartPolicyName(); got != tt.wantPolicy {
+				t.Errorf("RestartPolicyName() = %q, want %q", got, tt.wantPolicy)
+			}
+			if got := tt.config.MaxRestarts(); got != tt.wantMax {
+				t.Errorf("MaxRestarts() = %d, want %d", got, tt.wantMax)
+			}
+			if got := tt.config.RestartDelay(); got != tt.wantDelay {
+				t.Errorf("RestartDelay() = %v, want %v", got, tt.wantDelay)
+			}
+			for exitCode, want := range tt.wantRestart {
+				if got := tt.config.ShouldRestart(exitCode); got != want {
+					t.Errorf("ShouldRestart(%d) = %v, want %v", exitCode, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRestartOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		delay     string
+		wantError bool
+	}{
+		{"defaults", "", "", false},
+		{"always", RestartAlways, "5s", false},
+		{"on-failure", RestartOnFailure, "1m30s", false},
+		{"never", RestartNever, "", false},
+		{"unknown policy", "sometimes", "", true},
+		{"invalid delay", RestartAlways, "5", true},
+		{"negative delay", RestartAlways, "-5s", true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRestartPolicy(tt.policy)
+			if err == nil {
+				err = ValidateRestartDelay(tt.delay)
+			}
+			
+			if tt.wantError && err == nil {
+				t.Errorf("expected error for policy %q delay %q", tt.policy, tt.delay)
+			}
+			if !tt.wantError && err != nil {
+				t.Errorf("unexpected error for policy %q delay %q: %v", tt.policy, tt.delay, err)
+			}
+		})
+	}
+}
+
+func TestComputeRestartDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		maxDelay time.Duration
+		factor   float64
+		jitter   float64
+		retryNum int
+		random   float64
+		want     time.Duration
+	}{
+		{"first restart", time.Second, time.Minute, 2, 0, 0, 0.5, time.Second},
+		{"third restart", time.Second, time.Minute, 2, 0, 2, 0.5, 4 * time.Second},
+		{"capped at max delay", time.Second, time.Minute, 2, 0, 10, 0.5, time.Minute},
+		{"huge retry count stays capped", time.Second, time.Minute, 2, 0, 5000, 0.5, time.Minute},
+		{"constant delay with factor 1", 5 * time.Second, time.Minute, 1, 0, 8, 0.5, 5 * time.Second},
+		{"jitter low end", 10 * time.Second, time.Minute, 2, 0.5, 0, 0, 5 * time.Second},
+		{"jitter high end", 10 * time.Second, time.Minute, 2, 0.5, 0, 1, 15 * time.Second},
+		{"jitter never exceeds max delay", 10 * time.Second, 10 * time.Second, 2, 0.5, 3, 1, 10 * time.Second},
+		{"zero base restarts immediately", 0, time.Minute, 2, 0.5, 3, 1, 0},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeRestartDelay(tt.base, tt.maxDelay, tt.factor, tt.jitter, tt.retryNum, tt.random)
+			if got != tt.want {
+				t.Errorf("computeRestartDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRestartBackoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    RestartPolicy
+		wantError bool
+	}{
+		{"defaults", RestartPolicy{}, false},
+		{"all settings", RestartPolicy{MaxDelay: "2m", BackoffFactor: 1.5, Jitter: 0.2, ResetAfter: "30m"}, false},
+		{"invalid max delay", RestartPolicy{MaxDelay: "forever"}, true},
+		{"invalid reset after", RestartPolicy{ResetAfter: "-1m"}, true},
+		{"factor below one", RestartPolicy{BackoffFactor: 0.5}, true},
+		{"jitter above one", RestartPolicy{Jitter: 1.5}, true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRestartBackoff(tt.policy)
+			if tt.wantError && err == nil {
+				t.Error("ValidateRestartBackoff() = nil, want error")
+			}
+			if !tt.wantError && err != nil {
+				t.Errorf("ValidateRestartBackoff() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeStopSignal(t *testing.T) {
+	tests := []struct {
+		signal    string
+		want      string
+		wantError bool
+	}{
+		{"SIGTERM", "SIGTERM", false},
+		{"term", "SIGTERM", false},
+		{"sigint", "SIGINT", false},
+		{" HUP ", "SIGHUP", false},
+		{"KILL", "SIGKILL", false},
+		{"SIGSTOP", "", true},
+		{"15", "", true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.signal, func(t *testing.T) {
+			got, err := NormalizeStopSignal(tt.signal)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("NormalizeStopSignal(%q) = %q, want error", tt.signal, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeStopSignal(%q) = %q, %v, want %q", tt.signal, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopDefaults(t *testing.T) {
+	config := &Config{}
+	if got := config.StopSignalName(); got != "SIGTERM" {
+		t.Errorf("StopSignalName() = %q, want SIGTERM", got)
+	}
+	if got := config.StopTimeoutDuration(); got != 10*time.Second {
+		t.Errorf("StopTimeoutDuration() = %v, want 10s", got)
+	}
+	
+	config = &Config{StopSignal: "int", StopTimeout: "30s"}
+	if got := config.StopSignalName(); got != "SIGINT" {
+		t.Errorf("StopSignalName() = %q, want SIGINT", got)
+	}
+	if got := config.StopTimeoutDuration(); got != 30*time.Second {
+		t.Errorf("StopTimeoutDuration() = %v, want 30s", got)
+	}
+	
+	if err := ValidateStopTimeout("-1s"); err == nil {
+		t.Error("ValidateStopTimeout(-1s) = nil, want error")
+	}
+}
+
+func TestDecodeConfigFileMaxRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantMax int
+	}{
+		{"max_retry_num without max_retry", "max_retry_num = 3\n", 3},
+		{"explicit unlimited overrides max_retry_num", "max_retry_num = 3\n[restart]\nmax_retry = 0\n", 0},
+		{"explicit limit overrides max_retry_num", "max_retry_num = 3\n[restart]\nmax_retry = 5\n", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "task.toml")
+			if err := os.WriteFile(path, []byte("executable = \"app\"\n"+tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var config Config
+			if err := DecodeConfigFile(path, &config); err != nil {
+				t.Fatalf("DecodeConfigFile() = %v", err)
+			}
+			if got := config.MaxRestarts(); got != tt.wantMax {
+				t.Errorf("MaxRestarts() = %d, want %d", got, tt.wantMax)
+			}
+
+			// The limit is kept when the configuration is written back
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = toml.NewEncoder(file).Encode(config)
+			file.Close()
+			if err != nil {
+				t.Fatalf("Encode() = %v", err)
+			}
+			var decoded Config
+			if err := DecodeConfigFile(path, &decoded); err != nil {
+				t.Fatalf("DecodeConfigFile() = %v", err)
+			}
+			if got := decoded.MaxRestarts(); got != tt.wantMax {
+				t.Errorf("MaxRestarts() after writing the configuration back = %d, want %d", got, tt.wantMax)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package task
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,26 +11,30 @@ import (
 	"syscall"
 	"time"
 	"taskd/internal/config"
-	
-	"github.com/BurntSushi/toml"
 )
 
 // TaskMonitor task monitor (runs in daemon process)
 type TaskMonitor struct {
-	checkInterval time.Duration
-	stopChan      chan struct{}
-	manager       *Manager
-	mu            sync.RWMutex
-	isRunning     bool
+	checkInterval  time.Duration
+	stopChan       chan struct{}
+	exitChan       chan string            // Names of supervised tasks that have exited
+	retryChan      chan string            // Names of tasks whose restart delay has passed
+	pendingRetries map[string]*time.Timer // Delayed restarts, only used by the monitoring loop
+	manager        *Manager
+	mu             sync.RWMutex
+	isRunning      bool
 }
 
 // NewTaskMonitor creates a new task monitor
 func NewTaskMonitor(checkInterval time.Duration) *TaskMonitor {
 	return &TaskMonitor{
-		checkInterval: checkInterval,
-		stopChan:      make(chan struct{}),
-		manager:       GetManager(),
-		isRunning:     false,
+		checkInterval:  checkInterval,
+		stopChan:       make(chan struct{}),
+		exitChan:       make(chan string, 64),
+		retryChan:      make(chan string),
+		pendingRetries: make(map[string]*time.Timer),
+		manager:        GetManager(),
+		isRunning:      false,
 	}
 }
 
@@ -45,15 +50,30 @@ func (tm *TaskMonitor) Start() {
 	
 	fmt.Printf("TaskMonitor: Starting monitoring with interval %v\n", tm.checkInterval)
 	
+	// Tasks started by the daemon report their exit (and real exit code) from cmd.Wait()
+	tm.manager.SetTaskExitHandler(tm.notifyTaskExit)
+	defer tm.manager.SetTaskExitHandler(nil)
+	
+	// Processes adopted from a previous daemon are checked for liveness as well
+	tm.manager.watchAdoptedLiveness()
+	
 	ticker := time.NewTicker(tm.checkInterval)
 	defer ticker.Stop()
 	
 	for {
 		select {
 		case <-ticker.C:
+			tickStart := time.Now()
 			tm.checkAndRestartTasks()
+			tm.manager.sampleResourceUsage()
+			tm.manager.metrics.observeTick(time.Since(tickStart))
+		case taskName := <-tm.exitChan:
+			tm.handleTaskExit(taskName)
+		case taskName := <-tm.retryChan:
+			tm.handleRetryDue(taskName)
 		case <-tm.stopChan:
 			fmt.Println("TaskMonitor: Stopping monitoring")
+			tm.cancelPendingRetries()
 			tm.mu.Lock()
 			tm.isRunning = false
 			tm.mu.Unlock()
@@ -81,6 +101,35 @@ func (tm *TaskMonitor) IsRunning() bool {
 	return tm.isRunning
 }
 
+// notifyTaskExit queues an exit event for the monitoring loop
+func (tm *TaskMonitor) notifyTaskExit(taskName string) {
+	select {
+	case tm.exitChan <- taskName:
+	default:
+		// Queue is full, the next periodic check will pick the task up
+	}
+}
+
+// handleTaskExit decides whether a task that has just exited should be restarted
+func (tm *TaskMonitor) handleTaskExit(taskName string) {
+	state := tm.manager.loadRuntimeState()
+	runtimeInfo, exists := state.Tasks[taskName]
+	if !exists {
+		return
+	}
+	
+	fmt.Printf("TaskMonitor: Task %s exited (exit code: %d)\n", taskName, runtimeInfo.ExitCode)
+	
+	// A task that stayed up long enough is healthy again, its backoff starts over
+	tm.resetRetryCountIfHealthy(taskName, runtimeInfo)
+	
+	if tm.shouldRetryTask(taskName, runtimeInfo) {
+		tm.scheduleRetry(taskName, runtimeInfo)
+	} else if tm.shouldEnterCrashLoop(taskName, runtimeInfo) {
+		tm.enterCrashLoop(taskName, runtimeInfo)
+	}
+}
+
 // checkAndRestartTasks checks and restarts tasks
 func (tm *TaskMonitor) checkAndRestartTasks() {
 	// 1. Read runtime.json to get current state
@@ -89,23 +138,33 @@ func (tm *TaskMonitor) checkAndRestartTasks() {
 		return
 	}
 	
-	// 2. Check status of each task
-	for taskName, runtimeInfo := range state.Tasks {
+	// 2. Check status of each task, in dependency order so that tasks are
+	// restarted after the tasks they depend on
+	names := make([]string, 0, len(state.Tasks))
+	for taskName := range state.Tasks {
+		names = append(names, taskName)
+	}
+	for _, taskName := range tm.manager.sortByDependencyOrder(names) {
+		runtimeInfo := state.Tasks[taskName]
 		// Skip daemon itself
 		if taskName == "taskd" {
 			continue
 		}
 		
-		// Only check tasks marked as running
-		if runtimeInfo.Status == "running" {
+		// Only check tasks marked as running, tasks supervised by this daemon
+		// report their exit through cmd.Wait() instead of PID polling
+		if runtimeInfo.Status == "running" && !tm.manager.isSupervising(taskName) {
 			tm.checkTaskProcess(taskName, runtimeInfo)
 		}
 		
+		// Reset the backoff of tasks that have been running long enough
+		tm.resetRetryCountIfHealthy(taskName, runtimeInfo)
+		
 		// Check if auto-restart is needed
 		if tm.shouldRetryTask(taskName, runtimeInfo) {
-			tm.retryTask(taskName)
-		} else if tm.shouldLogRetryLimitReached(taskName, runtimeInfo) {
-			tm.logRetryLimitReached(taskName, runtimeInfo)
+			tm.scheduleRetry(taskName, runtimeInfo)
+		} else if tm.shouldEnterCrashLoop(taskName, runtimeInfo) {
+			tm.enterCrashLoop(taskName, runtimeInfo)
 		}
 	}
 }
@@ -113,7 +172,7 @@ func (tm *TaskMonitor) checkAndRestartTasks() {
 // checkTaskProcess checks the process status of a single task
 func (tm *TaskMonitor) checkTaskProcess(taskName string, runtimeInfo *TaskRuntimeInfo) {
 	checker := NewProcessChecker()
-	status, err := checker.CheckTaskProcess(runtimeInfo.PID)
+	status, err := checker.CheckTaskProcessWithValidation(runtimeInfo)
 	
 	if err != nil {
 		fmt.Printf("TaskMonitor: Error checking process for task %s (PID %d): %v\n", 
@@ -121,6 +180,15 @@ func (tm *TaskMonitor) checkTaskProcess(taskName string, runtimeInfo *TaskRuntim
 		return
 	}
 	
+	// The PID now belongs to another process, the task process has exited
+	// with an unknown exit code, which is not taken for a success
+	if status.PIDReused {
+		fmt.Printf("TaskMonitor: Task %s (PID %d) exited and its PID was reused, updating status\n", 
+			taskName, runtimeInfo.PID)
+		tm.updateTaskExitedStatus(taskName, runtimeInfo, -1)
+		return
+	}
+	
 	// If process doesn't exist, update task status to stopped
 	if !status.Exists {
 		fmt.Printf("TaskMonitor: Task %s (PID %d) process no longer exists, updating status\n", 
@@ -133,12 +201,13 @@ func (tm *TaskMonitor) checkTaskProcess(taskName string, runtimeInfo *TaskRuntim
 }
 
 // getProcessExitCode tries to get the exit code of a process
+// It returns -1 when the exit code is unknown, which is not taken for a success.
 func (tm *TaskMonitor) getProcessExitCode(pid int) int {
 	checker := NewProcessChecker()
 	exitCode, err := checker.GetProcessExitCode(pid)
 	if err != nil {
-		// Cannot get exit code, use default value
-		return 0
+		// Cannot get exit code
+		return -1
 	}
 	return exitCode
 }
@@ -157,30 +226,36 @@ func (tm *TaskMonitor) updateTaskExitedStatus(taskName string, runtimeInfo *Task
 		RetryNum:       runtimeInfo.RetryNum, // Keep retry count
 	}
 	
-	// Update runtime state
-	state := tm.manager.loadRuntimeState()
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
-	}
-	state.Tasks[taskName] = updatedInfo
+	// Update runtime state, unless the task was started again since it was checked
+	err := tm.manager.updateRuntimeState(func(state *RuntimeState) error {
+		if current, exists := state.Tasks[taskName]; exists && current.PID != runtimeInfo.PID {
+			return errTaskRestarted
+		}
+		state.Tasks[taskName] = updatedInfo
+		return nil
+	})
 	
-	if err := tm.manager.saveRuntimeStateWithData(state); err != nil {
+	if errors.Is(err, errTaskRestarted) {
+		return
+	}
+	if err != nil {
 		fmt.Printf("TaskMonitor: Error updating runtime state for task %s: %v\n", taskName, err)
 	} else {
 		fmt.Printf("TaskMonitor: Updated task %s status to stopped (exit code: %d)\n", taskName, exitCode)
 	}
 }
 
+// errTaskRestarted is returned by an update when the task was started again meanwhile
+var errTaskRestarted = errors.New("task was restarted")
+
 // updateTaskState generic method for updating task state
 func (tm *TaskMonitor) updateTaskState(taskName string, updatedInfo *TaskRuntimeInfo) error {
-	state := tm.manager.loadRuntimeState()
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
-	}
-	
-	state.Tasks[taskName] = updatedInfo
+	err := tm.manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks[taskName] = updatedInfo
+		return nil
+	})
 	
-	if err := tm.manager.saveRuntimeStateWithData(state); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to update runtime state for task %s: %w", taskName, err)
 	}
 	
@@ -201,14 +276,63 @@ func (tm *TaskMonitor) shouldRetryTask(taskName string, runtimeInfo *TaskRuntime
 	}
 	
 	// Check restart conditions:
-	// 1. Task configuration has auto_start = true
-	// 2. Task status is stopped
-	// 3. stopped_by_taskd = false (not manually stopped by user)
-	// 4. retry_num < max_retry_num (hasn't reached retry limit)
-	return config.AutoStart &&
-		runtimeInfo.Status == "stopped" &&
+	// 1. Task status is stopped
+	// 2. stopped_by_taskd = false (not manually stopped by user)
+	// 3. The restart policy asks for a restart given the exit code
+	// 4. retry_num < max retry (hasn't reached retry limit)
+	maxRetry := config.MaxRestarts()
+	return runtimeInfo.Status == "stopped" &&
 		!runtimeInfo.StoppedByTaskd &&
-		(config.MaxRetryNum <= 0 || runtimeInfo.RetryNum < config.MaxRetryNum)
+		config.ShouldRestart(runtimeInfo.ExitCode) &&
+		(maxRetry <= 0 || runtimeInfo.RetryNum < maxRetry)
+}
+
+// scheduleRetry restarts a task once its backoff delay has passed
+// The delay grows exponentially with the number of consecutive restarts
+func (tm *TaskMonitor) scheduleRetry(taskName string, runtimeInfo *TaskRuntimeInfo) {
+	if _, pending := tm.pendingRetries[taskName]; pending {
+		return // Already waiting for the restart delay
+	}
+	
+	var delay time.Duration
+	if config := tm.getTaskConfig(taskName); config != nil {
+		delay = config.NextRestartDelay(runtimeInfo.RetryNum)
+	}
+	
+	if delay <= 0 {
+		tm.retryTask(taskName)
+		return
+	}
+	
+	fmt.Printf("TaskMonitor: Restarting task %s in %v (retry %d)\n", taskName, delay.Round(time.Millisecond), runtimeInfo.RetryNum+1)
+	tm.pendingRetries[taskName] = time.AfterFunc(delay, func() {
+		select {
+		case tm.retryChan <- taskName:
+		case <-tm.stopChan:
+		}
+	})
+}
+
+// handleRetryDue restarts a task whose restart delay has passed
+// The state is checked again because the task may have been started or stopped meanwhile
+func (tm *TaskMonitor) handleRetryDue(taskName string) {
+	delete(tm.pendingRetries, taskName)
+	
+	state := tm.manager.loadRuntimeState()
+	runtimeInfo, exists := state.Tasks[taskName]
+	if !exists || !tm.shouldRetryTask(taskName, runtimeInfo) {
+		return
+	}
+	
+	tm.retryTask(taskName)
+}
+
+// cancelPendingRetries cancels delayed restarts when the monitor stops
+func (tm *TaskMonitor) cancelPendingRetries() {
+	for taskName, timer := range tm.pendingRetries {
+		timer.Stop()
+		delete(tm.pendingRetries, taskName)
+	}
 }
 
 // getTaskConfig gets task configuration
@@ -222,7 +346,7 @@ func (tm *TaskMonitor) getTaskConfig(taskName string) *Config {
 	configPath := filepath.Join(config.GetTaskDTasksDir(), taskName+".toml")
 	var taskConfig Config
 	
-	if _, err := toml.DecodeFile(configPath, &taskConfig); err != nil {
+	if err := DecodeConfigFile(configPath, &taskConfig); err != nil {
 		fmt.Printf("TaskMonitor: Error loading config for task %s: %v\n", taskName, err)
 		return nil
 	}
@@ -234,12 +358,13 @@ func (tm *TaskMonitor) getTaskConfig(taskName string) *Config {
 func (tm *TaskMonitor) retryTask(taskName string) {
 	fmt.Printf("TaskMonitor: Attempting to restart task %s\n", taskName)
 	
-	// 1. Start the task
-	if err := tm.manager.StartTask(taskName); err != nil {
+	// 1. Start the task (keeping its retry count)
+	if err := tm.manager.startTaskForRetry(taskName); err != nil {
 		fmt.Printf("TaskMonitor: Failed to restart task %s: %v\n", taskName, err)
 		tm.handleRetryFailure(taskName, err)
 		return
 	}
+	tm.manager.metrics.taskRestarted(taskName)
 	
 	// 2. Update retry count
 	if err := tm.incrementRetryCount(taskName); err != nil {
@@ -251,59 +376,93 @@ func (tm *TaskMonitor) retryTask(taskName string) {
 
 // incrementRetryCount increments the retry count
 func (tm *TaskMonitor) incrementRetryCount(taskName string) error {
-	state := tm.manager.loadRuntimeState()
-	if state.Tasks == nil {
-		return fmt.Errorf("no runtime state found")
-	}
-	
-	runtimeInfo, exists := state.Tasks[taskName]
-	if !exists {
-		return fmt.Errorf("task %s not found in runtime state", taskName)
-	}
-	
-	// Increment retry count
-	runtimeInfo.RetryNum++
-	
-	// Save updated state
-	return tm.manager.saveRuntimeStateWithData(state)
+	return tm.manager.updateRuntimeState(func(state *RuntimeState) error {
+		runtimeInfo, exists := state.Tasks[taskName]
+		if !exists {
+			return fmt.Errorf("task %s not found in runtime state", taskName)
+		}
+		
+		// Increment retry count
+		runtimeInfo.RetryNum++
+		return nil
+	})
 }
 
 // handleRetryFailure handles restart failure
+// A restart that could not even be spawned counts as a retry attempt, so the
+// backoff keeps growing and the task enters crash-loop at its retry limit
 func (tm *TaskMonitor) handleRetryFailure(taskName string, err error) {
 	// Log error info, but don't affect monitoring of other tasks
 	fmt.Printf("TaskMonitor: Retry failed for task %s: %v\n", taskName, err)
 	
-	// Update task status, mark restart failure
-	state := tm.manager.loadRuntimeState()
-	if state.Tasks != nil {
-		if runtimeInfo, exists := state.Tasks[taskName]; exists {
-			// Create failure status info
-			failedInfo := &TaskRuntimeInfo{
-				Name:           taskName,
-				Status:         "stopped",
-				PID:            0,
-				StartTime:      runtimeInfo.StartTime,
-				EndTime:        time.Now(),
-				ExitCode:       -1, // Use -1 to indicate restart failure
-				StoppedByTaskd: false,
-				RetryNum:       runtimeInfo.RetryNum, // Keep current retry count
-			}
-			
-			// Update state
-			if updateErr := tm.updateTaskState(taskName, failedInfo); updateErr != nil {
-				fmt.Printf("TaskMonitor: Failed to update task state after retry failure: %v\n", updateErr)
-			}
+	// Keep the in-memory task consistent with the state written below, a
+	// later state save would bring its "failed" status back otherwise
+	tm.manager.markTaskRetryFailed(taskName)
+	
+	// Update the existing entry in place, mark restart failure
+	var updatedInfo TaskRuntimeInfo
+	updateErr := tm.manager.updateRuntimeState(func(state *RuntimeState) error {
+		runtimeInfo, exists := state.Tasks[taskName]
+		if !exists {
+			return fmt.Errorf("task %s not found in runtime state", taskName)
 		}
+		
+		runtimeInfo.Status = "stopped"
+		runtimeInfo.PID = 0
+		runtimeInfo.EndTime = time.Now()
+		runtimeInfo.ExitCode = -1 // Use -1 to indicate restart failure
+		runtimeInfo.StoppedByTaskd = false
+		runtimeInfo.RetryNum++
+		updatedInfo = *runtimeInfo
+		return nil
+	})
+	if updateErr != nil {
+		fmt.Printf("TaskMonitor: Failed to update task state after retry failure: %v\n", updateErr)
+		return
 	}
 	
-	// Additional error handling logic can be added here, such as:
-	// - Log to file
-	// - Send notifications
-	// - Trigger alerts
+	if tm.shouldEnterCrashLoop(taskName, &updatedInfo) {
+		tm.enterCrashLoop(taskName, &updatedInfo)
+		return
+	}
+	
+	// Without a restart delay the next periodic check retries the task, so a
+	// task that cannot be spawned is not retried in a tight loop
+	config := tm.getTaskConfig(taskName)
+	if config != nil && config.RestartDelay() > 0 &&
+		tm.shouldRetryTask(taskName, &updatedInfo) {
+		tm.scheduleRetry(taskName, &updatedInfo)
+	}
+}
+
+// resetRetryCountIfHealthy resets the retry count of a task that stayed up for its reset_after window
+func (tm *TaskMonitor) resetRetryCountIfHealthy(taskName string, runtimeInfo *TaskRuntimeInfo) {
+	if runtimeInfo.RetryNum == 0 || runtimeInfo.StartTime.IsZero() {
+		return
+	}
+	
+	config := tm.getTaskConfig(taskName)
+	if config == nil {
+		return
+	}
+	
+	// Uptime of the current run, or of the last run if the task has exited
+	end := time.Now()
+	if runtimeInfo.Status != "running" {
+		end = runtimeInfo.EndTime
+	}
+	if end.Sub(runtimeInfo.StartTime) < config.RestartResetAfter() {
+		return
+	}
+	
+	fmt.Printf("TaskMonitor: Task %s stayed up for at least %v, resetting retry count\n",
+		taskName, config.RestartResetAfter())
+	tm.manager.resetTaskRetryCount(taskName)
+	runtimeInfo.RetryNum = 0
 }
 
-// shouldLogRetryLimitReached checks if retry limit reached info should be logged
-func (tm *TaskMonitor) shouldLogRetryLimitReached(taskName string, runtimeInfo *TaskRuntimeInfo) bool {
+// shouldEnterCrashLoop checks if a task the policy would restart has reached its retry limit
+func (tm *TaskMonitor) shouldEnterCrashLoop(taskName string, runtimeInfo *TaskRuntimeInfo) bool {
 	// Skip daemon itself
 	if taskName == "taskd" {
 		return false
@@ -315,34 +474,63 @@ func (tm *TaskMonitor) shouldLogRetryLimitReached(taskName string, runtimeInfo *
 		return false
 	}
 	
-	// Check if it's an auto-start task that has reached retry limit
-	return config.AutoStart &&
-		runtimeInfo.Status == "stopped" &&
+	// Check if it's a task the policy would restart that has reached retry limit
+	maxRetry := config.MaxRestarts()
+	return runtimeInfo.Status == "stopped" &&
 		!runtimeInfo.StoppedByTaskd &&
-		config.MaxRetryNum > 0 &&
-		runtimeInfo.RetryNum >= config.MaxRetryNum
+		config.ShouldRestart(runtimeInfo.ExitCode) &&
+		maxRetry > 0 &&
+		runtimeInfo.RetryNum >= maxRetry
 }
 
-// logRetryLimitReached logs retry limit reached info
-func (tm *TaskMonitor) logRetryLimitReached(taskName string, runtimeInfo *TaskRuntimeInfo) {
+// enterCrashLoop marks a task that has reached its retry limit as crash-looping
+// Crash-looping tasks are not restarted again until they are started manually
+func (tm *TaskMonitor) enterCrashLoop(taskName string, runtimeInfo *TaskRuntimeInfo) {
 	config := tm.getTaskConfig(taskName)
 	if config == nil {
 		return
 	}
 	
-	fmt.Printf("TaskMonitor: Task %s has reached maximum retry limit (%d/%d), stopping automatic restart attempts\n",
-		taskName, runtimeInfo.RetryNum, config.MaxRetryNum)
+	fmt.Printf("TaskMonitor: Task %s has reached maximum retry limit (%d/%d), marking it as crash-looping\n",
+		taskName, runtimeInfo.RetryNum, config.MaxRestarts())
+	
+	if err := tm.manager.markTaskCrashLoop(taskName); err != nil {
+		fmt.Printf("TaskMonitor: Failed to mark task %s as crash-looping: %v\n", taskName, err)
+	}
 }
 
 // StateUpdater state update interface
+// Every change to the runtime state goes through Update, which reads, modifies
+// and writes the state as one step, so concurrent writers don't lose updates.
 type StateUpdater interface {
 	UpdateTaskState(name string, info *TaskRuntimeInfo) error
 	UpdateDaemonState(status *DaemonStatus) error
 	GetRuntimeState() (*RuntimeState, error)
 	SaveRuntimeState(state *RuntimeState) error
+	Update(fn func(state *RuntimeState) error) error
+}
+
+// NewStateStore creates the runtime state store of a backend (see taskdconfig.StateBackend*)
+// It returns the store and the path of its file. Unknown backends use the JSON file.
+func NewStateStore(backend string) (StateUpdater, string) {
+	jsonPath := config.GetTaskDRuntimeFile()
+	switch backend {
+	case config.StateBackendBolt:
+		dbPath := config.GetTaskDStateDBFile()
+		return NewBoltStateManager(dbPath, jsonPath), dbPath
+	case config.StateBackendJSON, "":
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: unknown state backend %q, using %s\n", backend, config.StateBackendJSON)
+	}
+	return NewFileStateManager(jsonPath), jsonPath
 }
 
 // FileStateManager file state manager
+// The CLI and the daemon both write the state file, so every access holds an
+// advisory lock on a lock file next to it (shared for reads, exclusive for
+// writes). The in-process mutex serializes goroutines sharing a manager.
+// Functions passed to Update must not read the state through another store,
+// that would wait for the lock held by Update.
 type FileStateManager struct {
 	statePath string
 	mu        sync.RWMutex
@@ -357,21 +545,10 @@ func NewFileStateManager(statePath string) *FileStateManager {
 
 // UpdateTaskState updates task state
 func (fsm *FileStateManager) UpdateTaskState(name string, info *TaskRuntimeInfo) error {
-	fsm.mu.Lock()
-	defer fsm.mu.Unlock()
-	
-	state, err := fsm.loadRuntimeStateUnsafe()
-	if err != nil {
-		return fmt.Errorf("failed to load runtime state: %w", err)
-	}
-	
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
-	}
-	
-	state.Tasks[name] = info
-	
-	return fsm.saveRuntimeStateUnsafe(state)
+	return fsm.Update(func(state *RuntimeState) error {
+		state.Tasks[name] = info
+		return nil
+	})
 }
 
 // UpdateDaemonState updates daemon state
@@ -388,6 +565,7 @@ func (fsm *FileStateManager) UpdateDaemonState(status *DaemonStatus) error {
 	
 	if status.IsRunning {
 		daemonInfo.Status = "running"
+		setRuntimeIdentity(daemonInfo)
 	}
 	
 	return fsm.UpdateTaskState("taskd", daemonInfo)
@@ -398,6 +576,15 @@ func (fsm *FileStateManager) GetRuntimeState() (*RuntimeState, error) {
 	fsm.mu.RLock()
 	defer fsm.mu.RUnlock()
 	
+	// Without a state directory there is nothing to lock or read
+	unlock, err := fsm.lockStateFile(false)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		defer unlock()
+	}
+	
 	return fsm.loadRuntimeStateUnsafe()
 }
 
@@ -406,29 +593,70 @@ func (fsm *FileStateManager) SaveRuntimeState(state *RuntimeState) error {
 	fsm.mu.Lock()
 	defer fsm.mu.Unlock()
 	
+	unlock, err := fsm.lockStateFile(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	
 	return fsm.saveRuntimeStateUnsafe(state)
 }
 
-// BatchUpdate batch updates multiple task states (improves concurrency performance)
-func (fsm *FileStateManager) BatchUpdate(updates map[string]*TaskRuntimeInfo) error {
+// Update loads the runtime state, applies fn to it and saves it, holding the
+// state file lock throughout. The state is not saved if fn returns an error.
+func (fsm *FileStateManager) Update(fn func(state *RuntimeState) error) error {
 	fsm.mu.Lock()
 	defer fsm.mu.Unlock()
 	
+	unlock, err := fsm.lockStateFile(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	
 	state, err := fsm.loadRuntimeStateUnsafe()
 	if err != nil {
 		return fmt.Errorf("failed to load runtime state: %w", err)
 	}
 	
+	if err := fn(state); err != nil {
+		return err
+	}
+	
 	if state.Tasks == nil {
 		state.Tasks = make(map[string]*TaskRuntimeInfo)
 	}
+	return fsm.saveRuntimeStateUnsafe(state)
+}
+
+// BatchUpdate batch updates multiple task states (improves concurrency performance)
+func (fsm *FileStateManager) BatchUpdate(updates map[string]*TaskRuntimeInfo) error {
+	return fsm.Update(func(state *RuntimeState) error {
+		// Batch update
+		for name, info := range updates {
+			state.Tasks[name] = info
+		}
+		return nil
+	})
+}
+
+// lockStateFile takes the advisory lock of the state file and returns the function releasing it
+func (fsm *FileStateManager) lockStateFile(exclusive bool) (func(), error) {
+	lockPath := fsm.statePath + ".lock"
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
 	
-	// Batch update
-	for name, info := range updates {
-		state.Tasks[name] = info
+	if err := lockFile(file, exclusive); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock state file: %w", err)
 	}
 	
-	return fsm.saveRuntimeStateUnsafe(state)
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
 }
 
 // loadRuntimeStateUnsafe loads runtime state (no lock, internal use)
@@ -525,8 +753,9 @@ func (fsm *FileStateManager) saveRuntimeStateUnsafe(state *RuntimeState) error {
 	// Atomic update: write to temp file first, then rename
 	tempPath := fsm.statePath + ".tmp"
 	
-	// Write to temp file
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+	// Write to temp file, and flush it so the rename never exposes a partial file
+	if err := writeFileSync(tempPath, data); err != nil {
+		os.Remove(tempPath)
 		return fmt.Errorf("failed to write temp state file: %w", err)
 	}
 	
@@ -537,9 +766,31 @@ func (fsm *FileStateManager) saveRuntimeStateUnsafe(state *RuntimeState) error {
 		return fmt.Errorf("failed to rename temp state file: %w", err)
 	}
 	
+	// Make the rename itself durable
+	if err := syncDir(filepath.Dir(fsm.statePath)); err != nil {
+		fmt.Printf("Warning: Failed to sync state directory: %v\n", err)
+	}
+	
 	return nil
 }
 
+// writeFileSync writes a file and flushes it to disk
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 // copyFile copies a file
 func (fsm *FileStateManager) copyFile(src, dst string) error {
 	data, err := os.ReadFile(src)
@@ -559,18 +810,7 @@ func NewProcessChecker() *ProcessChecker {
 
 // CheckTaskProcess checks the status of a task process
 func (pc *ProcessChecker) CheckTaskProcess(pid int) (*ProcessStatus, error) {
-	if pid <= 0 {
-		return &ProcessStatus{
-			Exists:         false,
-			IsTaskd:        false,
-			ExitCode:       0,
-			ExecutablePath: "",
-		}, nil
-	}
-	
-	// Try to find the process
-	_, err := os.FindProcess(pid)
-	if err != nil {
+	if pid <= 0 || !isProcessAlive(pid) {
 		return &ProcessStatus{
 			Exists:         false,
 			IsTaskd:        false,
@@ -579,16 +819,15 @@ func (pc *ProcessChecker) CheckTaskProcess(pid int) (*ProcessStatus, error) {
 		}, nil
 	}
 	
-	// Check if process is still running
-	// On Windows, we'll use a different approach since Signal(0) may not be reliable
-	// We'll assume that if os.FindProcess succeeds and we can get the process,
-	// then the process exists. This is a simplified approach.
-	
 	// Process exists, now check if it's a taskd process
 	// This is critical for PID reuse detection
-	execPath, isTaskd, err := pc.getProcessExecutablePath(pid)
+	identity, err := readProcessIdentity(pid)
+	if errors.Is(err, errProcessNotFound) {
+		// The process exited after the liveness check
+		return &ProcessStatus{Exists: false}, nil
+	}
 	if err != nil {
-		// Can't determine executable path, assume it exists but unknown type
+		// Can't inspect the process, assume it exists but unknown type
 		return &ProcessStatus{
 			Exists:         true,
 			IsTaskd:        false,
@@ -597,18 +836,26 @@ func (pc *ProcessChecker) CheckTaskProcess(pid int) (*ProcessStatus, error) {
 		}, nil
 	}
 	
+	execPath, isTaskd, err := pc.getProcessExecutablePath(identity)
+	if err != nil {
+		return nil, err
+	}
+	
 	return &ProcessStatus{
 		Exists:         true,
 		IsTaskd:        isTaskd,
 		ExitCode:       0, // Process is running, no exit code
 		ExecutablePath: execPath,
+		StartTime:      identity.startTime,
 	}, nil
 }
 
-// CheckTaskProcessWithValidation checks if a process is the expected taskd process
-// This method helps detect PID reuse by validating the executable path
-func (pc *ProcessChecker) CheckTaskProcessWithValidation(pid int, expectedExecPath string) (*ProcessStatus, error) {
-	status, err := pc.CheckTaskProcess(pid)
+// CheckTaskProcessWithValidation checks if the process recorded in the runtime state is still running
+// This method detects PID reuse by comparing the start time and executable
+// recorded when the process started with those of the process now using the PID.
+// A reused PID is reported as a process that no longer exists.
+func (pc *ProcessChecker) CheckTaskProcessWithValidation(info *TaskRuntimeInfo) (*ProcessStatus, error) {
+	status, err := pc.CheckTaskProcess(info.PID)
 	if err != nil {
 		return status, err
 	}
@@ -617,35 +864,34 @@ func (pc *ProcessChecker) CheckTaskProcessWithValidation(pid int, expectedExecPa
 		return status, nil
 	}
 	
-	// If we have an expected executable path, validate it
-	if expectedExecPath != "" && status.ExecutablePath != "" {
-		// Compare executable paths to detect PID reuse
-		if status.ExecutablePath != expectedExecPath {
-			// PID has been reused by a different process
-			status.IsTaskd = false
-		}
+	current := processIdentity{startTime: status.StartTime, executable: status.ExecutablePath}
+	if !runtimeIdentity(info).sameProcess(current) {
+		// PID has been reused by a different process
+		return &ProcessStatus{
+			Exists:         false,
+			IsTaskd:        false,
+			ExitCode:       0,
+			ExecutablePath: status.ExecutablePath,
+			StartTime:      status.StartTime,
+			PIDReused:      true,
+		}, nil
 	}
 	
 	return status, nil
 }
 
 // getProcessExecutablePath gets the executable path of a process and checks if it's taskd
-func (pc *ProcessChecker) getProcessExecutablePath(pid int) (string, bool, error) {
+func (pc *ProcessChecker) getProcessExecutablePath(identity processIdentity) (string, bool, error) {
 	// Get current executable path for comparison
-	currentExec, err := os.Executable()
+	currentExec, err := currentExecutable()
 	if err != nil {
 		return "", false, fmt.Errorf("failed to get current executable path: %w", err)
 	}
 	
-	// For this implementation, we'll assume that if the process exists and we can access it,
-	// it's likely to be taskd. In a production system, you would use Windows API to get
-	// the actual executable path and compare it.
-	
-	// Since we're dealing with processes we started ourselves, and we're checking the PID
-	// that we recorded when we started the process, it's reasonable to assume it's taskd
-	// if the process still exists.
-	
-	return currentExec, true, nil
+	if identity.executable == "" {
+		return "", false, nil
+	}
+	return identity.executable, sameExecutable(identity.executable, currentExec), nil
 }
 
 // GetProcessExitCode gets the exit code of a terminated process
@@ -657,19 +903,18 @@ func (pc *ProcessChecker) GetProcessExitCode(pid int) (int, error) {
 	process, err := os.FindProcess(pid)
 	if err != nil {
 		// Process doesn't exist, we can't get exit code
-		return 0, fmt.Errorf("process %d not found", pid)
+		return -1, fmt.Errorf("process %d not found", pid)
 	}
 	
 	// Check if process is still running
 	if err := process.Signal(syscall.Signal(0)); err == nil {
 		// Process is still running, no exit code available
-		return 0, fmt.Errorf("process %d is still running", pid)
+		return -1, fmt.Errorf("process %d is still running", pid)
 	}
 	
-	// Process has exited, but we can't easily get the exit code in Go without Windows API
-	// For now, return 0 (success) as a default
-	// In a real implementation, you would use Windows API to get the actual exit code
-	return 0, nil
+	// Process has exited, but the exit code of a process that is not our child
+	// cannot be read once it is gone
+	return -1, fmt.Errorf("exit code of process %d is unknown", pid)
 }
 
 // DaemonStateManager manages daemon state persistence
@@ -686,15 +931,10 @@ func NewDaemonStateManager() *DaemonStateManager {
 
 // SaveDaemonState saves the daemon state to persistent storage
 func (dsm *DaemonStateManager) SaveDaemonState(daemonInfo *TaskRuntimeInfo) error {
-	state := dsm.manager.loadRuntimeState()
-	
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
-	}
-	
-	state.Tasks["taskd"] = daemonInfo
-	
-	return dsm.manager.saveRuntimeStateWithData(state)
+	return dsm.manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["taskd"] = daemonInfo
+		return nil
+	})
 }
 
 // LoadDaemonState loads the daemon state from persistent storage
@@ -707,13 +947,10 @@ func (dsm *DaemonStateManager) LoadDaemonState() (*TaskRuntimeInfo, bool) {
 
 // ClearDaemonState removes the daemon state from persistent storage
 func (dsm *DaemonStateManager) ClearDaemonState() error {
-	state := dsm.manager.loadRuntimeState()
-	
-	if state.Tasks != nil {
+	return dsm.manager.updateRuntimeState(func(state *RuntimeState) error {
 		delete(state.Tasks, "taskd")
-	}
-	
-	return dsm.manager.saveRuntimeStateWithData(state)
+		return nil
+	})
 }
 
 // updateDaemonStoppedStateWithManager updates daemon state to stopped using state manager
@@ -744,7 +981,7 @@ func (dm *DaemonManager) ValidateDaemonState() (*TaskRuntimeInfo, bool, error) {
 	// If daemon is marked as running, verify the process actually exists
 	if daemonInfo.Status == "running" {
 		checker := NewProcessChecker()
-		status, err := checker.CheckTaskProcess(daemonInfo.PID)
+		status, err := checker.CheckTaskProcessWithValidation(daemonInfo)
 		if err != nil {
 			return daemonInfo, false, fmt.Errorf("failed to check daemon process: %w", err)
 		}
@@ -773,6 +1010,13 @@ func (dm *DaemonManager) ValidateDaemonState() (*TaskRuntimeInfo, bool, error) {
 	return daemonInfo, true, nil
 }
 
+const (
+	// daemonStartTimeout is how long to wait for a new daemon to answer on its socket
+	daemonStartTimeout = 5 * time.Second
+	// daemonStopTimeout is how long to wait for the daemon to stop its tasks and exit
+	daemonStopTimeout = 60 * time.Second
+)
+
 // DaemonManager manages the daemon process lifecycle
 type DaemonManager struct {
 	mu sync.RWMutex
@@ -811,21 +1055,20 @@ func (dm *DaemonManager) StartDaemon() error {
 	cmd := exec.Command(execPath, "--daemon")
 	cmd.Dir = config.GetTaskDHome()
 	
-	// Set process attributes for proper daemon behavior on Windows
-	// DETACHED_PROCESS creates a process without a console window
-	// CREATE_NEW_PROCESS_GROUP creates a new process group
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | 0x00000008, // 0x00000008 is DETACHED_PROCESS
-		HideWindow:    true,
-	}
+	// Set process attributes for proper daemon behavior
+	// (see process_windows.go and process_unix.go)
+	setDetachedProcessAttr(cmd)
 	
 	// Start the process
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start daemon process: %w", err)
 	}
 	
-	// Give the process a moment to start
-	time.Sleep(100 * time.Millisecond)
+	// Wait until the daemon answers on its IPC socket
+	if err := dm.waitForDaemonReady(cmd, daemonStartTimeout); err != nil {
+		cmd.Process.Kill()
+		return err
+	}
 	
 	// 4. Update runtime state
 	daemonInfo := &TaskRuntimeInfo{
@@ -836,6 +1079,7 @@ func (dm *DaemonManager) StartDaemon() error {
 		StoppedByTaskd: false,
 		RetryNum:       0,
 	}
+	setRuntimeIdentity(daemonInfo)
 	
 	stateManager := NewDaemonStateManager()
 	if err := stateManager.SaveDaemonState(daemonInfo); err != nil {
@@ -847,11 +1091,98 @@ func (dm *DaemonManager) StartDaemon() error {
 	return nil
 }
 
+// waitForDaemonReady waits until a freshly started daemon answers on its IPC socket
+func (dm *DaemonManager) waitForDaemonReady(cmd *exec.Cmd, timeout time.Duration) error {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	
+	client := NewDaemonClient()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := client.Ping(); err == nil {
+			return nil
+		}
+		
+		select {
+		case err := <-exited:
+			return fmt.Errorf("daemon process exited during startup: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	
+	return fmt.Errorf("daemon did not become ready within %v", timeout)
+}
+
+// waitForDaemonExit waits until the daemon no longer answers on its IPC socket
+func (dm *DaemonManager) waitForDaemonExit(timeout time.Duration) bool {
+	client := NewDaemonClient()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := client.Ping(); err != nil {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+// RecordDaemonStarted records the current process as the running daemon (called by the daemon itself)
+func (dm *DaemonManager) RecordDaemonStarted() error {
+	daemonInfo := &TaskRuntimeInfo{
+		Name:           "taskd",
+		Status:         "running",
+		PID:            os.Getpid(),
+		StartTime:      time.Now(),
+		StoppedByTaskd: false,
+		RetryNum:       0,
+	}
+	setRuntimeIdentity(daemonInfo)
+	return dm.updateDaemonRuntimeState(daemonInfo)
+}
+
+// RecordDaemonStopped records that the current daemon process has stopped (called by the daemon itself)
+func (dm *DaemonManager) RecordDaemonStopped() error {
+	err := GetManager().updateRuntimeState(func(state *RuntimeState) error {
+		// Don't overwrite the state of a newer daemon that has already taken over
+		daemonInfo, exists := state.Tasks["taskd"]
+		if !exists || daemonInfo.PID != os.Getpid() {
+			return errDaemonReplaced
+		}
+		
+		state.Tasks["taskd"] = daemonStoppedInfo(daemonInfo)
+		return nil
+	})
+	
+	if errors.Is(err, errDaemonReplaced) {
+		return nil
+	}
+	return err
+}
+
+// errDaemonReplaced is returned by an update when another daemon has been recorded meanwhile
+var errDaemonReplaced = errors.New("daemon was replaced")
+
 // StopDaemon stops the daemon process
 func (dm *DaemonManager) StopDaemon() error {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 	
+	// Ask the daemon to stop its tasks and exit
+	if err := NewDaemonClient().Shutdown(); err == nil {
+		if !dm.waitForDaemonExit(daemonStopTimeout) {
+			return fmt.Errorf("daemon did not exit within %v", daemonStopTimeout)
+		}
+		
+		stateManager := NewDaemonStateManager()
+		if daemonInfo, exists := stateManager.LoadDaemonState(); exists {
+			return dm.updateDaemonStoppedStateWithManager(stateManager, daemonInfo)
+		}
+		return nil
+	}
+	
+	// The daemon does not answer, fall back to signalling the recorded PID
 	// 1. Load runtime state to get daemon PID
 	manager := GetManager()
 	state := manager.loadRuntimeState()
@@ -865,7 +1196,10 @@ func (dm *DaemonManager) StopDaemon() error {
 		return fmt.Errorf("daemon is not running (status: %s)", daemonInfo.Status)
 	}
 	
-	// 2. Find and terminate the daemon process
+	// 2. Find and terminate the daemon process, unless its PID was reused
+	if !processStillRunning(daemonInfo.PID, runtimeIdentity(daemonInfo)) {
+		return dm.updateDaemonStoppedState(daemonInfo)
+	}
 	process, err := os.FindProcess(daemonInfo.PID)
 	if err != nil {
 		// Process doesn't exist, update state and return
@@ -908,52 +1242,31 @@ func (dm *DaemonManager) EnsureDaemonRunning() error {
 }
 
 // isDaemonRunningLocked checks if daemon is running (must be called with lock held)
+// The daemon is considered running only when it answers on its IPC socket;
+// the PID recorded in runtime.json may belong to a process that has exited
 func (dm *DaemonManager) isDaemonRunningLocked() bool {
-	// Load daemon state
-	stateManager := NewDaemonStateManager()
-	daemonInfo, exists := stateManager.LoadDaemonState()
-	
-	if !exists || daemonInfo.Status != "running" {
-		return false
-	}
-	
-	// Use ProcessChecker to validate the process
-	checker := NewProcessChecker()
-	status, err := checker.CheckTaskProcess(daemonInfo.PID)
-	if err != nil {
-		return false
-	}
-	
-	// Process must exist and be a taskd process
-	return status.Exists && status.IsTaskd
+	return NewDaemonClient().Ping() == nil
 }
 
 // updateDaemonRuntimeState updates the daemon's runtime state
 func (dm *DaemonManager) updateDaemonRuntimeState(daemonInfo *TaskRuntimeInfo) error {
-	manager := GetManager()
-	state := manager.loadRuntimeState()
-	
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
-	}
-	
-	state.Tasks["taskd"] = daemonInfo
-	
-	// Save the updated state
-	return manager.saveRuntimeStateWithData(state)
+	return GetManager().updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["taskd"] = daemonInfo
+		return nil
+	})
 }
 
 // updateDaemonStoppedState updates the daemon state to stopped
 func (dm *DaemonManager) updateDaemonStoppedState(daemonInfo *TaskRuntimeInfo) error {
-	manager := GetManager()
-	state := manager.loadRuntimeState()
-	
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
-	}
-	
-	// Update daemon info to stopped state
-	stoppedInfo := &TaskRuntimeInfo{
+	return GetManager().updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["taskd"] = daemonStoppedInfo(daemonInfo)
+		return nil
+	})
+}
+
+// daemonStoppedInfo returns the stopped state of a daemon stopped by a user command
+func daemonStoppedInfo(daemonInfo *TaskRuntimeInfo) *TaskRuntimeInfo {
+	return &TaskRuntimeInfo{
 		Name:           "taskd",
 		Status:         "stopped",
 		PID:            0,
@@ -962,9 +1275,4 @@ func (dm *DaemonManager) updateDaemonStoppedState(daemonInfo *TaskRuntimeInfo) e
 		StoppedByTaskd: true, // Stopped by user command
 		RetryNum:       daemonInfo.RetryNum,
 	}
-	
-	state.Tasks["taskd"] = stoppedInfo
-	
-	// Save the updated state
-	return manager.saveRuntimeStateWithData(state)
 }
\ No newline at end of file
@@ -1,12 +1,18 @@
 package task
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
+
+	taskdconfig "taskd/internal/config"
 )
 
 func TestNewTaskMonitor(t *testing.T) {
@@ -191,17 +197,81 @@ func TestShouldRetryTask(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name:     "should retry - always policy without auto start",
+			taskName: "test-task",
+			runtimeInfo: &TaskRuntimeInfo{
+				Status:   "stopped",
+				ExitCode: 0,
+			},
+			config: &Config{
+				Restart: RestartPolicy{Policy: RestartAlways},
+			},
+			want: true,
+		},
+		{
+			name:     "should retry - on-failure policy after failure",
+			taskName: "test-task",
+			runtimeInfo: &TaskRuntimeInfo{
+				Status:   "stopped",
+				ExitCode: 1,
+			},
+			config: &Config{
+				Restart: RestartPolicy{Policy: RestartOnFailure},
+			},
+			want: true,
+		},
+		{
+			name:     "should not retry - on-failure policy after clean exit",
+			taskName: "test-task",
+			runtimeInfo: &TaskRuntimeInfo{
+				Status:   "stopped",
+				ExitCode: 0,
+			},
+			config: &Config{
+				AutoStart: true,
+				Restart:   RestartPolicy{Policy: RestartOnFailure},
+			},
+			want: false,
+		},
+		{
+			name:     "should not retry - never policy overrides auto start",
+			taskName: "test-task",
+			runtimeInfo: &TaskRuntimeInfo{
+				Status:   "stopped",
+				ExitCode: 1,
+			},
+			config: &Config{
+				AutoStart: true,
+				Restart:   RestartPolicy{Policy: RestartNever},
+			},
+			want: false,
+		},
+		{
+			name:     "should not retry - restart max_retry overrides max_retry_num",
+			taskName: "test-task",
+			runtimeInfo: &TaskRuntimeInfo{
+				Status:   "stopped",
+				ExitCode: 1,
+				RetryNum: 2,
+			},
+			config: &Config{
+				MaxRetryNum: 5,
+				Restart:     RestartPolicy{Policy: RestartAlways, MaxRetry: 2},
+			},
+			want: false,
+		},
 	}
 	
+	t.Setenv("TASKD_HOME", t.TempDir())
+	monitor := NewTaskMonitor(time.Second)
+	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Test the logic directly by checking the conditions
-			// This avoids the file system dependency
-			shouldRetry := tt.config.AutoStart &&
-				tt.runtimeInfo.Status == "stopped" &&
-				!tt.runtimeInfo.StoppedByTaskd &&
-				(tt.config.MaxRetryNum <= 0 || tt.runtimeInfo.RetryNum < tt.config.MaxRetryNum) &&
-				tt.taskName != "taskd" // Skip daemon task
+			tt.config.Executable = "test"
+			writeTestTaskConfig(t, tt.taskName, tt.config)
+			
+			shouldRetry := monitor.shouldRetryTask(tt.taskName, tt.runtimeInfo)
 			
 			if shouldRetry != tt.want {
 				t.Errorf("shouldRetryTask() = %v, want %v", shouldRetry, tt.want)
@@ -267,6 +337,188 @@ func TestProcessCheckerCheckTaskProcess(t *testing.T) {
 	})
 }
 
+func TestProcessCheckerCheckTaskProcessWithValidation(t *testing.T) {
+	checker := NewProcessChecker()
+	pid := os.Getpid()
+	identity, err := readProcessIdentity(pid)
+	if err != nil {
+		t.Fatalf("readProcessIdentity(%d) returned error: %v", pid, err)
+	}
+	if identity.startTime == 0 {
+		t.Fatal("readProcessIdentity() returned no start time")
+	}
+	
+	tests := []struct {
+		name       string
+		info       *TaskRuntimeInfo
+		wantExists bool
+		wantReused bool
+	}{
+		{"same process", &TaskRuntimeInfo{PID: pid, ProcessStartTime: identity.startTime, Executable: identity.executable}, true, false},
+		{"no identity recorded", &TaskRuntimeInfo{PID: pid}, true, false},
+		{"executable changed by exec", &TaskRuntimeInfo{PID: pid, ProcessStartTime: identity.startTime, Executable: "/bin/other"}, true, false},
+		{"different start time", &TaskRuntimeInfo{PID: pid, ProcessStartTime: identity.startTime + 1, Executable: identity.executable}, false, true},
+		{"different executable without start time", &TaskRuntimeInfo{PID: pid, Executable: "/bin/other"}, false, true},
+		{"no process", &TaskRuntimeInfo{PID: 0, ProcessStartTime: identity.startTime}, false, false},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := checker.CheckTaskProcessWithValidation(tt.info)
+			if err != nil {
+				t.Fatalf("CheckTaskProcessWithValidation() returned error: %v", err)
+			}
+			if status.Exists != tt.wantExists || status.PIDReused != tt.wantReused {
+				t.Errorf("CheckTaskProcessWithValidation() = exists %v, reused %v, want %v, %v",
+					status.Exists, status.PIDReused, tt.wantExists, tt.wantReused)
+			}
+		})
+	}
+}
+
+func TestCheckTaskProcessPIDReused(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	manager := &Manager{
+		tasks:          make(map[string]*Task),
+		builtinHandler: NewBuiltinTaskHandler(),
+		store:          NewFileStateManager(filepath.Join(t.TempDir(), "runtime.json")),
+		storeKey:       taskdconfig.GetStateBackend() + "\x00" + taskdconfig.GetTaskDHome(),
+	}
+	monitor := &TaskMonitor{manager: manager}
+
+	identity, err := readProcessIdentity(os.Getpid())
+	if err != nil {
+		t.Fatalf("readProcessIdentity() returned error: %v", err)
+	}
+	// The PID is now used by another process than the one recorded
+	runtimeInfo := &TaskRuntimeInfo{
+		Name:             "web",
+		Status:           "running",
+		PID:              os.Getpid(),
+		ProcessStartTime: identity.startTime + 1,
+		StartTime:        time.Now(),
+	}
+	if err := manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["web"] = runtimeInfo
+		return nil
+	}); err != nil {
+		t.Fatalf("updateRuntimeState() returned error: %v", err)
+	}
+
+	monitor.checkTaskProcess("web", runtimeInfo)
+
+	info := manager.loadRuntimeState().Tasks["web"]
+	if info == nil || info.Status != "stopped" || info.ExitCode != -1 {
+		t.Fatalf("task after its PID was reused = %+v, want stopped with exit code -1", info)
+	}
+	if config := (&Config{Restart: RestartPolicy{Policy: RestartOnFailure}}); !config.ShouldRestart(info.ExitCode) {
+		t.Error("on-failure policy does not restart a task whose exit code is unknown")
+	}
+}
+
+func TestCheckTaskProcessExitedWithUnknownCode(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	manager := &Manager{
+		tasks:          make(map[string]*Task),
+		builtinHandler: NewBuiltinTaskHandler(),
+		store:          NewFileStateManager(filepath.Join(t.TempDir(), "runtime.json")),
+		storeKey:       taskdconfig.GetStateBackend() + "\x00" + taskdconfig.GetTaskDHome(),
+	}
+	monitor := &TaskMonitor{manager: manager}
+
+	// A process that exited successfully, its exit code is gone once it was reaped
+	executable, args := exitCommand("0")
+	cmd := exec.Command(executable, args...)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	runtimeInfo := &TaskRuntimeInfo{
+		Name:      "web",
+		Status:    "running",
+		PID:       cmd.Process.Pid,
+		StartTime: time.Now(),
+	}
+	if err := manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["web"] = runtimeInfo
+		return nil
+	}); err != nil {
+		t.Fatalf("updateRuntimeState() returned error: %v", err)
+	}
+
+	monitor.checkTaskProcess("web", runtimeInfo)
+
+	info := manager.loadRuntimeState().Tasks["web"]
+	if info == nil || info.Status != "stopped" || info.ExitCode != -1 {
+		t.Fatalf("task after its process exited = %+v, want stopped with exit code -1", info)
+	}
+}
+
+func TestRetryTaskSpawnFailure(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	config := &Config{
+		Executable:  filepath.Join(t.TempDir(), "missing"),
+		Restart:     RestartPolicy{Policy: RestartAlways, Delay: "1h"},
+		MaxRetryNum: 2,
+	}
+	writeTestTaskConfig(t, "web", config)
+
+	manager := &Manager{
+		tasks:          map[string]*Task{"web": NewTask("web", config)},
+		builtinHandler: NewBuiltinTaskHandler(),
+		store:          NewFileStateManager(filepath.Join(t.TempDir(), "runtime.json")),
+		storeKey:       taskdconfig.GetStateBackend() + "\x00" + taskdconfig.GetTaskDHome(),
+	}
+	monitor := &TaskMonitor{
+		manager:        manager,
+		stopChan:       make(chan struct{}),
+		retryChan:      make(chan string),
+		pendingRetries: make(map[string]*time.Timer),
+	}
+	defer monitor.cancelPendingRetries()
+
+	startTime := time.Now().Add(-time.Minute)
+	if err := manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["web"] = &TaskRuntimeInfo{
+			Name:      "web",
+			Status:    "stopped",
+			StartTime: startTime,
+			ExitCode:  1,
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("updateRuntimeState() returned error: %v", err)
+	}
+
+	// A restart that fails to spawn counts as an attempt and schedules the next one
+	monitor.retryTask("web")
+
+	info := manager.loadRuntimeState().Tasks["web"]
+	if info == nil || info.Status != "stopped" || info.ExitCode != -1 || info.RetryNum != 1 {
+		t.Fatalf("task after a failed restart = %+v, want stopped with exit code -1 and retry 1", info)
+	}
+	if !info.StartTime.Equal(startTime) {
+		t.Errorf("StartTime = %v, want the existing entry to be kept (%v)", info.StartTime, startTime)
+	}
+	if status := manager.tasks["web"].GetRuntimeInfo().Status; status != "stopped" {
+		t.Errorf("in-memory status = %q, want stopped", status)
+	}
+	if _, pending := monitor.pendingRetries["web"]; !pending {
+		t.Error("no retry was scheduled after the failed restart")
+	}
+
+	// The last attempt reaches the retry limit
+	monitor.cancelPendingRetries()
+	monitor.retryTask("web")
+
+	info = manager.loadRuntimeState().Tasks["web"]
+	if info == nil || info.Status != "crash-loop" || info.RetryNum != 2 {
+		t.Fatalf("task after reaching the retry limit = %+v, want crash-loop with retry 2", info)
+	}
+	if _, pending := monitor.pendingRetries["web"]; pending {
+		t.Error("a retry was scheduled for a crash-looping task")
+	}
+}
+
 func TestNewFileStateManager(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "test-state.json")
 	fsm := NewFileStateManager(tempFile)
@@ -467,6 +719,149 @@ func TestFileStateManagerCorruptedStateRecovery(t *testing.T) {
 	}
 }
 
+func TestFileStateManagerUpdate(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "test-state.json")
+	fsm := NewFileStateManager(stateFile)
+	
+	if err := fsm.Update(incrementRetryNums("task1")); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	
+	// A failing update leaves the state untouched
+	failure := fmt.Errorf("update failed")
+	err := fsm.Update(func(state *RuntimeState) error {
+		state.Tasks["task1"].RetryNum = 100
+		return failure
+	})
+	if err != failure {
+		t.Errorf("Update() = %v, want %v", err, failure)
+	}
+	
+	state, err := fsm.GetRuntimeState()
+	if err != nil {
+		t.Fatalf("GetRuntimeState() failed: %v", err)
+	}
+	if got := state.Tasks["task1"].RetryNum; got != 1 {
+		t.Errorf("RetryNum = %d, want 1", got)
+	}
+	if _, err := os.Stat(stateFile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary state file left behind: %v", err)
+	}
+}
+
+// incrementRetryNums returns an update incrementing the retry count of the shared entry and of name
+func incrementRetryNums(name string) func(state *RuntimeState) error {
+	return func(state *RuntimeState) error {
+		for _, key := range []string{"shared", name} {
+			info, exists := state.Tasks[key]
+			if !exists {
+				info = &TaskRuntimeInfo{Name: key, Status: "stopped"}
+				state.Tasks[key] = info
+			}
+			info.RetryNum++
+		}
+		return nil
+	}
+}
+
+// TestRuntimeStateConcurrentWriters runs writers in other processes, like CLI
+// commands, and in this process, like the daemon's monitor, on one state store.
+// No update may be lost.
+func TestRuntimeStateConcurrentWriters(t *testing.T) {
+	for _, backend := range []string{taskdconfig.StateBackendJSON, taskdconfig.StateBackendBolt} {
+		t.Run(backend, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("TASKD_HOME", home)
+			writeStateBackendConfig(t, home, backend)
+			
+			const processes, goroutines, updates = 4, 4, 50
+			
+			var cmds []*exec.Cmd
+			var outputs []*bytes.Buffer
+			for i := 0; i < processes; i++ {
+				var output bytes.Buffer
+				cmd := exec.Command(os.Args[0], "-test.run=^TestRuntimeStateWriterProcess$")
+				cmd.Env = append(os.Environ(),
+					"TASKD_STATE_WRITER="+backend,
+					fmt.Sprintf("TASKD_STATE_WRITER_NAME=cli-%d", i),
+					fmt.Sprintf("TASKD_STATE_WRITER_UPDATES=%d", updates))
+				cmd.Stdout = &output
+				cmd.Stderr = &output
+				if err := cmd.Start(); err != nil {
+					t.Fatalf("failed to start writer process: %v", err)
+				}
+				cmds = append(cmds, cmd)
+				outputs = append(outputs, &output)
+			}
+			
+			manager := GetManager()
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func(name string) {
+					defer wg.Done()
+					for j := 0; j < updates; j++ {
+						if err := manager.updateRuntimeState(incrementRetryNums(name)); err != nil {
+							t.Errorf("updateRuntimeState() failed: %v", err)
+							return
+						}
+					}
+				}(fmt.Sprintf("monitor-%d", i))
+			}
+			wg.Wait()
+			
+			for i, cmd := range cmds {
+				if err := cmd.Wait(); err != nil {
+					t.Fatalf("writer process %d failed: %v\n%s", i, err, outputs[i])
+				}
+			}
+			
+			state := manager.loadRuntimeState()
+			if got, want := state.Tasks["shared"].RetryNum, (processes+goroutines)*updates; got != want {
+				t.Errorf("shared RetryNum = %d, want %d (updates were lost)", got, want)
+			}
+			for name, info := range state.Tasks {
+				if name != "shared" && info.RetryNum != updates {
+					t.Errorf("%s RetryNum = %d, want %d", name, info.RetryNum, updates)
+				}
+			}
+			if len(state.Tasks) != processes+goroutines+1 {
+				t.Errorf("state has %d entries, want %d", len(state.Tasks), processes+goroutines+1)
+			}
+		})
+	}
+}
+
+// TestRuntimeStateWriterProcess is the writer process of TestRuntimeStateConcurrentWriters
+func TestRuntimeStateWriterProcess(t *testing.T) {
+	backend := os.Getenv("TASKD_STATE_WRITER")
+	if backend == "" {
+		t.Skip("only runs as a writer process of TestRuntimeStateConcurrentWriters")
+	}
+	
+	updates, err := strconv.Atoi(os.Getenv("TASKD_STATE_WRITER_UPDATES"))
+	if err != nil {
+		t.Fatalf("invalid update count: %v", err)
+	}
+	
+	store, _ := NewStateStore(backend)
+	update := incrementRetryNums(os.Getenv("TASKD_STATE_WRITER_NAME"))
+	for i := 0; i < updates; i++ {
+		if err := store.Update(update); err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+}
+
+// writeStateBackendConfig selects the runtime state backend in the global configuration of home
+func writeStateBackendConfig(t *testing.T, home, backend string) {
+	t.Helper()
+	content := fmt.Sprintf("state_backend = %q\n", backend)
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config.toml: %v", err)
+	}
+}
+
 func TestNewDaemonStateManager(t *testing.T) {
 	dsm := NewDaemonStateManager()
 	if dsm == nil {
@@ -0,0 +1,418 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	taskdconfig "taskd/internal/config"
+)
+
+// Dependencies between tasks are declared with three lists of task names:
+//   requires: started before the task, which is not started if one of them fails;
+//             a required task cannot be stopped while the task runs (unless the stop cascades)
+//   wants:    started before the task, failures are only reported
+//   after:    not started, only ordered before the task when both are started together
+
+// dependencyNames returns the tasks started before this one, required tasks first
+func (c *Config) dependencyNames() []string {
+	return uniqueNames(append(append([]string{}, c.Requires...), c.Wants...))
+}
+
+// orderedAfter returns every task this one is ordered after, sorted
+func (c *Config) orderedAfter() []string {
+	names := uniqueNames(append(append(append([]string{}, c.Requires...), c.Wants...), c.After...))
+	sort.Strings(names)
+	return names
+}
+
+// requires reports whether name is one of the required tasks
+func (c *Config) requires(name string) bool {
+	for _, required := range c.Requires {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueNames removes duplicates, keeping the first occurrence of each name
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// sortByDependencies orders task names so that every task comes after the tasks it
+// requires, wants or is ordered after. Dependencies outside names are ignored and
+// names are visited alphabetically, so the order is stable. It fails if the
+// dependencies form a cycle.
+func sortByDependencies(names []string, configs map[string]*Config) ([]string, error) {
+	inSet := make(map[string]bool)
+	for _, name := range names {
+		inSet[name] = true
+	}
+	sorted := uniqueNames(names)
+	sort.Strings(sorted)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := make(map[string]int)
+	order := make([]string, 0, len(sorted))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					cycle := append(append([]string{}, path[i:]...), name)
+					return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+				}
+			}
+		}
+
+		marks[name] = visiting
+		path = append(path, name)
+		if config := configs[name]; config != nil {
+			for _, dependency := range config.orderedAfter() {
+				if !inSet[dependency] {
+					continue
+				}
+				if err := visit(dependency); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		marks[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range sorted {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// requiredBy returns the tasks that require name directly, sorted
+func requiredBy(name string, configs map[string]*Config) []string {
+	var dependents []string
+	for other, config := range configs {
+		if config.requires(name) {
+			dependents = append(dependents, other)
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// dependentsOf returns the tasks that require name, directly or through other tasks, sorted
+func dependentsOf(name string, configs map[string]*Config) []string {
+	found := make(map[string]bool)
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, other := range requiredBy(current, configs) {
+			if !found[other] && other != name {
+				found[other] = true
+				queue = append(queue, other)
+			}
+		}
+	}
+
+	dependents := make([]string, 0, len(found))
+	for other := range found {
+		dependents = append(dependents, other)
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// loadTaskConfigs loads the configuration files of all tasks, skipping invalid ones
+func loadTaskConfigs() (map[string]*Config, error) {
+	configs := make(map[string]*Config)
+	entries, err := os.ReadDir(taskdconfig.GetTaskDTasksDir())
+	if os.IsNotExist(err) {
+		return configs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+			continue
+		}
+		var config Config
+		if err := DecodeConfigFile(filepath.Join(taskdconfig.GetTaskDTasksDir(), entry.Name()), &config); err != nil {
+			continue
+		}
+		configs[strings.TrimSuffix(entry.Name(), ".toml")] = &config
+	}
+	return configs, nil
+}
+
+// ValidateDependencies checks the dependencies of a task being added or edited
+// against the other configured tasks. It fails on the builtin task and on cycles,
+// and returns the dependencies that do not exist yet.
+func ValidateDependencies(name string, config *Config) ([]string, error) {
+	builtin := NewBuiltinTaskHandler()
+	for _, dependency := range config.orderedAfter() {
+		if dependency == "" {
+			return nil, fmt.Errorf("dependency names cannot be empty")
+		}
+		if dependency == name {
+			return nil, fmt.Errorf("task '%s' cannot depend on itself", name)
+		}
+		if builtin.IsBuiltinTask(dependency) {
+			return nil, fmt.Errorf("task '%s' cannot depend on the builtin task '%s'", name, dependency)
+		}
+	}
+
+	configs, err := loadTaskConfigs()
+	if err != nil {
+		return nil, err
+	}
+	configs[name] = config
+
+	names := make([]string, 0, len(configs))
+	for n := range configs {
+		names = append(names, n)
+	}
+	if _, err := sortByDependencies(names, configs); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, dependency := range config.orderedAfter() {
+		if _, exists := configs[dependency]; !exists {
+			missing = append(missing, dependency)
+		}
+	}
+	return missing, nil
+}
+
+// startWithDependencies starts the tasks a task requires and wants that are not
+// running, in dependency order, and then starts the task itself with start.
+// The task is not started if one of the tasks it requires fails to start.
+func (m *Manager) startWithDependencies(name string, start func() error) error {
+	if m.builtinHandler.IsBuiltinTask(name) {
+		return start()
+	}
+
+	// Collect the tasks started along with this one, following configuration changes
+	configs := make(map[string]*Config)
+	tasks := make(map[string]*Task)
+	failed := make(map[string]error)
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, seen := configs[current]; seen || failed[current] != nil {
+			continue
+		}
+
+		if err := m.syncTaskConfig(current); err != nil {
+			failed[current] = err
+			continue
+		}
+		m.mu.RLock()
+		task, exists := m.tasks[current]
+		m.mu.RUnlock()
+		if !exists {
+			failed[current] = fmt.Errorf("task '%s' does not exist", current)
+			continue
+		}
+
+		tasks[current] = task
+		configs[current] = task.getConfig()
+		queue = append(queue, configs[current].dependencyNames()...)
+	}
+
+	if tasks[name] == nil {
+		return failed[name]
+	}
+
+	order, err := sortByDependencies(mapKeys(configs), configs)
+	if err != nil {
+		return fmt.Errorf("cannot start task '%s': %w", name, err)
+	}
+
+	for _, current := range order {
+		config := configs[current]
+
+		// A task whose required task failed is not started
+		var requirementErr error
+		for _, required := range config.Requires {
+			if err := failed[required]; err != nil {
+				requirementErr = fmt.Errorf("required task '%s' failed to start: %w", required, err)
+				break
+			}
+		}
+
+		if current == name {
+			for _, wanted := range config.Wants {
+				if err := failed[wanted]; err != nil {
+					fmt.Printf("Warning: task '%s' wants task '%s', which failed to start: %v\n", name, wanted, err)
+				}
+			}
+			if requirementErr != nil {
+				return fmt.Errorf("cannot start task '%s': %w", name, requirementErr)
+			}
+			return start()
+		}
+
+		if requirementErr != nil {
+			failed[current] = requirementErr
+			continue
+		}
+		if !tasks[current].IsRunning() {
+			fmt.Printf("Starting task %s, a dependency of %s\n", current, name)
+			if err := m.startTask(current); err != nil {
+				failed[current] = err
+				continue
+			}
+		}
+		// The tasks started after a dependency find it ready
+		if err := tasks[current].WaitReady(); err != nil {
+			failed[current] = fmt.Errorf("not ready: %w", err)
+		}
+	}
+	return nil
+}
+
+// dependencyClosure returns the tasks started along with name, directly or through
+// other tasks, sorted and without name itself
+func dependencyClosure(name string, configs map[string]*Config) []string {
+	found := make(map[string]bool)
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		config := configs[current]
+		if config == nil {
+			continue
+		}
+		for _, dependency := range config.dependencyNames() {
+			if !found[dependency] && dependency != name {
+				found[dependency] = true
+				queue = append(queue, dependency)
+			}
+		}
+	}
+	
+	dependencies := make([]string, 0, len(found))
+	for dependency := range found {
+		dependencies = append(dependencies, dependency)
+	}
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+// mapKeys returns the names of a configuration map
+func mapKeys(configs map[string]*Config) []string {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	return names
+}
+
+// taskConfigs returns the current configuration of every task
+func (m *Manager) taskConfigs() map[string]*Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	configs := make(map[string]*Config, len(m.tasks))
+	for name, task := range m.tasks {
+		configs[name] = task.getConfig()
+	}
+	return configs
+}
+
+// sortByDependencyOrder orders task names by their dependencies, falling back to
+// alphabetical order if they form a cycle
+func (m *Manager) sortByDependencyOrder(names []string) []string {
+	order, err := sortByDependencies(names, m.taskConfigs())
+	if err != nil {
+		fmt.Printf("Warning: %v, ignoring task dependencies\n", err)
+		order = append([]string{}, names...)
+		sort.Strings(order)
+	}
+	return order
+}
+
+// stoppedFirst returns, for each task of a start order, the tasks later in the
+// order that are ordered after it. Stopping them first stops the tasks in the
+// reverse of the start order, while unrelated tasks can stop together.
+func stoppedFirst(order []string, configs map[string]*Config) map[string][]string {
+	first := make(map[string][]string)
+	for i, name := range order {
+		for _, later := range order[i+1:] {
+			config := configs[later]
+			if config == nil {
+				continue
+			}
+			for _, dependency := range config.orderedAfter() {
+				if dependency == name {
+					first[name] = append(first[name], later)
+					break
+				}
+			}
+		}
+	}
+	return first
+}
+
+// runningDependents returns the running tasks that require name, directly or through other tasks
+func (m *Manager) runningDependents(name string) []*Task {
+	configs := m.taskConfigs()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var running []*Task
+	for _, dependent := range dependentsOf(name, configs) {
+		if task, exists := m.tasks[dependent]; exists && task.IsRunning() {
+			running = append(running, task)
+		}
+	}
+	return running
+}
+
+// stopDependents stops the running tasks that require name, the tasks depending on them first
+func (m *Manager) stopDependents(name string, opts StopOptions) error {
+	running := m.runningDependents(name)
+	names := make([]string, len(running))
+	for i, task := range running {
+		names[i] = task.name
+	}
+
+	order := m.sortByDependencyOrder(names)
+	opts.Cascade = false
+	for i := len(order) - 1; i >= 0; i-- {
+		fmt.Printf("Stopping task %s, which requires %s\n", order[i], name)
+		if err := m.stopTask(order[i], opts); err != nil {
+			return fmt.Errorf("failed to stop task '%s', which requires '%s': %w", order[i], name, err)
+		}
+	}
+	return nil
+}
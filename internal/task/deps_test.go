@@ -0,0 +1,120 @@
+package task
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortByDependencies(t *testing.T) {
+	configs := map[string]*Config{
+		"web":    {Requires: []string{"db"}, Wants: []string{"cache"}},
+		"db":     {},
+		"cache":  {After: []string{"db"}},
+		"worker": {Requires: []string{"web"}, After: []string{"missing"}},
+		"cron":   {},
+	}
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"worker", "web", "db", "cache", "cron"}, []string{"db", "cache", "cron", "web", "worker"}},
+		// Dependencies outside the set are ignored
+		{[]string{"worker", "cache"}, []string{"cache", "worker"}},
+		{nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		got, err := sortByDependencies(tt.names, configs)
+		if err != nil {
+			t.Errorf("sortByDependencies(%v) = %v", tt.names, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortByDependencies(%v) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestSortByDependenciesCycle(t *testing.T) {
+	configs := map[string]*Config{
+		"a": {Requires: []string{"b"}},
+		"b": {Wants: []string{"c"}},
+		"c": {After: []string{"a"}},
+	}
+
+	_, err := sortByDependencies([]string{"a", "b", "c"}, configs)
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("sortByDependencies() = %v, want a cycle error", err)
+	}
+}
+
+func TestDependentsOf(t *testing.T) {
+	configs := map[string]*Config{
+		"db":     {},
+		"web":    {Requires: []string{"db"}},
+		"worker": {Requires: []string{"web"}},
+		"report": {Wants: []string{"db"}, After: []string{"web"}},
+	}
+
+	if got := dependentsOf("db", configs); !reflect.DeepEqual(got, []string{"web", "worker"}) {
+		t.Errorf("dependentsOf(db) = %v, want [web worker]", got)
+	}
+	if got := requiredBy("db", configs); !reflect.DeepEqual(got, []string{"web"}) {
+		t.Errorf("requiredBy(db) = %v, want [web]", got)
+	}
+}
+
+func TestStoppedFirst(t *testing.T) {
+	configs := map[string]*Config{
+		"db":     {},
+		"cache":  {},
+		"web":    {Requires: []string{"db"}, Wants: []string{"cache"}},
+		"report": {After: []string{"web"}},
+		"cron":   {},
+	}
+
+	got := stoppedFirst([]string{"cache", "cron", "db", "web", "report"}, configs)
+	want := map[string][]string{
+		"cache": {"web"},
+		"db":    {"web"},
+		"web":   {"report"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stoppedFirst() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	writeTestTaskConfig(t, "db", &Config{Executable: "db"})
+	writeTestTaskConfig(t, "web", &Config{Executable: "web", Requires: []string{"db"}})
+
+	tests := []struct {
+		name        string
+		config      *Config
+		wantMissing []string
+		wantErr     string
+	}{
+		{"api", &Config{Requires: []string{"db"}, Wants: []string{"cache"}}, []string{"cache"}, ""},
+		{"api", &Config{Requires: []string{"api"}}, nil, "itself"},
+		{"api", &Config{After: []string{"taskd"}}, nil, "builtin"},
+		{"api", &Config{Requires: []string{""}}, nil, "empty"},
+		// Editing db to require web closes a cycle through the existing configuration
+		{"db", &Config{Requires: []string{"web"}}, nil, "db -> web -> db"},
+	}
+
+	for _, tt := range tests {
+		missing, err := ValidateDependencies(tt.name, tt.config)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateDependencies(%s, %+v) = %v, want an error containing %q", tt.name, tt.config, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(missing, tt.wantMissing) {
+			t.Errorf("ValidateDependencies(%s, %+v) = %v, %v, want %v", tt.name, tt.config, missing, err, tt.wantMissing)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+//go:build !windows
+
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// addTestDependencyTasks configures long-running tasks with dependencies in a test daemon
+func addTestDependencyTasks(t *testing.T, manager *Manager, configs map[string]*Config) {
+	t.Helper()
+	for name, config := range configs {
+		if config.Executable == "" {
+			config.Executable, config.Args = trapCommand("exit 0")
+		}
+		config.WorkDir = t.TempDir()
+		writeTestTaskConfig(t, name, config)
+		if err := manager.syncTaskConfig(name); err != nil {
+			t.Fatalf("syncTaskConfig(%s) = %v", name, err)
+		}
+	}
+}
+
+func TestStartTaskStartsDependencies(t *testing.T) {
+	manager, _ := newTestDaemon(t)
+	addTestDependencyTasks(t, manager, map[string]*Config{
+		"db":    {},
+		"cache": {},
+		"web":   {Requires: []string{"db"}, Wants: []string{"cache"}},
+	})
+
+	if err := manager.StartTask("web"); err != nil {
+		t.Fatalf("StartTask(web) = %v", err)
+	}
+	for _, name := range []string{"db", "cache", "web"} {
+		if !manager.isSupervising(name) {
+			t.Errorf("task %s is not running", name)
+		}
+	}
+
+	// db is required by a running task
+	err := manager.StopTask("db")
+	if err == nil || !strings.Contains(err.Error(), "web") {
+		t.Fatalf("StopTask(db) = %v, want an error naming web", err)
+	}
+	if !manager.isSupervising("db") {
+		t.Fatal("db was stopped although web requires it")
+	}
+
+	// cache is only wanted
+	if err := manager.StopTask("cache"); err != nil {
+		t.Errorf("StopTask(cache) = %v", err)
+	}
+
+	if err := manager.StopTaskWithOptions("db", StopOptions{Cascade: true}); err != nil {
+		t.Fatalf("StopTaskWithOptions(db, cascade) = %v", err)
+	}
+	for _, name := range []string{"db", "web"} {
+		if manager.isSupervising(name) {
+			t.Errorf("task %s is still running after the cascading stop", name)
+		}
+	}
+}
+
+func TestStartTaskFailedRequirement(t *testing.T) {
+	manager, _ := newTestDaemon(t)
+	addTestDependencyTasks(t, manager, map[string]*Config{
+		"web":    {Requires: []string{"db"}},
+		"worker": {Wants: []string{"db"}},
+	})
+
+	// A missing required task keeps the task from starting
+	err := manager.StartTask("web")
+	if err == nil || !strings.Contains(err.Error(), "required task 'db'") {
+		t.Errorf("StartTask(web) = %v, want a required task error", err)
+	}
+	if manager.isSupervising("web") {
+		t.Error("web started without the task it requires")
+	}
+
+	// A missing wanted task does not
+	if err := manager.StartTask("worker"); err != nil {
+		t.Errorf("StartTask(worker) = %v", err)
+	}
+	if !manager.isSupervising("worker") {
+		t.Error("worker did not start")
+	}
+}
+
+func TestStopAllTasksStopsDependentsFirst(t *testing.T) {
+	manager, _ := newTestDaemon(t)
+	web := &Config{Requires: []string{"db"}}
+	// web takes a while to exit, db must still be running meanwhile
+	web.Executable, web.Args = trapCommand("sleep 0.3; exit 0")
+	addTestDependencyTasks(t, manager, map[string]*Config{
+		"db":  {},
+		"web": web,
+	})
+	if err := manager.StartTask("web"); err != nil {
+		t.Fatalf("StartTask(web) = %v", err)
+	}
+
+	manager.StopAllTasks()
+
+	end := func(name string) time.Time {
+		task := manager.tasks[name]
+		task.mu.RLock()
+		defer task.mu.RUnlock()
+		return task.endTime
+	}
+	if end("db").Before(end("web")) {
+		t.Errorf("db stopped at %v, before web which requires it at %v", end("db"), end("web"))
+	}
+}
@@ -0,0 +1,153 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parseEnvFile parses the KEY=VALUE lines of a dotenv file
+// Blank lines and lines starting with # are skipped, and a leading "export " is
+// ignored. Single-quoted values are taken literally, double-quoted values support
+// \n, \t, \" and \\ escapes, and unquoted values end at " #".
+func parseEnvFile(data string) ([]string, error) {
+	var env []string
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !variableName.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// parseEnvValue returns the value of a dotenv line, without quotes and comment
+func parseEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		return value[1 : 1+end], checkEnvValueEnd(value[2+end:])
+	case strings.HasPrefix(value, `"`):
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			switch c := value[i]; {
+			case c == '"':
+				return b.String(), checkEnvValueEnd(value[i+1:])
+			case c == '\\' && i+1 < len(value):
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(value[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// checkEnvValueEnd checks that only a comment follows a quoted value
+func checkEnvValueEnd(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected text after the closing quote")
+	}
+	return nil
+}
+
+// LoadEnv returns the variables of the env files followed by the env list
+// Later entries override earlier ones. Values written as secret://NAME are replaced
+// with secret(NAME). Relative env file paths are resolved from the working directory.
+func (c *Config) LoadEnv(secret func(name string) (string, error)) ([]string, error) {
+	return c.loadEnv(secret, false)
+}
+
+// displayEnv returns the environment of LoadEnv as it is shown by info and list
+// Env files are where credentials live, so their values are masked like secrets.
+func (c *Config) displayEnv() ([]string, error) {
+	return c.loadEnv(maskSecret, true)
+}
+
+// loadEnv returns the environment of LoadEnv, with the env file values masked if maskFiles is set
+func (c *Config) loadEnv(secret func(name string) (string, error), maskFiles bool) ([]string, error) {
+	var env []string
+	for _, file := range c.EnvFile {
+		path, err := NewPathResolver().ResolvePath(file, processDir(c))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve env file path: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file: %w", err)
+		}
+		entries, err := parseEnvFile(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("env file %s: %w", path, err)
+		}
+		if maskFiles {
+			for i, entry := range entries {
+				key, _, _ := strings.Cut(entry, "=")
+				entries[i] = key + "=" + SecretMask
+			}
+		}
+		env = append(env, entries...)
+	}
+	env = append(env, c.Env...)
+
+	for i, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		name, ok := SecretRef(value)
+		if !ok {
+			continue
+		}
+		value, err := secret(name)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", key, err)
+		}
+		env[i] = key + "=" + value
+	}
+	return env, nil
+}
+
+// maskSecret stands in for the secret lookup when a configuration is shown
+func maskSecret(name string) (string, error) {
+	return SecretMask, nil
+}
+
+// resolvedEnvFiles returns the paths of the env files, resolved from the working directory
+func resolvedEnvFiles(c *Config) []string {
+	var paths []string
+	for _, file := range c.EnvFile {
+		if path, err := NewPathResolver().ResolvePath(file, processDir(c)); err == nil {
+			file = path
+		}
+		paths = append(paths, file)
+	}
+	return paths
+}
@@ -0,0 +1,135 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	data := `# database settings
+DB_HOST=localhost
+export DB_PORT = 5432
+DB_NAME=app # inline comment
+DB_URL=postgres://host/db#fragment
+
+SINGLE='literal $HOME \n # kept'
+DOUBLE="line one\nsay \"hi\"" # comment
+EMPTY=
+PASSWORD=secret://db-password
+`
+	env, err := parseEnvFile(data)
+	if err != nil {
+		t.Fatalf("parseEnvFile() = %v", err)
+	}
+	want := []string{
+		"DB_HOST=localhost",
+		"DB_PORT=5432",
+		"DB_NAME=app",
+		"DB_URL=postgres://host/db#fragment",
+		`SINGLE=literal $HOME \n # kept`,
+		"DOUBLE=line one\nsay \"hi\"",
+		"EMPTY=",
+		"PASSWORD=secret://db-password",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("parseEnvFile() = %q, want %q", env, want)
+	}
+
+	for _, invalid := range []string{"NO_EQUALS", "1KEY=x", `KEY="unterminated`, "KEY='x' trailing"} {
+		if _, err := parseEnvFile(invalid); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("parseEnvFile(%q) error = %v, want an error on line 1", invalid, err)
+		}
+	}
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "app.env"), []byte("MODE=file\nTOKEN=secret://api-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{
+		WorkDir: workDir,
+		EnvFile: []string{"app.env"},
+		Env:     []string{"MODE=env", "DB_PASSWORD=secret://db-password"},
+	}
+	secrets := map[string]string{"api-token": "t0ken", "db-password": "pa55"}
+	lookup := func(name string) (string, error) {
+		if value, ok := secrets[name]; ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("secret '%s' not found", name)
+	}
+
+	env, err := config.LoadEnv(lookup)
+	if err != nil {
+		t.Fatalf("LoadEnv() = %v", err)
+	}
+	want := []string{"MODE=file", "TOKEN=t0ken", "MODE=env", "DB_PASSWORD=pa55"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("LoadEnv() = %q, want %q", env, want)
+	}
+	if config.Env[1] != "DB_PASSWORD=secret://db-password" {
+		t.Errorf("LoadEnv() modified the env list: %q", config.Env)
+	}
+
+	masked, _ := config.displayEnv()
+	want = []string{"MODE=" + SecretMask, "TOKEN=" + SecretMask, "MODE=env", "DB_PASSWORD=" + SecretMask}
+	if !reflect.DeepEqual(masked, want) {
+		t.Errorf("displayEnv() = %q, want %q", masked, want)
+	}
+
+	delete(secrets, "db-password")
+	if _, err := config.LoadEnv(lookup); err == nil || !strings.Contains(err.Error(), "env DB_PASSWORD") {
+		t.Errorf("LoadEnv() with a missing secret = %v", err)
+	}
+	config.EnvFile = []string{"missing.env"}
+	if _, err := config.LoadEnv(lookup); err == nil || !strings.Contains(err.Error(), "failed to read env file") {
+		t.Errorf("LoadEnv() with a missing env file = %v", err)
+	}
+}
+
+func TestTaskDetailInfoMasksEnvFiles(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	manager := &Manager{
+		tasks:          make(map[string]*Task),
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+	manager.SetDaemonMode(true)
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "db.env"), []byte("DB_USER=app-user\nDB_PASSWORD=hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeTestTaskConfig(t, "db-client", &Config{
+		Executable: "db-client",
+		WorkDir:    workDir,
+		EnvFile:    []string{"db.env"},
+		Env:        []string{"MODE=prod"},
+	})
+	if err := manager.syncTaskConfig("db-client"); err != nil {
+		t.Fatalf("syncTaskConfig() = %v", err)
+	}
+
+	info, err := manager.getTaskDetailInfo("db-client")
+	if err != nil {
+		t.Fatalf("getTaskDetailInfo() = %v", err)
+	}
+	// info -o json prints the detail info as it is
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"app-user", "hunter2"} {
+		if strings.Contains(string(data), value) {
+			t.Errorf("info JSON contains the env file value %q: %s", value, data)
+		}
+	}
+	if info.Resolved == nil || !reflect.DeepEqual(info.Resolved.Env, []string{"DB_USER=" + SecretMask, "DB_PASSWORD=" + SecretMask, "MODE=prod"}) {
+		t.Errorf("Resolved = %+v, want the env file variables with masked values", info.Resolved)
+	}
+}
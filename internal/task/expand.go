@@ -0,0 +1,197 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"taskd/internal/config"
+)
+
+// Built-in variables, available in every task and taking precedence over environment variables
+const (
+	VarTaskDHome = "TASKD_HOME" // TaskD home directory
+	VarTaskName  = "TASK_NAME"  // Name of the task
+	VarDate      = "DATE"       // Date the task starts, 2006-01-02
+	VarTimestamp = "TIMESTAMP"  // Time the task starts, 20060102-150405
+)
+
+// envVarPrefix selects the environment of the daemon in ${env:VAR}
+const envVarPrefix = "env:"
+
+// variableName matches the names allowed in ${VAR} and ${env:VAR}
+var variableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// VariableExpander expands ${VAR} and ${env:VAR} in task configuration fields
+// ${VAR} is a built-in variable, a variable of the task's env list or of the
+// environment of the daemon, in that order. ${env:VAR} only reads the environment
+// of the daemon. $${ is written as ${, other $ signs are kept for the shell.
+type VariableExpander struct {
+	builtins map[string]string
+	env      map[string]string
+}
+
+// NewVariableExpander creates an expander for a task starting at now
+func NewVariableExpander(taskName string, now time.Time) *VariableExpander {
+	return &VariableExpander{
+		builtins: map[string]string{
+			VarTaskDHome: config.GetTaskDHome(),
+			VarTaskName:  taskName,
+			VarDate:      now.Format("2006-01-02"),
+			VarTimestamp: now.Format("20060102-150405"),
+		},
+		env: make(map[string]string),
+	}
+}
+
+// ExpandEnv expands the values of a task's env list (KEY=VALUE) in order
+// Each value can use the ones before it, and later calls to Expand all of them.
+func (e *VariableExpander) ExpandEnv(env []string) ([]string, error) {
+	if len(env) == 0 {
+		return env, nil
+	}
+
+	expanded := make([]string, 0, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		value, err := e.Expand(value)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", key, err)
+		}
+		e.env[key] = value
+		expanded = append(expanded, key+"="+value)
+	}
+	return expanded, nil
+}
+
+// Expand returns value with its variables expanded
+func (e *VariableExpander) Expand(value string) (string, error) {
+	return e.expand(value, false)
+}
+
+// ExpandShell returns a shell command with its variables expanded
+// References that are not variables of the task, like ${1}, ${x:-default} or a
+// variable set by the command itself, are kept for the shell. ${env:VAR} must
+// still be defined.
+func (e *VariableExpander) ExpandShell(command string) (string, error) {
+	return e.expand(command, true)
+}
+
+// expand returns value with its variables expanded, keeping unknown references in shell mode
+func (e *VariableExpander) expand(value string, shell bool) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			b.WriteString(value)
+			return b.String(), nil
+		}
+		// $${ is a literal ${
+		if start > 0 && value[start-1] == '$' {
+			b.WriteString(value[:start])
+			b.WriteString("{")
+			value = value[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			if shell {
+				b.WriteString(value)
+				return b.String(), nil
+			}
+			return "", fmt.Errorf("unterminated variable reference in '%s'", value[start:])
+		}
+		reference := value[start+2 : start+end]
+		expanded, err := e.lookup(reference)
+		if err != nil {
+			if !shell || strings.HasPrefix(reference, envVarPrefix) {
+				return "", err
+			}
+			expanded = value[start : start+end+1]
+		}
+		b.WriteString(value[:start])
+		b.WriteString(expanded)
+		value = value[start+end+1:]
+	}
+}
+
+// lookup returns the value of a variable reference, the text between ${ and }
+func (e *VariableExpander) lookup(reference string) (string, error) {
+	name, fromEnv := strings.CutPrefix(reference, envVarPrefix)
+	if !variableName.MatchString(name) {
+		return "", fmt.Errorf("invalid variable reference '${%s}'", reference)
+	}
+
+	if !fromEnv {
+		if value, ok := e.builtins[name]; ok {
+			return value, nil
+		}
+		if value, ok := e.env[name]; ok {
+			return value, nil
+		}
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("undefined variable '${%s}'", reference)
+}
+
+// Expand returns a copy of the configuration with the variables of executable, args,
+// workdir, env, env_file, stdin, stdout and stderr expanded for a task starting at now
+// In shell mode the executable and args are expanded as shell commands.
+func (c *Config) Expand(taskName string, now time.Time) (*Config, error) {
+	expander := NewVariableExpander(taskName, now)
+	expanded := *c
+
+	expandCommand := expander.Expand
+	if c.Shell {
+		expandCommand = expander.ExpandShell
+	}
+
+	var err error
+	if expanded.Env, err = expander.ExpandEnv(c.Env); err != nil {
+		return nil, err
+	}
+	if expanded.Executable, err = expandCommand(c.Executable); err != nil {
+		return nil, fmt.Errorf("executable: %w", err)
+	}
+	if c.Args != nil {
+		expanded.Args = make([]string, len(c.Args))
+		for i, arg := range c.Args {
+			if expanded.Args[i], err = expandCommand(arg); err != nil {
+				return nil, fmt.Errorf("args: %w", err)
+			}
+		}
+	}
+	if len(c.EnvFile) > 0 {
+		expanded.EnvFile = make([]string, len(c.EnvFile))
+		for i, file := range c.EnvFile {
+			if expanded.EnvFile[i], err = expander.Expand(file); err != nil {
+				return nil, fmt.Errorf("env_file: %w", err)
+			}
+		}
+	}
+
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"workdir", &expanded.WorkDir},
+		{"stdin", &expanded.Stdin},
+		{"stdout", &expanded.Stdout},
+		{"stderr", &expanded.Stderr},
+	}
+	for _, field := range fields {
+		if *field.value, err = expander.Expand(*field.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+	return &expanded, nil
+}
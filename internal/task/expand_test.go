@@ -0,0 +1,136 @@
+package task
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVariableExpanderExpand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TASKD_HOME", home)
+	t.Setenv("APP_ROOT", "/srv/app")
+	t.Setenv("TASK_NAME", "from-environment")
+
+	now := time.Date(2026, 3, 7, 9, 5, 1, 0, time.Local)
+	expander := NewVariableExpander("web", now)
+	if _, err := expander.ExpandEnv([]string{"LOG_DIR=${APP_ROOT}/logs", "APP_ROOT=/opt/web"}); err != nil {
+		t.Fatalf("ExpandEnv() = %v", err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"plain value", "plain value", ""},
+		{"${TASKD_HOME}/logs", home + "/logs", ""},
+		{"${LOG_DIR}/${TASK_NAME}-${DATE}.log", "/srv/app/logs/web-2026-03-07.log", ""},
+		{"run-${TIMESTAMP}", "run-20260307-090501", ""},
+		// The task's env list comes before the environment, ${env:VAR} only reads the environment
+		{"${APP_ROOT} ${env:APP_ROOT}", "/opt/web /srv/app", ""},
+		{"${env:TASK_NAME}", "from-environment", ""},
+		{"$${APP_ROOT} $HOME $$", "${APP_ROOT} $HOME $$", ""},
+		{"${UNDEFINED_TASKD_TEST_VAR}", "", "undefined variable '${UNDEFINED_TASKD_TEST_VAR}'"},
+		{"${env:LOG_DIR}", "", "undefined variable '${env:LOG_DIR}'"},
+		{"${APP ROOT}", "", "invalid variable reference"},
+		{"${}", "", "invalid variable reference"},
+		{"${APP_ROOT", "", "unterminated variable reference"},
+	}
+
+	for _, tt := range tests {
+		got, err := expander.Expand(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expand(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestVariableExpanderExpandShell(t *testing.T) {
+	t.Setenv("APP_ROOT", "/srv/app")
+	expander := NewVariableExpander("web", time.Now())
+
+	tests := []struct {
+		command string
+		want    string
+		wantErr string
+	}{
+		{"cd ${APP_ROOT} && ./run ${TASK_NAME}", "cd /srv/app && ./run web", ""},
+		// Shell parameter expansions and the variables of the script are kept
+		{"echo ${PORT:-8080} ${1} ${#}", "echo ${PORT:-8080} ${1} ${#}", ""},
+		{"for f in *; do echo ${f}; done", "for f in *; do echo ${f}; done", ""},
+		{"echo ${APP_ROOT} ${x:-${APP_ROOT}}", "echo /srv/app ${x:-${APP_ROOT}}", ""},
+		{"echo ${unterminated", "echo ${unterminated", ""},
+		{"echo ${env:UNDEFINED_TASKD_TEST_VAR}", "", "undefined variable '${env:UNDEFINED_TASKD_TEST_VAR}'"},
+	}
+
+	for _, tt := range tests {
+		got, err := expander.ExpandShell(tt.command)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExpandShell(%q) error = %v, want %q", tt.command, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandShell(%q) = %q, %v, want %q", tt.command, got, err, tt.want)
+		}
+	}
+}
+
+func TestConfigExpand(t *testing.T) {
+	t.Setenv("TASKD_HOME", "/home/user/.taskd")
+
+	config := &Config{
+		Executable: "${APP}/bin/server",
+		Args:       []string{"--name", "${TASK_NAME}", "--data=${APP}/data"},
+		WorkDir:    "${APP}",
+		Env:        []string{"APP=/opt/my app", "CACHE=${APP}/cache"},
+		Stdin:      "${APP}/input.txt",
+		Stdout:     "${TASKD_HOME}/logs/${TASK_NAME}.log",
+		Stderr:     "err-${DATE}.log",
+	}
+	now := time.Date(2026, 3, 7, 9, 5, 1, 0, time.Local)
+
+	expanded, err := config.Expand("web", now)
+	if err != nil {
+		t.Fatalf("Expand() = %v", err)
+	}
+	want := &Config{
+		Executable: "/opt/my app/bin/server",
+		Args:       []string{"--name", "web", "--data=/opt/my app/data"},
+		WorkDir:    "/opt/my app",
+		Env:        []string{"APP=/opt/my app", "CACHE=/opt/my app/cache"},
+		Stdin:      "/opt/my app/input.txt",
+		Stdout:     "/home/user/.taskd/logs/web.log",
+		Stderr:     "err-2026-03-07.log",
+	}
+	if !reflect.DeepEqual(expanded, want) {
+		t.Errorf("Expand() = %+v, want %+v", expanded, want)
+	}
+	if config.Executable != "${APP}/bin/server" || config.Args[1] != "${TASK_NAME}" {
+		t.Errorf("Expand() modified the configuration: %+v", config)
+	}
+
+	// A shell command keeps the references of the shell, other fields do not
+	shell := &Config{Executable: `echo "${1:-none}" > ${TASK_NAME}.out`, Shell: true}
+	if expanded, err := shell.Expand("web", now); err != nil || expanded.Executable != `echo "${1:-none}" > web.out` {
+		t.Errorf("Expand() in shell mode = %v, want the shell reference kept", err)
+	}
+	shell.Stdout = "${1}.log"
+	if _, err := shell.Expand("web", now); err == nil {
+		t.Error("Expand() in shell mode accepted an invalid reference in stdout")
+	}
+
+	config.Stdout = "${UNDEFINED_TASKD_TEST_VAR}/out.log"
+	if _, err := config.Expand("web", now); err == nil || !strings.HasPrefix(err.Error(), "stdout: undefined variable") {
+		t.Errorf("Expand() error = %v, want an undefined variable in stdout", err)
+	}
+}
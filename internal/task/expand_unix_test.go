@@ -0,0 +1,52 @@
+//go:build !windows
+
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartTaskExpandsVariables(t *testing.T) {
+	workDir := t.TempDir()
+	config := &Config{
+		Executable: "sh",
+		Args:       []string{"-c", `echo "$GREETING"; echo ready; sleep 30`},
+		WorkDir:    workDir,
+		Env:        []string{"GREETING=hello from ${TASK_NAME}"},
+		Stdout:     "${TASK_NAME}-${DATE}.log",
+	}
+	task := NewTask("expand-test", config)
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() { task.StopWithOptions(StopOptions{Force: true}) })
+
+	outPath := filepath.Join(workDir, "expand-test-"+time.Now().Format("2006-01-02")+".log")
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(outPath); strings.Contains(string(data), "ready") {
+			if !strings.HasPrefix(string(data), "hello from expand-test\n") {
+				t.Errorf("output = %q, want the expanded environment", data)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("no output written to %s", outPath)
+}
+
+func TestStartTaskUndefinedVariable(t *testing.T) {
+	task := NewTask("expand-test", &Config{Executable: "${UNDEFINED_TASKD_TEST_VAR}/app", WorkDir: t.TempDir()})
+
+	err := task.Start()
+	if err == nil || !strings.Contains(err.Error(), "undefined variable") {
+		t.Fatalf("Start() = %v, want an undefined variable error", err)
+	}
+	if info := task.GetInfo(); info.Status != "failed" || !strings.Contains(info.LastError, "undefined variable") {
+		t.Errorf("GetInfo() = %s %q, want failed with the expansion error", info.Status, info.LastError)
+	}
+}
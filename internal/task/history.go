@@ -0,0 +1,174 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	taskdconfig "taskd/internal/config"
+)
+
+// maxRunHistory is how many runs are kept in the history file of a task
+const maxRunHistory = 100
+
+// Why a run ended
+const (
+	StopReasonUser      = "user-stop" // Stopped by the stop or delete command
+	StopReasonRestart   = "restart"   // Stopped to be restarted
+	StopReasonShutdown  = "shutdown"  // Stopped because the daemon shut down
+	StopReasonCrash     = "crash"     // Exited by itself with a non-zero exit code
+	StopReasonExited    = "exited"    // Exited by itself with exit code 0
+	StopReasonNotReady  = "not-ready" // Stopped because its readiness probe failed
+	StopReasonUnhealthy = "unhealthy" // Stopped because its liveness probe failed
+	StopReasonOOMKill   = "oom-kill"  // Killed by the out-of-memory killer, or failed at its memory limit on Windows
+)
+
+// RunRecord one finished run of a task, kept in the run history of the task
+type RunRecord struct {
+	Task      string    `json:"task"`
+	PID       int       `json:"pid"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	ExitCode  int       `json:"exit_code"`
+	Reason    string    `json:"reason"` // StopReason*
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Duration returns how long the run lasted
+func (r *RunRecord) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// Failed reports whether the run crashed, never became ready, became unhealthy or ran out of memory
+func (r *RunRecord) Failed() bool {
+	switch r.Reason {
+	case StopReasonCrash, StopReasonNotReady, StopReasonUnhealthy, StopReasonOOMKill:
+		return true
+	}
+	return false
+}
+
+// RunHistoryStore keeps the last maxRunHistory runs of each task
+type RunHistoryStore interface {
+	AppendRunRecord(record *RunRecord) error
+	LoadRunHistory(taskName string) ([]*RunRecord, error)
+}
+
+// NewRunHistoryStore returns the run history of a state backend (see taskdconfig.StateBackend*)
+// The bolt backend keeps it in its database, the others in history/<task>.jsonl.
+func NewRunHistoryStore(backend string) RunHistoryStore {
+	if backend == taskdconfig.StateBackendBolt {
+		return NewBoltStateManager(taskdconfig.GetTaskDStateDBFile(), taskdconfig.GetTaskDRuntimeFile())
+	}
+	return fileRunHistory{}
+}
+
+// AppendRunRecord adds a run to the history of its task, in the configured state backend
+func AppendRunRecord(record *RunRecord) error {
+	return NewRunHistoryStore(taskdconfig.GetStateBackend()).AppendRunRecord(record)
+}
+
+// LoadRunHistory returns the recorded runs of a task from the configured state backend, oldest first
+func LoadRunHistory(taskName string) ([]*RunRecord, error) {
+	return NewRunHistoryStore(taskdconfig.GetStateBackend()).LoadRunHistory(taskName)
+}
+
+// fileRunHistory run history kept in one JSON lines file per task
+type fileRunHistory struct{}
+
+// runHistoryPath returns the history file of a task
+func runHistoryPath(taskName string) string {
+	return filepath.Join(taskdconfig.GetTaskDHistoryDir(), taskName+".jsonl")
+}
+
+// AppendRunRecord adds a run to the history file of its task
+// The file holds one JSON record per line; once it has maxRunHistory records
+// the oldest ones are dropped.
+func (fileRunHistory) AppendRunRecord(record *RunRecord) error {
+	path := runHistoryPath(record.Task)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f, true); err != nil {
+		return fmt.Errorf("failed to lock history file: %w", err)
+	}
+	defer unlockFile(f)
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if n := len(lines); n > 0 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1]
+	}
+
+	if len(lines) < maxRunHistory {
+		// The file offset is at the end after reading it
+		if _, err := f.Write(line); err != nil {
+			return fmt.Errorf("failed to write history file: %w", err)
+		}
+		return nil
+	}
+
+	// Rewrite the file without the oldest records, readers are excluded by the lock
+	kept := append(lines[len(lines)-maxRunHistory+1:], line)
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate history file: %w", err)
+	}
+	if _, err := f.WriteAt(bytes.Join(kept, nil), 0); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// LoadRunHistory returns the recorded runs of a task, oldest first
+// A task without history has no runs; malformed lines are skipped.
+func (fileRunHistory) LoadRunHistory(taskName string) ([]*RunRecord, error) {
+	f, err := os.Open(runHistoryPath(taskName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f, false); err != nil {
+		return nil, fmt.Errorf("failed to lock history file: %w", err)
+	}
+	defer unlockFile(f)
+
+	var records []*RunRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return records, nil
+}
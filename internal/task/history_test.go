@@ -0,0 +1,79 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendRunRecord(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+
+	if records, err := LoadRunHistory("web"); err != nil || len(records) != 0 {
+		t.Fatalf("LoadRunHistory() = %v, %v, want no runs", records, err)
+	}
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < maxRunHistory+5; i++ {
+		record := &RunRecord{
+			Task:      "web",
+			PID:       1000 + i,
+			StartTime: start.Add(time.Duration(i) * time.Second),
+			EndTime:   start.Add(time.Duration(i)*time.Second + 500*time.Millisecond),
+			ExitCode:  i % 2,
+			Reason:    StopReasonExited,
+		}
+		if err := AppendRunRecord(record); err != nil {
+			t.Fatalf("AppendRunRecord() failed: %v", err)
+		}
+	}
+
+	// Only the most recent runs are kept, oldest first
+	records, err := LoadRunHistory("web")
+	if err != nil {
+		t.Fatalf("LoadRunHistory() failed: %v", err)
+	}
+	if len(records) != maxRunHistory {
+		t.Fatalf("LoadRunHistory() returned %d runs, want %d", len(records), maxRunHistory)
+	}
+	if records[0].PID != 1005 || records[len(records)-1].PID != 1000+maxRunHistory+4 {
+		t.Errorf("kept runs %d to %d, want 1005 to %d", records[0].PID, records[len(records)-1].PID, 1000+maxRunHistory+4)
+	}
+	if d := records[0].Duration(); d != 500*time.Millisecond {
+		t.Errorf("Duration() = %v, want 500ms", d)
+	}
+
+	// Malformed lines are skipped
+	f, err := os.OpenFile(runHistoryPath("web"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open history file: %v", err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+	if records, _ := LoadRunHistory("web"); len(records) != maxRunHistory {
+		t.Errorf("LoadRunHistory() returned %d runs after a malformed line, want %d", len(records), maxRunHistory)
+	}
+}
+
+func TestRunHistoryOfStateBackend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TASKD_HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte(`state_backend = "bolt"`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	record := &RunRecord{Task: "web", PID: 100, ExitCode: 2, Reason: StopReasonCrash}
+	if err := AppendRunRecord(record); err != nil {
+		t.Fatalf("AppendRunRecord() failed: %v", err)
+	}
+
+	// The bolt backend keeps the history in its database
+	if _, err := os.Stat(runHistoryPath("web")); !os.IsNotExist(err) {
+		t.Errorf("history file of the JSON backend was written: %v", err)
+	}
+	records, err := LoadRunHistory("web")
+	if err != nil || len(records) != 1 || records[0].PID != 100 || records[0].Reason != StopReasonCrash {
+		t.Errorf("LoadRunHistory() = %+v, %v, want the appended run", records, err)
+	}
+}
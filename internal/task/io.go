@@ -38,6 +38,7 @@ type TaskIOInfo struct {
 	StdoutPath string `json:"stdout_path,omitempty"`
 	StderrPath string `json:"stderr_path,omitempty"`
 	SameOutput bool   `json:"same_output"` // whether stdout and stderr point to the same file
+	Capture    string `json:"capture,omitempty"` // capture format of the output files
 }
 
 // Close closes all file handles
@@ -120,13 +121,13 @@ func (m *DefaultIOManager) CreateTaskIO(config *Config) (*TaskIO, error) {
 			return nil, fmt.Errorf("stdout disk space check failed: %w", err)
 		}
 		
-		file, err := os.OpenFile(stdoutPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		writer, err := openOutputFile(stdoutPath, config.Log)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open stdout file: %w", wrapFileError(err, stdoutPath, "create"))
+			return nil, fmt.Errorf("failed to open stdout file: %w", err)
 		}
 		
-		stdoutWriter = file
-		taskIO.files = append(taskIO.files, file)
+		stdoutWriter = writer
+		taskIO.files = append(taskIO.files, writer)
 	}
 	
 	// Handle standard error
@@ -155,22 +156,73 @@ func (m *DefaultIOManager) CreateTaskIO(config *Config) (*TaskIO, error) {
 				return nil, fmt.Errorf("stderr disk space check failed: %w", err)
 			}
 			
-			file, err := os.OpenFile(stderrPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			writer, err := openOutputFile(stderrPath, config.Log)
 			if err != nil {
-				return nil, fmt.Errorf("failed to open stderr file: %w", wrapFileError(err, stderrPath, "create"))
+				return nil, fmt.Errorf("failed to open stderr file: %w", err)
 			}
 			
-			stderrWriter = file
-			taskIO.files = append(taskIO.files, file)
+			stderrWriter = writer
+			taskIO.files = append(taskIO.files, writer)
 		}
 	}
 	
+	// Capture mode tags every line, the capture writers are closed first so
+	// buffered partial lines reach the files
+	if config.Log.Capture != "" {
+		if err := ValidateCaptureFormat(config.Log.Capture); err != nil {
+			taskIO.Close()
+			return nil, err
+		}
+		stdoutWriter, stderrWriter = wrapCaptureWriters(taskIO, stdoutWriter, stderrWriter, config.Log)
+	}
+	
 	taskIO.Stdout = stdoutWriter
 	taskIO.Stderr = stderrWriter
 	
 	return taskIO, nil
 }
 
+// wrapCaptureWriters wraps the output writers in capture writers
+// When both streams share a file, their capture writers share a lock.
+func wrapCaptureWriters(taskIO *TaskIO, stdoutWriter, stderrWriter io.Writer, logConfig LogConfig) (io.Writer, io.Writer) {
+	var captured []io.Closer
+	var stdoutCapture, stderrCapture io.Writer
+	
+	if stdoutWriter != nil {
+		writer := NewCaptureWriter(stdoutWriter, LogStreamStdout, logConfig, nil)
+		stdoutCapture = writer
+		captured = append(captured, writer)
+	}
+	
+	if stderrWriter != nil {
+		var mu *sync.Mutex
+		if stderrWriter == stdoutWriter {
+			mu = stdoutCapture.(*CaptureWriter).mu
+		}
+		writer := NewCaptureWriter(stderrWriter, LogStreamStderr, logConfig, mu)
+		stderrCapture = writer
+		captured = append(captured, writer)
+	}
+	
+	taskIO.files = append(captured, taskIO.files...)
+	return stdoutCapture, stderrCapture
+}
+
+// openOutputFile opens an output file for appending
+// When log rotation is configured (log.max_size > 0) a RotatingWriter is returned instead
+// of the raw file, so the child writes to a pipe and the daemon owns the file.
+func openOutputFile(path string, logConfig LogConfig) (io.WriteCloser, error) {
+	if logConfig.MaxSize > 0 {
+		return NewRotatingWriter(path, logConfig)
+	}
+	
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, wrapFileError(err, path, "create")
+	}
+	return file, nil
+}
+
 // GetTaskIOInfo gets task IO information
 func (m *DefaultIOManager) GetTaskIOInfo(config *Config) (*TaskIOInfo, error) {
 	info := &TaskIOInfo{}
@@ -214,5 +266,9 @@ func (m *DefaultIOManager) GetTaskIOInfo(config *Config) (*TaskIOInfo, error) {
 		}
 	}
 	
+	if info.StdoutPath != "" || info.StderrPath != "" {
+		info.Capture = config.Log.Capture
+	}
+	
 	return info, nil
 }
\ No newline at end of file
@@ -0,0 +1,158 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// IPCProtocolVersion is the version of the request/response protocol spoken
+// between CLI clients and the daemon. It must be bumped whenever a change to
+// IPCRequest or IPCResponse is not backward compatible.
+const IPCProtocolVersion = 1
+
+// IPC commands understood by the daemon
+const (
+	IPCCommandPing     = "ping"
+	IPCCommandStart    = "start"
+	IPCCommandStop     = "stop"
+	IPCCommandRestart  = "restart"
+	IPCCommandList     = "list"
+	IPCCommandInfo     = "info"
+	IPCCommandReload   = "reload"
+	IPCCommandShutdown = "shutdown"
+)
+
+// ErrDaemonUnavailable is returned by DaemonClient when no daemon is listening
+var ErrDaemonUnavailable = errors.New("daemon is not available")
+
+// IPCRequest request sent from a CLI client to the daemon
+type IPCRequest struct {
+	Version int    `json:"version"`
+	Command string `json:"command"`
+	Task    string `json:"task,omitempty"`
+
+	// Stop options, only used by the stop command (zero values use the task configuration)
+	StopTimeout time.Duration `json:"stop_timeout,omitempty"`
+	Force       bool          `json:"force,omitempty"`
+	Cascade     bool          `json:"cascade,omitempty"`
+
+	// Start options, only used by the start command
+	Wait bool `json:"wait,omitempty"`
+}
+
+// IPCResponse response sent from the daemon to a CLI client
+type IPCResponse struct {
+	Version int             `json:"version"`
+	OK      bool            `json:"ok"`
+	Error   string          `json:"error,omitempty"`
+	Tasks   []*TaskInfo     `json:"tasks,omitempty"`
+	Detail  *TaskDetailInfo `json:"detail,omitempty"`
+}
+
+// listenIPC listens on the daemon socket, removing a stale socket file left
+// behind by a daemon that did not shut down cleanly.
+// Unix domain sockets are also supported on Windows 10 (1803) and later.
+func listenIPC(socketPath string) (net.Listener, error) {
+	if _, err := os.Stat(socketPath); err == nil {
+		// Someone is still answering on this socket, refuse to take it over
+		if conn, err := net.DialTimeout("unix", socketPath, 500*time.Millisecond); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is already listening on %s", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", socketPath, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+
+	// Only the owning user may talk to the daemon
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	return listener, nil
+}
+
+// ipcRequestDeadline returns how long a request may take
+// Commands that stop a task may wait for its stop timeout before answering,
+// a cascading stop for the stop timeouts of the tasks requiring it as well.
+func ipcRequestDeadline(req *IPCRequest) time.Duration {
+	if req.Command == IPCCommandStart {
+		return startRequestDeadline(req)
+	}
+	if (req.Command != IPCCommandStop && req.Command != IPCCommandRestart) || req.Force {
+		return ipcRequestTimeout
+	}
+
+	stopTimeout := func(config *Config) time.Duration {
+		if req.StopTimeout > 0 {
+			return req.StopTimeout
+		}
+		if config == nil {
+			return defaultStopTimeout
+		}
+		return config.StopTimeoutDuration()
+	}
+
+	config, _ := loadTaskConfigFile(req.Task)
+	deadline := ipcRequestTimeout + stopTimeout(config) + stopKillWaitTimeout
+
+	if req.Command == IPCCommandStop && req.Cascade {
+		if configs, err := loadTaskConfigs(); err == nil {
+			for _, dependent := range dependentsOf(req.Task, configs) {
+				deadline += stopTimeout(configs[dependent]) + stopKillWaitTimeout
+			}
+		}
+	}
+	return deadline
+}
+
+// startRequestDeadline returns how long a start request may take
+// Starting a task waits for the readiness probes of its dependencies, and of the
+// task itself with --wait; a probe that fails stops its task.
+func startRequestDeadline(req *IPCRequest) time.Duration {
+	deadline := ipcRequestTimeout
+	configs, err := loadTaskConfigs()
+	if err != nil {
+		return deadline
+	}
+	
+	names := dependencyClosure(req.Task, configs)
+	if req.Wait {
+		names = append(names, req.Task)
+	}
+	for _, name := range names {
+		if config := configs[name]; config != nil && config.Readiness.Kind() != "" {
+			deadline += config.Readiness.TimeoutDuration() + config.StopTimeoutDuration() + stopKillWaitTimeout
+		}
+	}
+	return deadline
+}
+
+// dialIPC connects to the daemon socket
+func dialIPC(socketPath string, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", socketPath, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDaemonUnavailable, err)
+	}
+	return conn, nil
+}
+
+// writeIPCMessage writes a single JSON message to the connection
+func writeIPCMessage(conn net.Conn, msg interface{}) error {
+	return json.NewEncoder(conn).Encode(msg)
+}
+
+// readIPCMessage reads a single JSON message from the connection
+func readIPCMessage(conn net.Conn, msg interface{}) error {
+	return json.NewDecoder(conn).Decode(msg)
+}
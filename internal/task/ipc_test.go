@@ -0,0 +1,207 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	taskdconfig "taskd/internal/config"
+)
+
+// newTestDaemon starts a daemon server with an isolated TASKD_HOME and returns a client for it
+func newTestDaemon(t *testing.T) (*Manager, *DaemonClient) {
+	t.Helper()
+	t.Setenv("TASKD_HOME", t.TempDir())
+
+	manager := &Manager{
+		tasks:          make(map[string]*Task),
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+	manager.SetDaemonMode(true)
+
+	socketPath := filepath.Join(taskdconfig.GetTaskDHome(), "taskd.sock")
+	server := newDaemonServerWithPath(manager, socketPath)
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start daemon server: %v", err)
+	}
+	t.Cleanup(func() {
+		manager.StopAllTasks()
+		server.Stop()
+	})
+
+	return manager, newDaemonClientWithPath(socketPath)
+}
+
+// writeTestTaskConfig writes a task configuration file into the current TASKD_HOME
+func writeTestTaskConfig(t *testing.T, name string, config *Config) {
+	t.Helper()
+	tasksDir := taskdconfig.GetTaskDTasksDir()
+	if err := os.MkdirAll(tasksDir, 0755); err != nil {
+		t.Fatalf("failed to create tasks dir: %v", err)
+	}
+	file, err := os.Create(filepath.Join(tasksDir, name+".toml"))
+	if err != nil {
+		t.Fatalf("failed to create task config: %v", err)
+	}
+	defer file.Close()
+	if err := toml.NewEncoder(file).Encode(config); err != nil {
+		t.Fatalf("failed to write task config: %v", err)
+	}
+}
+
+// exitCommand returns a command that exits immediately with the given code
+func exitCommand(code string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/c", "exit", code}
+	}
+	return "sh", []string{"-c", "exit " + code}
+}
+
+func TestDaemonClientPing(t *testing.T) {
+	_, client := newTestDaemon(t)
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping() = %v, want nil", err)
+	}
+}
+
+func TestDaemonClientUnavailable(t *testing.T) {
+	client := newDaemonClientWithPath(filepath.Join(t.TempDir(), "missing.sock"))
+
+	err := client.Ping()
+	if !errors.Is(err, ErrDaemonUnavailable) {
+		t.Fatalf("Ping() = %v, want ErrDaemonUnavailable", err)
+	}
+}
+
+func TestDaemonServerRejectsProtocolMismatch(t *testing.T) {
+	_, client := newTestDaemon(t)
+
+	conn, err := dialIPC(client.socketPath, time.Second)
+	if err != nil {
+		t.Fatalf("dialIPC() = %v", err)
+	}
+	defer conn.Close()
+
+	if err := writeIPCMessage(conn, &IPCRequest{Version: IPCProtocolVersion + 1, Command: IPCCommandPing}); err != nil {
+		t.Fatalf("writeIPCMessage() = %v", err)
+	}
+
+	var resp IPCResponse
+	if err := readIPCMessage(conn, &resp); err != nil {
+		t.Fatalf("readIPCMessage() = %v", err)
+	}
+
+	if resp.OK {
+		t.Fatal("expected request with a newer protocol version to be rejected")
+	}
+	if !strings.Contains(resp.Error, "protocol version") {
+		t.Errorf("unexpected error message: %q", resp.Error)
+	}
+	if resp.Version != IPCProtocolVersion {
+		t.Errorf("response version = %d, want %d", resp.Version, IPCProtocolVersion)
+	}
+}
+
+func TestDaemonServerErrors(t *testing.T) {
+	_, client := newTestDaemon(t)
+
+	tests := []struct {
+		name string
+		req  *IPCRequest
+	}{
+		{"unknown command", &IPCRequest{Command: "bogus"}},
+		{"stop unknown task", &IPCRequest{Command: IPCCommandStop, Task: "missing"}},
+		{"start unknown task", &IPCRequest{Command: IPCCommandStart, Task: "missing"}},
+		{"info unknown task", &IPCRequest{Command: IPCCommandInfo, Task: "missing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.call(tt.req); err == nil {
+				t.Errorf("call(%s %q) = nil, want error", tt.req.Command, tt.req.Task)
+			}
+		})
+	}
+}
+
+func TestDaemonServerReportsExitCode(t *testing.T) {
+	manager, client := newTestDaemon(t)
+
+	executable, args := exitCommand("3")
+	writeTestTaskConfig(t, "exit-three", &Config{Executable: executable, Args: args})
+
+	exited := make(chan string, 1)
+	manager.SetTaskExitHandler(func(taskName string) {
+		exited <- taskName
+	})
+
+	if err := client.StartTask("exit-three"); err != nil {
+		t.Fatalf("StartTask() = %v", err)
+	}
+
+	select {
+	case name := <-exited:
+		if name != "exit-three" {
+			t.Fatalf("exit handler called for %q, want exit-three", name)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for task exit")
+	}
+
+	info, err := client.GetTaskDetailInfo("exit-three")
+	if err != nil {
+		t.Fatalf("GetTaskDetailInfo() = %v", err)
+	}
+	if info.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", info.ExitCode)
+	}
+	if info.Status == "running" {
+		t.Errorf("Status = %q, want task to be reported as exited", info.Status)
+	}
+}
+
+func TestDaemonServerListAndStop(t *testing.T) {
+	_, client := newTestDaemon(t)
+
+	executable, args := longRunningCommand()
+	writeTestTaskConfig(t, "long-running", &Config{Executable: executable, Args: args})
+
+	if err := client.StartTask("long-running"); err != nil {
+		t.Fatalf("StartTask() = %v", err)
+	}
+
+	tasks, err := client.ListTasks()
+	if err != nil {
+		t.Fatalf("ListTasks() = %v", err)
+	}
+	var found *TaskInfo
+	for _, info := range tasks {
+		if info.Name == "long-running" {
+			found = info
+		}
+	}
+	if found == nil {
+		t.Fatal("ListTasks() did not include long-running")
+	}
+	if found.Status != "running" || found.PID == 0 {
+		t.Errorf("long-running status = %q pid = %d, want running with a PID", found.Status, found.PID)
+	}
+
+	if err := client.StopTask("long-running"); err != nil {
+		t.Fatalf("StopTask() = %v", err)
+	}
+
+	info, err := client.GetTaskDetailInfo("long-running")
+	if err != nil {
+		t.Fatalf("GetTaskDetailInfo() = %v", err)
+	}
+	if info.Status == "running" {
+		t.Errorf("Status after stop = %q, want stopped", info.Status)
+	}
+}
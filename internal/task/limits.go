@@ -0,0 +1,128 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ResourceLimits resources a task may use ([limits] block), zero values are unlimited
+// Limits are applied to the task process before it runs and inherited by its
+// descendants. On Linux memory, cpu, cpu_weight and processes are enforced by the
+// task's cgroup when the daemon's cgroup delegates the controllers, open_files
+// and nice are set with setrlimit and setpriority. On Windows they are limits of
+// the task's Job Object (see limits_linux.go and limits_windows.go).
+type ResourceLimits struct {
+	Memory    string  `toml:"memory,omitempty"`    // Maximum memory, e.g. "512M" or "2G"
+	CPU       float64 `toml:"cpu,omitzero"`        // CPU quota in cores, e.g. 0.5 or 2
+	CPUWeight int     `toml:"cpu_weight,omitzero"` // Share of the CPU relative to other tasks, 1-10000 (default 100)
+	OpenFiles int     `toml:"open_files,omitzero"` // Maximum open files per process
+	Processes int     `toml:"processes,omitzero"`  // Maximum processes (and threads on Linux)
+	Nice      int     `toml:"nice,omitzero"`       // Scheduling priority, -20 (highest) to 19 (lowest)
+}
+
+// Nice values accepted by the [limits] block
+const (
+	minNice = -20
+	maxNice = 19
+)
+
+// maxCPUWeight is the highest cpu_weight, as for cgroup v2 cpu.weight
+const maxCPUWeight = 10000
+
+// memoryUnits are the suffixes of memory sizes, in powers of 1024
+var memoryUnits = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseMemorySize parses a memory size like "512M", "1.5G", "512MiB" or a number of bytes
+func ParseMemorySize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	i := strings.IndexFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := memoryUnits[s[i:]]
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if !ok || err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid memory size '%s': expected a number of bytes or a size like 512M or 2G", size)
+	}
+	bytes := value * float64(unit)
+	if bytes < 1 || bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid memory size '%s': out of range", size)
+	}
+	return int64(bytes), nil
+}
+
+// ValidateResourceLimits checks the values of a [limits] block
+func ValidateResourceLimits(l ResourceLimits) error {
+	if l.Memory != "" {
+		if _, err := ParseMemorySize(l.Memory); err != nil {
+			return err
+		}
+	}
+	if l.CPU < 0 {
+		return fmt.Errorf("cpu limit cannot be negative: %g", l.CPU)
+	}
+	if l.CPUWeight < 0 || l.CPUWeight > maxCPUWeight {
+		return fmt.Errorf("cpu weight must be between 1 and %d: %d", maxCPUWeight, l.CPUWeight)
+	}
+	if l.OpenFiles < 0 {
+		return fmt.Errorf("open files limit cannot be negative: %d", l.OpenFiles)
+	}
+	if l.Processes < 0 {
+		return fmt.Errorf("processes limit cannot be negative: %d", l.Processes)
+	}
+	if l.Nice < minNice || l.Nice > maxNice {
+		return fmt.Errorf("nice must be between %d and %d: %d", minNice, maxNice, l.Nice)
+	}
+	return nil
+}
+
+// MemoryBytes returns the memory limit in bytes, 0 without one
+func (l ResourceLimits) MemoryBytes() int64 {
+	if l.Memory == "" {
+		return 0
+	}
+	bytes, err := ParseMemorySize(l.Memory)
+	if err != nil {
+		return 0
+	}
+	return bytes
+}
+
+// IsZero reports whether no limit is set
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
+// String returns the limits that are set, e.g. "memory=512M cpu=0.5 nice=10"
+func (l ResourceLimits) String() string {
+	var parts []string
+	if l.Memory != "" {
+		parts = append(parts, "memory="+l.Memory)
+	}
+	if l.CPU > 0 {
+		parts = append(parts, "cpu="+strconv.FormatFloat(l.CPU, 'g', -1, 64))
+	}
+	if l.CPUWeight > 0 {
+		parts = append(parts, "cpu_weight="+strconv.Itoa(l.CPUWeight))
+	}
+	if l.OpenFiles > 0 {
+		parts = append(parts, "open_files="+strconv.Itoa(l.OpenFiles))
+	}
+	if l.Processes > 0 {
+		parts = append(parts, "processes="+strconv.Itoa(l.Processes))
+	}
+	if l.Nice != 0 {
+		parts = append(parts, "nice="+strconv.Itoa(l.Nice))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,160 @@
+//go:build linux
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// cgroupCPUPeriod is the period of cpu.max, in microseconds
+const cgroupCPUPeriod = 100000
+
+// daemonCgroupLeaf is the cgroup the daemon moves into when its own cgroup has to
+// delegate controllers: a cgroup v2 with processes cannot enable them for its children
+const daemonCgroupLeaf = "taskd.daemon"
+
+// taskCgroupBase is the cgroup the task cgroups are created in, the cgroup of
+// the daemon when it first started a task
+var taskCgroupBase = sync.OnceValue(currentCgroupDir)
+
+// cgroupControllers returns the cgroup v2 controllers that enforce the limits
+func cgroupControllers(l ResourceLimits) []string {
+	var controllers []string
+	if l.Memory != "" {
+		controllers = append(controllers, "memory")
+	}
+	if l.CPU > 0 || l.CPUWeight > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if l.Processes > 0 {
+		controllers = append(controllers, "pids")
+	}
+	return controllers
+}
+
+// enableCgroupControllers enables the controllers the limits need for the cgroups below base
+// When base has processes, the daemon moves into a leaf cgroup first. Controllers
+// that cannot be enabled are left out; setCgroupLimits then reports their limits
+// as not set.
+func enableCgroupControllers(base string, l ResourceLimits) {
+	for _, controller := range cgroupControllers(l) {
+		enabled, _ := os.ReadFile(filepath.Join(base, "cgroup.subtree_control"))
+		if strings.Contains(" "+strings.TrimSpace(string(enabled))+" ", " "+controller+" ") {
+			continue
+		}
+		err := writeCgroupFile(base, "cgroup.subtree_control", "+"+controller)
+		if errors.Is(err, syscall.EBUSY) && moveToLeafCgroup(base) == nil {
+			writeCgroupFile(base, "cgroup.subtree_control", "+"+controller)
+		}
+	}
+}
+
+// moveToLeafCgroup moves the current process into a cgroup of its own below base
+func moveToLeafCgroup(base string) error {
+	dir := filepath.Join(base, daemonCgroupLeaf)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(os.Getpid()))
+}
+
+// setCgroupLimits writes the limits a cgroup enforces to its control files
+// It returns the limits that were not set, because their controller is not enabled.
+func setCgroupLimits(dir string, l ResourceLimits) ResourceLimits {
+	remaining := l
+	if l.Memory != "" && writeCgroupFile(dir, "memory.max", strconv.FormatInt(l.MemoryBytes(), 10)) == nil {
+		remaining.Memory = ""
+	}
+	if l.CPU > 0 {
+		quota := int64(l.CPU * cgroupCPUPeriod)
+		if writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)) == nil {
+			remaining.CPU = 0
+		}
+	}
+	if l.CPUWeight > 0 && writeCgroupFile(dir, "cpu.weight", strconv.Itoa(l.CPUWeight)) == nil {
+		remaining.CPUWeight = 0
+	}
+	if l.Processes > 0 && writeCgroupFile(dir, "pids.max", strconv.Itoa(l.Processes)) == nil {
+		remaining.Processes = 0
+	}
+	return remaining
+}
+
+// writeCgroupFile writes a value to an existing control file of a cgroup
+func writeCgroupFile(dir, name, value string) error {
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// cgroupOOMKills returns how many processes of a cgroup the out-of-memory killer killed
+func cgroupOOMKills(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			kills, _ := strconv.Atoi(strings.TrimSpace(value))
+			return kills
+		}
+	}
+	return 0
+}
+
+// applyExecLimits applies the open files and nice limits to a process stopped at its exec
+// It returns the limits that could not be applied.
+func applyExecLimits(pid int, l ResourceLimits) error {
+	var errs []error
+	if l.OpenFiles > 0 {
+		if err := setProcessRlimit(pid, syscall.RLIMIT_NOFILE, uint64(l.OpenFiles)); err != nil {
+			errs = append(errs, fmt.Errorf("open files limit: %w", err))
+		}
+	}
+	if l.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Nice); err != nil {
+			errs = append(errs, fmt.Errorf("nice: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitForExecStop waits until a process started with PTRACE_TRACEME stops at its exec
+func waitForExecStop(pid int) error {
+	var status syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &status, syscall.WALL, nil); err != nil {
+		return fmt.Errorf("failed to wait for process %d to start: %w", pid, err)
+	}
+	if !status.Stopped() {
+		return fmt.Errorf("process %d exited before it started", pid)
+	}
+	return nil
+}
+
+// setProcessRlimit sets the soft and hard limit of a resource of another process (prlimit)
+func setProcessRlimit(pid, resource int, value uint64) error {
+	limit := syscall.Rlimit{Cur: value, Max: value}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+		uintptr(unsafe.Pointer(&limit)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+//go:build linux
+
+package task
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestSetCgroupLimits(t *testing.T) {
+	dir := t.TempDir()
+	// The pids controller is not enabled: pids.max does not exist
+	for _, name := range []string{"memory.max", "cpu.max", "cpu.weight"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("max\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	limits := ResourceLimits{Memory: "256M", CPU: 1.5, CPUWeight: 200, Processes: 32, OpenFiles: 100}
+	remaining := setCgroupLimits(dir, limits)
+	if want := (ResourceLimits{Processes: 32, OpenFiles: 100}); remaining != want {
+		t.Errorf("setCgroupLimits() = %+v, want %+v", remaining, want)
+	}
+
+	for name, want := range map[string]string{
+		"memory.max": strconv.Itoa(256 << 20),
+		"cpu.max":    "150000 100000",
+		"cpu.weight": "200",
+	} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pids.max")); !os.IsNotExist(err) {
+		t.Errorf("pids.max was created")
+	}
+}
+
+func TestProcessGroupLimitExceeded(t *testing.T) {
+	dir := t.TempDir()
+	events := filepath.Join(dir, "memory.events")
+	if err := os.WriteFile(events, []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &processGroup{cgroup: dir, limits: ResourceLimits{Memory: "64M"}}
+	group.oomKills = cgroupOOMKills(dir)
+	if got := group.limitExceeded(); got != "" {
+		t.Errorf("limitExceeded() before an OOM kill = %q, want empty", got)
+	}
+
+	if err := os.WriteFile(events, []byte("low 0\nhigh 0\nmax 9\noom 2\noom_kill 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := group.limitExceeded(); !strings.Contains(got, "memory limit of 64M exceeded") {
+		t.Errorf("limitExceeded() after an OOM kill = %q", got)
+	}
+}
+
+func TestStartAppliesProcessLimits(t *testing.T) {
+	// The child is started as soon as the shell runs, it must inherit the limits
+	task := startTestTask(t, &Config{
+		Executable: "sh",
+		Args:       []string{"-c", "sleep 30 & echo ready; wait"},
+		Limits:     ResourceLimits{OpenFiles: 64, Nice: 5},
+	})
+	pids := append([]int{task.GetInfo().PID}, waitForDescendants(t, task, 1)...)
+
+	for _, pid := range pids {
+		data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "limits"))
+		if err != nil {
+			t.Fatalf("failed to read process limits: %v", err)
+		}
+		if limits := string(data); !strings.Contains(limits, "Max open files            64                   64") {
+			t.Errorf("open files limit not applied to process %d:\n%s", pid, limits)
+		}
+
+		// The kernel returns 20 - nice
+		if prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid); err != nil || 20-prio != 5 {
+			t.Errorf("nice of process %d = %d, %v, want 5", pid, 20-prio, err)
+		}
+	}
+}
+
+func TestStartRejectsMemoryLimitWithoutCgroup(t *testing.T) {
+	limits := ResourceLimits{Memory: "1G"}
+	group := &processGroup{limits: limits, processLimits: limits, seen: make(map[int]bool)}
+	cmd := exec.Command("sh", "-c", "exit 0")
+	setDetachedProcessAttr(cmd)
+
+	err := group.start(cmd)
+	if err == nil || !strings.Contains(err.Error(), "memory controller") {
+		t.Fatalf("start() = %v, want the memory limit rejected", err)
+	}
+	if cmd.Process != nil {
+		t.Error("the process was started")
+	}
+}
+
+func TestStartReportsCgroupOnlyLimits(t *testing.T) {
+	limits := ResourceLimits{CPU: 0.5, Processes: 10}
+	group := &processGroup{limits: limits, processLimits: limits, seen: make(map[int]bool)}
+	cmd := exec.Command("sh", "-c", "exit 0")
+	setDetachedProcessAttr(cmd)
+
+	if err := group.start(cmd); err != nil {
+		t.Fatalf("start() = %v", err)
+	}
+	cmd.Wait()
+	if group.limitsErr == nil || !strings.Contains(group.limitsErr.Error(), "cpu=0.5 processes=10") {
+		t.Errorf("limitsErr = %v, want the cpu and processes limits reported", group.limitsErr)
+	}
+}
+
+func TestReleaseAtLeast(t *testing.T) {
+	tests := []struct {
+		release string
+		want    bool
+	}{
+		{"5.7.0", true},
+		{"5.15.0-91-generic", true},
+		{"6.1.0", true},
+		{"5.6.19", false},
+		{"4.19.0-25-amd64", false},
+		{"5.10+", true},
+		{"invalid", false},
+	}
+
+	for _, tt := range tests {
+		if got := releaseAtLeast(tt.release, 5, 7); got != tt.want {
+			t.Errorf("releaseAtLeast(%q, 5, 7) = %v, want %v", tt.release, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package task
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMemorySize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1048576, false},
+		{"512K", 512 << 10, false},
+		{"512M", 512 << 20, false},
+		{"512mb", 512 << 20, false},
+		{"512MiB", 512 << 20, false},
+		{"2G", 2 << 30, false},
+		{"1.5G", 3 << 29, false},
+		{" 1T ", 1 << 40, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"12X", 0, true},
+		{"-1G", 0, true},
+		{"0", 0, true},
+		{"1e30G", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMemorySize(tt.size)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMemorySize(%q) = %d, %v, want %d (error %v)", tt.size, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateResourceLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  ResourceLimits
+		wantErr string
+	}{
+		{"none", ResourceLimits{}, ""},
+		{"all", ResourceLimits{Memory: "1G", CPU: 1.5, CPUWeight: 200, OpenFiles: 1024, Processes: 64, Nice: -5}, ""},
+		{"invalid memory", ResourceLimits{Memory: "lots"}, "invalid memory size"},
+		{"negative cpu", ResourceLimits{CPU: -1}, "cpu limit"},
+		{"cpu weight too high", ResourceLimits{CPUWeight: 10001}, "cpu weight"},
+		{"negative open files", ResourceLimits{OpenFiles: -1}, "open files"},
+		{"negative processes", ResourceLimits{Processes: -1}, "processes"},
+		{"nice too low", ResourceLimits{Nice: -21}, "nice"},
+		{"nice too high", ResourceLimits{Nice: 20}, "nice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateResourceLimits(tt.limits)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidateResourceLimits() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateResourceLimits() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResourceLimitsString(t *testing.T) {
+	limits := ResourceLimits{Memory: "512M", CPU: 0.5, CPUWeight: 50, OpenFiles: 256, Processes: 32, Nice: 10}
+	want := "memory=512M cpu=0.5 cpu_weight=50 open_files=256 processes=32 nice=10"
+	if got := limits.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (ResourceLimits{}).String(); got != "" {
+		t.Errorf("String() without limits = %q, want empty", got)
+	}
+}
+
+func TestFinishRunRecordsOOMKill(t *testing.T) {
+	task := NewTask("oom", &Config{})
+	task.exitCode = -1
+	task.oomKilled = true
+	task.finishRun(42)
+
+	runs := task.takeFinishedRuns()
+	if len(runs) != 1 || runs[0].Reason != StopReasonOOMKill || !runs[0].Failed() {
+		t.Errorf("finished runs = %+v, want one failed %s run", runs, StopReasonOOMKill)
+	}
+}
@@ -0,0 +1,176 @@
+//go:build windows
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var procSetInformationJobObject = kernel32.NewProc("SetInformationJobObject")
+
+const (
+	jobObjectExtendedLimitInformation  = 9  // JobObjectExtendedLimitInformation information class
+	jobObjectCPURateControlInformation = 15 // JobObjectCpuRateControlInformation information class
+
+	jobObjectLimitActiveProcess = 0x0008 // JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+	jobObjectLimitPriorityClass = 0x0020 // JOB_OBJECT_LIMIT_PRIORITY_CLASS
+	jobObjectLimitJobMemory     = 0x0200 // JOB_OBJECT_LIMIT_JOB_MEMORY
+	jobObjectLimitKillOnClose   = 0x2000 // JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+
+	jobObjectCPURateControlEnable      = 0x1 // JOB_OBJECT_CPU_RATE_CONTROL_ENABLE
+	jobObjectCPURateControlWeightBased = 0x2 // JOB_OBJECT_CPU_RATE_CONTROL_WEIGHT_BASED
+	jobObjectCPURateControlHardCap     = 0x4 // JOB_OBJECT_CPU_RATE_CONTROL_HARD_CAP
+
+	highPriorityClass        = 0x0080
+	aboveNormalPriorityClass = 0x8000
+	belowNormalPriorityClass = 0x4000
+	idlePriorityClass        = 0x0040
+)
+
+// jobObjectBasicLimitInformation is JOBOBJECT_BASIC_LIMIT_INFORMATION
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+// ioCounters is IO_COUNTERS
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+// jobObjectExtendedLimitInfo is JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+type jobObjectExtendedLimitInfo struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// jobObjectCPURateControlInfo is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION, Value is
+// the CpuRate or the Weight of the union
+type jobObjectCPURateControlInfo struct {
+	ControlFlags uint32
+	Value        uint32
+}
+
+// setJobLimits sets the limits of a task on its Job Object
+// The job also kills its processes when its last handle is closed, so no process
+// of a task outlives the daemon that supervises it. It returns the limits that
+// could not be set.
+func setJobLimits(job syscall.Handle, l ResourceLimits) error {
+	var errs []error
+
+	var info jobObjectExtendedLimitInfo
+	info.BasicLimitInformation.LimitFlags = jobObjectLimitKillOnClose
+	if bytes := l.MemoryBytes(); bytes > 0 {
+		info.BasicLimitInformation.LimitFlags |= jobObjectLimitJobMemory
+		info.JobMemoryLimit = uintptr(bytes)
+		if uint64(bytes) > uint64(^uintptr(0)) {
+			info.JobMemoryLimit = ^uintptr(0)
+		}
+	}
+	if l.Processes > 0 {
+		info.BasicLimitInformation.LimitFlags |= jobObjectLimitActiveProcess
+		info.BasicLimitInformation.ActiveProcessLimit = uint32(l.Processes)
+	}
+	if l.Nice != 0 {
+		info.BasicLimitInformation.LimitFlags |= jobObjectLimitPriorityClass
+		info.BasicLimitInformation.PriorityClass = windowsPriorityClass(l.Nice)
+	}
+	r, _, err := procSetInformationJobObject.Call(uintptr(job), jobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	if basic := (ResourceLimits{Memory: l.Memory, Processes: l.Processes, Nice: l.Nice}); r == 0 && !basic.IsZero() {
+		errs = append(errs, fmt.Errorf("%s: %w", basic, err))
+	}
+
+	// A job has either a hard cap or a weight
+	if l.CPU > 0 || l.CPUWeight > 0 {
+		rate := jobObjectCPURateControlInfo{
+			ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlWeightBased,
+			Value:        jobCPUWeight(l.CPUWeight),
+		}
+		if l.CPU > 0 {
+			rate = jobObjectCPURateControlInfo{
+				ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+				Value:        jobCPURate(l.CPU, runtime.NumCPU()),
+			}
+			if l.CPUWeight > 0 {
+				errs = append(errs, fmt.Errorf("cpu_weight=%d: a job with a cpu quota has no weight", l.CPUWeight))
+			}
+		}
+		r, _, err := procSetInformationJobObject.Call(uintptr(job), jobObjectCPURateControlInformation,
+			uintptr(unsafe.Pointer(&rate)), unsafe.Sizeof(rate))
+		if r == 0 {
+			errs = append(errs, fmt.Errorf("%s: %w", ResourceLimits{CPU: l.CPU, CPUWeight: l.CPUWeight}, err))
+		}
+	}
+
+	if l.OpenFiles > 0 {
+		errs = append(errs, fmt.Errorf("open_files=%d: not supported on Windows", l.OpenFiles))
+	}
+	return errors.Join(errs...)
+}
+
+// jobMemoryLimitReached reports whether the processes of a job used nearly all the
+// memory of its limit. A job's allocations fail at the limit instead of the
+// process being killed, so a failed allocation may leave the peak a little below it.
+func jobMemoryLimitReached(job syscall.Handle) bool {
+	var info jobObjectExtendedLimitInfo
+	r, _, _ := procQueryInformationJobObject.Call(uintptr(job), jobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info), 0)
+	if r == 0 || info.JobMemoryLimit == 0 {
+		return false
+	}
+	return info.PeakJobMemoryUsed >= info.JobMemoryLimit/10*9
+}
+
+// windowsPriorityClass returns the priority class closest to a nice value
+func windowsPriorityClass(nice int) uint32 {
+	switch {
+	case nice <= -11:
+		return highPriorityClass
+	case nice < 0:
+		return aboveNormalPriorityClass
+	case nice <= 10:
+		return belowNormalPriorityClass
+	default:
+		return idlePriorityClass
+	}
+}
+
+// jobCPURate converts a quota in cores to a CpuRate, the share of all processors in 1/100 percent
+func jobCPURate(cores float64, numCPU int) uint32 {
+	rate := math.Round(cores / float64(numCPU) * 10000)
+	return uint32(math.Max(1, math.Min(rate, 10000)))
+}
+
+// jobCPUWeight converts a cpu_weight (1-10000, default 100) to a job weight (1-9, default 5)
+func jobCPUWeight(weight int) uint32 {
+	jobWeight := weight * 5 / 100
+	if jobWeight < 1 {
+		return 1
+	}
+	if jobWeight > 9 {
+		return 9
+	}
+	return uint32(jobWeight)
+}
@@ -0,0 +1,280 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Liveness probe kinds, a probe checks exactly one of them
+const (
+	LivenessTCP     = "tcp"     // A TCP address accepts connections
+	LivenessHTTP    = "http"    // An HTTP endpoint answers with a 2xx status
+	LivenessCommand = "command" // A command exits with code 0
+)
+
+// Liveness probe defaults, used when interval, timeout or failure_threshold are not set
+const (
+	defaultLivenessInterval         = 10 * time.Second
+	defaultLivenessTimeout          = 5 * time.Second
+	defaultLivenessFailureThreshold = 3
+)
+
+// Health of a running task with a liveness probe
+const (
+	HealthUnknown   = "unknown"   // No check has completed yet
+	HealthHealthy   = "healthy"   // The last check passed
+	HealthUnhealthy = "unhealthy" // The last check failed
+)
+
+// LivenessProbe checks periodically that a ready task still works ([liveness] block)
+// After failure_threshold consecutive failures the task is stopped, and the
+// restart policy decides whether it is started again.
+type LivenessProbe struct {
+	TCP              string `toml:"tcp,omitempty"`               // host:port, or a port on 127.0.0.1
+	HTTP             string `toml:"http,omitempty"`              // http:// or https:// URL
+	Command          string `toml:"command,omitempty"`           // Run in the task's working directory and environment
+	Interval         string `toml:"interval,omitempty"`          // Time between checks, default 10s
+	Timeout          string `toml:"timeout,omitempty"`           // How long a single check may take, default 5s
+	FailureThreshold int    `toml:"failure_threshold,omitempty"` // Consecutive failures before the task is stopped, default 3
+}
+
+// Kind returns the kind of check of the probe, empty if the task has no probe
+func (p LivenessProbe) Kind() string {
+	switch {
+	case p.TCP != "":
+		return LivenessTCP
+	case p.HTTP != "":
+		return LivenessHTTP
+	case p.Command != "":
+		return LivenessCommand
+	}
+	return ""
+}
+
+// Target returns what the probe checks: the address, URL or command
+func (p LivenessProbe) Target() string {
+	switch p.Kind() {
+	case LivenessTCP:
+		return tcpProbeAddress(p.TCP)
+	case LivenessHTTP:
+		return p.HTTP
+	case LivenessCommand:
+		return p.Command
+	}
+	return ""
+}
+
+// IntervalDuration returns the time between two checks
+func (p LivenessProbe) IntervalDuration() time.Duration {
+	return parseDurationOrDefault(p.Interval, defaultLivenessInterval)
+}
+
+// TimeoutDuration returns how long a single check may take
+func (p LivenessProbe) TimeoutDuration() time.Duration {
+	return parseDurationOrDefault(p.Timeout, defaultLivenessTimeout)
+}
+
+// Threshold returns the number of consecutive failures after which the task is stopped
+func (p LivenessProbe) Threshold() int {
+	if p.FailureThreshold <= 0 {
+		return defaultLivenessFailureThreshold
+	}
+	return p.FailureThreshold
+}
+
+// ValidateLivenessProbe checks that a probe has at most one check and valid settings
+func ValidateLivenessProbe(p LivenessProbe) error {
+	kinds := 0
+	for _, value := range []string{p.TCP, p.HTTP, p.Command} {
+		if value != "" {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("a liveness probe checks one of tcp, http or command")
+	}
+	if kinds == 0 && (p.Interval != "" || p.Timeout != "" || p.FailureThreshold != 0) {
+		return fmt.Errorf("liveness interval, timeout and failure threshold need a tcp, http or command check")
+	}
+
+	switch p.Kind() {
+	case LivenessTCP:
+		if _, port, err := net.SplitHostPort(tcpProbeAddress(p.TCP)); err != nil || port == "" {
+			return fmt.Errorf("invalid liveness tcp address '%s': expected host:port or a port", p.TCP)
+		}
+	case LivenessHTTP:
+		u, err := url.Parse(p.HTTP)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid liveness http URL '%s': expected an http:// or https:// URL", p.HTTP)
+		}
+	case LivenessCommand:
+		if parts, err := SplitCommandLine(p.Command); err != nil {
+			return fmt.Errorf("invalid liveness command '%s': %w", p.Command, err)
+		} else if len(parts) == 0 {
+			return fmt.Errorf("liveness command cannot be empty")
+		}
+	}
+
+	for name, value := range map[string]string{"interval": p.Interval, "timeout": p.Timeout} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid liveness %s '%s': expected a positive duration", name, value)
+		}
+	}
+	if p.FailureThreshold < 0 {
+		return fmt.Errorf("invalid liveness failure threshold %d: cannot be negative", p.FailureThreshold)
+	}
+	return nil
+}
+
+// newLivenessCheck returns the check of a probe for a task running in workDir with env
+func newLivenessCheck(p LivenessProbe, workDir string, env []string) probeCheck {
+	switch p.Kind() {
+	case LivenessTCP:
+		return tcpCheck(tcpProbeAddress(p.TCP))
+	case LivenessHTTP:
+		return httpCheck(p.HTTP)
+	case LivenessCommand:
+		return commandCheck(p.Command, workDir, env)
+	}
+	return func(ctx context.Context) (string, error) { return "", nil }
+}
+
+// startLivenessLocked starts checking the liveness of the running process, called with t.mu held
+// The checks start once the process is ready and end when it exits.
+func (t *Task) startLivenessLocked() {
+	config := t.runConfigLocked()
+	probe := config.Liveness
+	if probe.Kind() == "" || t.exited == nil || t.watchedRun == t.exited {
+		return
+	}
+	t.watchedRun = t.exited
+
+	env, err := processEnv(config)
+	if err != nil {
+		fmt.Printf("Warning: liveness probe of task %s runs without the task environment: %v\n", t.name, err)
+	}
+	check := newLivenessCheck(probe, processDir(config), env)
+	go t.watchLiveness(probe, check, t.exited, t.readyDone)
+}
+
+// watchLiveness checks a running process every interval once it is ready
+// After FailureThreshold consecutive failures the process is stopped and its run fails.
+func (t *Task) watchLiveness(probe LivenessProbe, check probeCheck, exited <-chan struct{}, readyDone <-chan struct{}) {
+	if readyDone != nil {
+		select {
+		case <-exited:
+			return
+		case <-readyDone:
+		}
+		// A process that did not become ready is stopped by the readiness probe
+		t.mu.RLock()
+		ready := t.ready
+		t.mu.RUnlock()
+		if !ready {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(probe.IntervalDuration())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), probe.TimeoutDuration())
+		output, err := check(ctx)
+		cancel()
+
+		failures, current := t.recordLivenessCheck(exited, output, err)
+		if !current {
+			return
+		}
+		if failures < probe.Threshold() {
+			continue
+		}
+
+		failure := fmt.Errorf("liveness probe failed %d times in a row: %w", failures, err)
+		t.mu.Lock()
+		t.probeFailure = failure
+		t.mu.Unlock()
+		fmt.Printf("Warning: task %s is unhealthy, stopping it: %v\n", t.name, failure)
+		if stopErr := t.StopWithOptions(StopOptions{Reason: StopReasonUnhealthy}); stopErr != nil {
+			fmt.Printf("Warning: failed to stop task %s: %v\n", t.name, stopErr)
+		}
+		return
+	}
+}
+
+// recordLivenessCheck records the result of a check of the run that closes exited
+// It returns the consecutive failures, and false if the process has exited meanwhile.
+// The health callback is called when the health or the failure count changes.
+func (t *Task) recordLivenessCheck(exited <-chan struct{}, output string, err error) (int, bool) {
+	t.mu.Lock()
+	if t.status != "running" || t.exited == nil || (<-chan struct{})(t.exited) != exited {
+		t.mu.Unlock()
+		return 0, false
+	}
+
+	previousHealth, previousFailures := t.health, t.healthFailures
+	t.lastProbeTime = time.Now()
+	if err != nil {
+		t.health = HealthUnhealthy
+		t.healthFailures++
+		t.lastProbeOutput = err.Error()
+	} else {
+		t.health = HealthHealthy
+		t.healthFailures = 0
+		t.lastProbeOutput = output
+	}
+	failures := t.healthFailures
+	changed := t.health != previousHealth || failures != previousFailures
+	onHealth := t.onHealth
+	t.mu.Unlock()
+
+	if changed && onHealth != nil {
+		onHealth(t.name)
+	}
+	return failures, true
+}
+
+// watchAdoptedLiveness starts the liveness checks of task processes adopted from a
+// previous daemon, the processes started by this one are already checked
+func (m *Manager) watchAdoptedLiveness() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, task := range m.tasks {
+		task.mu.Lock()
+		if task.status == "running" {
+			task.startLivenessLocked()
+		}
+		task.mu.Unlock()
+	}
+}
+
+// onTaskHealthChange saves the runtime state when the health of a task changes
+func (m *Manager) onTaskHealthChange(taskName string) {
+	m.requestStateSave()
+}
+
+// lastLivenessCheck returns the consecutive failures of the running process and the
+// time and output of the last liveness check
+func (t *Task) lastLivenessCheck() (int, time.Time, string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	failures := 0
+	if t.status == "running" {
+		failures = t.healthFailures
+	}
+	return failures, t.lastProbeTime, t.lastProbeOutput
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateLivenessProbe(t *testing.T) {
+	tests := []struct {
+		probe   LivenessProbe
+		wantErr string
+	}{
+		{LivenessProbe{}, ""},
+		{LivenessProbe{TCP: "5432"}, ""},
+		{LivenessProbe{HTTP: "https://127.0.0.1/health", Interval: "30s", Timeout: "2s", FailureThreshold: 5}, ""},
+		{LivenessProbe{Command: "redis-cli ping"}, ""},
+		{LivenessProbe{TCP: "5432", Command: "true"}, "one of"},
+		{LivenessProbe{FailureThreshold: 2}, "need a tcp"},
+		{LivenessProbe{TCP: "db"}, "invalid liveness tcp address"},
+		{LivenessProbe{HTTP: "ftp://example.com"}, "invalid liveness http URL"},
+		{LivenessProbe{Command: "  "}, "cannot be empty"},
+		{LivenessProbe{TCP: "5432", Interval: "-1s"}, "invalid liveness interval"},
+		{LivenessProbe{TCP: "5432", Timeout: "fast"}, "invalid liveness timeout"},
+		{LivenessProbe{TCP: "5432", FailureThreshold: -1}, "cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		err := ValidateLivenessProbe(tt.probe)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateLivenessProbe(%+v) = %v", tt.probe, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ValidateLivenessProbe(%+v) = %v, want an error containing %q", tt.probe, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLivenessProbeDefaults(t *testing.T) {
+	var probe LivenessProbe
+	if probe.IntervalDuration() != defaultLivenessInterval {
+		t.Errorf("IntervalDuration() = %v, want %v", probe.IntervalDuration(), defaultLivenessInterval)
+	}
+	if probe.TimeoutDuration() != defaultLivenessTimeout {
+		t.Errorf("TimeoutDuration() = %v, want %v", probe.TimeoutDuration(), defaultLivenessTimeout)
+	}
+	if probe.Threshold() != defaultLivenessFailureThreshold {
+		t.Errorf("Threshold() = %d, want %d", probe.Threshold(), defaultLivenessFailureThreshold)
+	}
+
+	probe = LivenessProbe{Interval: "1m", Timeout: "1s", FailureThreshold: 1}
+	if probe.IntervalDuration() != time.Minute || probe.TimeoutDuration() != time.Second || probe.Threshold() != 1 {
+		t.Errorf("probe %+v = %v, %v, %d", probe, probe.IntervalDuration(), probe.TimeoutDuration(), probe.Threshold())
+	}
+}
@@ -0,0 +1,109 @@
+//go:build !windows
+
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForHealth waits until the task reports the given health
+func waitForHealth(t *testing.T, task *Task, health string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if task.GetInfo().Health == health {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("health = %q, want %q", task.GetInfo().Health, health)
+}
+
+func TestLivenessProbeStopsUnhealthyTask(t *testing.T) {
+	workDir := t.TempDir()
+	marker := filepath.Join(workDir, "healthy")
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan string, 16)
+	executable, args := trapCommand("exit 0")
+	task := NewTask("live-test", &Config{
+		Executable: executable,
+		Args:       args,
+		WorkDir:    workDir,
+		Liveness:   LivenessProbe{Command: "test -f healthy", Interval: "50ms", FailureThreshold: 2},
+	})
+	task.SetHealthCallback(func(name string) { changes <- name })
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() {
+		if task.IsRunning() {
+			task.StopWithOptions(StopOptions{Force: true})
+		}
+	})
+
+	if health := task.GetInfo().Health; health != HealthUnknown {
+		t.Errorf("health before the first check = %q, want %q", health, HealthUnknown)
+	}
+	waitForHealth(t, task, HealthHealthy)
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Error("health callback was not called")
+	}
+	if info := task.GetRuntimeInfo(); info.Health != HealthHealthy || info.LastProbeTime.IsZero() {
+		t.Errorf("runtime info = %+v, want the healthy check recorded", info)
+	}
+
+	// The task is stopped after two failed checks in a row
+	os.Remove(marker)
+	deadline := time.Now().Add(5 * time.Second)
+	for task.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	info := task.GetInfo()
+	if info.Status != "stopped" || !strings.Contains(info.LastError, "liveness probe failed 2 times in a row") {
+		t.Errorf("task after the failed checks = %s (%s), want stopped with the probe error", info.Status, info.LastError)
+	}
+	if info.ExitCode != -1 {
+		t.Errorf("exit code = %d, want -1 so that the on-failure policy restarts the task", info.ExitCode)
+	}
+	runs := task.takeFinishedRuns()
+	if len(runs) != 1 || runs[0].Reason != StopReasonUnhealthy || !runs[0].Failed() {
+		t.Errorf("finished runs = %+v, want one failed unhealthy run", runs)
+	}
+}
+
+func TestLivenessProbeOfRunConfig(t *testing.T) {
+	executable, args := trapCommand("exit 0")
+	config := &Config{Executable: executable, Args: args, WorkDir: t.TempDir()}
+	task := NewTask("live-test", config)
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() {
+		if task.IsRunning() {
+			task.StopWithOptions(StopOptions{Force: true})
+		}
+	})
+
+	// A probe added by an edit applies to the next run, not to the running process
+	edited := *config
+	edited.Liveness = LivenessProbe{Command: "false", Interval: "50ms"}
+	task.setConfig(&edited)
+
+	task.mu.Lock()
+	task.startLivenessLocked()
+	task.mu.Unlock()
+
+	time.Sleep(200 * time.Millisecond)
+	if info := task.GetInfo(); info.Status != "running" || info.Health != "" {
+		t.Errorf("task = %s with health %q, want running without liveness checks", info.Status, info.Health)
+	}
+}
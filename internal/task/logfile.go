@@ -0,0 +1,365 @@
+package task
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Log stream names
+const (
+	LogStreamStdout = "stdout"
+	LogStreamStderr = "stderr"
+)
+
+// logTimestampLayouts are the timestamp formats recognised at the start of a log line
+var logTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05.999999",
+	"2006/01/02 15:04:05",
+}
+
+// LogLine a line of task output read from a log file
+type LogLine struct {
+	Stream string    // stdout or stderr
+	Text   string    // Line content without the trailing newline
+	Time   time.Time // Leading timestamp of the line (or of the line it continues), zero if unknown
+}
+
+// ParseLogTimestamp extracts a timestamp from the start of a log line
+// Timestamps may be wrapped in square brackets, e.g. "[2026-01-27 10:30:00] started"
+func ParseLogTimestamp(line string) (time.Time, bool) {
+	line = strings.TrimPrefix(line, "[")
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) == 0 || fields[0] == "" {
+		return time.Time{}, false
+	}
+
+	candidates := []string{fields[0]}
+	if len(fields) > 1 {
+		candidates = append(candidates, fields[0]+" "+fields[1])
+	}
+
+	for _, candidate := range candidates {
+		candidate = strings.TrimSuffix(candidate, "]")
+		for _, layout := range logTimestampLayouts {
+			if t, err := time.ParseInLocation(layout, candidate, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// LogFollower reads a log file incrementally, following it across truncation
+// and replacement (e.g. by log rotation)
+type LogFollower struct {
+	path     string
+	stream   string
+	file     *os.File
+	fileInfo os.FileInfo
+	offset   int64
+	partial  []byte
+	lastTime time.Time
+
+	// Captured lines split into partial records, joined per stream
+	pendingRecords map[string]*LogLine
+}
+
+// NewLogFollower creates a follower for the log file at path
+// The file does not have to exist yet, it is opened once it appears
+func NewLogFollower(path, stream string) *LogFollower {
+	return &LogFollower{
+		path:   path,
+		stream: stream,
+	}
+}
+
+// Poll returns the complete lines written since the previous call
+// The first call returns the whole current content of the file.
+func (f *LogFollower) Poll() ([]LogLine, error) {
+	info, statErr := os.Stat(f.path)
+
+	var lines []LogLine
+	if f.file != nil {
+		switch {
+		case statErr != nil || !os.SameFile(f.fileInfo, info):
+			// The file was removed or replaced, finish reading the old one first
+			drained, err := f.readNew()
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, drained...)
+			lines = append(lines, f.flushPartial()...)
+			f.closeFile()
+
+		case info.Size() < f.offset:
+			// The file was truncated, start again from the beginning
+			f.offset = 0
+			f.partial = nil
+		}
+	}
+
+	if f.file == nil {
+		if statErr != nil {
+			return lines, nil // Not created yet
+		}
+		if err := f.openFile(); err != nil {
+			return lines, err
+		}
+	}
+
+	newLines, err := f.readNew()
+	if err != nil {
+		return lines, err
+	}
+	return append(lines, newLines...), nil
+}
+
+// SeekTail positions the follower at the start of the last n lines of the file,
+// so the next Poll returns only those instead of the whole content. The file is
+// read backwards from its end until they are found. It reports whether the
+// lines start at the beginning of the file, which has no more lines then.
+func (f *LogFollower) SeekTail(n int) (bool, error) {
+	if f.file == nil {
+		if _, err := os.Stat(f.path); err != nil {
+			return true, nil // Not created yet, read from the start once it appears
+		}
+		if err := f.openFile(); err != nil {
+			return false, err
+		}
+	}
+
+	offset, err := f.tailOffset(n)
+	if err != nil {
+		return false, err
+	}
+	f.offset = offset
+	f.partial = nil
+	f.pendingRecords = nil
+	f.lastTime = time.Time{}
+	return offset == 0, nil
+}
+
+// tailOffset returns the offset of the last n lines of the open file
+func (f *LogFollower) tailOffset(n int) (int64, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0, wrapFileError(err, f.path, "stat")
+	}
+	end := info.Size()
+	if n <= 0 {
+		return end, nil
+	}
+
+	buf := make([]byte, 32*1024)
+	found := 0
+	for pos := end; pos > 0; {
+		size := int64(len(buf))
+		if pos < size {
+			size = pos
+		}
+		pos -= size
+		if _, err := f.file.ReadAt(buf[:size], pos); err != nil && err != io.EOF {
+			return 0, wrapFileError(err, f.path, "read")
+		}
+
+		// Every newline but the one ending the file starts a line after it
+		for i := size - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == end-1 {
+				continue
+			}
+			if found++; found == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// Flush returns the trailing partial line and unfinished captured lines, if any
+// Used when reading stops, so output without a final newline is not lost.
+func (f *LogFollower) Flush() []LogLine {
+	lines := f.flushPartial()
+	for _, stream := range []string{LogStreamStdout, LogStreamStderr} {
+		if pending := f.pendingRecords[stream]; pending != nil {
+			lines = append(lines, *pending)
+			delete(f.pendingRecords, stream)
+		}
+	}
+	return lines
+}
+
+// Close closes the log file
+func (f *LogFollower) Close() error {
+	return f.closeFile()
+}
+
+// openFile opens the log file from the beginning
+func (f *LogFollower) openFile() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return wrapFileError(err, f.path, "open")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return wrapFileError(err, f.path, "stat")
+	}
+
+	f.file = file
+	f.fileInfo = info
+	f.offset = 0
+	f.partial = nil
+	return nil
+}
+
+// closeFile closes the currently open file
+func (f *LogFollower) closeFile() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	f.fileInfo = nil
+	return err
+}
+
+// readNew reads everything after the current offset and splits it into lines
+func (f *LogFollower) readNew() ([]LogLine, error) {
+	buf := make([]byte, 32*1024)
+	var lines []LogLine
+
+	for {
+		n, err := f.file.ReadAt(buf, f.offset)
+		if n > 0 {
+			f.offset += int64(n)
+			lines = append(lines, f.splitLines(buf[:n])...)
+		}
+		if err == io.EOF || n == 0 {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, wrapFileError(err, f.path, "read")
+		}
+	}
+}
+
+// splitLines turns data into complete lines, keeping an unterminated tail for the next read
+func (f *LogFollower) splitLines(data []byte) []LogLine {
+	var lines []LogLine
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			f.partial = append(f.partial, data...)
+			return lines
+		}
+
+		text := string(append(f.partial, data[:i]...))
+		f.partial = nil
+		data = data[i+1:]
+		if line, ok := f.newLine(text); ok {
+			lines = append(lines, line)
+		}
+	}
+}
+
+// flushPartial returns the unterminated tail as a line
+func (f *LogFollower) flushPartial() []LogLine {
+	if len(f.partial) == 0 {
+		return nil
+	}
+	text := string(f.partial)
+	f.partial = nil
+	if line, ok := f.newLine(text); ok {
+		return []LogLine{line}
+	}
+	return nil
+}
+
+// newLine builds a LogLine, lines without a timestamp inherit the previous line's time
+// Lines written in a capture format are decoded; it returns false while a split
+// captured line is still incomplete.
+func (f *LogFollower) newLine(text string) (LogLine, bool) {
+	text = strings.TrimSuffix(text, "\r")
+	if record, ok := ParseCaptureRecord(text); ok {
+		return f.joinRecord(record)
+	}
+	if t, ok := ParseLogTimestamp(text); ok {
+		f.lastTime = t
+	}
+	return LogLine{Stream: f.stream, Text: text, Time: f.lastTime}, true
+}
+
+// joinRecord joins partial capture records of a stream into one line
+func (f *LogFollower) joinRecord(record CaptureRecord) (LogLine, bool) {
+	f.lastTime = record.Time
+
+	line := LogLine{Stream: record.Stream, Text: record.Line, Time: record.Time}
+	if pending := f.pendingRecords[record.Stream]; pending != nil {
+		line.Text = pending.Text + line.Text
+		line.Time = pending.Time
+	}
+
+	if record.Partial {
+		if f.pendingRecords == nil {
+			f.pendingRecords = make(map[string]*LogLine)
+		}
+		f.pendingRecords[record.Stream] = &line
+		return LogLine{}, false
+	}
+
+	delete(f.pendingRecords, record.Stream)
+	return line, true
+}
+
+// MergeLogLines interleaves the lines of two streams by timestamp
+// The order within each stream is kept; lines without a timestamp are taken
+// as soon as they are reached.
+func MergeLogLines(a, b []LogLine) []LogLine {
+	merged := make([]LogLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i].Time.IsZero() || (!b[j].Time.IsZero() && !b[j].Time.Before(a[i].Time)) {
+			merged = append(merged, a[i])
+			i++
+		} else {
+			merged = append(merged, b[j])
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	return append(merged, b[j:]...)
+}
+
+// FilterLogLinesSince keeps the lines logged at or after since
+// Lines with an unknown time are kept if the log was modified after since.
+func FilterLogLinesSince(lines []LogLine, since time.Time, modTime time.Time) []LogLine {
+	var filtered []LogLine
+	for _, line := range lines {
+		if line.Time.IsZero() {
+			if !modTime.Before(since) {
+				filtered = append(filtered, line)
+			}
+			continue
+		}
+		if !line.Time.Before(since) {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
+
+// TailLogLines returns the last n lines, a negative n returns all lines
+func TailLogLines(lines []LogLine, n int) []LogLine {
+	if n < 0 || n >= len(lines) {
+		return lines
+	}
+	return lines[len(lines)-n:]
+}
@@ -0,0 +1,290 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// lineTexts returns the text of each log line
+func lineTexts(lines []LogLine) []string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+	}
+	return texts
+}
+
+// equalTexts reports whether two string slices are equal
+func equalTexts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseLogTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"rfc3339", "2026-01-27T10:30:00Z started", time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC), true},
+		{"date and time", "2026-01-27 10:30:00 started", time.Date(2026, 1, 27, 10, 30, 0, 0, time.Local), true},
+		{"fractional seconds", "2026-01-27 10:30:00.250 started", time.Date(2026, 1, 27, 10, 30, 0, 250000000, time.Local), true},
+		{"go log format", "2026/01/27 10:30:00 started", time.Date(2026, 1, 27, 10, 30, 0, 0, time.Local), true},
+		{"bracketed", "[2026-01-27 10:30:00] started", time.Date(2026, 1, 27, 10, 30, 0, 0, time.Local), true},
+		{"timestamp only", "2026-01-27T10:30:00Z", time.Date(2026, 1, 27, 10, 30, 0, 0, time.UTC), true},
+		{"no timestamp", "started at 10:30", time.Time{}, false},
+		{"empty line", "", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLogTimestamp(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ParseLogTimestamp(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("ParseLogTimestamp(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFollowerPartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	os.WriteFile(path, []byte("one\ntw"), 0644)
+
+	follower := NewLogFollower(path, LogStreamStdout)
+	defer follower.Close()
+
+	lines, err := follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	if got := lineTexts(lines); !equalTexts(got, []string{"one"}) {
+		t.Errorf("first Poll() = %v, want [one]", got)
+	}
+
+	appendToFile(t, path, "o\nthree\n")
+
+	lines, err = follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	if got := lineTexts(lines); !equalTexts(got, []string{"two", "three"}) {
+		t.Errorf("second Poll() = %v, want [two three]", got)
+	}
+}
+
+func TestLogFollowerTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	os.WriteFile(path, []byte("old line one\nold line two\n"), 0644)
+
+	follower := NewLogFollower(path, LogStreamStdout)
+	defer follower.Close()
+	follower.Poll()
+
+	// Truncate in place and write shorter content
+	os.WriteFile(path, []byte("new\n"), 0644)
+
+	lines, err := follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	if got := lineTexts(lines); !equalTexts(got, []string{"new"}) {
+		t.Errorf("Poll() after truncation = %v, want [new]", got)
+	}
+}
+
+func TestLogFollowerReplacement(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.log")
+	os.WriteFile(path, []byte("first\n"), 0644)
+
+	follower := NewLogFollower(path, LogStreamStdout)
+	defer follower.Close()
+	follower.Poll()
+
+	// Rotate: more output goes to the old file, then it is renamed and a new file appears
+	appendToFile(t, path, "last before rotation\n")
+	if err := os.Rename(path, filepath.Join(dir, "out-1.log")); err != nil {
+		t.Fatalf("Rename() = %v", err)
+	}
+	os.WriteFile(path, []byte("after rotation\n"), 0644)
+
+	lines, err := follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	want := []string{"last before rotation", "after rotation"}
+	if got := lineTexts(lines); !equalTexts(got, want) {
+		t.Errorf("Poll() after replacement = %v, want %v", got, want)
+	}
+}
+
+func TestLogFollowerMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "later.log")
+
+	follower := NewLogFollower(path, LogStreamStderr)
+	defer follower.Close()
+
+	lines, err := follower.Poll()
+	if err != nil || len(lines) != 0 {
+		t.Fatalf("Poll() on missing file = %v, %v, want no lines", lines, err)
+	}
+
+	os.WriteFile(path, []byte("created\n"), 0644)
+	lines, err = follower.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	if len(lines) != 1 || lines[0].Stream != LogStreamStderr {
+		t.Errorf("Poll() = %v, want one stderr line", lines)
+	}
+}
+
+func TestLogFollowerSeekTail(t *testing.T) {
+	// Lines spread over more than one read buffer
+	var long []string
+	var content []byte
+	for i := 0; i < 5000; i++ {
+		line := fmt.Sprintf("line %d", i)
+		long = append(long, line)
+		content = append(content, line+"\n"...)
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		n           int
+		want        []string
+		wantAtStart bool
+	}{
+		{"last lines", "a\nb\nc\n", 2, []string{"b", "c"}, false},
+		{"unterminated last line", "a\nb\nc", 2, []string{"b", "c"}, false},
+		{"all lines", "a\nb\nc\n", 3, []string{"a", "b", "c"}, true},
+		{"fewer lines", "a\nb\n", 5, []string{"a", "b"}, true},
+		{"no lines", "a\nb\n", 0, nil, false},
+		{"empty file", "", 3, nil, true},
+		{"across read buffers", string(content), 4000, long[1000:], false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			f := NewLogFollower(path, LogStreamStdout)
+			defer f.Close()
+
+			atStart, err := f.SeekTail(tt.n)
+			if err != nil {
+				t.Fatalf("SeekTail() = %v", err)
+			}
+			lines, err := f.Poll()
+			if err != nil {
+				t.Fatalf("Poll() = %v", err)
+			}
+			got := lineTexts(append(lines, f.Flush()...))
+			if !equalTexts(got, tt.want) || atStart != tt.wantAtStart {
+				t.Errorf("SeekTail(%d) = %v, lines %q, want %v, %q", tt.n, atStart, got, tt.wantAtStart, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFollowerSeekTailFollows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("a\nb\n"), 0644)
+	f := NewLogFollower(path, LogStreamStdout)
+	defer f.Close()
+
+	f.SeekTail(1)
+	f.Poll()
+
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString("c\n")
+	file.Close()
+
+	lines, err := f.Poll()
+	if err != nil {
+		t.Fatalf("Poll() = %v", err)
+	}
+	if got := lineTexts(lines); !equalTexts(got, []string{"c"}) {
+		t.Errorf("lines after SeekTail() = %q, want the new line", got)
+	}
+}
+
+func TestMergeLogLines(t *testing.T) {
+	at := func(sec int) time.Time { return time.Date(2026, 1, 27, 10, 0, sec, 0, time.Local) }
+
+	stdout := []LogLine{
+		{Stream: LogStreamStdout, Text: "untimed"},
+		{Stream: LogStreamStdout, Text: "out 1", Time: at(1)},
+		{Stream: LogStreamStdout, Text: "out 3", Time: at(3)},
+	}
+	stderr := []LogLine{
+		{Stream: LogStreamStderr, Text: "err 2", Time: at(2)},
+		{Stream: LogStreamStderr, Text: "err 3", Time: at(3)},
+	}
+
+	got := lineTexts(MergeLogLines(stdout, stderr))
+	want := []string{"untimed", "out 1", "err 2", "out 3", "err 3"}
+	if !equalTexts(got, want) {
+		t.Errorf("MergeLogLines() = %v, want %v", got, want)
+	}
+}
+
+func TestFilterAndTailLogLines(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2026, 1, 27, 10, min, 0, 0, time.Local) }
+
+	lines := []LogLine{
+		{Text: "untimed"},
+		{Text: "a", Time: at(0)},
+		{Text: "b", Time: at(10)},
+		{Text: "c", Time: at(20)},
+	}
+
+	filtered := FilterLogLinesSince(lines, at(5), at(0))
+	if got := lineTexts(filtered); !equalTexts(got, []string{"b", "c"}) {
+		t.Errorf("FilterLogLinesSince() = %v, want [b c]", got)
+	}
+
+	// Untimed lines are kept when the file was written after since
+	filtered = FilterLogLinesSince(lines, at(5), at(30))
+	if got := lineTexts(filtered); !equalTexts(got, []string{"untimed", "b", "c"}) {
+		t.Errorf("FilterLogLinesSince() = %v, want [untimed b c]", got)
+	}
+
+	if got := lineTexts(TailLogLines(lines, 2)); !equalTexts(got, []string{"b", "c"}) {
+		t.Errorf("TailLogLines(2) = %v, want [b c]", got)
+	}
+	if got := TailLogLines(lines, -1); len(got) != len(lines) {
+		t.Errorf("TailLogLines(-1) returned %d lines, want all %d", len(got), len(lines))
+	}
+	if got := TailLogLines(lines, 0); len(got) != 0 {
+		t.Errorf("TailLogLines(0) returned %d lines, want none", len(got))
+	}
+}
+
+// appendToFile appends data to the file at path
+func appendToFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() = %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("WriteString() = %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package task
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp embedded in rotated log file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressSuffix is appended to compressed log backups
+const compressSuffix = ".gz"
+
+// RotatingWriter is an io.WriteCloser that writes to a log file and rolls it
+// over once it reaches the configured size (runs in daemon process)
+//
+// Rotated files are renamed to <name>-<timestamp><ext> next to the log file,
+// optionally gzipped in the background, and pruned by count (MaxBackups) and
+// age (MaxAge).
+type RotatingWriter struct {
+	path       string
+	maxSize    int64         // Bytes, 0 disables rotation
+	maxBackups int           // 0 keeps all backups
+	maxAge     time.Duration // 0 keeps backups regardless of age
+	compress   bool
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+	now    func() time.Time
+
+	compressFile func(path string) error // Gzips a backup, see compressLogFile
+	compressMu   sync.Mutex              // Held while compressing backups, one batch at a time
+	compressing  sync.WaitGroup          // Backups being compressed in the background
+	inFlight     map[string]bool         // Backups queued for compression, guarded by mu
+}
+
+// NewRotatingWriter opens (or creates) the log file at path, rotating it according to config
+func NewRotatingWriter(path string, config LogConfig) (*RotatingWriter, error) {
+	w := &RotatingWriter{
+		path:       path,
+		maxSize:    int64(config.MaxSize) * 1024 * 1024,
+		maxBackups: config.MaxBackups,
+		maxAge:     time.Duration(config.MaxAge) * 24 * time.Hour,
+		compress:   config.Compress,
+		now:        time.Now,
+
+		compressFile: compressLogFile,
+		inFlight:     make(map[string]bool),
+	}
+
+	if err := w.openExisting(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes p to the log file, rotating it first if p would exceed the size limit
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	// Reopen the file if a previous rotation failed half way
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+
+	// A single write larger than the limit still goes to a fresh file
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate forces the current log file to be rolled over
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close closes the current log file and waits for the backups being compressed
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	// The compression goroutines take mu once a backup is done
+	w.compressing.Wait()
+	return err
+}
+
+// openExisting opens the log file for appending and records its current size
+func (w *RotatingWriter) openExisting() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return wrapFileError(err, w.path, "create")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return wrapFileError(err, w.path, "stat")
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate renames the current log file to a backup, opens a new one and cleans up old backups
+func (w *RotatingWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		w.file = nil
+	}
+
+	if _, err := os.Stat(w.path); err == nil {
+		if err := os.Rename(w.path, w.backupName()); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", wrapFileError(err, w.path, "rename"))
+		}
+	}
+
+	if err := w.openExisting(); err != nil {
+		return err
+	}
+
+	// Cleanup problems must not stop the task output from being written
+	uncompressed, err := w.cleanupBackups()
+	if err != nil {
+		fmt.Printf("Warning: failed to clean up log backups of %s: %v\n", w.path, err)
+	}
+	if len(uncompressed) > 0 {
+		w.compressBackups(uncompressed)
+	}
+
+	return nil
+}
+
+// compressBackups gzips backups in the background, so that the task output
+// is not held up while a large log file is compressed
+// The caller holds mu; the backups stay in flight until they are compressed.
+func (w *RotatingWriter) compressBackups(paths []string) {
+	for _, path := range paths {
+		w.inFlight[path] = true
+	}
+
+	w.compressing.Add(1)
+	go func() {
+		defer w.compressing.Done()
+		w.compressMu.Lock()
+		defer w.compressMu.Unlock()
+
+		for _, path := range paths {
+			if err := w.compressFile(path); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("Warning: failed to compress log backup %s: %v\n", path, err)
+			}
+
+			w.mu.Lock()
+			delete(w.inFlight, path)
+			w.mu.Unlock()
+		}
+	}()
+}
+
+// backupName returns an unused backup file name for the current time
+func (w *RotatingWriter) backupName() string {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+	timestamp := w.now().Format(backupTimeFormat)
+
+	name := filepath.Join(dir, prefix+timestamp+ext)
+	for i := 1; fileExists(name) || fileExists(name+compressSuffix); i++ {
+		name = filepath.Join(dir, fmt.Sprintf("%s%s.%d%s", prefix, timestamp, i, ext))
+	}
+	return name
+}
+
+// logBackup is a rotated log file found next to the current log file
+type logBackup struct {
+	path      string
+	name      string // File name without the compression suffix
+	timestamp time.Time
+}
+
+// listBackups returns the rotated files of this log, newest first
+func (w *RotatingWriter) listBackups() ([]logBackup, error) {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []logBackup
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), compressSuffix)
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		// Strip the collision counter (".N") if present
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if len(stamp) > len(backupTimeFormat) {
+			stamp = stamp[:len(backupTimeFormat)]
+		}
+		timestamp, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue // Not one of our backups
+		}
+
+		backups = append(backups, logBackup{path: filepath.Join(dir, entry.Name()), name: name, timestamp: timestamp})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].timestamp.Equal(backups[j].timestamp) {
+			// Same timestamp, a higher collision counter means a newer backup
+			if len(backups[i].name) != len(backups[j].name) {
+				return len(backups[i].name) > len(backups[j].name)
+			}
+			return backups[i].name > backups[j].name
+		}
+		return backups[i].timestamp.After(backups[j].timestamp)
+	})
+
+	return backups, nil
+}
+
+// cleanupBackups removes backups beyond MaxBackups or older than MaxAge
+// It returns the kept backups still to be compressed. Backups being compressed
+// are left alone, a later rotation prunes them once they are done.
+func (w *RotatingWriter) cleanupBackups() ([]string, error) {
+	backups, err := w.listBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var uncompressed []string
+	var lastErr error
+	cutoff := w.now().Add(-w.maxAge)
+	kept := 0
+
+	for _, backup := range backups {
+		if w.inFlight[filepath.Join(filepath.Dir(backup.path), backup.name)] {
+			kept++
+			continue
+		}
+
+		tooMany := w.maxBackups > 0 && kept >= w.maxBackups
+		tooOld := w.maxAge > 0 && backup.timestamp.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				lastErr = err
+			}
+			continue
+		}
+		kept++
+
+		if w.compress && !strings.HasSuffix(backup.path, compressSuffix) {
+			uncompressed = append(uncompressed, backup.path)
+		}
+	}
+
+	return uncompressed, lastErr
+}
+
+// compressLogFile gzips path into path.gz and removes the original
+func compressLogFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, copyErr := io.Copy(gz, src)
+	closeErr := gz.Close()
+	if err := dst.Close(); err != nil && closeErr == nil {
+		closeErr = err
+	}
+	if copyErr != nil || closeErr != nil {
+		os.Remove(path + compressSuffix)
+		if copyErr != nil {
+			return fmt.Errorf("failed to compress %s: %w", path, copyErr)
+		}
+		return fmt.Errorf("failed to compress %s: %w", path, closeErr)
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// fileExists reports whether a file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
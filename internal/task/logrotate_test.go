@@ -0,0 +1,339 @@
+package task
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRotatingWriter creates a writer with a byte size limit and a controllable clock
+func newTestRotatingWriter(t *testing.T, maxSize int64, config LogConfig) (*RotatingWriter, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := NewRotatingWriter(path, config)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter() = %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	w.maxSize = maxSize
+	w.now = func() time.Time { return clock }
+	return w, &clock
+}
+
+// listLogFiles returns the names of all files in the log directory
+func listLogFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() = %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestRotatingWriterRotatesBySize(t *testing.T) {
+	w, clock := newTestRotatingWriter(t, 10, LogConfig{})
+
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("123456\n")); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+		*clock = clock.Add(time.Second)
+	}
+
+	files := listLogFiles(t, filepath.Dir(w.path))
+	if len(files) != 3 {
+		t.Fatalf("expected current log and 2 backups, got %v", files)
+	}
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	if string(data) != "123456\n" {
+		t.Errorf("current log = %q, want only the last write", data)
+	}
+}
+
+func TestRotatingWriterKeepsMaxBackups(t *testing.T) {
+	w, clock := newTestRotatingWriter(t, 5, LogConfig{MaxBackups: 2})
+
+	for i := 0; i < 6; i++ {
+		if _, err := w.Write([]byte("hello")); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+		*clock = clock.Add(time.Minute)
+	}
+
+	backups, err := w.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(backups))
+	}
+	// The newest backups are kept
+	if !backups[0].timestamp.After(backups[1].timestamp) {
+		t.Errorf("backups are not sorted newest first: %v", backups)
+	}
+}
+
+func TestRotatingWriterPrunesByAge(t *testing.T) {
+	w, clock := newTestRotatingWriter(t, 5, LogConfig{MaxAge: 1})
+
+	w.Write([]byte("old"))
+	w.Rotate()
+
+	// Two days later the first backup is past max_age
+	*clock = clock.Add(48 * time.Hour)
+	w.Write([]byte("new"))
+	w.Rotate()
+
+	backups, err := w.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() = %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup after age pruning, got %d", len(backups))
+	}
+
+	data, _ := os.ReadFile(backups[0].path)
+	if string(data) != "new" {
+		t.Errorf("remaining backup = %q, want the newest one", data)
+	}
+}
+
+func TestRotatingWriterCompressesBackups(t *testing.T) {
+	w, _ := newTestRotatingWriter(t, 1024, LogConfig{Compress: true})
+
+	w.Write([]byte("compress me\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("Rotate() = %v", err)
+	}
+	w.compressing.Wait()
+
+	backups, err := w.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() = %v", err)
+	}
+	if len(backups) != 1 || !strings.HasSuffix(backups[0].path, compressSuffix) {
+		t.Fatalf("expected a single gzipped backup, got %v", backups)
+	}
+
+	file, err := os.Open(backups[0].path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() = %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("ReadAll() = %v", err)
+	}
+	if string(data) != "compress me\n" {
+		t.Errorf("decompressed backup = %q", data)
+	}
+}
+
+func TestRotatingWriterCompressesInBackground(t *testing.T) {
+	w, _ := newTestRotatingWriter(t, 5, LogConfig{Compress: true})
+	release := make(chan struct{})
+	w.compressFile = func(path string) error {
+		<-release
+		return compressLogFile(path)
+	}
+
+	// Writes go on while the first backup is being compressed
+	done := make(chan struct{})
+	go func() {
+		for _, data := range []string{"hello", "world", "again"} {
+			w.Write([]byte(data))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() waits for the compression of a backup")
+	}
+
+	close(release)
+	w.compressing.Wait()
+
+	backups, err := w.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup.path, compressSuffix) {
+			t.Errorf("backup %s is not compressed", backup.path)
+		}
+	}
+}
+
+func TestRotatingWriterKeepsBackupsBeingCompressed(t *testing.T) {
+	w, _ := newTestRotatingWriter(t, 1024, LogConfig{MaxBackups: 1, Compress: true})
+	release := make(chan struct{})
+	w.compressFile = func(path string) error {
+		<-release
+		return compressLogFile(path)
+	}
+
+	for _, data := range []string{"first\n", "second\n"} {
+		w.Write([]byte(data))
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("Rotate() = %v", err)
+		}
+	}
+
+	// Both backups are queued for compression, none of them is pruned yet
+	backups, err := w.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected the 2 backups being compressed to be kept, got %v", backups)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close() returned while backups were being compressed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not return once the backups were compressed")
+	}
+	if len(w.inFlight) != 0 {
+		t.Errorf("backups still in flight after Close(): %v", w.inFlight)
+	}
+}
+
+func TestRotatingWriterBackupNameCollision(t *testing.T) {
+	w, _ := newTestRotatingWriter(t, 1024, LogConfig{})
+
+	// Rotating twice within the same clock tick must not overwrite the first backup
+	w.Write([]byte("first"))
+	w.Rotate()
+	w.Write([]byte("second"))
+	w.Rotate()
+
+	backups, err := w.listBackups()
+	if err != nil {
+		t.Fatalf("listBackups() = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(backups))
+	}
+
+	data, _ := os.ReadFile(backups[0].path)
+	if string(data) != "second" {
+		t.Errorf("newest backup = %q, want 'second'", data)
+	}
+}
+
+func TestRotatingWriterClosed(t *testing.T) {
+	w, _ := newTestRotatingWriter(t, 1024, LogConfig{})
+	w.Close()
+
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("Write() after Close() should fail")
+	}
+}
+
+func TestCreateTaskIOUsesRotatingWriter(t *testing.T) {
+	tempDir := t.TempDir()
+	config := &Config{
+		WorkDir: tempDir,
+		Stdout:  "out.log",
+		Stderr:  "out.log",
+		Log:     LogConfig{MaxSize: 1, MaxBackups: 3},
+	}
+
+	taskIO, err := GetIOManager().CreateTaskIO(config)
+	if err != nil {
+		t.Fatalf("CreateTaskIO() = %v", err)
+	}
+	defer taskIO.Close()
+
+	if _, ok := taskIO.Stdout.(*RotatingWriter); !ok {
+		t.Errorf("Stdout is %T, want *RotatingWriter", taskIO.Stdout)
+	}
+	if taskIO.Stdout != taskIO.Stderr {
+		t.Error("stdout and stderr pointing to the same file should share one writer")
+	}
+}
+
+func TestTaskOutputThroughRotatingWriter(t *testing.T) {
+	tempDir := t.TempDir()
+	executable, args := echoCommand()
+	task := NewTask("rotating-output", &Config{
+		Executable: executable,
+		Args:       args,
+		WorkDir:    tempDir,
+		Stdout:     "out.log",
+		Log:        LogConfig{MaxSize: 1},
+	})
+
+	exited := make(chan struct{})
+	task.SetExitCallback(func(string) { close(exited) })
+
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for task exit")
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "out.log"))
+	if err != nil {
+		t.Fatalf("ReadFile() = %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "test" {
+		t.Errorf("task output = %q, want 'test'", data)
+	}
+}
+
+func TestTaskStartFailureClosesOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	task := NewTask("missing-executable", &Config{
+		Executable: filepath.Join(tempDir, "missing"),
+		WorkDir:    tempDir,
+		Stdout:     "out.log",
+		Log:        LogConfig{MaxSize: 1},
+	})
+
+	if err := task.Start(); err == nil {
+		t.Fatal("Start() = nil, want an error for a missing executable")
+	}
+	if task.taskIO != nil {
+		t.Error("the output of a task that failed to start was left open")
+	}
+}
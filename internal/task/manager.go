@@ -1,12 +1,14 @@
 package task
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -23,6 +25,21 @@ type Manager struct {
 	tasks          map[string]*Task
 	mu             sync.RWMutex
 	builtinHandler *BuiltinTaskHandler
+	daemonMode     atomic.Bool           // Whether this manager runs inside the daemon process
+	exitHandler    func(taskName string) // Called after a supervised task exits and its state is saved
+
+	storeMu  sync.Mutex
+	store    StateUpdater // Runtime state store, see stateStore
+	storeKey string       // Backend and TaskD home of store
+
+	saveMu      sync.Mutex
+	saving      bool // The state saver is running, see requestStateSave
+	savePending bool // Another save was requested while the saver ran
+
+	stateCacheMu sync.Mutex
+	stateCache   *RuntimeState // Copy of the runtime state last read or written, see knownRuntimeState
+
+	metrics daemonMetrics // Counters of the daemon served by the metrics endpoint
 }
 
 // RuntimeState represents the runtime state of tasks
@@ -40,6 +57,18 @@ type TaskRuntimeInfo struct {
 	ExitCode       int       `json:"exit_code,omitempty"`
 	StoppedByTaskd bool      `json:"stopped_by_taskd"` // Whether the task was stopped by taskd stop command
 	RetryNum       int       `json:"retry_num"`        // Current retry count
+
+	// Identity of the process, used to detect that its PID was reused by another process
+	ProcessStartTime uint64 `json:"process_start_time,omitempty"` // OS specific start time of the process
+	Executable       string `json:"executable,omitempty"`         // Executable path of the process
+
+	LastScheduledRun time.Time `json:"last_scheduled_run,omitempty"` // When the scheduler last started the task
+
+	// Liveness probe results, the health is only set while the task runs
+	Health          string    `json:"health,omitempty"`            // Health* of the running process
+	HealthFailures  int       `json:"health_failures,omitempty"`   // Consecutive failed checks
+	LastProbeTime   time.Time `json:"last_probe_time,omitempty"`   // When the last check completed
+	LastProbeOutput string    `json:"last_probe_output,omitempty"` // Output of the last check, or its error
 }
 
 // DaemonStatus represents the status of the daemon process
@@ -56,6 +85,8 @@ type ProcessStatus struct {
 	IsTaskd      bool   `json:"is_taskd"`      // Whether it's a taskd process
 	ExitCode     int    `json:"exit_code"`     // Exit code (if exited)
 	ExecutablePath string `json:"executable_path"` // Executable file path
+	StartTime    uint64 `json:"start_time"`    // OS specific start time of the process
+	PIDReused    bool   `json:"pid_reused"`    // Whether the PID now belongs to another process
 }
 
 // GetManager get task manager singleton
@@ -66,12 +97,29 @@ func GetManager() *Manager {
 			builtinHandler: NewBuiltinTaskHandler(),
 		}
 		taskManager.loadTasks()
-		// Clean up stale runtime state after loading tasks
-		taskManager.cleanupRuntimeState()
 	})
 	return taskManager
 }
 
+// SetDaemonMode marks the manager as running inside the daemon process
+// In daemon mode the manager owns the task processes and never tries to start another daemon
+func (m *Manager) SetDaemonMode(enabled bool) {
+	m.daemonMode.Store(enabled)
+}
+
+// isDaemonMode reports whether the manager runs inside the daemon process
+// It does not take m.mu, so it is safe to call while the lock is held
+func (m *Manager) isDaemonMode() bool {
+	return m.daemonMode.Load()
+}
+
+// SetTaskExitHandler sets the function called after a task exits and its runtime state is saved
+func (m *Manager) SetTaskExitHandler(handler func(taskName string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exitHandler = handler
+}
+
 // ValidateBuiltinTaskOperation validates if an operation is allowed on a builtin task
 func (m *Manager) ValidateBuiltinTaskOperation(taskName, operation string) error {
 	return m.builtinHandler.ValidateOperation(taskName, operation)
@@ -98,7 +146,7 @@ func StartTask(name string) error {
 // StopTask stop a task
 func StopTask(name string) error {
 	manager := GetManager()
-	return manager.stopTask(name)
+	return manager.stopTask(name, StopOptions{})
 }
 
 // GetTaskStatus get task status
@@ -147,6 +195,7 @@ func (m *Manager) addTask(taskName string, config *Config) error {
 	task := NewTask(taskName, config)
 	// Set exit callback to update runtime state when task exits
 	task.SetExitCallback(m.onTaskExit)
+	task.SetHealthCallback(m.onTaskHealthChange)
 	m.tasks[taskName] = task
 
 	return nil
@@ -159,6 +208,8 @@ func (m *Manager) listTasks() ([]*TaskInfo, error) {
 		// Continue execution, don't block list display due to daemon startup failure
 	}
 	
+	state := m.knownRuntimeState()
+	
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -170,22 +221,51 @@ func (m *Manager) listTasks() ([]*TaskInfo, error) {
 	}
 	
 	// Then add other tasks
-	for _, task := range m.tasks {
+	for name, task := range m.tasks {
 		info := task.GetInfo()
+		if runtimeInfo, exists := state.Tasks[name]; exists {
+			info.RetryNum = runtimeInfo.RetryNum
+		}
 		tasks = append(tasks, info)
 	}
 
 	return tasks, nil
 }
 
-// StartTask start a task by name
+// StartTask start a task by name, after the tasks it requires and wants
 func (m *Manager) StartTask(name string) error {
-	return m.startTask(name)
+	return m.StartTaskWithOptions(name, StartOptions{})
+}
+
+// StartTaskWithOptions start a task by name, waiting for it to be ready if asked to
+func (m *Manager) StartTaskWithOptions(name string, opts StartOptions) error {
+	err := m.startWithDependencies(name, func() error {
+		return m.startTask(name)
+	})
+	if err != nil || !opts.Wait || m.builtinHandler.IsBuiltinTask(name) {
+		return err
+	}
+	
+	m.mu.RLock()
+	task, exists := m.tasks[name]
+	m.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("task '%s' does not exist", name)
+	}
+	if err := task.WaitReady(); err != nil {
+		return fmt.Errorf("task '%s' is not ready: %w", name, err)
+	}
+	return nil
 }
 
 // StopTask stop a task by name
 func (m *Manager) StopTask(name string) error {
-	return m.stopTask(name)
+	return m.stopTask(name, StopOptions{})
+}
+
+// StopTaskWithOptions stop a task by name, overriding its stop timeout or forcing a kill
+func (m *Manager) StopTaskWithOptions(name string, opts StopOptions) error {
+	return m.stopTask(name, opts)
 }
 
 // RestartTask restart a task by name (stop if running, then start)
@@ -222,6 +302,8 @@ func (m *Manager) startTask(name string) error {
 	if err == nil {
 		// Reset retry count when manually starting a task
 		m.resetTaskRetryCount(name)
+		// A manual start re-enables automatic restarts
+		m.setTaskStoppedByTaskd(name, false)
 		// Save runtime state after successful start
 		m.saveRuntimeState()
 		
@@ -235,6 +317,152 @@ func (m *Manager) startTask(name string) error {
 	return err
 }
 
+// startTaskForRetry starts a task on behalf of the monitor
+// Unlike startTask it keeps the retry count and StoppedByTaskd flag untouched
+func (m *Manager) startTaskForRetry(name string) error {
+	return m.startWithDependencies(name, func() error {
+		m.mu.RLock()
+		task, exists := m.tasks[name]
+		m.mu.RUnlock()
+
+		if !exists {
+			return fmt.Errorf("task '%s' does not exist", name)
+		}
+
+		if err := task.Start(); err != nil {
+			return err
+		}
+
+		m.saveRuntimeState()
+		return nil
+	})
+}
+
+// markTaskRetryFailed marks a task the monitor failed to restart as stopped
+func (m *Manager) markTaskRetryFailed(name string) {
+	m.mu.RLock()
+	task, exists := m.tasks[name]
+	m.mu.RUnlock()
+
+	if exists {
+		task.markRetryFailed()
+	}
+}
+
+// markTaskCrashLoop marks a task that keeps failing as crash-looping
+func (m *Manager) markTaskCrashLoop(name string) error {
+	m.mu.RLock()
+	task, exists := m.tasks[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("task '%s' does not exist", name)
+	}
+
+	task.markCrashLoop()
+	return m.saveRuntimeState()
+}
+
+// StopAllTasks stops every running task, used when the daemon shuts down
+func (m *Manager) StopAllTasks() {
+	m.mu.RLock()
+	running := make(map[string]*Task)
+	for name, task := range m.tasks {
+		if task.IsRunning() {
+			running[name] = task
+		}
+	}
+	m.mu.RUnlock()
+
+	// Stop the processes in parallel, so shutdown takes at most the longest stop
+	// timeout of each dependency level. Like stopDependents, a task is stopped
+	// once the tasks depending on it have stopped.
+	names := make([]string, 0, len(running))
+	for name := range running {
+		names = append(names, name)
+	}
+	order := m.sortByDependencyOrder(names)
+	first := stoppedFirst(order, m.taskConfigs())
+	stopped := make(map[string]chan struct{}, len(order))
+	for _, name := range order {
+		stopped[name] = make(chan struct{})
+	}
+	
+	var wg sync.WaitGroup
+	for _, name := range order {
+		wg.Add(1)
+		go func(name string, task *Task) {
+			defer wg.Done()
+			defer close(stopped[name])
+			for _, dependent := range first[name] {
+				<-stopped[dependent]
+			}
+			if err := task.StopWithOptions(StopOptions{Reason: StopReasonShutdown}); err != nil {
+				fmt.Printf("Warning: failed to stop task %s during shutdown: %v\n", name, err)
+			}
+		}(name, running[name])
+	}
+	wg.Wait()
+	
+	// Record the stops one at a time, they rewrite the runtime state file
+	for name, task := range running {
+		m.setTaskStoppedByTaskd(name, true)
+		m.recordTaskRuns(task)
+	}
+	m.saveRuntimeState()
+}
+
+// isSupervising reports whether the task process is owned by this manager,
+// in which case its exit is observed directly through cmd.Wait()
+func (m *Manager) isSupervising(name string) bool {
+	m.mu.RLock()
+	task, exists := m.tasks[name]
+	m.mu.RUnlock()
+
+	return exists && task.IsRunning()
+}
+
+// syncTaskConfig makes the in-memory task match its configuration file, so a
+// long-running daemon picks up tasks added, edited or deleted by the CLI
+func (m *Manager) syncTaskConfig(name string) error {
+	if m.builtinHandler.IsBuiltinTask(name) {
+		return nil
+	}
+
+	configPath := filepath.Join(taskdconfig.GetTaskDTasksDir(), name+".toml")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.tasks[name]
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		// Task was deleted, forget it unless it is still running
+		if exists && !existing.IsRunning() {
+			delete(m.tasks, name)
+		}
+		return nil
+	}
+
+	var config Config
+	if err := DecodeConfigFile(configPath, &config); err != nil {
+		return fmt.Errorf("failed to load task configuration: %w", err)
+	}
+
+	if exists {
+		// Keep the task instance (and its process), the configuration applies on next start
+		existing.setConfig(&config)
+		return nil
+	}
+
+	task := NewTask(name, &config)
+	task.SetExitCallback(m.onTaskExit)
+	task.SetHealthCallback(m.onTaskHealthChange)
+	m.tasks[name] = task
+
+	return nil
+}
+
 // startBuiltinTask starts a builtin task
 func (m *Manager) startBuiltinTask(name string) error {
 	if name == "taskd" {
@@ -259,7 +487,7 @@ func (m *Manager) stopBuiltinTask(name string) error {
 func (m *Manager) getBuiltinTaskStatus(name string) (*TaskInfo, error) {
 	if name == "taskd" {
 		// Get daemon status from runtime state
-		state := m.loadRuntimeState()
+		state := m.knownRuntimeState()
 		daemonInfo, exists := state.Tasks["taskd"]
 		
 		if !exists {
@@ -275,7 +503,7 @@ func (m *Manager) getBuiltinTaskStatus(name string) (*TaskInfo, error) {
 		
 		// Check if the daemon is actually running
 		daemonManager := GetDaemonManager()
-		isRunning := daemonManager.IsRunning()
+		isRunning := m.isDaemonMode() || daemonManager.IsRunning()
 		
 		status := "stopped"
 		if isRunning {
@@ -286,7 +514,7 @@ func (m *Manager) getBuiltinTaskStatus(name string) (*TaskInfo, error) {
 			Name:       "taskd",
 			Status:     status,
 			PID:        daemonInfo.PID,
-			StartTime:  daemonInfo.StartTime.Format("2006-01-02 15:04:05"),
+			StartTime:  formatInfoTime(daemonInfo.StartTime),
 			Executable: "taskd --daemon",
 			ExitCode:   daemonInfo.ExitCode,
 		}, nil
@@ -313,13 +541,13 @@ func (m *Manager) getBuiltinTaskDetailInfo(name string) (*TaskDetailInfo, error)
 		if exists {
 			// Check if the daemon is actually running
 			daemonManager := GetDaemonManager()
-			isRunning := daemonManager.IsRunning()
+			isRunning := m.isDaemonMode() || daemonManager.IsRunning()
 			
 			if isRunning {
 				status = "running"
 			}
 			pid = daemonInfo.PID
-			startTime = daemonInfo.StartTime.Format("2006-01-02 15:04:05")
+			startTime = formatInfoTime(daemonInfo.StartTime)
 		}
 		
 		return &TaskDetailInfo{
@@ -340,7 +568,7 @@ func (m *Manager) getBuiltinTaskDetailInfo(name string) (*TaskDetailInfo, error)
 	return nil, fmt.Errorf("unknown builtin task: %s", name)
 }
 
-func (m *Manager) stopTask(name string) error {
+func (m *Manager) stopTask(name string, opts StopOptions) error {
 	// Check if this is a builtin task
 	if m.builtinHandler.IsBuiltinTask(name) {
 		// For builtin tasks, we need to handle them specially
@@ -355,10 +583,24 @@ func (m *Manager) stopTask(name string) error {
 		return fmt.Errorf("task '%s' does not exist", name)
 	}
 
-	err := task.Stop()
+	// Tasks that require this one are stopped first, or keep it running
+	if opts.Cascade {
+		if err := m.stopDependents(name, opts); err != nil {
+			return err
+		}
+	} else if dependents := m.runningDependents(name); len(dependents) > 0 {
+		names := make([]string, len(dependents))
+		for i, dependent := range dependents {
+			names[i] = dependent.name
+		}
+		return fmt.Errorf("task '%s' is required by running tasks: %s (stop them first or use --cascade)", name, strings.Join(names, ", "))
+	}
+
+	err := task.StopWithOptions(opts)
 	
 	// Set StoppedByTaskd flag when manually stopping a task
 	m.setTaskStoppedByTaskd(name, true)
+	m.recordTaskRuns(task)
 	
 	// Always save runtime state after stop attempt, regardless of success
 	// This ensures that even if the task was already stopped, the state is consistent
@@ -405,9 +647,10 @@ func (m *Manager) restartTask(name string) error {
 
 	// Stop the task if it's running
 	if task.IsRunning() {
-		if err := task.Stop(); err != nil {
+		if err := task.StopWithOptions(StopOptions{Reason: StopReasonRestart}); err != nil {
 			return fmt.Errorf("failed to stop task before restart: %w", err)
 		}
+		m.recordTaskRuns(task)
 
 		// Wait a moment for the process to fully stop
 		time.Sleep(100 * time.Millisecond)
@@ -432,25 +675,32 @@ func (m *Manager) restartTask(name string) error {
 }
 
 func (m *Manager) removeTask(name string) error {
+	// Detach the task first, stopping it may take up to its stop timeout and
+	// must not block the other tasks meanwhile
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	task, exists := m.tasks[name]
 	if !exists {
+		m.mu.Unlock()
 		return fmt.Errorf("task '%s' does not exist", name)
 	}
+	delete(m.tasks, name)
+	m.mu.Unlock()
 
 	// Stop the task if it's running
 	if task.IsRunning() {
 		if err := task.Stop(); err != nil {
+			// Keep the task, unless another one was added under its name meanwhile
+			m.mu.Lock()
+			if _, taken := m.tasks[name]; !taken {
+				m.tasks[name] = task
+			}
+			m.mu.Unlock()
 			return fmt.Errorf("failed to stop task before removal: %w", err)
 		}
 	}
+	m.recordTaskRuns(task)
 
-	// Remove the task from the manager
-	delete(m.tasks, name)
-
-	// Save runtime state after removal
+	// Save runtime state after removal, the snapshot of the tasks takes m.mu
 	m.saveRuntimeState()
 
 	return nil
@@ -475,7 +725,7 @@ func (m *Manager) loadTasks() error {
 			configPath := filepath.Join(tasksDir, entry.Name())
 			var config Config
 
-			if _, err := toml.DecodeFile(configPath, &config); err != nil {
+			if err := DecodeConfigFile(configPath, &config); err != nil {
 				continue // skip invalid config files
 			}
 
@@ -486,6 +736,7 @@ func (m *Manager) loadTasks() error {
 			task := NewTask(taskName, &config)
 			// Set exit callback to update runtime state when task exits
 			task.SetExitCallback(m.onTaskExit)
+			task.SetHealthCallback(m.onTaskHealthChange)
 
 			// Restore runtime state if available
 			if runtimeInfo, exists := runtimeState.Tasks[taskName]; exists {
@@ -506,19 +757,38 @@ func (m *Manager) onTaskExit(taskName string) {
 		// Use a goroutine to avoid potential deadlocks
 		time.Sleep(100 * time.Millisecond) // Small delay to ensure task state is updated
 		m.saveRuntimeState()
+		
+		m.mu.RLock()
+		handler := m.exitHandler
+		task := m.tasks[taskName]
+		m.mu.RUnlock()
+		if task != nil {
+			m.recordTaskRuns(task)
+		}
+		if handler != nil {
+			handler(taskName)
+		}
 	}()
 }
 
-func (m *Manager) loadRuntimeState() *RuntimeState {
-	statePath := taskdconfig.GetTaskDRuntimeFile()
-
-	data, err := os.ReadFile(statePath)
-	if err != nil {
-		return &RuntimeState{Tasks: make(map[string]*TaskRuntimeInfo)}
+// recordTaskRuns writes the ended runs of a task to its run history
+// Only the daemon writes the history: a CLI that adopted a running task
+// observes the same exit as the daemon.
+func (m *Manager) recordTaskRuns(task *Task) {
+	runs := task.takeFinishedRuns()
+	if !m.isDaemonMode() {
+		return
+	}
+	for _, run := range runs {
+		if err := AppendRunRecord(run); err != nil {
+			fmt.Printf("Warning: failed to record run of task %s: %v\n", run.Task, err)
+		}
 	}
+}
 
-	var state RuntimeState
-	if err := json.Unmarshal(data, &state); err != nil {
+func (m *Manager) loadRuntimeState() *RuntimeState {
+	state, err := m.stateStore().GetRuntimeState()
+	if err != nil {
 		return &RuntimeState{Tasks: make(map[string]*TaskRuntimeInfo)}
 	}
 
@@ -526,103 +796,184 @@ func (m *Manager) loadRuntimeState() *RuntimeState {
 		state.Tasks = make(map[string]*TaskRuntimeInfo)
 	}
 
-	return &state
+	m.cacheRuntimeState(state)
+	return state
 }
 
-// cleanupRuntimeState removes stale entries from runtime state
-func (m *Manager) cleanupRuntimeState() error {
-	statePath := taskdconfig.GetTaskDRuntimeFile()
+// cacheRuntimeState keeps a copy of the runtime state read or written by this process
+func (m *Manager) cacheRuntimeState(state *RuntimeState) {
+	tasks := make(map[string]*TaskRuntimeInfo, len(state.Tasks))
+	for name, info := range state.Tasks {
+		if info != nil {
+			copied := *info
+			tasks[name] = &copied
+		}
+	}
 
-	// Load current state
-	state := m.loadRuntimeState()
+	m.stateCacheMu.Lock()
+	m.stateCache = &RuntimeState{Tasks: tasks}
+	m.stateCacheMu.Unlock()
+}
 
-	// Update each task's runtime info instead of removing stopped tasks
-	updatedTasks := make(map[string]*TaskRuntimeInfo)
-	for name, info := range state.Tasks {
-		if task, exists := m.tasks[name]; exists {
-			// Get current runtime info from the task
-			if currentInfo := task.GetRuntimeInfo(); currentInfo != nil {
-				updatedTasks[name] = currentInfo
-			} else {
-				// Keep the old info if we can't get current info
-				updatedTasks[name] = info
-			}
-		} else if m.builtinHandler.IsBuiltinTask(name) {
-			// Keep builtin tasks in runtime state even if they're not in m.tasks
-			// Builtin tasks don't have .toml files and are managed differently
-			updatedTasks[name] = info
-		} else {
-			// Task no longer exists in manager and is not builtin, remove from runtime state
-			// This handles the case where regular tasks are deleted
+// knownRuntimeState returns the runtime state, without reading it in the daemon
+// The daemon reads the state on every monitor tick and writes it on every change
+// it makes, so its last copy is at most a tick behind the commands of the CLI.
+// Reading the state each time would lock it for every refresh of list and top.
+// The returned state is shared and must not be modified.
+func (m *Manager) knownRuntimeState() *RuntimeState {
+	if m.isDaemonMode() {
+		m.stateCacheMu.Lock()
+		cached := m.stateCache
+		m.stateCacheMu.Unlock()
+		if cached != nil {
+			return cached
+		}
+	}
+	return m.loadRuntimeState()
+}
+
+// stateStore returns the runtime state store of the current TaskD home and
+// the backend selected in the global configuration
+func (m *Manager) stateStore() StateUpdater {
+	backend := taskdconfig.GetStateBackend()
+	home := taskdconfig.GetTaskDHome()
+
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
+	if m.store == nil || m.storeKey != backend+"\x00"+home {
+		m.store, _ = NewStateStore(backend)
+		m.storeKey = backend + "\x00" + home
+	}
+	return m.store
+}
+
+// updateRuntimeState applies fn to the runtime state under the state file lock
+// fn must not call loadRuntimeState or take m.mu, see FileStateManager.
+func (m *Manager) updateRuntimeState(fn func(state *RuntimeState) error) error {
+	// Errors of fn are not write failures
+	var fnErr error
+	err := m.stateStore().Update(func(state *RuntimeState) error {
+		if fnErr = fn(state); fnErr == nil {
+			// Cached under the state lock, so concurrent updates are cached in order
+			m.cacheRuntimeState(state)
 		}
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		m.metrics.stateWriteFailed()
 	}
+	return err
+}
 
-	// Add any new tasks that aren't in the runtime state yet
+// snapshotRuntimeInfo returns the runtime information of every task
+// It is taken before the state file is locked, see updateRuntimeState, and
+// must not be called with m.mu held.
+func (m *Manager) snapshotRuntimeInfo() map[string]*TaskRuntimeInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make(map[string]*TaskRuntimeInfo)
 	for name, task := range m.tasks {
-		if _, exists := updatedTasks[name]; !exists {
-			if runtimeInfo := task.GetRuntimeInfo(); runtimeInfo != nil {
-				updatedTasks[name] = runtimeInfo
-			}
+		if info := task.GetRuntimeInfo(); info != nil {
+			infos[name] = info
 		}
 	}
+	return infos
+}
 
-	// Save updated state
-	state.Tasks = updatedTasks
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal runtime state: %w", err)
+// CleanupRuntimeState removes the runtime state of tasks that are no longer configured
+// The daemon calls it when it starts; the CLI never rewrites the state when it
+// loads the tasks. The remaining entries are kept as loaded, with the flags
+// (StoppedByTaskd, RetryNum) other processes set.
+func (m *Manager) CleanupRuntimeState() error {
+	m.mu.RLock()
+	configured := make(map[string]bool, len(m.tasks))
+	for name := range m.tasks {
+		configured[name] = true
 	}
+	m.mu.RUnlock()
 
-	return os.WriteFile(statePath, data, 0644)
+	return m.updateRuntimeState(func(state *RuntimeState) error {
+		for name := range state.Tasks {
+			// Builtin tasks don't have .toml files and are managed differently
+			if !configured[name] && !m.builtinHandler.IsBuiltinTask(name) {
+				delete(state.Tasks, name)
+			}
+		}
+		return nil
+	})
 }
 
 func (m *Manager) saveRuntimeState() error {
-	statePath := taskdconfig.GetTaskDRuntimeFile()
+	current := m.snapshotRuntimeInfo()
 
-	// Load current state to preserve builtin task information and user-set flags
-	currentState := m.loadRuntimeState()
-	state := &RuntimeState{Tasks: make(map[string]*TaskRuntimeInfo)}
+	return m.updateRuntimeState(func(currentState *RuntimeState) error {
+		// The current state preserves builtin task information and user-set flags
+		tasks := make(map[string]*TaskRuntimeInfo)
 
-	// Add regular tasks
-	for name, task := range m.tasks {
-		info := task.GetRuntimeInfo()
-		if info != nil {
+		// Add regular tasks
+		for name, info := range current {
 			// Check if we have existing runtime info with user-set flags
 			if existingInfo, exists := currentState.Tasks[name]; exists {
 				// Preserve user-set flags like StoppedByTaskd and RetryNum
 				info.StoppedByTaskd = existingInfo.StoppedByTaskd
 				info.RetryNum = existingInfo.RetryNum
 			}
-			state.Tasks[name] = info
+			tasks[name] = info
 		}
-	}
 
-	// Preserve builtin task information from current state
-	for name, info := range currentState.Tasks {
-		if m.builtinHandler.IsBuiltinTask(name) {
-			// Keep builtin task info from current state
-			state.Tasks[name] = info
+		// Preserve builtin task information from current state
+		for name, info := range currentState.Tasks {
+			if m.builtinHandler.IsBuiltinTask(name) {
+				// Keep builtin task info from current state
+				tasks[name] = info
+			}
 		}
-	}
 
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal runtime state: %w", err)
+		currentState.Tasks = tasks
+		return nil
+	})
+}
+
+// requestStateSave saves the runtime state in the background
+// A single saver writes the state; requests made while it runs are coalesced
+// into one more save, so bursts of changes don't pile up writers.
+func (m *Manager) requestStateSave() {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+	if m.saving {
+		m.savePending = true
+		return
 	}
+	m.saving = true
+	go m.runStateSaver()
+}
 
-	return os.WriteFile(statePath, data, 0644)
+// runStateSaver saves the runtime state until no save is pending
+func (m *Manager) runStateSaver() {
+	for {
+		m.saveRuntimeState()
+
+		m.saveMu.Lock()
+		if !m.savePending {
+			m.saving = false
+			m.saveMu.Unlock()
+			return
+		}
+		m.savePending = false
+		m.saveMu.Unlock()
+	}
 }
 
 // saveRuntimeStateWithData saves the given runtime state data
 func (m *Manager) saveRuntimeStateWithData(state *RuntimeState) error {
-	statePath := taskdconfig.GetTaskDRuntimeFile()
-
-	data, err := json.MarshalIndent(state, "", "  ")
+	err := m.stateStore().SaveRuntimeState(state)
 	if err != nil {
-		return fmt.Errorf("failed to marshal runtime state: %w", err)
+		m.metrics.stateWriteFailed()
+		return err
 	}
-
-	return os.WriteFile(statePath, data, 0644)
+	m.cacheRuntimeState(state)
+	return nil
 }
 
 // GetTaskDetailInfo get detailed task information (replaces GetTaskStatus)
@@ -645,13 +996,14 @@ func (m *Manager) ReloadTask(name string) error {
 	configPath := filepath.Join(taskdconfig.GetTaskDTasksDir(), name+".toml")
 	var config Config
 
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
+	if err := DecodeConfigFile(configPath, &config); err != nil {
 		return fmt.Errorf("failed to load task configuration: %w", err)
 	}
 
 	// Create new task instance
 	newTask := NewTask(name, &config)
 	newTask.SetExitCallback(m.onTaskExit)
+	newTask.SetHealthCallback(m.onTaskHealthChange)
 
 	// Replace the existing task
 	m.tasks[name] = newTask
@@ -659,6 +1011,16 @@ func (m *Manager) ReloadTask(name string) error {
 	return nil
 }
 
+// loadTaskConfigFile loads the configuration file of a task
+func loadTaskConfigFile(name string) (*Config, error) {
+	configPath := filepath.Join(taskdconfig.GetTaskDTasksDir(), name+".toml")
+	var config Config
+	if err := DecodeConfigFile(configPath, &config); err != nil {
+		return nil, fmt.Errorf("failed to load task configuration: %w", err)
+	}
+	return &config, nil
+}
+
 func (m *Manager) getTaskDetailInfo(name string) (*TaskDetailInfo, error) {
 	// Ensure daemon is running if needed
 	if err := m.ensureDaemonForCommand(); err != nil {
@@ -679,12 +1041,21 @@ func (m *Manager) getTaskDetailInfo(name string) (*TaskDetailInfo, error) {
 		return nil, fmt.Errorf("task '%s' does not exist", name)
 	}
 
-	// Get basic task info
+	// Get basic task info, and one snapshot of the configuration, which an
+	// edit may replace meanwhile
 	basicInfo := task.GetInfo()
+	config := task.getConfig()
 
+	// Expand variables as the running process, or the next start, uses them
+	resolved, expandErr := task.resolvedConfig()
+	ioConfig := resolved
+	if expandErr != nil {
+		ioConfig = config
+	}
+	
 	// Get IO info
 	ioManager := GetIOManager()
-	ioInfo, err := ioManager.GetTaskIOInfo(task.config)
+	ioInfo, err := ioManager.GetTaskIOInfo(ioConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get IO info: %w", err)
 	}
@@ -695,96 +1066,177 @@ func (m *Manager) getTaskDetailInfo(name string) (*TaskDetailInfo, error) {
 		Status:      basicInfo.Status,
 		PID:         basicInfo.PID,
 		StartTime:   basicInfo.StartTime,
-		Executable:  basicInfo.Executable,
+		Executable:  config.Executable,
 		ExitCode:    basicInfo.ExitCode,
 		LastError:   basicInfo.LastError,
-		DisplayName: task.config.DisplayName,
-		Description: task.config.Description,
-		WorkDir:     task.config.WorkDir,
-		Args:        task.config.Args,
-		Env:         task.config.Env,
-		InheritEnv:  task.config.InheritEnv,
+		DisplayName: config.DisplayName,
+		Description: config.Description,
+		WorkDir:     config.WorkDir,
+		Args:        config.Args,
+		Shell:       config.Shell,
+		Env:         config.Env,
+		EnvFile:     config.EnvFile,
+		InheritEnv:  config.InheritEnv,
+		Stdin:       config.Stdin,
+		Stdout:      config.Stdout,
+		Stderr:      config.Stderr,
 		IOInfo:      ioInfo,
 	}
+	if expandErr == nil {
+		// Secret and env file values never leave the process that starts the task
+		var env []string
+		if env, expandErr = resolved.displayEnv(); expandErr == nil &&
+			(!reflect.DeepEqual(resolved, config) || strings.Join(env, "\n") != strings.Join(config.Env, "\n")) {
+			detailInfo.Resolved = &ResolvedConfig{
+				Executable: resolved.Executable,
+				Args:       resolved.Args,
+				WorkDir:    resolved.WorkDir,
+				Env:        env,
+				EnvFile:    resolvedEnvFiles(resolved),
+			}
+		}
+	}
+	if expandErr != nil {
+		detailInfo.ExpandError = expandErr.Error()
+	}
 
-	return detailInfo, nil
-}
+	// Add restart policy information
+	detailInfo.RestartPolicy = config.RestartPolicyName()
+	detailInfo.MaxRetry = config.MaxRestarts()
+	detailInfo.RestartDelay = config.RestartDelay().String()
+	detailInfo.MaxRestartDelay = config.MaxRestartDelay().String()
+	detailInfo.BackoffFactor = config.RestartBackoffFactor()
+	detailInfo.Jitter = config.Restart.Jitter
+	detailInfo.ResetAfter = config.RestartResetAfter().String()
+	if runtimeInfo, exists := m.loadRuntimeState().Tasks[name]; exists {
+		detailInfo.RetryNum = runtimeInfo.RetryNum
+	}
 
-// resetTaskRetryCount resets the retry count for a task
-func (m *Manager) resetTaskRetryCount(taskName string) {
-	state := m.loadRuntimeState()
-	if state.Tasks == nil {
-		return
+	detailInfo.ChildPIDs = task.descendantPIDs()
+	
+	// Add stop behaviour
+	detailInfo.StopSignal = config.StopSignalName()
+	detailInfo.StopTimeout = config.StopTimeoutDuration().String()
+	
+	// Add schedule
+	detailInfo.Schedule = basicInfo.Schedule
+	detailInfo.LastScheduledRun = basicInfo.LastScheduledRun
+	detailInfo.NextScheduledRun = basicInfo.NextScheduledRun
+	if config.Schedule != "" {
+		detailInfo.ScheduleOverlap = config.ScheduleOverlapPolicy()
+		detailInfo.ScheduleJitter = config.ScheduleJitter
 	}
 	
-	runtimeInfo, exists := state.Tasks[taskName]
-	if !exists {
-		return
+	// Add dependencies
+	detailInfo.Requires = config.Requires
+	detailInfo.Wants = config.Wants
+	detailInfo.After = config.After
+	detailInfo.RequiredBy = requiredBy(name, m.taskConfigs())
+	
+	// Add readiness probe
+	if probe := config.Readiness; probe.Kind() != "" {
+		detailInfo.Readiness = probe.Kind() + " " + probe.Target()
+		detailInfo.ReadinessTimeout = probe.TimeoutDuration().String()
+		detailInfo.ReadinessInterval = probe.IntervalDuration().String()
 	}
 	
-	// Reset retry count
-	runtimeInfo.RetryNum = 0
+	// Add liveness probe and its last check
+	if probe := config.Liveness; probe.Kind() != "" {
+		detailInfo.Liveness = probe.Kind() + " " + probe.Target()
+		detailInfo.LivenessInterval = probe.IntervalDuration().String()
+		detailInfo.LivenessTimeout = probe.TimeoutDuration().String()
+		detailInfo.FailureThreshold = probe.Threshold()
+		detailInfo.Health = basicInfo.Health
+		failures, probeTime, output := task.lastLivenessCheck()
+		detailInfo.HealthFailures = failures
+		detailInfo.LastProbeTime = formatInfoTime(probeTime)
+		detailInfo.LastProbeOutput = output
+	}
+	
+	detailInfo.Limits = config.Limits.String()
+	detailInfo.Usage = basicInfo.Usage
+
+	return detailInfo, nil
+}
+
+// resetTaskRetryCount resets the retry count for a task
+func (m *Manager) resetTaskRetryCount(taskName string) {
+	err := m.updateRuntimeState(func(state *RuntimeState) error {
+		// Reset retry count
+		if runtimeInfo, exists := state.Tasks[taskName]; exists {
+			runtimeInfo.RetryNum = 0
+		}
+		return nil
+	})
 	
 	// Save updated state
-	if err := m.saveRuntimeStateWithData(state); err != nil {
+	if err != nil {
 		fmt.Printf("Warning: Failed to reset retry count for task %s: %v\n", taskName, err)
 	}
 }
 
+// errTaskNotInManager is returned by setTaskStoppedByTaskd's update when the task is unknown
+var errTaskNotInManager = errors.New("task not found in manager")
+
 // setTaskStoppedByTaskd sets the StoppedByTaskd flag for a task
 func (m *Manager) setTaskStoppedByTaskd(taskName string, stoppedByTaskd bool) {
-	state := m.loadRuntimeState()
-	if state.Tasks == nil {
-		state.Tasks = make(map[string]*TaskRuntimeInfo)
+	// The task is looked up before the state file is locked
+	m.mu.RLock()
+	task, taskExists := m.tasks[taskName]
+	m.mu.RUnlock()
+	
+	var taskInfo *TaskRuntimeInfo
+	if taskExists {
+		taskInfo = task.GetRuntimeInfo()
 	}
 	
-	runtimeInfo, exists := state.Tasks[taskName]
-	if !exists {
-		// If task doesn't exist in runtime state, create a new entry
-		// This can happen if the task was just started and hasn't been saved yet
-		m.mu.RLock()
-		task, taskExists := m.tasks[taskName]
-		m.mu.RUnlock()
-		
-		if taskExists {
-			// Get current task info and create runtime info
-			taskInfo := task.GetInfo()
-			runtimeInfo = &TaskRuntimeInfo{
-				Name:           taskName,
-				Status:         taskInfo.Status,
-				PID:            taskInfo.PID,
-				StartTime:      task.startTime,
-				EndTime:        task.endTime,
-				ExitCode:       taskInfo.ExitCode,
-				StoppedByTaskd: false,
-				RetryNum:       0,
+	err := m.updateRuntimeState(func(state *RuntimeState) error {
+		runtimeInfo, exists := state.Tasks[taskName]
+		if !exists {
+			// If task doesn't exist in runtime state, create a new entry
+			// This can happen if the task was just started and hasn't been saved yet
+			if taskInfo == nil {
+				// Task doesn't exist in manager either, can't set flag
+				return errTaskNotInManager
 			}
+			runtimeInfo = taskInfo
+			runtimeInfo.StoppedByTaskd = false
+			runtimeInfo.RetryNum = 0
 			state.Tasks[taskName] = runtimeInfo
-		} else {
-			// Task doesn't exist in manager either, can't set flag
-			fmt.Printf("Warning: Task %s not found in manager, cannot set StoppedByTaskd flag\n", taskName)
-			return
 		}
-	}
-	
-	// Set StoppedByTaskd flag
-	runtimeInfo.StoppedByTaskd = stoppedByTaskd
+		
+		// Set StoppedByTaskd flag
+		runtimeInfo.StoppedByTaskd = stoppedByTaskd
+		
+		// If manually stopped, also update end time and status
+		if stoppedByTaskd {
+			runtimeInfo.EndTime = time.Now()
+			runtimeInfo.Status = "stopped"
+			runtimeInfo.PID = 0
+			runtimeInfo.ProcessStartTime = 0
+			runtimeInfo.Executable = ""
+		}
+		return nil
+	})
 	
-	// If manually stopped, also update end time and status
-	if stoppedByTaskd {
-		runtimeInfo.EndTime = time.Now()
-		runtimeInfo.Status = "stopped"
-		runtimeInfo.PID = 0
+	if errors.Is(err, errTaskNotInManager) {
+		fmt.Printf("Warning: Task %s not found in manager, cannot set StoppedByTaskd flag\n", taskName)
+		return
 	}
 	
 	// Save updated state
-	if err := m.saveRuntimeStateWithData(state); err != nil {
+	if err != nil {
 		fmt.Printf("Warning: Failed to set StoppedByTaskd flag for task %s: %v\n", taskName, err)
 	}
 }
 
 // ensureDaemonForCommand ensures daemon is running when needed
 func (m *Manager) ensureDaemonForCommand() error {
+	// The daemon never needs to start itself
+	if m.isDaemonMode() {
+		return nil
+	}
+	
 	// Check if daemon is needed
 	if m.needsDaemon() {
 		daemonManager := GetDaemonManager()
@@ -797,7 +1249,7 @@ func (m *Manager) ensureDaemonForCommand() error {
 func (m *Manager) needsDaemon() bool {
 	// Check if there are running tasks or tasks that need auto-start
 	// Daemon is only needed when monitoring running tasks or auto-restarting tasks
-	return m.hasRunningTasks() || m.hasAutoStartTasks()
+	return m.hasRunningTasks() || m.hasAutoStartTasks() || m.hasScheduledTasks()
 }
 
 // hasRunningTasks checks if there are any running tasks
@@ -841,7 +1293,7 @@ func (m *Manager) hasAutoStartTasks() bool {
 				// If task is stopped but not by user, and hasn't reached retry limit, daemon is needed for restart
 				if runtimeInfo.Status == "stopped" && 
 				   !runtimeInfo.StoppedByTaskd &&
-				   (task.config.MaxRetryNum <= 0 || runtimeInfo.RetryNum < task.config.MaxRetryNum) {
+				   (task.config.MaxRestarts() <= 0 || runtimeInfo.RetryNum < task.config.MaxRestarts()) {
 					return true
 				}
 			} else {
@@ -854,6 +1306,25 @@ func (m *Manager) hasAutoStartTasks() bool {
 	return false
 }
 
+// hasScheduledTasks checks if there are tasks the daemon has to start on a schedule
+func (m *Manager) hasScheduledTasks() bool {
+	return len(m.scheduledTasks()) > 0
+}
+
+// scheduledTasks returns the tasks that have a schedule
+func (m *Manager) scheduledTasks() map[string]*Task {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	scheduled := make(map[string]*Task)
+	for name, task := range m.tasks {
+		if task.getConfig().Schedule != "" {
+			scheduled[name] = task
+		}
+	}
+	return scheduled
+}
+
 // hasAnyTasks checks if there are any configured tasks
 func (m *Manager) hasAnyTasks() bool {
 	m.mu.RLock()
@@ -1,12 +1,32 @@
 package task
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
+
+	taskdconfig "taskd/internal/config"
 )
 
+// echoCommand returns a short-lived command that works on the current platform
+func echoCommand() (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/c", "echo", "test"}
+	}
+	return "sh", []string{"-c", "echo test"}
+}
+
+// longRunningCommand returns a command that keeps running until it is stopped
+func longRunningCommand() (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/c", "ping", "-t", "127.0.0.1"}
+	}
+	return "sh", []string{"-c", "sleep 60"}
+}
+
 func TestGetManager(t *testing.T) {
 	manager1 := GetManager()
 	manager2 := GetManager()
@@ -739,9 +759,10 @@ func TestManagerStartTaskDaemonIntegration(t *testing.T) {
 	manager := GetManager()
 	
 	// Create a test task that will start successfully
+	executable, args := echoCommand()
 	testConfig := &Config{
-		Executable: "cmd",
-		Args:       []string{"/c", "echo", "test"},
+		Executable: executable,
+		Args:       args,
 		AutoStart:  true,
 	}
 	
@@ -797,9 +818,10 @@ func TestManagerRestartTaskDaemonIntegration(t *testing.T) {
 	manager := GetManager()
 	
 	// Create a test task that will restart successfully
+	executable, args := echoCommand()
 	testConfig := &Config{
-		Executable: "cmd",
-		Args:       []string{"/c", "echo", "test"},
+		Executable: executable,
+		Args:       args,
 		AutoStart:  true,
 	}
 	
@@ -1008,9 +1030,10 @@ func TestManagerStopTaskSetsStoppedByTaskdFlag(t *testing.T) {
 	manager := GetManager()
 	
 	// Create a test task
+	executable, args := longRunningCommand()
 	testConfig := &Config{
-		Executable: "cmd",
-		Args:       []string{"/c", "ping", "-t", "127.0.0.1"},
+		Executable: executable,
+		Args:       args,
 	}
 	
 	// Add task to manager
@@ -1038,7 +1061,7 @@ func TestManagerStopTaskSetsStoppedByTaskdFlag(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 	
 	// Stop the task
-	err = manager.stopTask("test-stop-task")
+	err = manager.stopTask("test-stop-task", StopOptions{})
 	if err != nil {
 		t.Fatalf("Failed to stop task: %v", err)
 	}
@@ -1136,4 +1159,142 @@ func TestManagerSaveRuntimeStatePreservesUserFlags(t *testing.T) {
 	if taskInfo.RetryNum != 5 {
 		t.Errorf("RetryNum should be preserved as 5, got: %d", taskInfo.RetryNum)
 	}
-}
\ No newline at end of file
+}
+func TestManagerCleanupRuntimeState(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	manager := &Manager{
+		tasks:          map[string]*Task{"kept": NewTask("kept", &Config{Executable: "sleep"})},
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+
+	initialState := &RuntimeState{
+		Tasks: map[string]*TaskRuntimeInfo{
+			"kept":    {Name: "kept", Status: "stopped", ExitCode: 1, StoppedByTaskd: true, RetryNum: 2},
+			"deleted": {Name: "deleted", Status: "stopped"},
+			"taskd":   {Name: "taskd", Status: "running"},
+		},
+	}
+	if err := manager.saveRuntimeStateWithData(initialState); err != nil {
+		t.Fatalf("Failed to save initial state: %v", err)
+	}
+
+	if err := manager.CleanupRuntimeState(); err != nil {
+		t.Fatalf("CleanupRuntimeState() = %v", err)
+	}
+
+	state := manager.loadRuntimeState()
+	if _, exists := state.Tasks["deleted"]; exists {
+		t.Error("runtime state of a deleted task should be removed")
+	}
+	if _, exists := state.Tasks["taskd"]; !exists {
+		t.Error("runtime state of the builtin taskd task should be kept")
+	}
+	kept := state.Tasks["kept"]
+	if kept == nil || !kept.StoppedByTaskd || kept.RetryNum != 2 || kept.ExitCode != 1 {
+		t.Errorf("runtime state of a configured task = %+v, want it unchanged", kept)
+	}
+}
+
+func TestManagerSaveRuntimeStateWhileTasksChange(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	manager := &Manager{
+		tasks:          make(map[string]*Task),
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+
+	// Health changes save the state while the daemon syncs task configurations
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			manager.onTaskHealthChange("web")
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("task-%d", i)
+		writeTestTaskConfig(t, name, &Config{Executable: "sleep"})
+		if err := manager.syncTaskConfig(name); err != nil {
+			t.Fatalf("syncTaskConfig(%s) = %v", name, err)
+		}
+	}
+	<-done
+
+	// The saves are coalesced into a single writer that stops once they are done
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		manager.saveMu.Lock()
+		saving := manager.saving
+		manager.saveMu.Unlock()
+		if !saving {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("state saver still running after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// countingStateStore is a state store counting its reads
+type countingStateStore struct {
+	StateUpdater
+	reads int
+}
+
+func (s *countingStateStore) GetRuntimeState() (*RuntimeState, error) {
+	s.reads++
+	return s.StateUpdater.GetRuntimeState()
+}
+
+func TestManagerListTasksInDaemonDoesNotReadState(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TASKD_HOME", home)
+	store := &countingStateStore{StateUpdater: NewFileStateManager(filepath.Join(home, "runtime.json"))}
+	manager := &Manager{
+		tasks:          map[string]*Task{"web": NewTask("web", &Config{Executable: "sleep 30"})},
+		builtinHandler: NewBuiltinTaskHandler(),
+		store:          store,
+		storeKey:       taskdconfig.GetStateBackend() + "\x00" + taskdconfig.GetTaskDHome(),
+	}
+	manager.SetDaemonMode(true)
+
+	err := manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["taskd"] = &TaskRuntimeInfo{Name: "taskd", Status: "running", PID: os.Getpid()}
+		state.Tasks["web"] = &TaskRuntimeInfo{Name: "web", Status: "stopped", RetryNum: 2}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateRuntimeState() = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		tasks, err := manager.listTasks()
+		if err != nil {
+			t.Fatalf("listTasks() = %v", err)
+		}
+		retries := make(map[string]int)
+		for _, info := range tasks {
+			retries[info.Name] = info.RetryNum
+		}
+		if _, ok := retries["taskd"]; !ok || retries["web"] != 2 {
+			t.Fatalf("listTasks() retries = %v, want taskd and web with 2 retries", retries)
+		}
+	}
+
+	// The state written by the daemon is known, every list answers from it
+	if store.reads != 0 {
+		t.Errorf("listTasks() read the runtime state %d times, want 0", store.reads)
+	}
+
+	// A later update is seen by the next list
+	manager.updateRuntimeState(func(state *RuntimeState) error {
+		state.Tasks["web"].RetryNum = 3
+		return nil
+	})
+	tasks, _ := manager.listTasks()
+	for _, info := range tasks {
+		if info.Name == "web" && info.RetryNum != 3 {
+			t.Errorf("web RetryNum = %d after the update, want 3", info.RetryNum)
+		}
+	}
+}
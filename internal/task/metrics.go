@@ -0,0 +1,291 @@
+package task
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsContentType is the content type of the Prometheus text exposition format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsRequestTimeout bounds how long a single scrape may take
+const metricsRequestTimeout = 10 * time.Second
+
+// daemonMetrics counters of the daemon itself, served by the metrics endpoint
+// They start at zero when the daemon starts, as Prometheus expects of counters.
+type daemonMetrics struct {
+	mu                 sync.Mutex
+	restarts           map[string]uint64   // Automatic restarts of each task by the monitor
+	cpu                map[string]*taskCPU // CPU time of each task, see observeCPU
+	tickCount          uint64
+	tickSeconds        float64 // Total duration of the monitor ticks
+	stateWriteFailures uint64
+}
+
+// taskRestarted counts an automatic restart of a task
+func (d *daemonMetrics) taskRestarted(taskName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.restarts == nil {
+		d.restarts = make(map[string]uint64)
+	}
+	d.restarts[taskName]++
+}
+
+// taskCPU the CPU time of a task counted from the usage samples
+type taskCPU struct {
+	total time.Duration // CPU time counted over every run
+	run   time.Time     // Start time of the run of the last sample
+	last  time.Duration // CPU time of the running processes at the last sample
+}
+
+// observeCPU counts the CPU time of the running processes of a task at a sample
+// The CPU time of a run grows between samples, drops when a process of the task
+// exits and takes its CPU time with it, and starts again with every run. Only
+// the increases are counted, so the total never decreases: it covers the exited
+// runs and processes up to their last sample.
+func (d *daemonMetrics) observeCPU(taskName string, run time.Time, cpuTime time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cpu == nil {
+		d.cpu = make(map[string]*taskCPU)
+	}
+	c := d.cpu[taskName]
+	if c == nil {
+		c = &taskCPU{}
+		d.cpu[taskName] = c
+	}
+	if !c.run.Equal(run) {
+		c.run, c.last = run, 0
+	}
+	if cpuTime > c.last {
+		c.total += cpuTime - c.last
+	}
+	c.last = cpuTime
+}
+
+// observeTick records the duration of a monitor tick
+func (d *daemonMetrics) observeTick(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tickCount++
+	d.tickSeconds += duration.Seconds()
+}
+
+// stateWriteFailed counts a runtime state update that could not be written
+func (d *daemonMetrics) stateWriteFailed() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stateWriteFailures++
+}
+
+// taskMetrics values of the metrics of one task
+type taskMetrics struct {
+	name      string
+	up        bool
+	exitCode  int
+	startTime time.Time      // Start of the last run, zero if the task never ran
+	usage     *ResourceUsage // Last usage sample of the running process, nil if not sampled
+}
+
+// metrics returns the values of the metrics of the task
+func (t *Task) metrics() taskMetrics {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return taskMetrics{
+		name:      t.name,
+		up:        t.status == "running",
+		exitCode:  t.exitCode,
+		startTime: t.startTime,
+		usage:     t.usageLocked(),
+	}
+}
+
+// writeMetrics writes the metrics of the tasks and the daemon in the Prometheus text format
+func (m *Manager) writeMetrics(w io.Writer) error {
+	m.mu.RLock()
+	tasks := make([]taskMetrics, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		tasks = append(tasks, task.metrics())
+	}
+	m.mu.RUnlock()
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].name < tasks[j].name })
+
+	m.metrics.mu.Lock()
+	restarts := make(map[string]uint64, len(m.metrics.restarts))
+	for name, count := range m.metrics.restarts {
+		restarts[name] = count
+	}
+	cpuSeconds := make(map[string]float64, len(m.metrics.cpu))
+	for name, c := range m.metrics.cpu {
+		cpuSeconds[name] = c.total.Seconds()
+	}
+	tickCount, tickSeconds := m.metrics.tickCount, m.metrics.tickSeconds
+	stateWriteFailures := m.metrics.stateWriteFailures
+	m.metrics.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+
+	writeMetricHeader(out, "taskd_task_up", "gauge", "Whether the task process is running (1) or not (0).")
+	for _, task := range tasks {
+		up := 0
+		if task.up {
+			up = 1
+		}
+		writeTaskSample(out, "taskd_task_up", task.name, float64(up))
+	}
+
+	writeMetricHeader(out, "taskd_task_restarts_total", "counter", "Automatic restarts of the task by its restart policy since the daemon started.")
+	for _, task := range tasks {
+		writeTaskSample(out, "taskd_task_restarts_total", task.name, float64(restarts[task.name]))
+	}
+
+	writeMetricHeader(out, "taskd_task_last_exit_code", "gauge", "Exit code of the last run of the task, 0 before it first exits.")
+	for _, task := range tasks {
+		writeTaskSample(out, "taskd_task_last_exit_code", task.name, float64(task.exitCode))
+	}
+
+	writeMetricHeader(out, "taskd_task_start_time_seconds", "gauge", "Start time of the last run of the task since the Unix epoch.")
+	for _, task := range tasks {
+		if !task.startTime.IsZero() {
+			writeTaskSample(out, "taskd_task_start_time_seconds", task.name, float64(task.startTime.UnixMilli())/1000)
+		}
+	}
+
+	writeMetricHeader(out, "taskd_task_cpu_seconds_total", "counter", "User and system CPU time of the processes of the task over its runs since the daemon started.")
+	for _, task := range tasks {
+		if seconds, ok := cpuSeconds[task.name]; ok {
+			writeTaskSample(out, "taskd_task_cpu_seconds_total", task.name, seconds)
+		}
+	}
+
+	writeMetricHeader(out, "taskd_task_memory_bytes", "gauge", "Resident memory of the running processes of the task.")
+	for _, task := range tasks {
+		if task.usage != nil {
+			writeTaskSample(out, "taskd_task_memory_bytes", task.name, float64(task.usage.RSS))
+		}
+	}
+
+	writeMetricHeader(out, "taskd_monitor_tick_duration_seconds", "summary", "Duration of the monitor ticks checking, restarting and sampling the tasks.")
+	fmt.Fprintf(out, "taskd_monitor_tick_duration_seconds_sum %s\n", formatMetricValue(tickSeconds))
+	fmt.Fprintf(out, "taskd_monitor_tick_duration_seconds_count %d\n", tickCount)
+
+	writeMetricHeader(out, "taskd_state_write_failures_total", "counter", "Runtime state updates that could not be written.")
+	fmt.Fprintf(out, "taskd_state_write_failures_total %d\n", stateWriteFailures)
+
+	return out.Flush()
+}
+
+// writeMetricHeader writes the HELP and TYPE lines of a metric
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeTaskSample writes a sample of a metric labeled with a task name
+func writeTaskSample(w io.Writer, name, taskName string, value float64) {
+	fmt.Fprintf(w, "%s{task=\"%s\"} %s\n", name, escapeLabelValue(taskName), formatMetricValue(value))
+}
+
+// labelValueEscaper escapes a label value of the text format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes the backslashes, double quotes and line feeds of a label value
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// formatMetricValue formats a sample value, without an exponent for whole numbers
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// MetricsServer serves the metrics of the tasks and the daemon over HTTP (runs in daemon process)
+type MetricsServer struct {
+	address  string
+	manager  *Manager
+	server   *http.Server
+	listener net.Listener
+	mu       sync.Mutex
+}
+
+// NewMetricsServer creates a metrics server listening on a port of the loopback interface
+func NewMetricsServer(manager *Manager, port int) *MetricsServer {
+	return newMetricsServerWithAddress(manager, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+}
+
+// newMetricsServerWithAddress creates a metrics server listening on the given address
+func newMetricsServerWithAddress(manager *Manager, address string) *MetricsServer {
+	return &MetricsServer{
+		address: address,
+		manager: manager,
+	}
+}
+
+// Start starts serving /metrics
+func (s *MetricsServer) Start() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: metricsRequestTimeout,
+		WriteTimeout:      metricsRequestTimeout,
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.server = server
+	s.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+// Addr returns the address the server listens on, empty before Start
+func (s *MetricsServer) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop stops the server and closes its connections
+func (s *MetricsServer) Stop() {
+	s.mu.Lock()
+	server := s.server
+	s.server = nil
+	s.mu.Unlock()
+
+	if server != nil {
+		server.Close()
+	}
+}
+
+// handleMetrics answers a scrape
+func (s *MetricsServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	if err := s.manager.writeMetrics(w); err != nil {
+		fmt.Printf("Metrics: Failed to write metrics: %v\n", err)
+	}
+}
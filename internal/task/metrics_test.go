@@ -0,0 +1,247 @@
+package task
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	taskdconfig "taskd/internal/config"
+)
+
+// getMetrics scrapes a metrics server and returns the response and its body
+func getMetrics(t *testing.T, method string, server *MetricsServer) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, "http://"+server.Addr()+"/metrics", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s /metrics: %v", method, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestMetricsServer(t *testing.T) {
+	start := time.Unix(1760000000, 500*int64(time.Millisecond))
+	web := &Task{name: "web", config: &Config{}, status: "running", startTime: start}
+	web.usage.add(start, start.Add(10*time.Second), processStats{rss: 4096, cpuTime: 1500 * time.Millisecond})
+	job := &Task{name: `job "nightly"`, config: &Config{}, status: "stopped", startTime: start, exitCode: 3}
+	idle := &Task{name: "idle", config: &Config{}, status: "stopped"}
+
+	manager := &Manager{
+		tasks:          map[string]*Task{web.name: web, job.name: job, idle.name: idle},
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+	manager.metrics.observeCPU("web", start, 1500*time.Millisecond)
+	manager.metrics.taskRestarted("web")
+	manager.metrics.taskRestarted("web")
+	manager.metrics.observeTick(250 * time.Millisecond)
+	manager.metrics.observeTick(500 * time.Millisecond)
+	manager.metrics.stateWriteFailed()
+
+	server := newMetricsServerWithAddress(manager, "127.0.0.1:0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	defer server.Stop()
+
+	resp, body := getMetrics(t, http.MethodGet, server)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Content-Type"); got != metricsContentType {
+		t.Errorf("Content-Type = %q, want %q", got, metricsContentType)
+	}
+
+	wantLines := []string{
+		"# TYPE taskd_task_up gauge",
+		`taskd_task_up{task="web"} 1`,
+		`taskd_task_up{task="idle"} 0`,
+		`taskd_task_up{task="job \"nightly\""} 0`,
+		"# TYPE taskd_task_restarts_total counter",
+		`taskd_task_restarts_total{task="web"} 2`,
+		`taskd_task_restarts_total{task="idle"} 0`,
+		`taskd_task_last_exit_code{task="job \"nightly\""} 3`,
+		`taskd_task_start_time_seconds{task="web"} 1760000000.5`,
+		`taskd_task_cpu_seconds_total{task="web"} 1.5`,
+		`taskd_task_memory_bytes{task="web"} 4096`,
+		"# TYPE taskd_monitor_tick_duration_seconds summary",
+		"taskd_monitor_tick_duration_seconds_sum 0.75",
+		"taskd_monitor_tick_duration_seconds_count 2",
+		"taskd_state_write_failures_total 1",
+	}
+	lines := strings.Split(body, "\n")
+	for _, want := range wantLines {
+		found := false
+		for _, line := range lines {
+			if line == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("metrics have no line %q:\n%s", want, body)
+		}
+	}
+
+	// Tasks that never ran or are not running have no start time or usage
+	for _, unwanted := range []string{
+		`taskd_task_start_time_seconds{task="idle"}`,
+		`taskd_task_cpu_seconds_total{task="idle"}`,
+		`taskd_task_memory_bytes{task="job \"nightly\""}`,
+	} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("metrics have %q:\n%s", unwanted, body)
+		}
+	}
+
+	if resp, _ := getMetrics(t, http.MethodPost, server); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+// scrapeCPUSeconds scrapes the CPU time of a task from a metrics server
+func scrapeCPUSeconds(t *testing.T, server *MetricsServer, taskName string) float64 {
+	t.Helper()
+	_, body := getMetrics(t, http.MethodGet, server)
+	prefix := `taskd_task_cpu_seconds_total{task="` + taskName + `"} `
+	for _, line := range strings.Split(body, "\n") {
+		if value, ok := strings.CutPrefix(line, prefix); ok {
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("invalid sample %q: %v", line, err)
+			}
+			return seconds
+		}
+	}
+	t.Fatalf("metrics have no CPU time of %s:\n%s", taskName, body)
+	return 0
+}
+
+func TestCPUSecondsMetricNeverDecreases(t *testing.T) {
+	run1 := time.Unix(1760000000, 0)
+	run2 := run1.Add(time.Minute)
+	web := &Task{name: "web", config: &Config{}, status: "running", startTime: run1}
+	manager := &Manager{
+		tasks:          map[string]*Task{web.name: web},
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+
+	server := newMetricsServerWithAddress(manager, "127.0.0.1:0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	defer server.Stop()
+
+	samples := []struct {
+		name    string
+		run     time.Time
+		cpuTime time.Duration
+		want    float64
+	}{
+		{"first sample", run1, 1500 * time.Millisecond, 1.5},
+		{"run grows", run1, 2 * time.Second, 2},
+		{"child exits", run1, 1200 * time.Millisecond, 2},
+		{"run grows after the exit", run1, 1500 * time.Millisecond, 2.3},
+		{"restart", run2, 300 * time.Millisecond, 2.6},
+		{"restarted run grows", run2, time.Second, 3.3},
+	}
+
+	last := 0.0
+	for _, s := range samples {
+		manager.metrics.observeCPU("web", s.run, s.cpuTime)
+		got := scrapeCPUSeconds(t, server, "web")
+		if got < last {
+			t.Errorf("%s: CPU seconds dropped from %v to %v", s.name, last, got)
+		}
+		if diff := got - s.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: CPU seconds = %v, want %v", s.name, got, s.want)
+		}
+		last = got
+	}
+
+	// The CPU time stays when the task is stopped
+	web.status = "stopped"
+	if got := scrapeCPUSeconds(t, server, "web"); got != last {
+		t.Errorf("CPU seconds of the stopped task = %v, want %v", got, last)
+	}
+}
+
+func TestMetricsServerStop(t *testing.T) {
+	manager := &Manager{tasks: make(map[string]*Task)}
+	server := newMetricsServerWithAddress(manager, "127.0.0.1:0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	addr := server.Addr()
+	server.Stop()
+
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Error("GET /metrics after Stop() succeeded, want an error")
+	}
+	server.Stop() // Stopping twice is harmless
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"web", "web"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\tasks`, `C:\\tasks`},
+		{"two\nlines", `two\nlines`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLabelValue(tt.value); got != tt.want {
+			t.Errorf("escapeLabelValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// failingStateStore is a state store whose writes fail
+type failingStateStore struct {
+	StateUpdater
+}
+
+func (failingStateStore) Update(fn func(state *RuntimeState) error) error {
+	if err := fn(&RuntimeState{Tasks: make(map[string]*TaskRuntimeInfo)}); err != nil {
+		return err
+	}
+	return errors.New("no space left on device")
+}
+
+func (failingStateStore) SaveRuntimeState(state *RuntimeState) error {
+	return errors.New("no space left on device")
+}
+
+func TestStateWriteFailuresMetric(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	manager := &Manager{
+		tasks:    make(map[string]*Task),
+		store:    failingStateStore{},
+		storeKey: taskdconfig.GetStateBackend() + "\x00" + taskdconfig.GetTaskDHome(),
+	}
+
+	if err := manager.updateRuntimeState(func(state *RuntimeState) error { return nil }); err == nil {
+		t.Fatal("updateRuntimeState() = nil, want the write error")
+	}
+	// An error of the update function itself is not a write failure
+	manager.updateRuntimeState(func(state *RuntimeState) error { return errTaskNotInManager })
+	manager.saveRuntimeStateWithData(&RuntimeState{})
+
+	if got := manager.metrics.stateWriteFailures; got != 2 {
+		t.Errorf("state write failures = %d, want 2", got)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package task
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setDetachedProcessAttr configures cmd to run detached from the controlling terminal
+// Setsid starts the process in a new session, which also makes it the leader of
+// a new process group (so Setpgid is implied and must not be set as well).
+// The process therefore does not receive SIGHUP when the terminal is closed.
+func setDetachedProcessAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid: true,
+	}
+}
+
+// isProcessAlive reports whether a process with the given PID exists
+// Signal 0 performs error checking only; EPERM means the process exists but
+// belongs to another user
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,46 @@
+//go:build windows
+
+package task
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcessFlag is the DETACHED_PROCESS creation flag, which is not exported by syscall
+const detachedProcessFlag = 0x00000008
+
+// setDetachedProcessAttr configures cmd to run detached from the parent console
+// DETACHED_PROCESS creates a process without a console window
+// CREATE_NEW_PROCESS_GROUP creates a new process group, so the process
+// survives the terminal that started it being closed
+func setDetachedProcessAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcessFlag,
+		HideWindow:    true,
+	}
+}
+
+// processQueryLimitedInformation is the PROCESS_QUERY_LIMITED_INFORMATION access right
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code reported by GetExitCodeProcess for a running process
+const stillActive = 259
+
+// isProcessAlive reports whether a process with the given PID exists
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActive
+}
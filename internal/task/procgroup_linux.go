@@ -0,0 +1,285 @@
+//go:build linux
+
+package task
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// listProcesses reads the process table from /proc
+func listProcesses() ([]processEntry, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []processEntry
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue // The process exited meanwhile
+		}
+		if p, ok := parseProcStat(string(data)); ok {
+			processes = append(processes, p)
+		}
+	}
+	return processes, nil
+}
+
+// parseProcStat parses /proc/<pid>/stat: "pid (comm) state ppid pgrp ... starttime ..."
+// The command name may contain spaces and parentheses, so fields are read after its last ')'.
+// The start time is left at 0 when the line is too short to contain it.
+func parseProcStat(stat string) (processEntry, bool) {
+	pid, fields, ok := procStatFields(stat)
+	if !ok || len(fields) < 3 {
+		return processEntry{}, false
+	}
+	ppid, err1 := strconv.Atoi(fields[1])
+	pgid, err2 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil {
+		return processEntry{}, false
+	}
+
+	entry := processEntry{
+		pid:    pid,
+		ppid:   ppid,
+		pgid:   pgid,
+		zombie: fields[0] == "Z",
+	}
+
+	// starttime is field 22 of the line, fields start at field 3 (state)
+	if len(fields) > 19 {
+		entry.startTime, _ = strconv.ParseUint(fields[19], 10, 64)
+	}
+	return entry, true
+}
+
+// procStatFields splits /proc/<pid>/stat into the PID and the fields after the
+// command name, starting at field 3 (state)
+func procStatFields(stat string) (int, []string, bool) {
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return 0, nil, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:open]))
+	if err != nil {
+		return 0, nil, false
+	}
+	return pid, strings.Fields(stat[end+1:]), true
+}
+
+// createTaskCgroup creates a cgroup v2 for a task below the daemon's own cgroup
+// and sets the limits it can enforce (see limits_linux.go). It returns an empty
+// path when cgroup v2 is not mounted or not writable, or processes cannot be
+// created in it (before Linux 5.7), and the limits not set.
+func createTaskCgroup(taskName string, limits ResourceLimits) (string, ResourceLimits) {
+	base := taskCgroupBase()
+	if base == "" || !cloneIntoCgroupSupported() {
+		return "", limits
+	}
+	if !limits.IsZero() {
+		enableCgroupControllers(base, limits)
+	}
+
+	dir := filepath.Join(base, "taskd-"+taskName)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return "", limits
+	}
+	return dir, setCgroupLimits(dir, limits)
+}
+
+// currentCgroupDir returns the cgroup v2 directory of the current process
+func currentCgroupDir() string {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+
+	var cgroupPath string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			cgroupPath = strings.TrimPrefix(line, "0::")
+			break
+		}
+	}
+	if cgroupPath == "" {
+		return ""
+	}
+
+	mountPoint, mountRoot := cgroup2Mount()
+	if mountPoint == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(mountRoot, cgroupPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.Join(mountPoint, rel)
+}
+
+// cgroup2Mount finds the mount point and root of the cgroup v2 hierarchy in /proc/self/mountinfo
+func cgroup2Mount() (string, string) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// "<id> <parent> <major:minor> <root> <mount point> <options> ... - <fstype> <source> <options>"
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && i >= 5 {
+				if fields[i+1] == "cgroup2" {
+					return fields[4], fields[3]
+				}
+				break
+			}
+		}
+	}
+	return "", ""
+}
+
+// cloneIntoCgroupSupported reports whether processes can be created in a cgroup
+// (CLONE_INTO_CGROUP, Linux 5.7)
+var cloneIntoCgroupSupported = sync.OnceValue(func() bool {
+	return kernelVersionAtLeast(5, 7)
+})
+
+// kernelVersionAtLeast reports whether the running kernel is at least major.minor
+func kernelVersionAtLeast(major, minor int) bool {
+	var uname syscall.Utsname
+	if err := syscall.Uname(&uname); err != nil {
+		return false
+	}
+	var release strings.Builder
+	for _, c := range uname.Release {
+		if c == 0 {
+			break
+		}
+		release.WriteByte(byte(c))
+	}
+	return releaseAtLeast(release.String(), major, minor)
+}
+
+// releaseAtLeast reports whether a kernel release like "5.15.0-91-generic" is at least major.minor
+func releaseAtLeast(release string, major, minor int) bool {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err1 := strconv.Atoi(parts[0])
+	gotMinor, err2 := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return gotMajor > major || gotMajor == major && gotMinor >= minor
+}
+
+// startProcess starts the task process in the task's cgroup (CLONE_INTO_CGROUP),
+// stopped at its exec until the limits the cgroup does not enforce are applied
+// (see applyExecLimits), so no process it starts escapes the limits
+func (g *processGroup) startProcess(cmd *exec.Cmd) error {
+	if g.cgroup != "" {
+		dir, err := os.Open(g.cgroup)
+		if err != nil {
+			removeCgroup(g.cgroup)
+			g.cgroup = ""
+			return fmt.Errorf("failed to open cgroup: %w", err)
+		}
+		defer dir.Close()
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	}
+
+	execLimits := ResourceLimits{OpenFiles: g.processLimits.OpenFiles, Nice: g.processLimits.Nice}
+	if !execLimits.IsZero() {
+		// Only the thread that started a traced process may resume it
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		cmd.SysProcAttr.Ptrace = true
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	g.pgid = cmd.Process.Pid
+
+	var errs []error
+	if !execLimits.IsZero() {
+		// The process has not run any code of the task yet
+		if err := waitForExecStop(g.pgid); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return err
+		}
+		errs = append(errs, applyExecLimits(g.pgid, execLimits))
+		if err := syscall.PtraceDetach(g.pgid); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return fmt.Errorf("failed to resume process %d: %w", g.pgid, err)
+		}
+	}
+	cgroupOnly := ResourceLimits{CPU: g.processLimits.CPU, CPUWeight: g.processLimits.CPUWeight, Processes: g.processLimits.Processes}
+	if !cgroupOnly.IsZero() {
+		errs = append(errs, fmt.Errorf("%s: needs the cgroup v2 cpu and pids controllers, which the daemon's cgroup does not delegate", cgroupOnly))
+	}
+	g.limitsErr = errors.Join(errs...)
+	return nil
+}
+
+// cgroupPids returns the processes in a cgroup
+func cgroupPids(dir string) []int {
+	if dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, line := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(line); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// killCgroup kills every process in a cgroup
+// cgroup.kill (Linux 5.14) also catches processes forked while killing.
+func killCgroup(dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.kill"), []byte("1"), 0644); err == nil {
+		return
+	}
+	for _, pid := range cgroupPids(dir) {
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+}
+
+// removeCgroup removes a cgroup, which only succeeds once it is empty
+func removeCgroup(dir string) {
+	if dir != "" {
+		os.Remove(dir)
+	}
+}
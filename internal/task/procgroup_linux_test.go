@@ -0,0 +1,114 @@
+//go:build linux
+
+package task
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name string
+		stat string
+		want processEntry
+		ok   bool
+	}{
+		{"plain", "1234 (sleep) S 1200 1200 1200 0 -1", processEntry{pid: 1234, ppid: 1200, pgid: 1200}, true},
+		{"zombie", "99 (sh) Z 1 99 99 0", processEntry{pid: 99, ppid: 1, pgid: 99, zombie: true}, true},
+		{"command with spaces and parentheses", "42 (my (odd) cmd) R 7 42 42 0", processEntry{pid: 42, ppid: 7, pgid: 42}, true},
+		{"with start time", "7 (sh) S 1 7 7 0 -1 4194304 139 0 0 0 0 0 0 0 20 0 1 0 254180 2560000", processEntry{pid: 7, ppid: 1, pgid: 7, startTime: 254180}, true},
+		{"truncated", "42 (sh) R", processEntry{}, false},
+		{"garbage", "not a stat line", processEntry{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProcStat(tt.stat)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseProcStat(%q) = %+v, %v, want %+v, %v", tt.stat, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// waitForDescendants waits until the task has at least n running descendants
+func waitForDescendants(t *testing.T, task *Task, n int) []int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pids := task.descendantPIDs(); len(pids) >= n {
+			return pids
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("task did not start %d descendants, has %v", n, task.descendantPIDs())
+	return nil
+}
+
+// assertProcessesGone fails if any of the processes is still running
+func assertProcessesGone(t *testing.T, pids []int) {
+	t.Helper()
+	for _, pid := range pids {
+		if data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat")); err == nil {
+			if p, ok := parseProcStat(string(data)); ok && !p.zombie {
+				t.Errorf("descendant %d is still running after stop", pid)
+			}
+		}
+	}
+}
+
+func TestStopKillsDescendants(t *testing.T) {
+
+	// A shell wrapper whose children keep running when the shell is killed
+	task := startTestTask(t, &Config{
+		Executable: "sh",
+		Args:       []string{"-c", "sleep 60 & sleep 60 & echo ready; wait"},
+	})
+	pids := waitForDescendants(t, task, 2)
+
+	if err := task.StopWithOptions(StopOptions{Force: true}); err != nil {
+		t.Fatalf("StopWithOptions() = %v", err)
+	}
+	assertProcessesGone(t, pids)
+}
+
+func TestStopKillsDescendantsOutsideGroup(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid is not available")
+	}
+
+	// The child moves to a new session and process group
+	task := startTestTask(t, &Config{
+		Executable: "sh",
+		Args:       []string{"-c", "setsid sleep 60 & echo ready; wait"},
+	})
+	pids := waitForDescendants(t, task, 1)
+
+	if err := task.StopWithOptions(StopOptions{Timeout: 2 * time.Second}); err != nil {
+		t.Fatalf("StopWithOptions() = %v", err)
+	}
+	assertProcessesGone(t, pids)
+}
+
+func TestExitKillsRemainingDescendants(t *testing.T) {
+	// The shell exits and leaves its background child running
+	task := startTestTask(t, &Config{
+		Executable: "sh",
+		Args:       []string{"-c", "sleep 60 & echo ready; sleep 1"},
+	})
+	pids := waitForDescendants(t, task, 2)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for task.IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("task did not exit")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assertProcessesGone(t, pids)
+}
@@ -0,0 +1,85 @@
+//go:build !windows && !linux
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listProcesses reads the process table with ps
+func listProcesses() ([]processEntry, error) {
+	output, err := exec.Command("ps", "-axo", "pid=,ppid=,pgid=,stat=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []processEntry
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		pgid, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		processes = append(processes, processEntry{
+			pid:    pid,
+			ppid:   ppid,
+			pgid:   pgid,
+			zombie: strings.HasPrefix(fields[3], "Z"),
+		})
+	}
+	return processes, nil
+}
+
+// createTaskCgroup returns an empty path, cgroups are only available on Linux
+func createTaskCgroup(taskName string, limits ResourceLimits) (string, ResourceLimits) {
+	return "", limits
+}
+
+// cgroupPids returns no processes without cgroups
+func cgroupPids(dir string) []int {
+	return nil
+}
+
+// killCgroup does nothing without cgroups
+func killCgroup(dir string) {}
+
+// removeCgroup does nothing without cgroups
+func removeCgroup(dir string) {}
+
+// cgroupOOMKills returns 0 without cgroups
+func cgroupOOMKills(dir string) int {
+	return 0
+}
+
+// startProcess starts the task process and applies its nice value
+// Other limits would need setrlimit in the task process, which is not available here.
+func (g *processGroup) startProcess(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	g.pgid = cmd.Process.Pid
+
+	var errs []error
+	l := g.processLimits
+	if l.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, g.pgid, l.Nice); err != nil {
+			errs = append(errs, fmt.Errorf("nice: %w", err))
+		}
+		l.Nice = 0
+	}
+	if !l.IsZero() {
+		errs = append(errs, fmt.Errorf("%s: not supported on this platform", l))
+	}
+	g.limitsErr = errors.Join(errs...)
+	return nil
+}
@@ -0,0 +1,223 @@
+//go:build !windows
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// unixStopSignals maps stop signal names to signals
+var unixStopSignals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGINT":  syscall.SIGINT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// processEntry a process in the system process table
+type processEntry struct {
+	pid       int
+	ppid      int
+	pgid      int
+	zombie    bool
+	startTime uint64 // Only read on Linux
+}
+
+// processGroup tracks every process started for a task
+// The task process leads its own session and process group (Setsid), so a
+// signal sent to the group reaches every descendant that stayed in it.
+// Descendants that left the group are still found through their parent
+// process while it lives, and on Linux through the task's cgroup when
+// cgroup v2 can be used. Descendants found once are remembered, so they are
+// still tracked after their parent exited and they were reparented.
+type processGroup struct {
+	pgid   int
+	cgroup string // cgroup directory, empty when cgroups are not available
+
+	limits        ResourceLimits // Resource limits of the task
+	processLimits ResourceLimits // Limits the cgroup does not enforce, applied to the task process
+	limitsErr     error          // Limits that could not be applied, the task runs without them
+	oomKills      int            // Processes of the cgroup killed by the OOM killer before the task started
+
+	mu   sync.Mutex
+	seen map[int]bool // descendants found so far
+}
+
+// newProcessGroup prepares the process group of a task before it starts
+func newProcessGroup(taskName string, limits ResourceLimits) *processGroup {
+	g := &processGroup{limits: limits, seen: make(map[int]bool)}
+	g.cgroup, g.processLimits = createTaskCgroup(taskName, limits)
+	g.oomKills = cgroupOOMKills(g.cgroup)
+	return g
+}
+
+// adoptProcessGroup tracks the process group of a task process started by
+// another taskd instance, which made it the leader of its own group
+func adoptProcessGroup(pid int) *processGroup {
+	return &processGroup{pgid: pid, seen: make(map[int]bool)}
+}
+
+// start starts the task process as the leader of the group, with the limits of the task
+// A memory limit needs the cgroup: the address-space limit of setrlimit would
+// break tasks that reserve more memory than they use (Go, Java), so without
+// the memory controller the task is not started.
+func (g *processGroup) start(cmd *exec.Cmd) error {
+	if g.processLimits.Memory != "" {
+		return fmt.Errorf("memory limit of %s: needs the cgroup v2 memory controller, which the daemon's cgroup does not delegate", g.limits.Memory)
+	}
+	return g.startProcess(cmd)
+}
+
+// limitExceeded describes the limit the processes of the group ran into, empty if none
+func (g *processGroup) limitExceeded() string {
+	if g.cgroup == "" || cgroupOOMKills(g.cgroup) <= g.oomKills {
+		return ""
+	}
+	if g.limits.Memory != "" {
+		return fmt.Sprintf("killed by the out-of-memory killer: memory limit of %s exceeded", g.limits.Memory)
+	}
+	return "killed by the out-of-memory killer"
+}
+
+// signal sends the named signal to every process of the group
+func (g *processGroup) signal(name string) error {
+	sig, ok := unixStopSignals[name]
+	if !ok {
+		sig = syscall.SIGTERM
+	}
+
+	// Descendants outside the process group are looked up before the group is
+	// signalled, while their parents still link them to the task
+	outside := g.membersOutsideGroup()
+
+	err := syscall.Kill(-g.pgid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		err = nil
+	}
+
+	for _, pid := range outside {
+		syscall.Kill(pid, sig)
+	}
+	return err
+}
+
+// kill kills every process of the group
+func (g *processGroup) kill() error {
+	outside := g.membersOutsideGroup()
+
+	err := syscall.Kill(-g.pgid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		err = nil
+	}
+
+	for _, pid := range outside {
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+	killCgroup(g.cgroup)
+	return err
+}
+
+// alive reports whether any process of the group is still running
+func (g *processGroup) alive() bool {
+	return len(g.members()) > 0
+}
+
+// descendants returns the PIDs of the running processes of the group, except the task process
+func (g *processGroup) descendants() []int {
+	var pids []int
+	for _, pid := range g.members() {
+		if pid != g.pgid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// release frees the resources of the group once its processes have exited
+func (g *processGroup) release() {
+	removeCgroup(g.cgroup)
+}
+
+// members returns the PIDs of the running processes of the group, sorted
+func (g *processGroup) members() []int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	found := make(map[int]bool)
+
+	if processes, err := listProcesses(); err == nil {
+		children := make(map[int][]int)
+		var queue []int
+		running := make(map[int]bool)
+		for _, p := range processes {
+			if p.zombie {
+				continue
+			}
+			running[p.pid] = true
+			children[p.ppid] = append(children[p.ppid], p.pid)
+			if p.pgid == g.pgid {
+				found[p.pid] = true
+				queue = append(queue, p.pid)
+			}
+		}
+
+		// Follow parent links from the group, including the leader's children
+		// when the leader itself has already exited
+		queue = append(queue, g.pgid)
+		for len(queue) > 0 {
+			pid := queue[0]
+			queue = queue[1:]
+			for _, child := range children[pid] {
+				if !found[child] {
+					found[child] = true
+					queue = append(queue, child)
+				}
+			}
+		}
+
+		// Descendants found earlier whose parent has exited since
+		for pid := range g.seen {
+			if running[pid] {
+				found[pid] = true
+			} else {
+				delete(g.seen, pid)
+			}
+		}
+	}
+
+	for _, pid := range cgroupPids(g.cgroup) {
+		found[pid] = true
+	}
+
+	for pid := range found {
+		if pid != g.pgid {
+			g.seen[pid] = true
+		}
+	}
+
+	pids := make([]int, 0, len(found))
+	for pid := range found {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+// membersOutsideGroup returns the running processes of the group that left its process group
+func (g *processGroup) membersOutsideGroup() []int {
+	var pids []int
+	for _, pid := range g.members() {
+		if pgid, err := syscall.Getpgid(pid); err == nil && pgid != g.pgid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,246 @@
+//go:build windows
+
+package task
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                      = syscall.NewLazyDLL("kernel32.dll")
+	procGenerateConsoleCtrlEvent  = kernel32.NewProc("GenerateConsoleCtrlEvent")
+	procCreateJobObjectW          = kernel32.NewProc("CreateJobObjectW")
+	procAssignProcessToJobObject  = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject        = kernel32.NewProc("TerminateJobObject")
+	procQueryInformationJobObject = kernel32.NewProc("QueryInformationJobObject")
+	procThread32First             = kernel32.NewProc("Thread32First")
+	procThread32Next              = kernel32.NewProc("Thread32Next")
+	procOpenThread                = kernel32.NewProc("OpenThread")
+	procResumeThread              = kernel32.NewProc("ResumeThread")
+)
+
+const (
+	ctrlBreakEvent              = 1      // CTRL_BREAK_EVENT console control event
+	processSetQuota             = 0x0100 // PROCESS_SET_QUOTA access right
+	processTerminate            = 0x0001 // PROCESS_TERMINATE access right
+	jobObjectBasicProcessIDList = 3      // JobObjectBasicProcessIdList information class
+	maxJobProcessIDs            = 1024   // Size of the PID list queried from a job
+	jobTerminatedExitCode       = 1
+	createSuspended             = 0x00000004 // CREATE_SUSPENDED creation flag
+	th32csSnapThread            = 0x00000004 // TH32CS_SNAPTHREAD snapshot flag
+	threadSuspendResume         = 0x0002     // THREAD_SUSPEND_RESUME access right
+)
+
+// threadEntry32 is THREADENTRY32
+type threadEntry32 struct {
+	Size           uint32
+	Usage          uint32
+	ThreadID       uint32
+	OwnerProcessID uint32
+	BasePri        int32
+	DeltaPri       int32
+	Flags          uint32
+}
+
+// jobObjectBasicProcessIDListInfo is JOBOBJECT_BASIC_PROCESS_ID_LIST
+type jobObjectBasicProcessIDListInfo struct {
+	NumberOfAssignedProcesses uint32
+	NumberOfProcessIdsInList  uint32
+	ProcessIdList             [maxJobProcessIDs]uintptr
+}
+
+// processGroup tracks every process started for a task in a Job Object
+// Processes created by a process in a job belong to the same job, so
+// terminating the job terminates the whole process tree. Without a job (e.g.
+// when the daemon itself runs in a job that forbids nesting) only the task
+// process is tracked.
+type processGroup struct {
+	pid int
+	job syscall.Handle
+
+	limits    ResourceLimits // Resource limits of the task, set on the job
+	limitsErr error          // Limits that could not be set on the job
+}
+
+// newProcessGroup prepares the process group of a task before it starts
+func newProcessGroup(taskName string, limits ResourceLimits) *processGroup {
+	job, _, _ := procCreateJobObjectW.Call(0, 0)
+	g := &processGroup{job: syscall.Handle(job), limits: limits}
+	if g.job != 0 {
+		g.limitsErr = setJobLimits(g.job, limits)
+	}
+	return g
+}
+
+// adoptProcessGroup tracks a task process started by another taskd instance
+// Its job handle is not inherited, so only the task process itself is tracked.
+func adoptProcessGroup(pid int) *processGroup {
+	return &processGroup{pid: pid}
+}
+
+// start creates the task process suspended, assigns it to the job and resumes it
+// The process runs no code before it is in the job, so the processes it creates
+// are in the job as well and subject to its limits.
+func (g *processGroup) start(cmd *exec.Cmd) error {
+	cmd.SysProcAttr.CreationFlags |= createSuspended
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	g.pid = cmd.Process.Pid
+	g.limitsErr = g.assign()
+
+	if err := resumeProcess(g.pid); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("failed to resume process %d: %w", g.pid, err)
+	}
+	return nil
+}
+
+// assign assigns the task process to the job, which applies the limits of the task
+// It returns the limits that could not be applied, the task runs without them.
+func (g *processGroup) assign() error {
+	if g.job != 0 {
+		handle, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(g.pid))
+		if err == nil {
+			r, _, _ := procAssignProcessToJobObject.Call(uintptr(g.job), uintptr(handle))
+			syscall.CloseHandle(handle)
+			if r != 0 {
+				return g.limitsErr
+			}
+		}
+
+		// The process could not be assigned, fall back to tracking the task process only
+		syscall.CloseHandle(g.job)
+		g.job = 0
+	}
+
+	if !g.limits.IsZero() {
+		return fmt.Errorf("%s: the task process is not in a job", g.limits)
+	}
+	return nil
+}
+
+// resumeProcess resumes the threads of a process created suspended
+func resumeProcess(pid int) error {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(th32csSnapThread, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	entry := threadEntry32{Size: uint32(unsafe.Sizeof(threadEntry32{}))}
+	resumed := false
+	r, _, err := procThread32First.Call(uintptr(snapshot), uintptr(unsafe.Pointer(&entry)))
+	for ; r != 0; r, _, err = procThread32Next.Call(uintptr(snapshot), uintptr(unsafe.Pointer(&entry))) {
+		if int(entry.OwnerProcessID) != pid {
+			continue
+		}
+		thread, _, openErr := procOpenThread.Call(threadSuspendResume, 0, uintptr(entry.ThreadID))
+		if thread == 0 {
+			return fmt.Errorf("failed to open thread %d: %w", entry.ThreadID, openErr)
+		}
+		result, _, resumeErr := procResumeThread.Call(thread)
+		syscall.CloseHandle(syscall.Handle(thread))
+		if int32(result) == -1 {
+			return fmt.Errorf("failed to resume thread %d: %w", entry.ThreadID, resumeErr)
+		}
+		resumed = true
+	}
+	if !resumed {
+		return fmt.Errorf("no thread found: %w", err)
+	}
+	return nil
+}
+
+// limitExceeded describes the limit the processes of the group ran into, empty if none
+func (g *processGroup) limitExceeded() string {
+	if g.job == 0 || g.limits.Memory == "" || !jobMemoryLimitReached(g.job) {
+		return ""
+	}
+	return fmt.Sprintf("memory limit of %s reached", g.limits.Memory)
+}
+
+// signal sends CTRL_BREAK to the task's process group
+// Windows has no signals; the task runs in its own console process group
+// (CREATE_NEW_PROCESS_GROUP), whose ID is the PID of the task process. The
+// signal name is not used. The event only reaches processes attached to a
+// console, otherwise an error is returned and the caller kills the group.
+func (g *processGroup) signal(name string) error {
+	r, _, err := procGenerateConsoleCtrlEvent.Call(ctrlBreakEvent, uintptr(g.pid))
+	if r == 0 {
+		return fmt.Errorf("failed to send CTRL_BREAK to process group %d: %w", g.pid, err)
+	}
+	return nil
+}
+
+// kill terminates every process of the group
+func (g *processGroup) kill() error {
+	if g.job != 0 {
+		r, _, err := procTerminateJobObject.Call(uintptr(g.job), jobTerminatedExitCode)
+		if r == 0 {
+			return fmt.Errorf("failed to terminate job of process %d: %w", g.pid, err)
+		}
+		return nil
+	}
+
+	process, err := os.FindProcess(g.pid)
+	if err != nil {
+		return nil
+	}
+	return process.Kill()
+}
+
+// alive reports whether any process of the group is still running
+func (g *processGroup) alive() bool {
+	if g.job == 0 {
+		return isProcessAlive(g.pid)
+	}
+	return len(g.members()) > 0
+}
+
+// descendants returns the PIDs of the running processes of the group, except the task process
+func (g *processGroup) descendants() []int {
+	var pids []int
+	for _, pid := range g.members() {
+		if pid != g.pid {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// release closes the job handle
+// The job is created with KILL_ON_JOB_CLOSE, so closing it terminates remaining processes.
+func (g *processGroup) release() {
+	if g.job != 0 {
+		syscall.CloseHandle(g.job)
+		g.job = 0
+	}
+}
+
+// members returns the PIDs of the processes in the job, sorted
+func (g *processGroup) members() []int {
+	if g.job == 0 {
+		return nil
+	}
+
+	var info jobObjectBasicProcessIDListInfo
+	r, _, _ := procQueryInformationJobObject.Call(uintptr(g.job), jobObjectBasicProcessIDList,
+		uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info), 0)
+	if r == 0 {
+		return nil
+	}
+
+	pids := make([]int, 0, info.NumberOfProcessIdsInList)
+	for i := uint32(0); i < info.NumberOfProcessIdsInList; i++ {
+		pids = append(pids, int(info.ProcessIdList[i]))
+	}
+	sort.Ints(pids)
+	return pids
+}
@@ -0,0 +1,87 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// errProcessNotFound is returned when no process with the given PID exists
+var errProcessNotFound = errors.New("process not found")
+
+// processIdentity identifies a process beyond its PID, which the OS reuses
+// once the process has exited
+type processIdentity struct {
+	startTime  uint64 // OS specific, only comparable on the same host and boot; 0 when unknown
+	executable string // Empty when unknown
+}
+
+// sameProcess reports whether two identities describe the same process
+// The start time is authoritative: a process keeps it across exec, which
+// changes its executable (e.g. a wrapper script exec'ing the real program).
+// The executable is only compared when a start time is missing. Unknown
+// values never cause a mismatch.
+func (id processIdentity) sameProcess(other processIdentity) bool {
+	if id.startTime != 0 && other.startTime != 0 {
+		return id.startTime == other.startTime
+	}
+	if id.executable != "" && other.executable != "" {
+		return sameExecutable(id.executable, other.executable)
+	}
+	return true
+}
+
+// sameExecutable reports whether two paths refer to the same executable
+func sameExecutable(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return a == b
+}
+
+// runtimeIdentity returns the identity recorded in the runtime state for a process
+func runtimeIdentity(info *TaskRuntimeInfo) processIdentity {
+	return processIdentity{startTime: info.ProcessStartTime, executable: info.Executable}
+}
+
+// setRuntimeIdentity records the identity of the process of a runtime state entry
+func setRuntimeIdentity(info *TaskRuntimeInfo) {
+	if id, err := readProcessIdentity(info.PID); err == nil {
+		info.ProcessStartTime = id.startTime
+		info.Executable = id.executable
+	}
+}
+
+// processStillRunning reports whether pid still belongs to the process with
+// the expected identity, so that a reused PID is not taken for it
+func processStillRunning(pid int, expected processIdentity) bool {
+	if !isProcessAlive(pid) {
+		return false
+	}
+	current, err := readProcessIdentity(pid)
+	if errors.Is(err, errProcessNotFound) {
+		return false
+	}
+	if err != nil {
+		return true // The process exists but cannot be inspected
+	}
+	return expected.sameProcess(current)
+}
+
+// currentExecutable returns the path of the running taskd executable
+func currentExecutable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path, nil
+}
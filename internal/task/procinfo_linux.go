@@ -0,0 +1,88 @@
+//go:build linux
+
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicksPerSecond is USER_HZ, the unit of the CPU times in /proc/<pid>/stat
+const clockTicksPerSecond = 100
+
+// readProcessIdentity reads the start time and executable of a process from /proc
+// The start time is in clock ticks since boot (field 22 of /proc/<pid>/stat).
+// The executable is empty when /proc/<pid>/exe cannot be read, e.g. for a
+// process of another user.
+func readProcessIdentity(pid int) (processIdentity, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return processIdentity{}, errProcessNotFound
+		}
+		return processIdentity{}, err
+	}
+
+	entry, ok := parseProcStat(string(data))
+	if !ok {
+		return processIdentity{}, errProcessNotFound
+	}
+	if entry.zombie {
+		return processIdentity{}, errProcessNotFound
+	}
+
+	id := processIdentity{startTime: entry.startTime}
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		// The executable was replaced or removed since the process started
+		id.executable = strings.TrimSuffix(exe, " (deleted)")
+	}
+	return id, nil
+}
+
+// readProcessStats reads the resource usage of a process from /proc
+// The open files are the entries of /proc/<pid>/fd, 0 when it cannot be read.
+func readProcessStats(pid int) (processStats, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	data, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return processStats{}, errProcessNotFound
+		}
+		return processStats{}, err
+	}
+
+	stats, ok := parseProcStatUsage(string(data), int64(os.Getpagesize()))
+	if !ok {
+		return processStats{}, errProcessNotFound
+	}
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		stats.openFiles = len(fds)
+	}
+	return stats, nil
+}
+
+// parseProcStatUsage reads the CPU times (fields 14 and 15), the thread count
+// (field 20) and the resident pages (field 24) of /proc/<pid>/stat
+func parseProcStatUsage(stat string, pageSize int64) (processStats, bool) {
+	_, fields, ok := procStatFields(stat)
+	if !ok || len(fields) < 22 || fields[0] == "Z" {
+		return processStats{}, false
+	}
+
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	threads, err3 := strconv.Atoi(fields[17])
+	pages, err4 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return processStats{}, false
+	}
+	return processStats{
+		rss:     pages * pageSize,
+		cpuTime: time.Duration(utime+stime) * time.Second / clockTicksPerSecond,
+		threads: threads,
+	}, true
+}
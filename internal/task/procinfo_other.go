@@ -0,0 +1,83 @@
+//go:build !windows && !linux
+
+package task
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// psStartLayout is the layout of the lstart column of ps
+const psStartLayout = "Mon Jan 2 15:04:05 2006"
+
+// readProcessIdentity reads the start time and executable of a process with ps
+// The start time is in seconds since the epoch.
+func readProcessIdentity(pid int) (processIdentity, error) {
+	output, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "lstart=,comm=").Output()
+	if err != nil {
+		// ps exits with status 1 when no process matches
+		if _, ok := err.(*exec.ExitError); ok {
+			return processIdentity{}, errProcessNotFound
+		}
+		return processIdentity{}, err
+	}
+
+	// "Mon Oct 16 10:00:00 2026 /usr/bin/sleep", the command may contain spaces
+	fields := strings.Fields(strings.TrimSpace(string(output)))
+	if len(fields) < 5 {
+		return processIdentity{}, errProcessNotFound
+	}
+
+	var id processIdentity
+	if start, err := time.ParseInLocation(psStartLayout, strings.Join(fields[:5], " "), time.Local); err == nil {
+		id.startTime = uint64(start.Unix())
+	}
+	id.executable = strings.Join(fields[5:], " ")
+	return id, nil
+}
+
+// readProcessStats reads the resident memory and CPU time of a process with ps
+// ps does not report the threads and open files of a process on every system,
+// they are left at 0.
+func readProcessStats(pid int) (processStats, error) {
+	output, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "rss=,time=").Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return processStats{}, errProcessNotFound
+		}
+		return processStats{}, err
+	}
+
+	// "  1234  0:01.25", rss in KiB
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return processStats{}, errProcessNotFound
+	}
+	rss, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return processStats{}, errProcessNotFound
+	}
+	return processStats{rss: rss * 1024, cpuTime: parsePSTime(fields[1])}, nil
+}
+
+// parsePSTime parses the time column of ps, "[[dd-]hh:]mm:ss[.cc]"
+func parsePSTime(value string) time.Duration {
+	var total time.Duration
+	if days, rest, ok := strings.Cut(value, "-"); ok {
+		d, _ := strconv.Atoi(days)
+		total += time.Duration(d) * 24 * time.Hour
+		value = rest
+	}
+	parts := strings.Split(value, ":")
+	for i, part := range parts {
+		n, _ := strconv.ParseFloat(part, 64)
+		unit := time.Second
+		for j := i; j < len(parts)-1; j++ {
+			unit *= 60
+		}
+		total += time.Duration(n * float64(unit))
+	}
+	return total
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProcessIdentitySameProcess(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  processIdentity
+		wants bool
+	}{
+		{"same", processIdentity{100, "/bin/sh"}, processIdentity{100, "/bin/sh"}, true},
+		{"start time differs", processIdentity{100, "/bin/sh"}, processIdentity{200, "/bin/sh"}, false},
+		{"exec'd into another executable", processIdentity{100, "/bin/sh"}, processIdentity{100, "/bin/sleep"}, true},
+		{"executable differs without start time", processIdentity{0, "/bin/sh"}, processIdentity{200, "/bin/sleep"}, false},
+		{"executable matches without start time", processIdentity{0, "/bin/sh"}, processIdentity{200, "/bin/sh"}, true},
+		{"nothing known", processIdentity{}, processIdentity{200, "/bin/sh"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.sameProcess(tt.b); got != tt.wants {
+				t.Errorf("%+v.sameProcess(%+v) = %v, want %v", tt.a, tt.b, got, tt.wants)
+			}
+		})
+	}
+}
+
+func TestReadProcessIdentity(t *testing.T) {
+	id, err := readProcessIdentity(os.Getpid())
+	if err != nil {
+		t.Fatalf("readProcessIdentity() returned error: %v", err)
+	}
+	exe, _ := currentExecutable()
+	if !sameExecutable(id.executable, exe) {
+		t.Errorf("executable = %q, want %q", id.executable, exe)
+	}
+
+	if !processStillRunning(os.Getpid(), id) {
+		t.Error("processStillRunning() = false for the current process")
+	}
+	reused := processIdentity{startTime: id.startTime + 1}
+	if processStillRunning(os.Getpid(), reused) {
+		t.Error("processStillRunning() = true for a different start time")
+	}
+}
+
+func TestRestoreRuntimeStateReusedPID(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+
+	id, err := readProcessIdentity(os.Getpid())
+	if err != nil {
+		t.Fatalf("readProcessIdentity() returned error: %v", err)
+	}
+
+	// The recorded process has exited, and the test process now has its PID
+	task := NewTask("reused", &Config{Executable: "sleep"})
+	task.restoreRuntimeState(&TaskRuntimeInfo{
+		Name:             "reused",
+		Status:           "running",
+		PID:              os.Getpid(),
+		ProcessStartTime: id.startTime + 1,
+		Executable:       id.executable,
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if info := task.GetInfo(); info.Status == "stopped" {
+			if info.PID != 0 {
+				t.Errorf("PID = %d, want 0", info.PID)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("task with a reused PID is still %q", task.GetInfo().Status)
+}
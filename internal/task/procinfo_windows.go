@@ -0,0 +1,125 @@
+//go:build windows
+
+package task
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+	procK32GetProcessMemoryInfo    = kernel32.NewProc("K32GetProcessMemoryInfo")
+	procGetProcessHandleCount      = kernel32.NewProc("GetProcessHandleCount")
+)
+
+// processMemoryCounters is PROCESS_MEMORY_COUNTERS
+type processMemoryCounters struct {
+	CB                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// errorInvalidParameter is ERROR_INVALID_PARAMETER, returned by OpenProcess for an unknown PID
+const errorInvalidParameter syscall.Errno = 87
+
+// readProcessIdentity reads the creation time and executable of a process
+// The start time is the creation time as a FILETIME (100ns intervals since 1601).
+func readProcessIdentity(pid int) (processIdentity, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		if errors.Is(err, errorInvalidParameter) {
+			return processIdentity{}, errProcessNotFound
+		}
+		return processIdentity{}, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err == nil && exitCode != stillActive {
+		return processIdentity{}, errProcessNotFound
+	}
+
+	var id processIdentity
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err == nil {
+		id.startTime = uint64(creation.HighDateTime)<<32 | uint64(creation.LowDateTime)
+	}
+
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	r, _, _ := procQueryFullProcessImageNameW.Call(uintptr(handle), 0,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r != 0 {
+		id.executable = syscall.UTF16ToString(buf[:size])
+	}
+	return id, nil
+}
+
+// readProcessStats reads the resource usage of a process
+// The resident memory is the working set, and the open files are the open handles.
+func readProcessStats(pid int) (processStats, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		if errors.Is(err, errorInvalidParameter) {
+			return processStats{}, errProcessNotFound
+		}
+		return processStats{}, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err == nil && exitCode != stillActive {
+		return processStats{}, errProcessNotFound
+	}
+
+	var stats processStats
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err == nil {
+		stats.cpuTime = filetimeDuration(kernel) + filetimeDuration(user)
+	}
+
+	counters := processMemoryCounters{CB: uint32(unsafe.Sizeof(processMemoryCounters{}))}
+	if r, _, _ := procK32GetProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.CB)); r != 0 {
+		stats.rss = int64(counters.WorkingSetSize)
+	}
+
+	var handles uint32
+	if r, _, _ := procGetProcessHandleCount.Call(uintptr(handle), uintptr(unsafe.Pointer(&handles))); r != 0 {
+		stats.openFiles = int(handles)
+	}
+
+	stats.threads = processThreadCount(pid)
+	return stats, nil
+}
+
+// filetimeDuration converts a FILETIME holding a duration (100ns intervals) to a time.Duration
+func filetimeDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
+
+// processThreadCount returns the number of threads of a process from a process snapshot, 0 when unknown
+func processThreadCount(pid int) int {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return 0
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	entry := syscall.ProcessEntry32{Size: uint32(unsafe.Sizeof(syscall.ProcessEntry32{}))}
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		if int(entry.ProcessID) == pid {
+			return int(entry.Threads)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,348 @@
+package task
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Readiness probe kinds, a probe checks exactly one of them
+const (
+	ReadinessTCP     = "tcp"     // A TCP address accepts connections
+	ReadinessHTTP    = "http"    // An HTTP endpoint answers with a 2xx status
+	ReadinessLog     = "log"     // A line written to stdout matches a regular expression
+	ReadinessCommand = "command" // A command exits with code 0
+)
+
+// Readiness probe defaults, used when timeout or interval are not set
+const (
+	defaultReadinessTimeout  = 30 * time.Second
+	defaultReadinessInterval = time.Second
+	readinessAttemptTimeout  = 5 * time.Second // Upper bound of a single check
+)
+
+// ReadinessProbe decides when a started task is ready ([readiness] block)
+// Until the probe succeeds the task has the "starting" status. A task that is
+// not ready within the timeout is stopped and fails.
+type ReadinessProbe struct {
+	TCP      string `toml:"tcp,omitempty"`      // host:port, or a port on 127.0.0.1
+	HTTP     string `toml:"http,omitempty"`     // http:// or https:// URL
+	Log      string `toml:"log,omitempty"`      // Regular expression matched against stdout lines
+	Command  string `toml:"command,omitempty"`  // Run in the task's working directory and environment
+	Timeout  string `toml:"timeout,omitempty"`  // How long the task may take to become ready, default 30s
+	Interval string `toml:"interval,omitempty"` // Time between checks, default 1s
+}
+
+// Kind returns the kind of check of the probe, empty if the task has no probe
+func (p ReadinessProbe) Kind() string {
+	switch {
+	case p.TCP != "":
+		return ReadinessTCP
+	case p.HTTP != "":
+		return ReadinessHTTP
+	case p.Log != "":
+		return ReadinessLog
+	case p.Command != "":
+		return ReadinessCommand
+	}
+	return ""
+}
+
+// Target returns what the probe checks: the address, URL, pattern or command
+func (p ReadinessProbe) Target() string {
+	switch p.Kind() {
+	case ReadinessTCP:
+		return tcpProbeAddress(p.TCP)
+	case ReadinessHTTP:
+		return p.HTTP
+	case ReadinessLog:
+		return p.Log
+	case ReadinessCommand:
+		return p.Command
+	}
+	return ""
+}
+
+// TimeoutDuration returns how long the task may take to become ready
+func (p ReadinessProbe) TimeoutDuration() time.Duration {
+	return parseDurationOrDefault(p.Timeout, defaultReadinessTimeout)
+}
+
+// IntervalDuration returns the time between two checks
+func (p ReadinessProbe) IntervalDuration() time.Duration {
+	return parseDurationOrDefault(p.Interval, defaultReadinessInterval)
+}
+
+// ValidateReadinessProbe checks that a probe has at most one check and valid settings
+// A log probe needs the stdout of the task in a file.
+func ValidateReadinessProbe(p ReadinessProbe, stdout string) error {
+	kinds := 0
+	for _, value := range []string{p.TCP, p.HTTP, p.Log, p.Command} {
+		if value != "" {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("a readiness probe checks one of tcp, http, log or command")
+	}
+	if kinds == 0 && (p.Timeout != "" || p.Interval != "") {
+		return fmt.Errorf("readiness timeout and interval need a tcp, http, log or command check")
+	}
+
+	switch p.Kind() {
+	case ReadinessTCP:
+		if _, port, err := net.SplitHostPort(tcpProbeAddress(p.TCP)); err != nil || port == "" {
+			return fmt.Errorf("invalid readiness tcp address '%s': expected host:port or a port", p.TCP)
+		}
+	case ReadinessHTTP:
+		u, err := url.Parse(p.HTTP)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid readiness http URL '%s': expected an http:// or https:// URL", p.HTTP)
+		}
+	case ReadinessLog:
+		if _, err := regexp.Compile(p.Log); err != nil {
+			return fmt.Errorf("invalid readiness log pattern '%s': %w", p.Log, err)
+		}
+		if stdout == "" {
+			return fmt.Errorf("a readiness log probe needs stdout redirected to a file")
+		}
+	case ReadinessCommand:
+		if parts, err := SplitCommandLine(p.Command); err != nil {
+			return fmt.Errorf("invalid readiness command '%s': %w", p.Command, err)
+		} else if len(parts) == 0 {
+			return fmt.Errorf("readiness command cannot be empty")
+		}
+	}
+
+	for name, value := range map[string]string{"timeout": p.Timeout, "interval": p.Interval} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid readiness %s '%s': expected a positive duration", name, value)
+		}
+	}
+	return nil
+}
+
+// tcpProbeAddress completes a bare port to an address on the loopback interface
+func tcpProbeAddress(address string) string {
+	if _, err := strconv.Atoi(address); err == nil {
+		return net.JoinHostPort("127.0.0.1", address)
+	}
+	return address
+}
+
+// probeCheck performs a single check of a probe, nil means it passed
+// The output describes the result for the task's health information.
+type probeCheck func(ctx context.Context) (string, error)
+
+// newReadinessCheck returns the check of a probe for a task started in workDir with env
+// A log check reads the stdout file from logOffset, the size it had before the task started,
+// decoding the records of capture mode when capture is set.
+func newReadinessCheck(p ReadinessProbe, workDir string, env []string, logPath string, logOffset int64, capture bool) probeCheck {
+	switch p.Kind() {
+	case ReadinessTCP:
+		return tcpCheck(tcpProbeAddress(p.TCP))
+	case ReadinessHTTP:
+		return httpCheck(p.HTTP)
+	case ReadinessLog:
+		scanner := &logPatternScanner{path: logPath, offset: logOffset, capture: capture, pattern: regexp.MustCompile(p.Log)}
+		return func(ctx context.Context) (string, error) {
+			return scanner.check()
+		}
+	case ReadinessCommand:
+		return commandCheck(p.Command, workDir, env)
+	}
+	return func(ctx context.Context) (string, error) { return "", nil }
+}
+
+// tcpCheck passes when address accepts a connection
+func tcpCheck(address string) probeCheck {
+	return func(ctx context.Context) (string, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return "", err
+		}
+		conn.Close()
+		return "connected to " + address, nil
+	}
+}
+
+// httpCheck passes when a GET of rawURL answers with a 2xx status
+func httpCheck(rawURL string) probeCheck {
+	return func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return resp.Status, fmt.Errorf("%s answered %s", rawURL, resp.Status)
+		}
+		return resp.Status, nil
+	}
+}
+
+// commandCheck passes when command exits with code 0, run in workDir with env
+func commandCheck(command, workDir string, env []string) probeCheck {
+	parts, parseErr := SplitCommandLine(command)
+	if parseErr == nil && len(parts) == 0 {
+		parseErr = fmt.Errorf("command is empty")
+	}
+	return func(ctx context.Context) (string, error) {
+		if parseErr != nil {
+			return "", parseErr
+		}
+		cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+		cmd.Dir = workDir
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		out := truncateProbeOutput(strings.TrimSpace(string(output)))
+		if err != nil && out != "" {
+			return out, fmt.Errorf("%w: %s", err, out)
+		}
+		return out, err
+	}
+}
+
+// truncateProbeOutput keeps the last line of a probe command's output, shortened
+func truncateProbeOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	last := lines[len(lines)-1]
+	if len(last) > 200 {
+		last = last[:200] + "..."
+	}
+	return last
+}
+
+// logPatternScanner looks for a line matching a pattern in the lines appended to a file
+type logPatternScanner struct {
+	path    string
+	offset  int64  // Where the next read starts
+	partial string // Last line read without its newline
+	capture bool   // The file holds capture records, only their stdout lines are matched
+	record  string // Stdout line of the partial capture records read so far
+	pattern *regexp.Regexp
+}
+
+// check reads the lines written since the last check, nil if one of them matches
+// It returns the matching line.
+func (s *logPatternScanner) check() (string, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return "", fmt.Errorf("no output yet: %w", err)
+	}
+	defer file.Close()
+
+	// The file was truncated or replaced by log rotation, start over
+	if info, err := file.Stat(); err == nil && info.Size() < s.offset {
+		s.offset = 0
+		s.partial = ""
+		s.record = ""
+	}
+	if _, err := file.Seek(s.offset, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		chunk, err := reader.ReadString('\n')
+		s.offset += int64(len(chunk))
+		line := s.partial + chunk
+		if err != nil {
+			// Keep an unterminated line until it is complete, but match it already.
+			// Capture records are written whole, an unterminated one is not decoded yet.
+			s.partial = line
+			if !s.capture && s.pattern.MatchString(line) {
+				return line, nil
+			}
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("no output line matches '%s' yet", s.pattern)
+			}
+			return "", err
+		}
+		s.partial = ""
+		line = strings.TrimRight(line, "\r\n")
+		if s.capture {
+			var ok bool
+			if line, ok = s.decodeRecord(line); !ok {
+				continue
+			}
+		}
+		if s.pattern.MatchString(line) {
+			return line, nil
+		}
+	}
+}
+
+// decodeRecord returns the stdout line of a capture record, joined to the partial records before it
+// It returns false for records of other streams and lines that are not records.
+func (s *logPatternScanner) decodeRecord(text string) (string, bool) {
+	record, ok := ParseCaptureRecord(text)
+	if !ok || record.Stream != LogStreamStdout {
+		return "", false
+	}
+	line := s.record + record.Line
+	if record.Partial {
+		s.record = line
+	} else {
+		s.record = ""
+	}
+	return line, true
+}
+
+// errExitedBeforeReady is returned by waitUntilReady when the task exits during the probe
+var errExitedBeforeReady = errors.New("task exited before it was ready")
+
+// waitUntilReady runs a check every interval until it succeeds, the task exits or the timeout passes
+// It returns the error of the last check when the task is not ready in time.
+func waitUntilReady(check probeCheck, timeout, interval time.Duration, exited <-chan struct{}) error {
+	deadline := time.Now().Add(timeout)
+	lastErr := errors.New("no check completed")
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("not ready after %s: %w", timeout, lastErr)
+		}
+
+		attempt := readinessAttemptTimeout
+		if remaining < attempt {
+			attempt = remaining
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), attempt)
+		_, err := check(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		wait := interval
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		select {
+		case <-exited:
+			return errExitedBeforeReady
+		case <-time.After(wait):
+		}
+	}
+}
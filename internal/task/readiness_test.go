@@ -0,0 +1,164 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateReadinessProbe(t *testing.T) {
+	tests := []struct {
+		probe   ReadinessProbe
+		stdout  string
+		wantErr string
+	}{
+		{ReadinessProbe{}, "", ""},
+		{ReadinessProbe{TCP: "8080"}, "", ""},
+		{ReadinessProbe{TCP: "db.local:5432", Timeout: "1m", Interval: "500ms"}, "", ""},
+		{ReadinessProbe{HTTP: "http://127.0.0.1:8080/health"}, "", ""},
+		{ReadinessProbe{Log: "listening on .*"}, "out.log", ""},
+		{ReadinessProbe{Command: "pg_isready -q"}, "", ""},
+		{ReadinessProbe{TCP: "8080", HTTP: "http://localhost"}, "", "one of"},
+		{ReadinessProbe{Timeout: "10s"}, "", "need a tcp"},
+		{ReadinessProbe{TCP: "localhost"}, "", "invalid readiness tcp address"},
+		{ReadinessProbe{HTTP: "localhost:8080/health"}, "", "invalid readiness http URL"},
+		{ReadinessProbe{Log: "ready"}, "", "stdout"},
+		{ReadinessProbe{Log: "ready ("}, "out.log", "invalid readiness log pattern"},
+		{ReadinessProbe{Command: "   "}, "", "cannot be empty"},
+		{ReadinessProbe{Command: `check "unterminated`}, "", "invalid readiness command"},
+		{ReadinessProbe{TCP: "8080", Timeout: "0s"}, "", "invalid readiness timeout"},
+		{ReadinessProbe{TCP: "8080", Interval: "soon"}, "", "invalid readiness interval"},
+	}
+
+	for _, tt := range tests {
+		err := ValidateReadinessProbe(tt.probe, tt.stdout)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ValidateReadinessProbe(%+v) = %v", tt.probe, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ValidateReadinessProbe(%+v) = %v, want an error containing %q", tt.probe, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReadinessProbeTarget(t *testing.T) {
+	tests := []struct {
+		probe      ReadinessProbe
+		wantKind   string
+		wantTarget string
+	}{
+		{ReadinessProbe{}, "", ""},
+		{ReadinessProbe{TCP: "8080"}, ReadinessTCP, "127.0.0.1:8080"},
+		{ReadinessProbe{TCP: "[::1]:8080"}, ReadinessTCP, "[::1]:8080"},
+		{ReadinessProbe{HTTP: "https://example.com/ready"}, ReadinessHTTP, "https://example.com/ready"},
+		{ReadinessProbe{Log: "ready"}, ReadinessLog, "ready"},
+		{ReadinessProbe{Command: "true"}, ReadinessCommand, "true"},
+	}
+
+	for _, tt := range tests {
+		if kind, target := tt.probe.Kind(), tt.probe.Target(); kind != tt.wantKind || target != tt.wantTarget {
+			t.Errorf("probe %+v = %q %q, want %q %q", tt.probe, kind, target, tt.wantKind, tt.wantTarget)
+		}
+	}
+}
+
+func TestLogPatternScanner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.log")
+	if err := os.WriteFile(path, []byte("listening on :8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+
+	// Lines written before the task started are skipped
+	scanner := &logPatternScanner{path: path, offset: info.Size(), pattern: regexp.MustCompile(`listening on :\d+`)}
+	if _, err := scanner.check(); err == nil {
+		t.Fatal("check() matched output of a previous run")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	file.WriteString("starting\nlistening ")
+	if _, err := scanner.check(); err == nil {
+		t.Fatal("check() matched an incomplete line")
+	}
+	file.WriteString("on :9090\n")
+	if line, err := scanner.check(); err != nil || line != "listening on :9090" {
+		t.Errorf("check() = %q, %v, want a match across two writes", line, err)
+	}
+}
+
+func TestLogPatternScannerCapture(t *testing.T) {
+	for _, format := range []string{CaptureText, CaptureJSON} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.log")
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			logConfig := LogConfig{Capture: format, MaxLineSize: 16}
+			var mu sync.Mutex
+			stdout := NewCaptureWriter(file, LogStreamStdout, logConfig, &mu)
+			stderr := NewCaptureWriter(file, LogStreamStderr, logConfig, &mu)
+
+			// The pattern is anchored and the line has characters JSON escapes
+			scanner := &logPatternScanner{path: path, capture: true, pattern: regexp.MustCompile(`^ready "\w+" on :\d+$`)}
+
+			stderr.Write([]byte("ready \"stderr\" on :1\n"))
+			stdout.Write([]byte("starting\n"))
+			if line, err := scanner.check(); err == nil {
+				t.Fatalf("check() = %q, matched a stderr line", line)
+			}
+
+			// A line longer than the maximum line size is split into partial records
+			stdout.Write([]byte("ready \"web\" on :8080\n"))
+			if line, err := scanner.check(); err != nil || line != `ready "web" on :8080` {
+				t.Errorf("check() = %q, %v, want the decoded line", line, err)
+			}
+		})
+	}
+}
+
+func TestWaitUntilReady(t *testing.T) {
+	attempts := 0
+	check := func(ctx context.Context) (string, error) {
+		attempts++
+		if attempts < 3 {
+			return "", errors.New("connection refused")
+		}
+		return "", nil
+	}
+	if err := waitUntilReady(check, time.Second, time.Millisecond, nil); err != nil {
+		t.Errorf("waitUntilReady() = %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+
+	// The error of the last check is kept
+	failing := func(ctx context.Context) (string, error) { return "", errors.New("connection refused") }
+	err := waitUntilReady(failing, 50*time.Millisecond, 10*time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "not ready after 50ms: connection refused") {
+		t.Errorf("waitUntilReady() = %v, want a timeout with the last error", err)
+	}
+
+	exited := make(chan struct{})
+	close(exited)
+	if err := waitUntilReady(failing, time.Minute, time.Minute, exited); !errors.Is(err, errExitedBeforeReady) {
+		t.Errorf("waitUntilReady() = %v, want %v", err, errExitedBeforeReady)
+	}
+}
@@ -0,0 +1,120 @@
+//go:build !windows
+
+package task
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// delayedReadyCommand returns a command that prints "ready" after a delay and keeps running
+func delayedReadyCommand(delay string) (string, []string) {
+	return "sh", []string{"-c", "sleep " + delay + "; echo ready; while :; do sleep 0.05; done"}
+}
+
+// closedTCPPort returns a local address nothing listens on
+func closedTCPPort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestReadinessLogProbe(t *testing.T) {
+	executable, args := delayedReadyCommand("0.3")
+	task := NewTask("ready-test", &Config{
+		Executable: executable,
+		Args:       args,
+		WorkDir:    t.TempDir(),
+		Stdout:     "out.log",
+		Readiness:  ReadinessProbe{Log: "^ready$", Interval: "50ms"},
+	})
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() {
+		if task.IsRunning() {
+			task.StopWithOptions(StopOptions{Force: true})
+		}
+	})
+
+	if status := task.GetInfo().Status; status != "starting" {
+		t.Errorf("status before the probe succeeded = %s, want starting", status)
+	}
+	if err := task.WaitReady(); err != nil {
+		t.Fatalf("WaitReady() = %v", err)
+	}
+	if status := task.GetInfo().Status; status != "running" {
+		t.Errorf("status once ready = %s, want running", status)
+	}
+}
+
+func TestReadinessProbeTimeout(t *testing.T) {
+	executable, args := trapCommand("exit 0")
+	task := NewTask("not-ready", &Config{
+		Executable: executable,
+		Args:       args,
+		WorkDir:    t.TempDir(),
+		Readiness:  ReadinessProbe{TCP: closedTCPPort(t), Timeout: "300ms", Interval: "50ms"},
+	})
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() {
+		if task.IsRunning() {
+			task.StopWithOptions(StopOptions{Force: true})
+		}
+	})
+
+	err := task.WaitReady()
+	if err == nil || !strings.Contains(err.Error(), "readiness probe failed: not ready after 300ms") {
+		t.Fatalf("WaitReady() = %v, want a readiness timeout", err)
+	}
+
+	// The task is stopped and its run fails
+	deadline := time.Now().Add(5 * time.Second)
+	for task.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	info := task.GetInfo()
+	if info.Status != "stopped" || !strings.Contains(info.LastError, "readiness probe failed") {
+		t.Errorf("task after the probe failed = %s (%s), want stopped with the probe error", info.Status, info.LastError)
+	}
+	runs := task.takeFinishedRuns()
+	if len(runs) != 1 || runs[0].Reason != StopReasonNotReady || !runs[0].Failed() {
+		t.Errorf("finished runs = %+v, want one failed not-ready run", runs)
+	}
+}
+
+func TestStartTaskWaitsForDependencyReadiness(t *testing.T) {
+	manager, client := newTestDaemon(t)
+	addTestDependencyTasks(t, manager, map[string]*Config{
+		"web": {Requires: []string{"db"}},
+	})
+
+	executable, args := delayedReadyCommand("0.3")
+	writeTestTaskConfig(t, "db", &Config{
+		Executable: executable,
+		Args:       args,
+		WorkDir:    t.TempDir(),
+		Stdout:     "out.log",
+		Readiness:  ReadinessProbe{Log: "ready", Interval: "50ms"},
+	})
+
+	if err := client.StartTaskWithOptions("web", StartOptions{Wait: true}); err != nil {
+		t.Fatalf("StartTaskWithOptions(web, wait) = %v", err)
+	}
+	db, err := manager.getTaskStatus("db")
+	if err != nil {
+		t.Fatalf("getTaskStatus(db) = %v", err)
+	}
+	if db.Status != "running" {
+		t.Errorf("db status when web started = %s, want running", db.Status)
+	}
+}
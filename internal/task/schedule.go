@@ -0,0 +1,300 @@
+package task
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Overlap policies, applied when a scheduled task is still running at its next tick
+const (
+	ScheduleOverlapSkip    = "skip"    // Let the running task finish and skip the tick
+	ScheduleOverlapQueue   = "queue"   // Start the task again as soon as the running one exits
+	ScheduleOverlapReplace = "replace" // Stop the running task and start a new one
+)
+
+// schedulerInterval is how often the scheduler checks for due runs and configuration changes
+const schedulerInterval = time.Second
+
+// ParseSchedule parses a schedule: a 5-field cron expression, a descriptor like
+// @daily or an interval like @every 1h
+func ParseSchedule(expr string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule '%s': %w", expr, err)
+	}
+	return schedule, nil
+}
+
+// ValidateSchedule checks that expr is empty or a valid schedule
+func ValidateSchedule(expr string) error {
+	if expr == "" {
+		return nil
+	}
+	_, err := ParseSchedule(expr)
+	return err
+}
+
+// ValidateScheduleOverlap checks that policy is a known overlap policy (empty means skip)
+func ValidateScheduleOverlap(policy string) error {
+	switch policy {
+	case "", ScheduleOverlapSkip, ScheduleOverlapQueue, ScheduleOverlapReplace:
+		return nil
+	default:
+		return fmt.Errorf("unknown schedule overlap policy '%s' (expected %s, %s or %s)",
+			policy, ScheduleOverlapSkip, ScheduleOverlapQueue, ScheduleOverlapReplace)
+	}
+}
+
+// ValidateScheduleJitter checks that jitter is empty or a valid non-negative duration
+func ValidateScheduleJitter(jitter string) error {
+	if jitter == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(jitter)
+	if err != nil {
+		return fmt.Errorf("invalid schedule jitter '%s': %w", jitter, err)
+	}
+	if d < 0 {
+		return fmt.Errorf("schedule jitter cannot be negative: %s", jitter)
+	}
+	return nil
+}
+
+// ScheduleOverlapPolicy returns the effective overlap policy of a scheduled task
+func (c *Config) ScheduleOverlapPolicy() string {
+	if c.ScheduleOverlap == "" {
+		return ScheduleOverlapSkip
+	}
+	return c.ScheduleOverlap
+}
+
+// ScheduleJitterDuration returns the maximum random delay added to each tick
+func (c *Config) ScheduleJitterDuration() time.Duration {
+	return parseDurationOrDefault(c.ScheduleJitter, 0)
+}
+
+// NextScheduledRun returns the next tick of the schedule after t, without jitter
+// It returns the zero time for a task without a valid schedule.
+func (c *Config) NextScheduledRun(t time.Time) time.Time {
+	if c.Schedule == "" {
+		return time.Time{}
+	}
+	schedule, err := ParseSchedule(c.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	return schedule.Next(t)
+}
+
+// Scheduler starts scheduled tasks at the ticks of their schedule (runs in daemon process)
+type Scheduler struct {
+	manager  *Manager
+	entries  map[string]*scheduleEntry // Only used by the scheduling loop
+	random   func() float64            // Selects the jitter of a tick
+	start    func(name string, replace bool) error
+	stopChan chan struct{}
+	doneChan chan struct{}
+	stopOnce sync.Once
+
+	mu       sync.Mutex
+	starting map[string]bool // Tasks with a run being started
+	runs     sync.WaitGroup  // Runs being started
+}
+
+// scheduleEntry the planned next run of a scheduled task
+type scheduleEntry struct {
+	expr     string // Schedule and jitter the run was planned with
+	jitter   time.Duration
+	schedule cron.Schedule // nil if expr is invalid
+	tick     time.Time     // Next tick of the schedule
+	fireAt   time.Time     // The tick delayed by its jitter
+	queued   bool          // A tick found the task running and queued a run
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return newScheduler(GetManager())
+}
+
+// newScheduler creates a scheduler for the tasks of a manager
+func newScheduler(manager *Manager) *Scheduler {
+	s := &Scheduler{
+		manager:  manager,
+		entries:  make(map[string]*scheduleEntry),
+		random:   rand.Float64,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+		starting: make(map[string]bool),
+	}
+	s.start = s.startTask
+	return s
+}
+
+// Start starts the scheduling loop in the background
+func (s *Scheduler) Start() {
+	fmt.Println("Scheduler: Starting")
+	go s.run()
+}
+
+// Stop stops the scheduling loop and waits for it and the runs being started
+// to finish, so no task is started afterwards
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+	<-s.doneChan
+}
+
+// run checks the schedules until the scheduler is stopped
+func (s *Scheduler) run() {
+	defer close(s.doneChan)
+	defer s.runs.Wait()
+
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	s.checkSchedules(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			s.checkSchedules(now)
+		case <-s.stopChan:
+			fmt.Println("Scheduler: Stopping")
+			return
+		}
+	}
+}
+
+// checkSchedules follows configuration changes and starts the runs that are due
+func (s *Scheduler) checkSchedules(now time.Time) {
+	tasks := s.manager.scheduledTasks()
+
+	// Forget tasks that were deleted or are no longer scheduled
+	for name := range s.entries {
+		if _, exists := tasks[name]; !exists {
+			delete(s.entries, name)
+		}
+	}
+
+	for name, task := range tasks {
+		config := task.getConfig()
+		entry := s.entries[name]
+		jitter := config.ScheduleJitterDuration()
+
+		// Plan the first run, and plan again when the schedule changes
+		if entry == nil || entry.expr != config.Schedule || entry.jitter != jitter {
+			entry = &scheduleEntry{expr: config.Schedule, jitter: jitter}
+			schedule, err := ParseSchedule(config.Schedule)
+			if err != nil {
+				fmt.Printf("Warning: task %s is not scheduled: %v\n", name, err)
+			}
+			entry.schedule = schedule
+			s.entries[name] = entry
+			s.plan(entry, now)
+		}
+
+		if entry.schedule != nil {
+			// A queued run starts once the previous one has exited
+			if entry.queued && !task.IsRunning() && !s.isStarting(name) {
+				entry.queued = false
+				fmt.Printf("Scheduler: Starting queued run of task %s\n", name)
+				s.startRun(name, task, false)
+			}
+
+			if !now.Before(entry.fireAt) {
+				s.fire(name, task, entry, config.ScheduleOverlapPolicy())
+				s.plan(entry, now)
+			}
+		}
+
+		// Set on every check, a task deleted and added again is a new instance
+		task.setNextScheduledRun(entry.fireAt)
+	}
+}
+
+// plan computes the next run of a task after now
+// Ticks missed while the daemon was not running are not caught up.
+func (s *Scheduler) plan(entry *scheduleEntry, now time.Time) {
+	if entry.schedule == nil {
+		return
+	}
+	entry.tick = entry.schedule.Next(now)
+	entry.fireAt = entry.tick
+	if entry.jitter > 0 {
+		entry.fireAt = entry.tick.Add(time.Duration(s.random() * float64(entry.jitter)))
+	}
+}
+
+// fire handles a tick of the schedule of a task according to its overlap policy
+func (s *Scheduler) fire(name string, task *Task, entry *scheduleEntry, overlap string) {
+	starting := s.isStarting(name)
+	if !starting && !task.IsRunning() {
+		fmt.Printf("Scheduler: Starting task %s (scheduled at %s)\n", name, entry.tick.Format("2006-01-02 15:04:05"))
+		s.startRun(name, task, false)
+		return
+	}
+
+	switch overlap {
+	case ScheduleOverlapQueue:
+		fmt.Printf("Scheduler: Task %s is still running, queueing the run scheduled at %s\n", name, entry.tick.Format("2006-01-02 15:04:05"))
+		entry.queued = true
+	case ScheduleOverlapReplace:
+		if starting {
+			fmt.Printf("Scheduler: Task %s is still starting, skipping the run scheduled at %s\n", name, entry.tick.Format("2006-01-02 15:04:05"))
+			return
+		}
+		fmt.Printf("Scheduler: Task %s is still running, replacing it with the run scheduled at %s\n", name, entry.tick.Format("2006-01-02 15:04:05"))
+		s.startRun(name, task, true)
+	default:
+		fmt.Printf("Scheduler: Task %s is still running, skipping the run scheduled at %s\n", name, entry.tick.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// startRun starts a scheduled run, replacing the running task if replace is set
+// The run is started in the background: stopping the replaced task and waiting
+// for the readiness of the new one must not hold up the other schedules. Only
+// one run of a task is started at a time.
+func (s *Scheduler) startRun(name string, task *Task, replace bool) {
+	s.mu.Lock()
+	if s.starting[name] {
+		s.mu.Unlock()
+		return
+	}
+	s.starting[name] = true
+	s.mu.Unlock()
+
+	task.setLastScheduledRun(time.Now())
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.starting, name)
+			s.mu.Unlock()
+		}()
+
+		if err := s.start(name, replace); err != nil {
+			fmt.Printf("Scheduler: Failed to start task %s: %v\n", name, err)
+		}
+	}()
+}
+
+// isStarting reports whether a run of a task is being started
+func (s *Scheduler) isStarting(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.starting[name]
+}
+
+// startTask starts a task through the manager, replacing the running task if replace is set
+func (s *Scheduler) startTask(name string, replace bool) error {
+	if replace {
+		return s.manager.restartTask(name)
+	}
+	return s.manager.StartTask(name)
+}
@@ -0,0 +1,137 @@
+package task
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"", false},
+		{"0 3 * * *", false},
+		{"*/15 9-17 * * 1-5", false},
+		{"@daily", false},
+		{"@every 1h", false},
+		{"bad", true},
+		{"61 * * * *", true},
+		{"0 3 * *", true},
+		{"@every soon", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSchedule(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSchedule(%q) = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateScheduleOptions(t *testing.T) {
+	for _, policy := range []string{"", ScheduleOverlapSkip, ScheduleOverlapQueue, ScheduleOverlapReplace} {
+		if err := ValidateScheduleOverlap(policy); err != nil {
+			t.Errorf("ValidateScheduleOverlap(%q) = %v", policy, err)
+		}
+	}
+	if err := ValidateScheduleOverlap("wait"); err == nil {
+		t.Error("ValidateScheduleOverlap(\"wait\") should fail")
+	}
+
+	tests := []struct {
+		jitter  string
+		wantErr bool
+	}{
+		{"", false},
+		{"0s", false},
+		{"10m", false},
+		{"-1m", true},
+		{"10", true},
+	}
+	for _, tt := range tests {
+		err := ValidateScheduleJitter(tt.jitter)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateScheduleJitter(%q) = %v, wantErr %v", tt.jitter, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSchedulerPlan(t *testing.T) {
+	now := time.Date(2026, 1, 27, 10, 30, 0, 0, time.Local)
+	schedule, err := ParseSchedule("0 3 * * *")
+	if err != nil {
+		t.Fatalf("ParseSchedule() = %v", err)
+	}
+
+	s := newScheduler(&Manager{tasks: make(map[string]*Task)})
+	s.random = func() float64 { return 0.5 }
+
+	entry := &scheduleEntry{schedule: schedule}
+	s.plan(entry, now)
+	wantTick := time.Date(2026, 1, 28, 3, 0, 0, 0, time.Local)
+	if !entry.tick.Equal(wantTick) || !entry.fireAt.Equal(wantTick) {
+		t.Errorf("plan() without jitter = %v, %v, want %v", entry.tick, entry.fireAt, wantTick)
+	}
+
+	entry = &scheduleEntry{schedule: schedule, jitter: 10 * time.Minute}
+	s.plan(entry, now)
+	if want := wantTick.Add(5 * time.Minute); !entry.tick.Equal(wantTick) || !entry.fireAt.Equal(want) {
+		t.Errorf("plan() with jitter = %v, %v, want %v, %v", entry.tick, entry.fireAt, wantTick, want)
+	}
+}
+
+func TestSchedulerStartsRunsInBackground(t *testing.T) {
+	config := &Config{Executable: "sleep", Schedule: "@every 1m"}
+	manager := &Manager{tasks: map[string]*Task{
+		"slow": NewTask("slow", config),
+		"fast": NewTask("fast", config),
+	}}
+	s := newScheduler(manager)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	started := make(map[string]int)
+	fastStarted := make(chan struct{})
+	s.start = func(name string, replace bool) error {
+		mu.Lock()
+		started[name]++
+		mu.Unlock()
+		if name == "slow" {
+			<-release // Waiting for the readiness of the task
+		} else {
+			close(fastStarted)
+		}
+		return nil
+	}
+
+	now := time.Date(2026, 1, 27, 10, 30, 0, 0, time.Local)
+	s.checkSchedules(now)
+
+	done := make(chan struct{})
+	go func() {
+		s.checkSchedules(now.Add(time.Minute))
+		// slow is still starting at its next tick and is not started twice
+		s.checkSchedules(now.Add(2 * time.Minute))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("checkSchedules() waits for the start of a task")
+	}
+	select {
+	case <-fastStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast was not started while slow was starting")
+	}
+
+	close(release)
+	s.runs.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if started["slow"] != 1 {
+		t.Errorf("slow was started %d times, want once", started["slow"])
+	}
+}
@@ -0,0 +1,114 @@
+//go:build !windows
+
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestScheduler returns a scheduler for a daemon manager holding one scheduled task
+func newTestScheduler(t *testing.T, overlap string) (*Scheduler, *Task) {
+	t.Helper()
+	t.Setenv("TASKD_HOME", t.TempDir())
+
+	manager := &Manager{
+		tasks:          make(map[string]*Task),
+		builtinHandler: NewBuiltinTaskHandler(),
+	}
+	manager.SetDaemonMode(true)
+	t.Cleanup(manager.StopAllTasks)
+
+	executable, args := trapCommand("exit 0")
+	config := &Config{
+		Executable:      executable,
+		Args:            args,
+		WorkDir:         t.TempDir(),
+		Schedule:        "@every 1m",
+		ScheduleOverlap: overlap,
+	}
+	// Starting a task reloads its configuration file
+	writeTestTaskConfig(t, "backup", config)
+	task := NewTask("backup", config)
+	manager.tasks["backup"] = task
+
+	return newScheduler(manager), task
+}
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		overlap    string
+		wantNewPID bool
+		wantQueued bool
+	}{
+		{ScheduleOverlapSkip, false, false},
+		{ScheduleOverlapQueue, false, true},
+		{ScheduleOverlapReplace, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.overlap, func(t *testing.T) {
+			s, task := newTestScheduler(t, tt.overlap)
+			now := time.Now()
+
+			// The first check only plans the next run
+			s.checkSchedules(now)
+			if task.IsRunning() {
+				t.Fatal("task started before its first tick")
+			}
+			if next := task.GetInfo().NextScheduledRun; next == "" {
+				t.Error("next scheduled run is not set")
+			}
+
+			s.checkSchedules(now.Add(time.Minute))
+			s.runs.Wait() // Runs are started in the background
+			if !task.IsRunning() {
+				t.Fatal("task was not started at its tick")
+			}
+			if last := task.GetInfo().LastScheduledRun; last == "" {
+				t.Error("last scheduled run is not set")
+			}
+			firstPID := task.GetInfo().PID
+
+			// The next tick finds the task still running
+			s.checkSchedules(now.Add(2 * time.Minute))
+			s.runs.Wait()
+			if !task.IsRunning() {
+				t.Fatal("task is not running after the overlapping tick")
+			}
+			if pid := task.GetInfo().PID; (pid != firstPID) != tt.wantNewPID {
+				t.Errorf("PID changed from %d to %d, want new PID %v", firstPID, pid, tt.wantNewPID)
+			}
+			if queued := s.entries["backup"].queued; queued != tt.wantQueued {
+				t.Errorf("queued = %v, want %v", queued, tt.wantQueued)
+			}
+
+			// A queued run starts as soon as the task has exited, before the next tick
+			if err := s.manager.stopTask("backup", StopOptions{}); err != nil {
+				t.Fatalf("stopTask() = %v", err)
+			}
+			s.checkSchedules(now.Add(2*time.Minute + time.Second))
+			s.runs.Wait()
+			if task.IsRunning() != tt.wantQueued {
+				t.Errorf("running after exit = %v, want %v", task.IsRunning(), tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestSchedulerForgetsUnscheduledTasks(t *testing.T) {
+	s, task := newTestScheduler(t, "")
+	s.checkSchedules(time.Now())
+	if _, exists := s.entries["backup"]; !exists {
+		t.Fatal("scheduled task has no entry")
+	}
+
+	config := *task.getConfig()
+	config.Schedule = ""
+	task.setConfig(&config)
+
+	s.checkSchedules(time.Now())
+	if _, exists := s.entries["backup"]; exists {
+		t.Error("entry of an unscheduled task was kept")
+	}
+}
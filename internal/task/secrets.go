@@ -0,0 +1,227 @@
+package task
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"taskd/internal/config"
+)
+
+// SecretRefPrefix starts an env value that is replaced by a secret when the task starts
+const SecretRefPrefix = "secret://"
+
+// SecretMask replaces the values of secrets wherever a configuration is shown
+const SecretMask = "******"
+
+// secretName matches the names of secrets
+var secretName = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// ValidateSecretName checks that a secret name only has letters, digits, '_', '.' and '-'
+func ValidateSecretName(name string) error {
+	if !secretName.MatchString(name) {
+		return fmt.Errorf("invalid secret name '%s': use letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
+
+// SecretRef returns the name of the secret an env value refers to, false if it is a plain value
+func SecretRef(value string) (string, bool) {
+	return strings.CutPrefix(value, SecretRefPrefix)
+}
+
+// SecretStore keeps named secrets in a file encrypted with AES-256-GCM
+// The key is kept in its own file, readable only by the user and created with the
+// first secret. Both files live in the TaskD home directory.
+type SecretStore struct {
+	path    string
+	keyPath string
+}
+
+// NewSecretStore returns the secret store of the TaskD home directory
+func NewSecretStore() *SecretStore {
+	return &SecretStore{
+		path:    config.GetTaskDSecretsFile(),
+		keyPath: config.GetTaskDSecretsKeyFile(),
+	}
+}
+
+// Get returns the value of a secret
+func (s *SecretStore) Get(name string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret '%s' not found", name)
+	}
+	return value, nil
+}
+
+// Names returns the names of the secrets, sorted
+func (s *SecretStore) Names() ([]string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Set stores a secret, replacing its previous value
+func (s *SecretStore) Set(name, value string) error {
+	if err := ValidateSecretName(name); err != nil {
+		return err
+	}
+	return s.update(func(secrets map[string]string) error {
+		secrets[name] = value
+		return nil
+	})
+}
+
+// Remove deletes a secret
+func (s *SecretStore) Remove(name string) error {
+	return s.update(func(secrets map[string]string) error {
+		if _, ok := secrets[name]; !ok {
+			return fmt.Errorf("secret '%s' not found", name)
+		}
+		delete(secrets, name)
+		return nil
+	})
+}
+
+// update modifies the secrets under the lock of the secrets file
+func (s *SecretStore) update(modify func(secrets map[string]string) error) error {
+	lock, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open secrets lock file: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock, true); err != nil {
+		return fmt.Errorf("failed to lock secrets file: %w", err)
+	}
+	defer unlockFile(lock)
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := modify(secrets); err != nil {
+		return err
+	}
+	return s.save(secrets)
+}
+
+// load reads and decrypts the secrets, there are none before the first is set
+func (s *SecretStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	gcm, err := s.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secrets file %s is corrupted", s.path)
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file %s, it does not match %s", s.path, s.keyPath)
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("secrets file %s is corrupted: %w", s.path, err)
+	}
+	return secrets, nil
+}
+
+// save encrypts the secrets with a new nonce and replaces the secrets file
+func (s *SecretStore) save(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	data := gcm.Seal(nonce, nonce, plaintext, nil)
+
+	tempPath := s.path + ".tmp"
+	if err := writeFileSync(tempPath, data); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	if err := os.Chmod(tempPath, 0600); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to restrict secrets file: %w", err)
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename secrets file: %w", err)
+	}
+	return nil
+}
+
+// cipher returns the AES-GCM cipher of the secrets key, creating the key if asked to
+func (s *SecretStore) cipher(create bool) (cipher.AEAD, error) {
+	encoded, err := os.ReadFile(s.keyPath)
+	if os.IsNotExist(err) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate secrets key: %w", err)
+		}
+		encoded = []byte(hex.EncodeToString(key) + "\n")
+		if err := os.MkdirAll(filepath.Dir(s.keyPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create secrets key directory: %w", err)
+		}
+		// O_EXCL so that a key written meanwhile by another process is never replaced
+		file, err := os.OpenFile(s.keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create secrets key: %w", err)
+		}
+		_, err = file.Write(encoded)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write secrets key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("secrets key %s is invalid", s.keyPath)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
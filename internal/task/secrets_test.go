@@ -0,0 +1,62 @@
+package task
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	taskdconfig "taskd/internal/config"
+)
+
+func TestSecretStore(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	store := NewSecretStore()
+
+	if names, err := store.Names(); err != nil || len(names) != 0 {
+		t.Fatalf("Names() of an empty store = %v, %v", names, err)
+	}
+	if err := store.Set("db-password", "s3cr3t value"); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	if err := store.Set("api.token", "tok"); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+	if err := store.Set("bad name", "x"); err == nil {
+		t.Error("Set() with an invalid name should fail")
+	}
+
+	if value, err := NewSecretStore().Get("db-password"); err != nil || value != "s3cr3t value" {
+		t.Errorf("Get() = %q, %v, want the stored value", value, err)
+	}
+	if names, _ := store.Names(); !reflect.DeepEqual(names, []string{"api.token", "db-password"}) {
+		t.Errorf("Names() = %v", names)
+	}
+
+	// The file holds no plain text and is only readable by the user
+	data, err := os.ReadFile(taskdconfig.GetTaskDSecretsFile())
+	if err != nil {
+		t.Fatalf("failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "db-password") {
+		t.Error("secrets file contains plain text")
+	}
+
+	if err := store.Remove("db-password"); err != nil {
+		t.Fatalf("Remove() = %v", err)
+	}
+	if _, err := store.Get("db-password"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Get() of a removed secret = %v, want not found", err)
+	}
+	if err := store.Remove("db-password"); err == nil {
+		t.Error("Remove() of a missing secret should fail")
+	}
+
+	// Another key cannot decrypt the file
+	if err := os.WriteFile(taskdconfig.GetTaskDSecretsKeyFile(), []byte(strings.Repeat("ab", 32)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("api.token"); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("Get() with another key = %v, want a decryption error", err)
+	}
+}
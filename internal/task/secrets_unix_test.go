@@ -0,0 +1,66 @@
+//go:build !windows
+
+package task
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartTaskInjectsSecretsAndMasksThem(t *testing.T) {
+	manager, client := newTestDaemon(t)
+	if err := NewSecretStore().Set("db-password", "pa55word"); err != nil {
+		t.Fatalf("Set() = %v", err)
+	}
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "db.env"), []byte("DB_USER=app\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{
+		Executable: "sh",
+		Args:       []string{"-c", `echo "$DB_USER:$DB_PASSWORD"; sleep 30`},
+		WorkDir:    workDir,
+		EnvFile:    []string{"db.env"},
+		Env:        []string{"DB_PASSWORD=secret://db-password"},
+		Stdout:     "out.log",
+	}
+	writeTestTaskConfig(t, "db-client", config)
+	if err := manager.syncTaskConfig("db-client"); err != nil {
+		t.Fatalf("syncTaskConfig() = %v", err)
+	}
+	if err := client.StartTask("db-client"); err != nil {
+		t.Fatalf("StartTask() = %v", err)
+	}
+
+	outPath := filepath.Join(workDir, "out.log")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, _ := os.ReadFile(outPath); strings.Contains(string(data), "\n") {
+			if string(data) != "app:pa55word\n" {
+				t.Errorf("output = %q, want the env file and the secret", data)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("task wrote no output")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	info, err := client.GetTaskDetailInfo("db-client")
+	if err != nil {
+		t.Fatalf("GetTaskDetailInfo() = %v", err)
+	}
+	data, _ := json.Marshal(info)
+	if strings.Contains(string(data), "pa55word") {
+		t.Errorf("task info contains the secret value: %s", data)
+	}
+	if info.Resolved == nil || !strings.Contains(strings.Join(info.Resolved.Env, " "), "DB_PASSWORD="+SecretMask) {
+		t.Errorf("Resolved = %+v, want the masked secret", info.Resolved)
+	}
+}
@@ -0,0 +1,201 @@
+package task
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"taskd/internal/config"
+)
+
+// ipcRequestTimeout bounds how long a single client connection may take
+const ipcRequestTimeout = 30 * time.Second
+
+// DaemonServer serves CLI requests over the daemon's IPC socket (runs in daemon process)
+type DaemonServer struct {
+	socketPath   string
+	manager      *Manager
+	listener     net.Listener
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+	closing      bool
+	shutdownChan chan struct{}
+	shutdownOnce sync.Once
+}
+
+// NewDaemonServer creates a new daemon server listening on the default socket
+func NewDaemonServer(manager *Manager) *DaemonServer {
+	return newDaemonServerWithPath(manager, config.GetTaskDSocketFile())
+}
+
+// newDaemonServerWithPath creates a daemon server listening on the given socket path
+func newDaemonServerWithPath(manager *Manager, socketPath string) *DaemonServer {
+	return &DaemonServer{
+		socketPath:   socketPath,
+		manager:      manager,
+		shutdownChan: make(chan struct{}),
+	}
+}
+
+// Start starts accepting client connections
+func (s *DaemonServer) Start() error {
+	listener, err := listenIPC(s.socketPath)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	return nil
+}
+
+// Stop stops accepting connections and waits for in-flight requests to finish
+func (s *DaemonServer) Stop() {
+	s.mu.Lock()
+	if s.closing || s.listener == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.closing = true
+	s.listener.Close()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	os.Remove(s.socketPath)
+}
+
+// ShutdownRequested returns a channel that is closed when a client asks the daemon to shut down
+func (s *DaemonServer) ShutdownRequested() <-chan struct{} {
+	return s.shutdownChan
+}
+
+// acceptLoop accepts client connections until the listener is closed
+func (s *DaemonServer) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closing := s.closing
+			s.mu.Unlock()
+			if closing {
+				return
+			}
+			fmt.Printf("DaemonServer: Error accepting connection: %v\n", err)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConnection(conn)
+		}()
+	}
+}
+
+// handleConnection reads a single request and writes its response
+func (s *DaemonServer) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ipcRequestTimeout))
+
+	var req IPCRequest
+	if err := readIPCMessage(conn, &req); err != nil {
+		writeIPCMessage(conn, s.errorResponse(fmt.Errorf("invalid request: %w", err)))
+		return
+	}
+
+	// Stopping a task may take up to its stop timeout
+	conn.SetDeadline(time.Now().Add(ipcRequestDeadline(&req)))
+
+	resp := s.handleRequest(&req)
+	if err := writeIPCMessage(conn, resp); err != nil {
+		fmt.Printf("DaemonServer: Error writing response for %s: %v\n", req.Command, err)
+	}
+}
+
+// handleRequest dispatches a request to the task manager
+func (s *DaemonServer) handleRequest(req *IPCRequest) *IPCResponse {
+	if req.Version != IPCProtocolVersion {
+		return s.errorResponse(fmt.Errorf("unsupported protocol version %d (daemon speaks version %d), please restart the daemon with 'taskd restart taskd'",
+			req.Version, IPCProtocolVersion))
+	}
+
+	switch req.Command {
+	case IPCCommandPing:
+		return s.okResponse()
+
+	case IPCCommandStart:
+		if err := s.manager.syncTaskConfig(req.Task); err != nil {
+			return s.errorResponse(err)
+		}
+		if err := s.manager.StartTaskWithOptions(req.Task, StartOptions{Wait: req.Wait}); err != nil {
+			return s.errorResponse(err)
+		}
+		return s.okResponse()
+
+	case IPCCommandStop:
+		opts := StopOptions{Timeout: req.StopTimeout, Force: req.Force, Cascade: req.Cascade}
+		if err := s.manager.StopTaskWithOptions(req.Task, opts); err != nil {
+			return s.errorResponse(err)
+		}
+		return s.okResponse()
+
+	case IPCCommandRestart:
+		if err := s.manager.syncTaskConfig(req.Task); err != nil {
+			return s.errorResponse(err)
+		}
+		if err := s.manager.RestartTask(req.Task); err != nil {
+			return s.errorResponse(err)
+		}
+		return s.okResponse()
+
+	case IPCCommandList:
+		tasks, err := s.manager.listTasks()
+		if err != nil {
+			return s.errorResponse(err)
+		}
+		resp := s.okResponse()
+		resp.Tasks = tasks
+		return resp
+
+	case IPCCommandInfo:
+		detail, err := s.manager.getTaskDetailInfo(req.Task)
+		if err != nil {
+			return s.errorResponse(err)
+		}
+		resp := s.okResponse()
+		resp.Detail = detail
+		return resp
+
+	case IPCCommandReload:
+		if err := s.manager.syncTaskConfig(req.Task); err != nil {
+			return s.errorResponse(err)
+		}
+		return s.okResponse()
+
+	case IPCCommandShutdown:
+		s.shutdownOnce.Do(func() {
+			close(s.shutdownChan)
+		})
+		return s.okResponse()
+
+	default:
+		return s.errorResponse(fmt.Errorf("unknown command '%s'", req.Command))
+	}
+}
+
+func (s *DaemonServer) okResponse() *IPCResponse {
+	return &IPCResponse{Version: IPCProtocolVersion, OK: true}
+}
+
+func (s *DaemonServer) errorResponse(err error) *IPCResponse {
+	return &IPCResponse{Version: IPCProtocolVersion, OK: false, Error: err.Error()}
+}
@@ -0,0 +1,290 @@
+package task
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the bolt state database
+var (
+	boltTasksBucket = []byte("tasks") // Task name -> TaskRuntimeInfo
+	boltRunsBucket  = []byte("runs")  // Task name -> bucket of RunRecord by sequence number
+	boltMetaBucket  = []byte("meta")
+
+	boltMigratedKey = []byte("migrated_from") // Set once runtime.json has been imported
+)
+
+// boltOpenTimeout bounds how long to wait for another process holding the database
+const boltOpenTimeout = 10 * time.Second
+
+// BoltStateManager runtime state stored in an embedded bbolt database
+// Besides the latest TaskRuntimeInfo of each task it keeps the run history of
+// the tasks (see RunHistoryStore). The database is opened for each operation:
+// bbolt holds an exclusive file lock while it is open, which serializes the CLI
+// and the daemon.
+type BoltStateManager struct {
+	dbPath      string
+	migratePath string // runtime.json imported when the database is created, may be empty
+}
+
+// NewBoltStateManager creates a bolt state manager
+// An existing runtime.json at migratePath is imported the first time the database is opened.
+func NewBoltStateManager(dbPath, migratePath string) *BoltStateManager {
+	return &BoltStateManager{
+		dbPath:      dbPath,
+		migratePath: migratePath,
+	}
+}
+
+// UpdateTaskState updates task state
+func (bsm *BoltStateManager) UpdateTaskState(name string, info *TaskRuntimeInfo) error {
+	return bsm.Update(func(state *RuntimeState) error {
+		state.Tasks[name] = info
+		return nil
+	})
+}
+
+// UpdateDaemonState updates daemon state
+func (bsm *BoltStateManager) UpdateDaemonState(status *DaemonStatus) error {
+	daemonInfo := &TaskRuntimeInfo{
+		Name:      "taskd",
+		Status:    "stopped",
+		PID:       status.PID,
+		StartTime: status.StartTime,
+	}
+	if status.IsRunning {
+		daemonInfo.Status = "running"
+		setRuntimeIdentity(daemonInfo)
+	}
+	return bsm.UpdateTaskState("taskd", daemonInfo)
+}
+
+// GetRuntimeState gets runtime state
+func (bsm *BoltStateManager) GetRuntimeState() (*RuntimeState, error) {
+	db, err := bsm.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var state *RuntimeState
+	err = db.View(func(tx *bolt.Tx) error {
+		state, err = readBoltTasks(tx)
+		return err
+	})
+	return state, err
+}
+
+// SaveRuntimeState saves runtime state
+func (bsm *BoltStateManager) SaveRuntimeState(state *RuntimeState) error {
+	return bsm.Update(func(current *RuntimeState) error {
+		current.Tasks = state.Tasks
+		return nil
+	})
+}
+
+// Update loads the runtime state, applies fn to it and saves it in one transaction
+// The state is not saved if fn returns an error.
+func (bsm *BoltStateManager) Update(fn func(state *RuntimeState) error) error {
+	db, err := bsm.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		state, err := readBoltTasks(tx)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(state); err != nil {
+			return err
+		}
+		if state.Tasks == nil {
+			state.Tasks = make(map[string]*TaskRuntimeInfo)
+		}
+
+		return writeBoltTasks(tx, state)
+	})
+}
+
+// AppendRunRecord adds a run to the history of its task
+// Once a task has maxRunHistory runs the oldest ones are dropped.
+func (bsm *BoltStateManager) AppendRunRecord(record *RunRecord) error {
+	db, err := bsm.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(boltRunsBucket).CreateBucketIfNotExists([]byte(record.Task))
+		if err != nil {
+			return err
+		}
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(runKey(seq), data); err != nil {
+			return err
+		}
+
+		// Keys are ordered by sequence, the oldest runs come first
+		var keys [][]byte
+		bucket.ForEach(func(key, value []byte) error {
+			keys = append(keys, key)
+			return nil
+		})
+		for len(keys) > maxRunHistory {
+			if err := bucket.Delete(keys[0]); err != nil {
+				return err
+			}
+			keys = keys[1:]
+		}
+		return nil
+	})
+}
+
+// LoadRunHistory returns the recorded runs of a task, oldest first
+// The database is opened read-only and is not created when it does not exist;
+// malformed records are skipped.
+func (bsm *BoltStateManager) LoadRunHistory(taskName string) ([]*RunRecord, error) {
+	if _, err := os.Stat(bsm.dbPath); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	db, err := bolt.Open(bsm.dbPath, 0644, &bolt.Options{Timeout: boltOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", bsm.dbPath, err)
+	}
+	defer db.Close()
+
+	var records []*RunRecord
+	err = db.View(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(boltRunsBucket)
+		if runs == nil {
+			return nil
+		}
+		bucket := runs.Bucket([]byte(taskName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var record RunRecord
+			if err := json.Unmarshal(value, &record); err == nil {
+				records = append(records, &record)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
+
+// open opens the database, creating its buckets and importing runtime.json on first use
+func (bsm *BoltStateManager) open() (*bolt.DB, error) {
+	db, err := bolt.Open(bsm.dbPath, 0644, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", bsm.dbPath, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltTasksBucket, boltRunsBucket, boltMetaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return bsm.migrate(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize state database %s: %w", bsm.dbPath, err)
+	}
+	return db, nil
+}
+
+// migrate imports the tasks of runtime.json into a new database
+// runtime.json is left in place, so switching back to the JSON backend keeps
+// the state it had before the migration.
+func (bsm *BoltStateManager) migrate(tx *bolt.Tx) error {
+	meta := tx.Bucket(boltMetaBucket)
+	if meta.Get(boltMigratedKey) != nil {
+		return nil
+	}
+
+	if bsm.migratePath != "" && tx.Bucket(boltTasksBucket).Stats().KeyN == 0 {
+		if _, err := os.Stat(bsm.migratePath); err == nil {
+			state, err := NewFileStateManager(bsm.migratePath).GetRuntimeState()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", bsm.migratePath, err)
+			}
+			if err := writeBoltTasks(tx, state); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Migrated runtime state of %d tasks from %s to %s\n", len(state.Tasks), bsm.migratePath, bsm.dbPath)
+		}
+	}
+
+	return meta.Put(boltMigratedKey, []byte(bsm.migratePath))
+}
+
+// readBoltTasks reads the runtime state from the tasks bucket
+func readBoltTasks(tx *bolt.Tx) (*RuntimeState, error) {
+	state := &RuntimeState{Tasks: make(map[string]*TaskRuntimeInfo)}
+	err := tx.Bucket(boltTasksBucket).ForEach(func(key, value []byte) error {
+		var info TaskRuntimeInfo
+		if err := json.Unmarshal(value, &info); err != nil {
+			return fmt.Errorf("failed to decode runtime state of task %s: %w", key, err)
+		}
+		state.Tasks[string(key)] = &info
+		return nil
+	})
+	return state, err
+}
+
+// writeBoltTasks replaces the content of the tasks bucket with the runtime state
+func writeBoltTasks(tx *bolt.Tx, state *RuntimeState) error {
+	bucket := tx.Bucket(boltTasksBucket)
+
+	var removed [][]byte
+	bucket.ForEach(func(key, value []byte) error {
+		if _, exists := state.Tasks[string(key)]; !exists {
+			removed = append(removed, key)
+		}
+		return nil
+	})
+	for _, key := range removed {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	for name, info := range state.Tasks {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("failed to encode runtime state of task %s: %w", name, err)
+		}
+		if err := bucket.Put([]byte(name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runKey returns the key of a run, big endian so that runs are ordered by sequence
+func runKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
@@ -0,0 +1,177 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestBoltStore creates a bolt store in a temporary directory, migrating runtime.json from the same directory
+func newTestBoltStore(t *testing.T) (*BoltStateManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	return NewBoltStateManager(filepath.Join(dir, "state.db"), filepath.Join(dir, "runtime.json")), dir
+}
+
+// setTaskInfo returns an update storing info for its task
+func setTaskInfo(info TaskRuntimeInfo) func(state *RuntimeState) error {
+	return func(state *RuntimeState) error {
+		state.Tasks[info.Name] = &info
+		return nil
+	}
+}
+
+func TestBoltStateManagerUpdate(t *testing.T) {
+	store, _ := newTestBoltStore(t)
+
+	if err := store.UpdateTaskState("task1", &TaskRuntimeInfo{Name: "task1", Status: "stopped", RetryNum: 2}); err != nil {
+		t.Fatalf("UpdateTaskState() failed: %v", err)
+	}
+
+	// A failing update leaves the state untouched
+	failure := fmt.Errorf("update failed")
+	err := store.Update(func(state *RuntimeState) error {
+		delete(state.Tasks, "task1")
+		return failure
+	})
+	if err != failure {
+		t.Errorf("Update() = %v, want %v", err, failure)
+	}
+
+	state, err := store.GetRuntimeState()
+	if err != nil {
+		t.Fatalf("GetRuntimeState() failed: %v", err)
+	}
+	if info := state.Tasks["task1"]; info == nil || info.RetryNum != 2 {
+		t.Fatalf("task1 = %+v, want RetryNum 2", info)
+	}
+
+	// Removing a task removes it from the database
+	if err := store.SaveRuntimeState(&RuntimeState{Tasks: map[string]*TaskRuntimeInfo{}}); err != nil {
+		t.Fatalf("SaveRuntimeState() failed: %v", err)
+	}
+	state, _ = store.GetRuntimeState()
+	if len(state.Tasks) != 0 {
+		t.Errorf("state has %d tasks, want 0", len(state.Tasks))
+	}
+}
+
+func TestBoltStateManagerRunHistory(t *testing.T) {
+	store, dir := newTestBoltStore(t)
+
+	// Reading the history neither needs nor creates the database
+	if records, err := store.LoadRunHistory("web"); err != nil || len(records) != 0 {
+		t.Fatalf("LoadRunHistory() = %v, %v, want no runs", records, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state.db")); !os.IsNotExist(err) {
+		t.Fatalf("LoadRunHistory() created the database: %v", err)
+	}
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < maxRunHistory+5; i++ {
+		record := &RunRecord{
+			Task:      "web",
+			PID:       1000 + i,
+			StartTime: start.Add(time.Duration(i) * time.Second),
+			EndTime:   start.Add(time.Duration(i)*time.Second + 500*time.Millisecond),
+			ExitCode:  1,
+			Reason:    StopReasonCrash,
+			LastError: "exit status 1",
+		}
+		if err := store.AppendRunRecord(record); err != nil {
+			t.Fatalf("AppendRunRecord() failed: %v", err)
+		}
+	}
+	if err := store.AppendRunRecord(&RunRecord{Task: "worker", PID: 42, Reason: StopReasonUser}); err != nil {
+		t.Fatalf("AppendRunRecord() failed: %v", err)
+	}
+
+	// Only the most recent runs are kept, oldest first
+	records, err := store.LoadRunHistory("web")
+	if err != nil {
+		t.Fatalf("LoadRunHistory() failed: %v", err)
+	}
+	if len(records) != maxRunHistory {
+		t.Fatalf("LoadRunHistory() returned %d runs, want %d", len(records), maxRunHistory)
+	}
+	if records[0].PID != 1005 || records[len(records)-1].PID != 1000+maxRunHistory+4 {
+		t.Errorf("kept runs %d to %d, want 1005 to %d", records[0].PID, records[len(records)-1].PID, 1000+maxRunHistory+4)
+	}
+	if r := records[0]; r.Reason != StopReasonCrash || r.LastError != "exit status 1" || r.Duration() != 500*time.Millisecond {
+		t.Errorf("run = %+v", r)
+	}
+
+	if records, _ := store.LoadRunHistory("worker"); len(records) != 1 || records[0].Reason != StopReasonUser {
+		t.Errorf("LoadRunHistory(worker) = %+v, want the run stopped by the user", records)
+	}
+
+	// Saving the runtime state leaves the run history alone
+	if err := store.SaveRuntimeState(&RuntimeState{Tasks: map[string]*TaskRuntimeInfo{}}); err != nil {
+		t.Fatalf("SaveRuntimeState() failed: %v", err)
+	}
+	if records, _ := store.LoadRunHistory("web"); len(records) != maxRunHistory {
+		t.Errorf("LoadRunHistory() returned %d runs after a state update, want %d", len(records), maxRunHistory)
+	}
+}
+
+func TestBoltStateManagerMigration(t *testing.T) {
+	store, dir := newTestBoltStore(t)
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	legacy := &RuntimeState{Tasks: map[string]*TaskRuntimeInfo{
+		"web":    {Name: "web", Status: "running", PID: 100, StartTime: start, RetryNum: 1},
+		"worker": {Name: "worker", Status: "stopped", ExitCode: 3, StoppedByTaskd: true},
+	}}
+	data, _ := json.Marshal(legacy)
+	if err := os.WriteFile(filepath.Join(dir, "runtime.json"), data, 0644); err != nil {
+		t.Fatalf("failed to write runtime.json: %v", err)
+	}
+
+	state, err := store.GetRuntimeState()
+	if err != nil {
+		t.Fatalf("GetRuntimeState() failed: %v", err)
+	}
+	if len(state.Tasks) != 2 || state.Tasks["web"].PID != 100 || state.Tasks["worker"].ExitCode != 3 {
+		t.Errorf("migrated state = %+v", state.Tasks)
+	}
+
+	// runtime.json is kept, and only imported once
+	if err := store.UpdateTaskState("worker", &TaskRuntimeInfo{Name: "worker", Status: "stopped", ExitCode: 4}); err != nil {
+		t.Fatalf("UpdateTaskState() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "runtime.json")); err != nil {
+		t.Errorf("runtime.json was removed: %v", err)
+	}
+	state, _ = NewBoltStateManager(filepath.Join(dir, "state.db"), filepath.Join(dir, "runtime.json")).GetRuntimeState()
+	if state.Tasks["worker"].ExitCode != 4 {
+		t.Errorf("worker ExitCode = %d, want 4 (runtime.json imported again)", state.Tasks["worker"].ExitCode)
+	}
+}
+
+func TestNewStateStore(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+
+	tests := []struct {
+		backend string
+		file    string
+		bolt    bool
+	}{
+		{"", "runtime.json", false},
+		{"json", "runtime.json", false},
+		{"bolt", "state.db", true},
+		{"unknown", "runtime.json", false},
+	}
+
+	for _, tt := range tests {
+		store, path := NewStateStore(tt.backend)
+		if filepath.Base(path) != tt.file {
+			t.Errorf("NewStateStore(%q) path = %s, want %s", tt.backend, path, tt.file)
+		}
+		if _, isBolt := store.(*BoltStateManager); isBolt != tt.bolt {
+			t.Errorf("NewStateStore(%q) = %T", tt.backend, store)
+		}
+	}
+}
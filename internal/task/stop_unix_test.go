@@ -0,0 +1,228 @@
+//go:build !windows
+
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	taskdconfig "taskd/internal/config"
+)
+
+// trapCommand returns a shell command that runs until stopped, running onTerm on SIGTERM
+func trapCommand(onTerm string) (string, []string) {
+	return "sh", []string{"-c", "trap '" + onTerm + "' TERM; echo ready; while :; do sleep 0.05; done"}
+}
+
+// startTestTask starts a task and waits until it has written its first line of output
+func startTestTask(t *testing.T, config *Config) *Task {
+	t.Helper()
+	config.WorkDir = t.TempDir()
+	config.Stdout = "out.log"
+
+	task := NewTask("stop-test", config)
+	if err := task.Start(); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() {
+		if task.IsRunning() {
+			task.StopWithOptions(StopOptions{Force: true})
+		}
+	})
+
+	// Wait for the trap to be installed
+	outPath := filepath.Join(config.WorkDir, "out.log")
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(outPath); strings.Contains(string(data), "ready") {
+			return task
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("task did not become ready")
+	return nil
+}
+
+func TestStopSendsStopSignal(t *testing.T) {
+	executable, args := trapCommand("echo terminating; exit 0")
+	task := startTestTask(t, &Config{Executable: executable, Args: args, StopTimeout: "5s"})
+
+	if err := task.Stop(); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+
+	info := task.GetInfo()
+	if info.Status != "stopped" {
+		t.Errorf("status = %s, want stopped", info.Status)
+	}
+	if info.ExitCode != 0 {
+		t.Errorf("exit code = %d, want 0 from the trap handler", info.ExitCode)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(task.config.WorkDir, "out.log"))
+	if !strings.Contains(string(data), "terminating") {
+		t.Errorf("output = %q, want the trap handler to have run", data)
+	}
+}
+
+func TestStopKillsAfterTimeout(t *testing.T) {
+	// The task ignores SIGTERM
+	executable, args := trapCommand("")
+	task := startTestTask(t, &Config{Executable: executable, Args: args})
+
+	start := time.Now()
+	if err := task.StopWithOptions(StopOptions{Timeout: 200 * time.Millisecond}); err != nil {
+		t.Fatalf("StopWithOptions() = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < 200*time.Millisecond {
+		t.Errorf("task was killed after %v, before the timeout", elapsed)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("stop took %v, want the override to replace the 10s default", elapsed)
+	}
+
+	info := task.GetInfo()
+	if info.Status != "stopped" || info.ExitCode != -1 {
+		t.Errorf("status = %s, exit code = %d, want stopped with -1", info.Status, info.ExitCode)
+	}
+}
+
+func TestStopForceSkipsStopSignal(t *testing.T) {
+	executable, args := trapCommand("echo terminating; exit 0")
+	task := startTestTask(t, &Config{Executable: executable, Args: args})
+
+	if err := task.StopWithOptions(StopOptions{Force: true}); err != nil {
+		t.Fatalf("StopWithOptions() = %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(task.config.WorkDir, "out.log"))
+	if strings.Contains(string(data), "terminating") {
+		t.Errorf("output = %q, a forced stop should not run the trap handler", data)
+	}
+	if info := task.GetInfo(); info.ExitCode != -1 {
+		t.Errorf("exit code = %d, want -1", info.ExitCode)
+	}
+}
+
+func TestRemoveTaskStopsOutsideManagerLock(t *testing.T) {
+	t.Setenv("TASKD_HOME", t.TempDir())
+	// The task ignores SIGTERM, so stopping it takes its whole stop timeout
+	executable, args := trapCommand("")
+	task := startTestTask(t, &Config{Executable: executable, Args: args, StopTimeout: "1s"})
+	manager := &Manager{
+		tasks:          map[string]*Task{"stop-test": task},
+		builtinHandler: NewBuiltinTaskHandler(),
+		store:          NewFileStateManager(filepath.Join(t.TempDir(), "runtime.json")),
+		storeKey:       taskdconfig.GetStateBackend() + "\x00" + taskdconfig.GetTaskDHome(),
+	}
+
+	removed := make(chan error, 1)
+	go func() { removed <- manager.removeTask("stop-test") }()
+	time.Sleep(100 * time.Millisecond)
+
+	// The other tasks can be used while the removed one is stopping
+	start := time.Now()
+	manager.mu.RLock()
+	_, exists := manager.tasks["stop-test"]
+	manager.mu.RUnlock()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the task map was locked for %v while the task was stopping", elapsed)
+	}
+	if exists {
+		t.Error("the task is still listed while it is being removed")
+	}
+
+	select {
+	case err := <-removed:
+		if err != nil {
+			t.Fatalf("removeTask() = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("removeTask() did not return")
+	}
+	if task.IsRunning() {
+		t.Error("the removed task is still running")
+	}
+}
+
+func TestDaemonClientStopWithOptions(t *testing.T) {
+	manager, client := newTestDaemon(t)
+
+	// The task ignores SIGTERM and has a long stop timeout
+	executable, args := trapCommand("")
+	writeTestTaskConfig(t, "stubborn", &Config{Executable: executable, Args: args, StopTimeout: "1m"})
+	if err := client.StartTask("stubborn"); err != nil {
+		t.Fatalf("StartTask() = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	if err := client.StopTaskWithOptions("stubborn", StopOptions{Timeout: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("StopTaskWithOptions() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("stop took %v, want the timeout override to be used", elapsed)
+	}
+	if manager.isSupervising("stubborn") {
+		t.Error("task is still running after stop")
+	}
+}
+
+func TestStopRecordsRunReason(t *testing.T) {
+	executable, args := trapCommand("exit 0")
+	task := startTestTask(t, &Config{Executable: executable, Args: args})
+
+	if err := task.StopWithOptions(StopOptions{Reason: StopReasonRestart}); err != nil {
+		t.Fatalf("StopWithOptions() = %v", err)
+	}
+	runs := task.takeFinishedRuns()
+	if len(runs) != 1 || runs[0].Reason != StopReasonRestart {
+		t.Fatalf("finished runs = %+v, want one run ended by %s", runs, StopReasonRestart)
+	}
+
+	// A run that exits by itself is a crash unless it exits with 0
+	for _, tt := range []struct {
+		script string
+		reason string
+	}{
+		{"exit 3", StopReasonCrash},
+		{"exit 0", StopReasonExited},
+	} {
+		task.setConfig(&Config{Executable: "sh", Args: []string{"-c", tt.script}, WorkDir: task.config.WorkDir})
+		if err := task.Start(); err != nil {
+			t.Fatalf("Start() = %v", err)
+		}
+		<-task.exited
+		runs := task.takeFinishedRuns()
+		if len(runs) != 1 || runs[0].Reason != tt.reason || runs[0].PID == 0 {
+			t.Errorf("%q: finished runs = %+v, want one run ended by %s", tt.script, runs, tt.reason)
+		}
+	}
+}
+
+func TestDaemonRecordsRunHistory(t *testing.T) {
+	_, client := newTestDaemon(t)
+
+	executable, args := trapCommand("exit 0")
+	writeTestTaskConfig(t, "recorded", &Config{Executable: executable, Args: args})
+	if err := client.StartTask("recorded"); err != nil {
+		t.Fatalf("StartTask() = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := client.StopTask("recorded"); err != nil {
+		t.Fatalf("StopTask() = %v", err)
+	}
+
+	records, err := LoadRunHistory("recorded")
+	if err != nil {
+		t.Fatalf("LoadRunHistory() failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Reason != StopReasonUser || records[0].EndTime.Before(records[0].StartTime) {
+		t.Errorf("history = %+v, want one run stopped by the user", records)
+	}
+}
@@ -2,31 +2,54 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"sync"
-	"syscall"
 	"time"
 )
 
 // Task task instance
 type Task struct {
-	name      string
-	config    *Config
-	process   *os.Process
-	status    string
-	startTime time.Time
-	endTime   time.Time
-	exitCode  int
-	lastError string
-	mu        sync.RWMutex
-	ctx       context.Context
-	cancel    context.CancelFunc
-	onExit    func(taskName string) // Callback when task exits
-	taskIO    *TaskIO              // IO manager
+	name       string
+	config     *Config
+	process    *os.Process
+	status     string
+	startTime  time.Time
+	endTime    time.Time
+	exitCode   int
+	lastError  string
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	onExit     func(taskName string) // Callback when task exits
+	taskIO     *TaskIO               // IO manager
+	exited     chan struct{}         // Closed when the running process has exited
+	group      *processGroup         // Processes started by the running task
+	stopping   bool                  // A stop is waiting for the processes to exit
+	identity   processIdentity       // Identity of the running process, to detect PID reuse
+	stopReason string                // StopReason* of the running stop
+	finished   []*RunRecord          // Ended runs not yet written to the run history
+	runConfig  *Config               // Configuration of the running process, with variables expanded
+	oomKilled  bool                  // The last run was killed by the out-of-memory killer or its memory limit
+	usage      usageWindow           // Resource usage samples of the running process, taken by the daemon
+
+	ready        bool          // The running process passed its readiness probe, or has none
+	readyDone    chan struct{} // Closed once the readiness of the running process is decided
+	readinessErr error         // Why the running process did not become ready
+	probeFailure error         // Failed probe that made taskd stop the running process
+
+	health          string                // Health* of the running process, empty without a liveness probe
+	healthFailures  int                   // Consecutive failed liveness checks
+	lastProbeTime   time.Time             // When the last liveness check completed
+	lastProbeOutput string                // Output of the last liveness check, or its error
+	watchedRun      chan struct{}         // Exited channel of the run whose liveness is checked
+	onHealth        func(taskName string) // Callback when the health of the task changes
+
+	lastScheduledRun time.Time // When the scheduler last started the task
+	nextScheduledRun time.Time // Next run planned by the scheduler, only known in the daemon
 }
 
 // NewTask create a new task
@@ -48,6 +71,27 @@ func (t *Task) SetExitCallback(callback func(taskName string)) {
 	t.onExit = callback
 }
 
+// SetHealthCallback sets the callback function to be called when the health of the task changes
+func (t *Task) SetHealthCallback(callback func(taskName string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onHealth = callback
+}
+
+// getConfig returns the task configuration, which is replaced rather than modified
+func (t *Task) getConfig() *Config {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.config
+}
+
+// setConfig replaces the task configuration, it takes effect on the next start
+func (t *Task) setConfig(config *Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.config = config
+}
+
 // Start start the task
 func (t *Task) Start() error {
 	t.mu.Lock()
@@ -62,139 +106,434 @@ func (t *Task) Start() error {
 		t.ctx, t.cancel = context.WithCancel(context.Background())
 	}
 	
-	// Parse executable and arguments
-	executable, args := t.parseExecutable()
+	// Expand the variables of this run (see expand.go)
+	config, err := t.config.Expand(t.name, time.Now())
+	if err != nil {
+		t.status = "failed"
+		t.lastError = err.Error()
+		return fmt.Errorf("failed to expand variables: %w", err)
+	}
+	
+	// Parse executable and arguments (see cmdline.go)
+	executable, args, err := config.Command()
+	if err != nil {
+		t.status = "failed"
+		t.lastError = err.Error()
+		return err
+	}
 	
 	// Create command
 	cmd := exec.CommandContext(t.ctx, executable, args...)
 	
-	// Set process attributes for proper background execution on Windows
-	// DETACHED_PROCESS creates a process without a console window
-	// CREATE_NEW_PROCESS_GROUP creates a new process group
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | 0x00000008, // 0x00000008 is DETACHED_PROCESS
-		HideWindow:    true,
+	// Set process attributes for proper background execution
+	// (see process_windows.go and process_unix.go, and cmdline_*.go for shell mode)
+	setDetachedProcessAttr(cmd)
+	if config.Shell {
+		setShellCommandLine(cmd, config.Executable)
 	}
 	
-	// Set working directory
-	// Always set working directory - use config value or default to user home
-	workDir := t.config.WorkDir
-	if workDir == "" {
-		if homeDir, err := os.UserHomeDir(); err == nil {
-			workDir = homeDir
-		}
-	}
-	if workDir != "" {
-		cmd.Dir = workDir
+	// Set working directory and environment variables
+	cmd.Dir = processDir(config)
+	cmd.Env, err = processEnv(config)
+	if err != nil {
+		t.status = "failed"
+		t.lastError = err.Error()
+		return err
 	}
 	
-	// Set environment variables
-	if t.config.InheritEnv {
-		cmd.Env = os.Environ()
-	}
-	for _, env := range t.config.Env {
-		cmd.Env = append(cmd.Env, env)
+	// A log readiness probe only reads the output written by this run
+	var logPath string
+	var logOffset int64
+	if config.Readiness.Kind() == ReadinessLog {
+		if path, err := NewPathResolver().ResolvePath(config.Stdout, config.WorkDir); err == nil {
+			logPath = path
+			if info, err := os.Stat(path); err == nil {
+				logOffset = info.Size()
+			}
+		}
 	}
 	
 	// Setup standard input/output
-	if err := t.setupIO(cmd); err != nil {
+	if err := t.setupIO(cmd, config); err != nil {
 		return fmt.Errorf("failed to setup IO: %w", err)
 	}
 	
-	// Start process
-	if err := cmd.Start(); err != nil {
+	// Track the task process and its descendants, with the resource limits of the task
+	// (see procgroup_*.go and limits_*.go)
+	group := newProcessGroup(t.name, config.Limits)
+	
+	// Start process, with its limits applied before it runs
+	if err := group.start(cmd); err != nil {
+		group.release()
+		// The log files and rotating writers of this run are not used by any process
+		if t.taskIO != nil {
+			if closeErr := t.taskIO.Close(); closeErr != nil {
+				fmt.Printf("Warning: failed to close IO resources: %v\n", closeErr)
+			}
+			t.taskIO = nil
+		}
 		t.status = "failed"
 		t.lastError = err.Error()
 		return fmt.Errorf("failed to start process '%s': %w", executable, err)
 	}
 	
+	if group.limitsErr != nil {
+		fmt.Printf("Warning: task %s runs without some of its limits: %v\n", t.name, group.limitsErr)
+	}
+	t.group = group
+	t.stopping = false
+	
+	// The process is not reaped before waitForExit, so its PID cannot be reused yet
+	t.identity, _ = readProcessIdentity(cmd.Process.Pid)
+	
 	t.process = cmd.Process
+	t.runConfig = config
 	t.status = "running"
 	t.startTime = time.Now()
 	t.lastError = ""
 	t.exitCode = 0
+	t.oomKilled = false
 	
 	// Wait for process to exit asynchronously
-	go t.waitForExit(cmd)
+	t.exited = make(chan struct{})
+	go t.waitForExit(cmd, t.exited)
+	
+	// A task with a readiness probe is starting until the probe succeeds
+	t.ready = true
+	t.readyDone = nil
+	t.readinessErr = nil
+	t.probeFailure = nil
+	if probe := config.Readiness; probe.Kind() != "" {
+		t.ready = false
+		t.readyDone = make(chan struct{})
+		check := newReadinessCheck(probe, cmd.Dir, cmd.Env, logPath, logOffset, config.Log.Capture != "")
+		go t.watchReadiness(probe, check, t.exited, t.readyDone)
+	}
+	
+	// A task with a liveness probe is checked periodically once it is ready
+	t.health = ""
+	t.healthFailures = 0
+	t.lastProbeTime = time.Time{}
+	t.lastProbeOutput = ""
+	if config.Liveness.Kind() != "" {
+		t.health = HealthUnknown
+		t.startLivenessLocked()
+	}
 	
 	return nil
 }
 
-// parseExecutable parses the executable string into command and arguments
-func (t *Task) parseExecutable() (string, []string) {
-	// If Args is already set, use Executable as command and Args as arguments
-	if len(t.config.Args) > 0 {
-		return t.config.Executable, t.config.Args
+// watchReadiness runs the readiness probe of a started process
+// A process that is not ready within the probe timeout is stopped and its run fails.
+func (t *Task) watchReadiness(probe ReadinessProbe, check probeCheck, exited <-chan struct{}, done chan struct{}) {
+	defer close(done)
+	
+	err := waitUntilReady(check, probe.TimeoutDuration(), probe.IntervalDuration(), exited)
+	
+	t.mu.Lock()
+	current := t.readyDone == done
+	if current {
+		if err == nil {
+			t.ready = true
+		} else {
+			t.readinessErr = fmt.Errorf("readiness probe failed: %w", err)
+			t.probeFailure = t.readinessErr
+		}
 	}
+	t.mu.Unlock()
 	
-	// Otherwise, parse the Executable string
-	// This is a simple implementation - for more complex parsing, we might need a proper shell parser
-	parts := strings.Fields(t.config.Executable)
-	if len(parts) == 0 {
-		return "", nil
+	if !current || err == nil || errors.Is(err, errExitedBeforeReady) {
+		return
+	}
+	fmt.Printf("Warning: task %s is not ready, stopping it: %v\n", t.name, err)
+	if stopErr := t.StopWithOptions(StopOptions{Reason: StopReasonNotReady}); stopErr != nil {
+		fmt.Printf("Warning: failed to stop task %s: %v\n", t.name, stopErr)
 	}
+}
+
+// WaitReady waits until the last started process has passed its readiness probe
+// It returns why the process did not become ready; a task without a probe is
+// ready once started.
+func (t *Task) WaitReady() error {
+	t.mu.RLock()
+	done := t.readyDone
+	t.mu.RUnlock()
 	
-	if len(parts) == 1 {
-		return parts[0], nil
+	if done == nil {
+		return nil
 	}
+	<-done
 	
-	return parts[0], parts[1:]
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.readyDone == done && t.readinessErr != nil {
+		return t.readinessErr
+	}
+	return nil
+}
+
+// processDir returns the working directory of a task process with config
+// Always set working directory - use config value or default to user home
+func processDir(config *Config) string {
+	workDir := config.WorkDir
+	if workDir == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			workDir = homeDir
+		}
+	}
+	return workDir
+}
+
+// processEnv returns the environment of a task process with config
+// The env files are read and the secrets resolved each time (see envfile.go).
+func processEnv(config *Config) ([]string, error) {
+	var env []string
+	if config.InheritEnv {
+		env = os.Environ()
+	}
+	taskEnv, err := config.LoadEnv(NewSecretStore().Get)
+	if err != nil {
+		return nil, err
+	}
+	return append(env, taskEnv...), nil
+}
+
+// runConfigLocked returns the configuration of the running process with variables
+// expanded, called with t.mu held
+// A process adopted from a previous daemon has its variables expanded again, with
+// the time it started.
+func (t *Task) runConfigLocked() *Config {
+	if t.runConfig != nil {
+		return t.runConfig
+	}
+	if config, err := t.config.Expand(t.name, t.startTime); err == nil {
+		return config
+	}
+	return t.config
+}
+
+// resolvedConfig returns the configuration with variables expanded, as the running
+// process uses it or as the next start would
+func (t *Task) resolvedConfig() (*Config, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	
+	if t.status == "running" {
+		return t.runConfigLocked(), nil
+	}
+	return t.config.Expand(t.name, time.Now())
+}
+
+// StartOptions how a task is started
+type StartOptions struct {
+	Wait bool // Return once the task has passed its readiness probe, or failed it
+}
+
+// StopOptions overrides the configured stop behaviour of a task
+type StopOptions struct {
+	Timeout time.Duration // Grace period before the process is killed, zero uses stop_timeout
+	Force   bool          // Kill the process without sending the stop signal first
+	Reason  string        // StopReason* recorded in the run history, defaults to StopReasonUser
+	Cascade bool          // Stop the running tasks that require this one first
 }
 
+// stopKillWaitTimeout bounds how long Stop waits for a killed process to be reaped,
+// which includes copying its remaining output
+const stopKillWaitTimeout = outputPipeWaitDelay + time.Second
+
 // Stop stop the task
+// The configured stop signal is sent first; the process is killed if it has
+// not exited after the stop timeout.
 func (t *Task) Stop() error {
+	return t.StopWithOptions(StopOptions{})
+}
+
+// StopWithOptions stops the task, overriding the configured timeout or skipping
+// the graceful step
+// The stop signal and the kill reach every process started by the task, and
+// the stop only completes once all of them have exited.
+func (t *Task) StopWithOptions(opts StopOptions) error {
 	t.mu.Lock()
-	defer t.mu.Unlock()
 	
 	if t.status != "running" {
+		t.mu.Unlock()
 		return fmt.Errorf("task is not running")
 	}
 	
-	// Cancel context first
-	t.cancel()
+	process := t.process
+	group := t.group
+	exited := t.exited
+	stopSignal := t.config.StopSignalName()
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = t.config.StopTimeoutDuration()
+	}
+	t.stopping = true
+	t.stopReason = opts.Reason
+	if t.stopReason == "" {
+		t.stopReason = StopReasonUser
+	}
+	t.mu.Unlock()
 	
-	// Send interrupt signal to process
-	if t.process != nil {
-		// Try to kill the process
-		if err := t.process.Kill(); err != nil {
-			// If kill fails, check if process is already dead
-			if t.process.Signal(os.Kill) != nil {
-				// Process is already dead, update status
-				t.status = "stopped"
-				t.endTime = time.Now()
-				t.process = nil
-				t.exitCode = 0
-				t.lastError = ""
-				// Clean up IO resources
-				if t.taskIO != nil {
-					t.taskIO.Close()
-					t.taskIO = nil
-				}
-				return nil
-			}
-			return fmt.Errorf("failed to terminate process: %w", err)
+	defer t.finishStop(group)
+	
+	// Ask the processes to exit and give them time to clean up
+	graceful := !opts.Force && stopSignal != "SIGKILL" && timeout > 0
+	if graceful {
+		if err := group.signal(stopSignal); err != nil {
+			fmt.Printf("Warning: failed to send %s to task %s, killing it: %v\n", stopSignal, t.name, err)
+			graceful = false
 		}
-		
-		// Update status immediately since we've terminated the process
-		t.status = "stopped"
-		t.endTime = time.Now()
-		t.process = nil
-		t.exitCode = -1 // Indicates forced termination
-		t.lastError = "Process terminated by user"
 	}
 	
-	// Clean up IO resources
-	if t.taskIO != nil {
-		if err := t.taskIO.Close(); err != nil {
-			// Log the error but don't fail the stop operation
-			fmt.Printf("Warning: failed to close IO resources: %v\n", err)
+	if graceful {
+		if waitForGroupExit(group, exited, time.Now().Add(timeout)) {
+			t.cancel()
+			return nil
 		}
-		t.taskIO = nil
+		fmt.Printf("Warning: task %s did not exit within %s after %s, killing it\n", t.name, timeout, stopSignal)
+	}
+	
+	leaderRunning := true
+	select {
+	case <-exited:
+		leaderRunning = false
+	default:
+	}
+	
+	// Cancelling the context kills the task process (exec.CommandContext)
+	t.cancel()
+	if err := group.kill(); err != nil {
+		fmt.Printf("Warning: failed to kill the processes of task %s: %v\n", t.name, err)
+		if killErr := process.Kill(); killErr != nil && !errors.Is(killErr, os.ErrProcessDone) && leaderRunning {
+			return fmt.Errorf("failed to terminate process: %w", killErr)
+		}
+	}
+	
+	if !waitForGroupExit(group, exited, time.Now().Add(stopKillWaitTimeout)) {
+		return fmt.Errorf("processes of task %s did not exit after being killed", t.name)
+	}
+	
+	if leaderRunning {
+		t.mu.Lock()
+		t.exitCode = -1 // Indicates forced termination
+		if t.probeFailure == nil {
+			t.lastError = "Process terminated by user"
+		}
+		t.mu.Unlock()
 	}
 	
 	return nil
 }
 
+// applyProbeFailure makes the run of a process stopped because of a failed probe fail,
+// called with t.mu held once the process has exited
+func (t *Task) applyProbeFailure() {
+	if t.stopping && t.probeFailure != nil {
+		t.exitCode = -1
+		t.lastError = t.probeFailure.Error()
+	}
+}
+
+// finishRun queues the record of the run that just ended, called with t.mu held
+// once the exit code is known. The manager writes it to the run history.
+func (t *Task) finishRun(pid int) {
+	reason := t.stopReason
+	if !t.stopping {
+		reason = StopReasonExited
+		if t.oomKilled {
+			reason = StopReasonOOMKill
+		} else if t.exitCode != 0 {
+			reason = StopReasonCrash
+		}
+	}
+	t.finished = append(t.finished, &RunRecord{
+		Task:      t.name,
+		PID:       pid,
+		StartTime: t.startTime,
+		EndTime:   t.endTime,
+		ExitCode:  t.exitCode,
+		Reason:    reason,
+		LastError: t.lastError,
+	})
+}
+
+// takeFinishedRuns returns the runs ended since the last call
+func (t *Task) takeFinishedRuns() []*RunRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runs := t.finished
+	t.finished = nil
+	return runs
+}
+
+// killRemainingProcesses kills the processes of the group still running after
+// the task process exited, and waits for them to exit
+func (t *Task) killRemainingProcesses(group *processGroup) {
+	pids := group.descendants()
+	if len(pids) == 0 {
+		return
+	}
+	fmt.Printf("Task %s exited, killing its remaining processes %v\n", t.name, pids)
+	if err := group.kill(); err != nil {
+		fmt.Printf("Warning: failed to kill the processes of task %s: %v\n", t.name, err)
+		return
+	}
+	
+	deadline := time.Now().Add(stopKillWaitTimeout)
+	for group.alive() {
+		if time.Now().After(deadline) {
+			fmt.Printf("Warning: processes of task %s did not exit after being killed\n", t.name)
+			return
+		}
+		time.Sleep(groupExitPollInterval)
+	}
+}
+
+// finishStop releases the process group once a stop has completed
+func (t *Task) finishStop(group *processGroup) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	t.stopping = false
+	if t.status != "running" {
+		group.release()
+	}
+}
+
+// groupExitPollInterval is how often Stop checks whether the descendants of a task have exited
+const groupExitPollInterval = 50 * time.Millisecond
+
+// waitForGroupExit waits until the task process and all its descendants have exited
+// It returns false if they are still running at the deadline.
+func waitForGroupExit(group *processGroup, exited <-chan struct{}, deadline time.Time) bool {
+	select {
+	case <-exited:
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+	
+	for group.alive() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(groupExitPollInterval)
+	}
+	return true
+}
+
+// descendantPIDs returns the PIDs of the running processes started by the task process
+func (t *Task) descendantPIDs() []int {
+	t.mu.RLock()
+	group := t.group
+	running := t.status == "running"
+	t.mu.RUnlock()
+	
+	if !running || group == nil {
+		return nil
+	}
+	return group.descendants()
+}
 
 // GetInfo get task information
 func (t *Task) GetInfo() *TaskInfo {
@@ -206,15 +545,65 @@ func (t *Task) GetInfo() *TaskInfo {
 		pid = t.process.Pid
 	}
 	
+	// A running process that has not passed its readiness probe is still starting
+	status := t.status
+	if status == "running" && !t.ready {
+		status = "starting"
+	}
+	
+	// Health is only known while the process runs
+	var health string
+	if t.status == "running" {
+		health = t.health
+	}
+	
 	return &TaskInfo{
-		Name:       t.name,
-		Status:     t.status,
-		PID:        pid,
-		StartTime:  t.startTime.Format("2006-01-02 15:04:05"),
-		Executable: t.config.Executable,
-		ExitCode:   t.exitCode,
-		LastError:  t.lastError,
+		Name:             t.name,
+		Status:           status,
+		Health:           health,
+		PID:              pid,
+		StartTime:        formatInfoTime(t.startTime),
+		Executable:       t.config.CommandLine(),
+		ExitCode:         t.exitCode,
+		LastError:        t.lastError,
+		Schedule:         t.config.Schedule,
+		LastScheduledRun: formatInfoTime(t.lastScheduledRun),
+		NextScheduledRun: formatInfoTime(t.nextScheduledRunLocked()),
+		Usage:            t.usageLocked(),
+	}
+}
+
+// nextScheduledRunLocked returns the next scheduled run, called with t.mu held
+// Outside the daemon the next tick is computed from the schedule, without jitter.
+func (t *Task) nextScheduledRunLocked() time.Time {
+	if !t.nextScheduledRun.IsZero() || t.config.Schedule == "" {
+		return t.nextScheduledRun
 	}
+	return t.config.NextScheduledRun(time.Now())
+}
+
+// setNextScheduledRun records the next run planned by the scheduler
+func (t *Task) setNextScheduledRun(next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextScheduledRun = next
+}
+
+// setLastScheduledRun records that the scheduler started the task
+func (t *Task) setLastScheduledRun(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastScheduledRun = at
+}
+
+// formatInfoTime formats a time for TaskInfo in RFC 3339, a task that never started has an empty start time
+// The time zone is kept so that the JSON and YAML output is unambiguous; the CLI
+// shows the times in local time.
+func formatInfoTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
 }
 
 // IsRunning check if task is currently running
@@ -224,6 +613,30 @@ func (t *Task) IsRunning() bool {
 	return t.status == "running"
 }
 
+// markCrashLoop marks a stopped task as crash-looping, so it is not restarted
+// automatically until it is started manually
+func (t *Task) markCrashLoop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if t.status != "running" {
+		t.status = "crash-loop"
+	}
+}
+
+// markRetryFailed marks a task whose automatic restart failed to spawn as
+// stopped with an unknown exit code, so it stays eligible for the next retry
+func (t *Task) markRetryFailed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if t.status == "failed" {
+		t.status = "stopped"
+		t.endTime = time.Now()
+		t.exitCode = -1
+	}
+}
+
 // GetRuntimeInfo get runtime information for persistence
 func (t *Task) GetRuntimeInfo() *TaskRuntimeInfo {
 	t.mu.RLock()
@@ -231,16 +644,23 @@ func (t *Task) GetRuntimeInfo() *TaskRuntimeInfo {
 	
 	// Always return runtime info for all tasks
 	runtimeInfo := &TaskRuntimeInfo{
-		Name:      t.name,
-		Status:    t.status,
-		StartTime: t.startTime,
-		EndTime:   t.endTime,
-		ExitCode:  t.exitCode,
+		Name:             t.name,
+		Status:           t.status,
+		StartTime:        t.startTime,
+		EndTime:          t.endTime,
+		ExitCode:         t.exitCode,
+		LastScheduledRun: t.lastScheduledRun,
+		LastProbeTime:    t.lastProbeTime,
+		LastProbeOutput:  t.lastProbeOutput,
 	}
 	
 	// Set PID only for running tasks
 	if t.status == "running" && t.process != nil {
+		runtimeInfo.Health = t.health
+		runtimeInfo.HealthFailures = t.healthFailures
 		runtimeInfo.PID = t.process.Pid
+		runtimeInfo.ProcessStartTime = t.identity.startTime
+		runtimeInfo.Executable = t.identity.executable
 	}
 	
 	return runtimeInfo
@@ -256,17 +676,26 @@ func (t *Task) restoreRuntimeState(info *TaskRuntimeInfo) {
 	t.startTime = info.StartTime
 	t.endTime = info.EndTime
 	t.exitCode = info.ExitCode
+	t.lastScheduledRun = info.LastScheduledRun
+	t.lastProbeTime = info.LastProbeTime
+	t.lastProbeOutput = info.LastProbeOutput
 	
 	// Check if the process is still running
 	if info.Status == "running" && info.PID > 0 {
 		if process, err := os.FindProcess(info.PID); err == nil {
 			// On Windows, FindProcess always succeeds even for non-existent PIDs
-			// We need to try to do something with the process to check if it's real
-			// For now, we'll assume the process might still be running
+			// The monitor finds out whether the process (and not another one
+			// that reused its PID) is still running
 			t.process = process
+			t.ready = true // The readiness of an adopted process is not checked again
+			t.health = info.Health
+			t.healthFailures = info.HealthFailures
+			t.identity = runtimeIdentity(info)
+			t.group = adoptProcessGroup(info.PID)
+			t.exited = make(chan struct{})
 			
 			// Start monitoring the process
-			go t.monitorExistingProcess(process)
+			go t.monitorExistingProcess(process, t.exited)
 			return
 		}
 		// If we can't find the process, mark it as stopped
@@ -276,9 +705,24 @@ func (t *Task) restoreRuntimeState(info *TaskRuntimeInfo) {
 }
 
 // monitorExistingProcess monitors an existing process
-func (t *Task) monitorExistingProcess(process *os.Process) {
-	// Wait for the process to exit
-	state, err := process.Wait()
+func (t *Task) monitorExistingProcess(process *os.Process, exited chan struct{}) {
+	t.mu.RLock()
+	identity := t.identity
+	t.mu.RUnlock()
+	
+	// Wait for the process to exit, unless it has exited and its PID was reused
+	var state *os.ProcessState
+	err := errProcessNotFound
+	if processStillRunning(process.Pid, identity) {
+		state, err = process.Wait()
+	}
+	if err != nil {
+		// On Unix only the parent can wait for a process, so a process adopted
+		// from a previous daemon or CLI has to be polled until it disappears
+		for processStillRunning(process.Pid, identity) {
+			time.Sleep(time.Second)
+		}
+	}
 	
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -288,12 +732,16 @@ func (t *Task) monitorExistingProcess(process *os.Process) {
 	t.process = nil
 	
 	if err != nil {
-		t.lastError = err.Error()
+		t.lastError = "process exited, exit code is unavailable for a process started by another taskd instance"
 		t.exitCode = -1
 	} else {
 		t.exitCode = state.ExitCode()
 		t.lastError = ""
 	}
+	t.applyProbeFailure()
+	t.finishRun(process.Pid)
+	
+	close(exited)
 	
 	// Notify manager to update runtime state when task exits
 	if t.onExit != nil {
@@ -301,16 +749,19 @@ func (t *Task) monitorExistingProcess(process *os.Process) {
 	}
 }
 
-func (t *Task) setupIO(cmd *exec.Cmd) error {
+// outputPipeWaitDelay bounds how long output is copied after the process exits
+const outputPipeWaitDelay = 5 * time.Second
+
+func (t *Task) setupIO(cmd *exec.Cmd, config *Config) error {
 	// Create task IO configuration
 	ioManager := GetIOManager()
 	
 	// Perform runtime validation before creating IO
-	if err := t.validateRuntimeIO(); err != nil {
+	if err := validateRuntimeIO(config); err != nil {
 		return fmt.Errorf("runtime IO validation failed: %w", err)
 	}
 	
-	taskIO, err := ioManager.CreateTaskIO(t.config)
+	taskIO, err := ioManager.CreateTaskIO(config)
 	if err != nil {
 		return fmt.Errorf("failed to create task IO: %w", err)
 	}
@@ -329,16 +780,21 @@ func (t *Task) setupIO(cmd *exec.Cmd) error {
 		cmd.Stderr = taskIO.Stderr
 	}
 	
+	// Output that is not a plain file (e.g. a RotatingWriter) is copied from a pipe.
+	// Bound how long Wait keeps copying after the process exits, in case a
+	// grandchild inherited the pipe and keeps it open.
+	cmd.WaitDelay = outputPipeWaitDelay
+	
 	return nil
 }
 
 // validateRuntimeIO performs runtime validation of IO configuration
-func (t *Task) validateRuntimeIO() error {
+func validateRuntimeIO(config *Config) error {
 	pathResolver := NewPathResolver()
 	
 	// Validate stdin file exists at runtime
-	if t.config.Stdin != "" {
-		stdinPath, err := pathResolver.ResolvePath(t.config.Stdin, t.config.WorkDir)
+	if config.Stdin != "" {
+		stdinPath, err := pathResolver.ResolvePath(config.Stdin, config.WorkDir)
 		if err != nil {
 			return fmt.Errorf("failed to resolve stdin path: %w", err)
 		}
@@ -372,8 +828,8 @@ func (t *Task) validateRuntimeIO() error {
 	}
 	
 	// Validate output directories exist and are writable
-	if t.config.Stdout != "" {
-		stdoutPath, err := pathResolver.ResolvePath(t.config.Stdout, t.config.WorkDir)
+	if config.Stdout != "" {
+		stdoutPath, err := pathResolver.ResolvePath(config.Stdout, config.WorkDir)
 		if err != nil {
 			return fmt.Errorf("failed to resolve stdout path: %w", err)
 		}
@@ -384,14 +840,14 @@ func (t *Task) validateRuntimeIO() error {
 		}
 	}
 	
-	if t.config.Stderr != "" {
-		stderrPath, err := pathResolver.ResolvePath(t.config.Stderr, t.config.WorkDir)
+	if config.Stderr != "" {
+		stderrPath, err := pathResolver.ResolvePath(config.Stderr, config.WorkDir)
 		if err != nil {
 			return fmt.Errorf("failed to resolve stderr path: %w", err)
 		}
 		
 		// Skip directory check if stderr is the same as stdout (already checked)
-		if t.config.Stdout == "" || stderrPath != t.config.Stdout {
+		if config.Stdout == "" || stderrPath != config.Stdout {
 			// Ensure parent directory exists and is writable
 			if err := pathResolver.EnsureDir(filepath.Dir(stderrPath)); err != nil {
 				return fmt.Errorf("stderr directory validation failed: %w", err)
@@ -402,9 +858,19 @@ func (t *Task) validateRuntimeIO() error {
 	return nil
 }
 
-func (t *Task) waitForExit(cmd *exec.Cmd) {
+func (t *Task) waitForExit(cmd *exec.Cmd, exited chan struct{}) {
 	err := cmd.Wait()
 	
+	// The descendants end with the task process, unless a running stop is
+	// giving them time to exit. Those left running would no longer be tracked
+	// once the group is released.
+	t.mu.RLock()
+	group, stopping := t.group, t.stopping
+	t.mu.RUnlock()
+	if group != nil && !stopping {
+		t.killRemainingProcesses(group)
+	}
+	
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	
@@ -424,6 +890,16 @@ func (t *Task) waitForExit(cmd *exec.Cmd) {
 		t.lastError = ""
 	}
 	
+	// A process that ran into its memory limit failed because of it
+	if t.exitCode != 0 && !t.stopping && t.group != nil {
+		if exceeded := t.group.limitExceeded(); exceeded != "" {
+			t.oomKilled = true
+			t.lastError = exceeded
+		}
+	}
+	t.applyProbeFailure()
+	t.finishRun(cmd.Process.Pid)
+	
 	// Clean up IO resources
 	if t.taskIO != nil {
 		if closeErr := t.taskIO.Close(); closeErr != nil {
@@ -433,6 +909,13 @@ func (t *Task) waitForExit(cmd *exec.Cmd) {
 		t.taskIO = nil
 	}
 	
+	close(exited)
+	
+	// A running stop releases the group once the descendants have exited as well
+	if t.group != nil && !t.stopping {
+		t.group.release()
+	}
+	
 	// Notify manager to update runtime state when task exits
 	// We need a way to callback to the manager to update the runtime state
 	if t.onExit != nil {
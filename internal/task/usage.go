@@ -0,0 +1,208 @@
+package task
+
+import "time"
+
+// usageWindowSize is the number of usage samples kept for a running task, five
+// minutes at the daemon's check interval
+const usageWindowSize = 60
+
+// processStats resource usage of one process
+// The threads and open files are 0 where the platform does not report them.
+type processStats struct {
+	rss       int64         // Resident memory in bytes
+	cpuTime   time.Duration // User and system CPU time
+	threads   int
+	openFiles int // Open file descriptors, open handles on Windows
+}
+
+// usageSample resource usage of the running processes of a task at one moment
+type usageSample struct {
+	at         time.Time
+	stats      processStats
+	cpuPercent float64 // Percent of one CPU used since the previous sample
+}
+
+// usageWindow the last usage samples of a run of a task, oldest first
+type usageWindow struct {
+	run     time.Time // Start time of the run the samples belong to
+	samples []usageSample
+}
+
+// ResourceUsage resource usage of the running processes of a task
+// The current values are those of the last sample taken by the daemon, the
+// ranges cover the samples it kept for the running process.
+type ResourceUsage struct {
+	Uptime     string  `json:"uptime"`
+	RSS        int64   `json:"rss"`         // Resident memory in bytes
+	CPUPercent float64 `json:"cpu_percent"` // Percent of one CPU, may exceed 100 on several cores
+	CPUTime    string  `json:"cpu_time"`    // CPU time used by the running processes
+	CPUSeconds float64 `json:"cpu_seconds"`
+	Threads    int     `json:"threads,omitempty"`    // Not reported on every platform
+	OpenFiles  int     `json:"open_files,omitempty"` // Open file descriptors, open handles on Windows
+	SampledAt  string  `json:"sampled_at"`
+
+	Samples         int        `json:"samples"`
+	Window          string     `json:"window"` // Time between the first and the last sample
+	RSSRange        UsageRange `json:"rss_range"`
+	CPUPercentRange UsageRange `json:"cpu_percent_range"`
+	ThreadsRange    UsageRange `json:"threads_range"`
+	OpenFilesRange  UsageRange `json:"open_files_range"`
+}
+
+// UsageRange minimum, average and maximum of a usage value over the samples
+type UsageRange struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+// readGroupStats sums the resource usage of processes, skipping those that exited
+// It returns false when none of the processes could be read.
+func readGroupStats(pids []int) (processStats, bool) {
+	var total processStats
+	found := false
+	for _, pid := range pids {
+		stats, err := readProcessStats(pid)
+		if err != nil {
+			continue
+		}
+		total.rss += stats.rss
+		total.cpuTime += stats.cpuTime
+		total.threads += stats.threads
+		total.openFiles += stats.openFiles
+		found = true
+	}
+	return total, found
+}
+
+// add records a sample of a run, the samples of an earlier run are dropped
+// The CPU percent is measured since the previous sample, or since the run
+// started for its first sample. It is 0 when a process of the task exited
+// and took its CPU time with it.
+func (w *usageWindow) add(run time.Time, at time.Time, stats processStats) {
+	if !w.run.Equal(run) {
+		w.run = run
+		w.samples = nil
+	}
+
+	since, cpuBefore := run, time.Duration(0)
+	if n := len(w.samples); n > 0 {
+		since, cpuBefore = w.samples[n-1].at, w.samples[n-1].stats.cpuTime
+	}
+	sample := usageSample{at: at, stats: stats}
+	if elapsed := at.Sub(since); elapsed > 0 && stats.cpuTime > cpuBefore {
+		sample.cpuPercent = float64(stats.cpuTime-cpuBefore) / float64(elapsed) * 100
+	}
+
+	if len(w.samples) == usageWindowSize {
+		w.samples = append(w.samples[:0], w.samples[1:]...)
+	}
+	w.samples = append(w.samples, sample)
+}
+
+// usage returns the resource usage of a run at now, nil without samples of the run
+func (w *usageWindow) usage(run time.Time, now time.Time) *ResourceUsage {
+	if !w.run.Equal(run) || len(w.samples) == 0 {
+		return nil
+	}
+
+	first, last := w.samples[0], w.samples[len(w.samples)-1]
+	usage := &ResourceUsage{
+		Uptime:     now.Sub(run).Round(time.Second).String(),
+		RSS:        last.stats.rss,
+		CPUPercent: last.cpuPercent,
+		CPUTime:    last.stats.cpuTime.Round(10 * time.Millisecond).String(),
+		CPUSeconds: last.stats.cpuTime.Seconds(),
+		Threads:    last.stats.threads,
+		OpenFiles:  last.stats.openFiles,
+		SampledAt:  formatInfoTime(last.at),
+		Samples:    len(w.samples),
+		Window:     last.at.Sub(first.at).Round(time.Second).String(),
+	}
+	usage.RSSRange = w.usageRange(func(s usageSample) float64 { return float64(s.stats.rss) })
+	usage.CPUPercentRange = w.usageRange(func(s usageSample) float64 { return s.cpuPercent })
+	usage.ThreadsRange = w.usageRange(func(s usageSample) float64 { return float64(s.stats.threads) })
+	usage.OpenFilesRange = w.usageRange(func(s usageSample) float64 { return float64(s.stats.openFiles) })
+	return usage
+}
+
+// usageRange returns the minimum, average and maximum of a value of the samples
+func (w *usageWindow) usageRange(value func(usageSample) float64) UsageRange {
+	r := UsageRange{Min: value(w.samples[0]), Max: value(w.samples[0])}
+	var sum float64
+	for _, s := range w.samples {
+		v := value(s)
+		if v < r.Min {
+			r.Min = v
+		}
+		if v > r.Max {
+			r.Max = v
+		}
+		sum += v
+	}
+	r.Avg = sum / float64(len(w.samples))
+	return r
+}
+
+// sampleUsage records the resource usage of the running processes of the task
+// The daemon calls it on every check, the last samples are shown by info. It
+// returns the run and the CPU time of the sample, false if none was taken.
+func (t *Task) sampleUsage(now time.Time) (time.Time, time.Duration, bool) {
+	t.mu.RLock()
+	running := t.status == "running" && t.process != nil
+	var pid int
+	if running {
+		pid = t.process.Pid
+	}
+	group, run := t.group, t.startTime
+	t.mu.RUnlock()
+
+	if !running {
+		return time.Time{}, 0, false
+	}
+
+	pids := []int{pid}
+	if group != nil {
+		if members := group.members(); len(members) > 0 {
+			pids = members
+		}
+	}
+	stats, ok := readGroupStats(pids)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// The process may have exited or been restarted while it was sampled
+	if t.status != "running" || !t.startTime.Equal(run) {
+		return time.Time{}, 0, false
+	}
+	t.usage.add(run, now, stats)
+	return run, stats.cpuTime, true
+}
+
+// usageLocked returns the resource usage of the running process, called with t.mu held
+func (t *Task) usageLocked() *ResourceUsage {
+	if t.status != "running" {
+		return nil
+	}
+	return t.usage.usage(t.startTime, time.Now())
+}
+
+// sampleResourceUsage samples the resource usage of the running tasks
+func (m *Manager) sampleResourceUsage() {
+	m.mu.RLock()
+	tasks := make([]*Task, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		tasks = append(tasks, task)
+	}
+	m.mu.RUnlock()
+
+	now := time.Now()
+	for _, task := range tasks {
+		if run, cpuTime, ok := task.sampleUsage(now); ok {
+			m.metrics.observeCPU(task.name, run, cpuTime)
+		}
+	}
+}
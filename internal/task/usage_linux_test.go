@@ -0,0 +1,73 @@
+//go:build linux
+
+package task
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseProcStatUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		stat string
+		want processStats
+		ok   bool
+	}{
+		{
+			"running",
+			"7 (my (odd) cmd) S 1 7 7 0 -1 4194304 139 0 0 0 250 50 0 0 20 0 4 0 254180 2560000 300",
+			processStats{rss: 300 * 4096, cpuTime: 3 * time.Second, threads: 4},
+			true,
+		},
+		{"zombie", "7 (sh) Z 1 7 7 0 -1 4194304 139 0 0 0 250 50 0 0 20 0 1 0 254180 0 0", processStats{}, false},
+		{"truncated", "7 (sh) S 1 7 7 0 -1", processStats{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProcStatUsage(tt.stat, 4096)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseProcStatUsage(%q) = %+v, %v, want %+v, %v", tt.stat, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReadProcessStats(t *testing.T) {
+	stats, err := readProcessStats(os.Getpid())
+	if err != nil {
+		t.Fatalf("readProcessStats(self) = %v", err)
+	}
+	if stats.rss <= 0 || stats.threads < 1 || stats.openFiles < 1 {
+		t.Errorf("readProcessStats(self) = %+v, want memory, threads and open files", stats)
+	}
+
+	if _, err := readProcessStats(1 << 30); err != errProcessNotFound {
+		t.Errorf("readProcessStats(unknown PID) = %v, want errProcessNotFound", err)
+	}
+}
+
+func TestSampleUsage(t *testing.T) {
+	executable, args := trapCommand("exit 0")
+	task := startTestTask(t, &Config{Executable: executable, Args: args})
+
+	task.sampleUsage(time.Now())
+	task.sampleUsage(time.Now())
+
+	usage := task.GetInfo().Usage
+	if usage == nil {
+		t.Fatal("GetInfo().Usage = nil after sampling the running task")
+	}
+	if usage.Samples != 2 || usage.RSS <= 0 || usage.Threads < 1 {
+		t.Errorf("GetInfo().Usage = %+v, want 2 samples with memory and threads", usage)
+	}
+
+	if err := task.StopWithOptions(StopOptions{Force: true}); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if usage := task.GetInfo().Usage; usage != nil {
+		t.Errorf("GetInfo().Usage of a stopped task = %+v, want nil", usage)
+	}
+}
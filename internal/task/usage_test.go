@@ -0,0 +1,67 @@
+package task
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestUsageWindowAdd(t *testing.T) {
+	run := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	var w usageWindow
+
+	// The first sample is measured since the run started
+	w.add(run, run.Add(10*time.Second), processStats{rss: 100, cpuTime: 2 * time.Second, threads: 2})
+	w.add(run, run.Add(15*time.Second), processStats{rss: 300, cpuTime: 7 * time.Second, threads: 4})
+	// A child exited and took its CPU time with it
+	w.add(run, run.Add(20*time.Second), processStats{rss: 200, cpuTime: 6 * time.Second, threads: 3})
+
+	want := []float64{20, 100, 0}
+	for i, sample := range w.samples {
+		if math.Abs(sample.cpuPercent-want[i]) > 1e-9 {
+			t.Errorf("sample %d cpuPercent = %v, want %v", i, sample.cpuPercent, want[i])
+		}
+	}
+
+	usage := w.usage(run, run.Add(21*time.Second))
+	if usage == nil {
+		t.Fatal("usage() = nil, want the usage of the run")
+	}
+	if usage.RSS != 200 || usage.Threads != 3 || usage.CPUTime != "6s" || usage.Uptime != "21s" {
+		t.Errorf("usage() = %+v, want the values of the last sample", usage)
+	}
+	if usage.Samples != 3 || usage.Window != "10s" {
+		t.Errorf("usage() samples = %d over %s, want 3 over 10s", usage.Samples, usage.Window)
+	}
+	if want := (UsageRange{Min: 100, Avg: 200, Max: 300}); usage.RSSRange != want {
+		t.Errorf("RSSRange = %+v, want %+v", usage.RSSRange, want)
+	}
+	if want := (UsageRange{Min: 0, Avg: 40, Max: 100}); usage.CPUPercentRange != want {
+		t.Errorf("CPUPercentRange = %+v, want %+v", usage.CPUPercentRange, want)
+	}
+
+	if usage := w.usage(run.Add(time.Minute), run.Add(2*time.Minute)); usage != nil {
+		t.Errorf("usage() of another run = %+v, want nil", usage)
+	}
+}
+
+func TestUsageWindowKeepsLastSamples(t *testing.T) {
+	run := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	var w usageWindow
+	for i := 1; i <= usageWindowSize+5; i++ {
+		w.add(run, run.Add(time.Duration(i)*time.Second), processStats{rss: int64(i)})
+	}
+	if len(w.samples) != usageWindowSize {
+		t.Fatalf("window has %d samples, want %d", len(w.samples), usageWindowSize)
+	}
+	if w.samples[0].stats.rss != 6 {
+		t.Errorf("oldest sample rss = %d, want 6", w.samples[0].stats.rss)
+	}
+
+	// A new run starts with an empty window
+	next := run.Add(time.Hour)
+	w.add(next, next.Add(time.Second), processStats{rss: 1})
+	if len(w.samples) != 1 || !w.run.Equal(next) {
+		t.Errorf("window after restart has %d samples of run %v, want 1 of %v", len(w.samples), w.run, next)
+	}
+}